@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nawa/cryptoexchange-dashboard/usecase"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+type BackfillCommand struct {
+	cobra.Command
+	ExchangeAPICommand
+	DBCommand
+	Step           time.Duration
+	CandleInterval time.Duration
+}
+
+var (
+	backfillCmd = &BackfillCommand{
+		Command: cobra.Command{
+			Use:   "backfill",
+			Short: "Reconstructs balances before the first sync",
+			Long: "Rebuilds balances before the first sync from the trade, deposit and withdrawal history " +
+				"and values them by past market prices. Reconstructed balances are marked in the database",
+		},
+	}
+)
+
+func init() {
+	err := backfillCmd.ExchangeAPICommand.BindArgs(&backfillCmd.Command)
+	if err != nil {
+		panic(err)
+	}
+	err = backfillCmd.DBCommand.BindArgs(&backfillCmd.Command)
+	if err != nil {
+		panic(err)
+	}
+	backfillCmd.Command.Flags().DurationVar(&backfillCmd.Step, "step", 24*time.Hour, "Period between reconstructed balances")
+	backfillCmd.Command.Flags().DurationVar(&backfillCmd.CandleInterval, "candles-interval", 24*time.Hour,
+		"Interval of market candles downloaded to value balances: 1m, 5m, 30m, 1h or 24h, 0 uses saved prices only")
+
+	backfillCmd.PreRunE = backfillCmd.preRun
+	backfillCmd.RunE = backfillCmd.run
+	rootCmd.AddCommand(&backfillCmd.Command)
+}
+
+func (c *BackfillCommand) preRun(_ *cobra.Command, _ []string) error {
+	err := c.DBCommand.CheckArgs()
+	if err != nil {
+		return err
+	}
+
+	if c.Step < time.Minute {
+		return errors.New("--step is < 1m")
+	}
+	return c.ExchangeAPICommand.CheckArgs()
+}
+
+func (c *BackfillCommand) run(_ *cobra.Command, _ []string) error {
+	exchange, err := c.CreateExchange()
+	if err != nil {
+		return err
+	}
+
+	balanceStorage, err := c.CreateBalanceStorage()
+	if err != nil {
+		return err
+	}
+
+	tradeStorage, err := c.CreateTradeStorage()
+	if err != nil {
+		return err
+	}
+
+	transferStorage, err := c.CreateTransferStorage()
+	if err != nil {
+		return err
+	}
+
+	marketStorage, err := c.CreateMarketStorage()
+	if err != nil {
+		return err
+	}
+
+	// the history is walked back from the current balance, so it must be complete up to now
+	err = usecase.NewTradeUsecase(exchange, tradeStorage).SyncFromExchange()
+	if err != nil {
+		return err
+	}
+	err = usecase.NewTransferUsecase(exchange, transferStorage).SyncFromExchange()
+	if err != nil {
+		return err
+	}
+
+	backfillUsecase := usecase.NewBackfillUsecase(exchange, balanceStorage, tradeStorage, transferStorage,
+		usecase.NewMarketUsecase(exchange, marketStorage))
+	count, err := backfillUsecase.Reconstruct(c.Step, c.CandleInterval)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%d balance snapshots reconstructed\n", count)
+	return nil
+}
@@ -2,11 +2,17 @@ package cmd
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"math"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/nawa/cryptoexchange-dashboard/http"
+	"github.com/nawa/cryptoexchange-dashboard/storage"
+	"github.com/nawa/cryptoexchange-dashboard/storage/memory"
 	"github.com/nawa/cryptoexchange-dashboard/usecase"
 
 	log "github.com/Sirupsen/logrus"
@@ -16,10 +22,18 @@ import (
 type HTTPCommand struct {
 	cobra.Command
 	ExchangeAPICommand
-	MongoCommand
-	HTTPAddress string
+	DBCommand
+	NotifiersCommand
+	HTTPAddress   string
+	Demo          bool
+	SyncPeriod    int
+	UpdatesPeriod int
+	TargetsPath   string
 }
 
+// targetsTolerance is the allowed rounding error of the sum of target weights
+const targetsTolerance = 0.001
+
 var (
 	httpCmd = &HTTPCommand{
 		Command: cobra.Command{
@@ -31,7 +45,7 @@ var (
 )
 
 func init() {
-	err := httpCmd.MongoCommand.BindArgs(&httpCmd.Command)
+	err := httpCmd.DBCommand.BindArgs(&httpCmd.Command)
 	if err != nil {
 		panic(err)
 	}
@@ -41,7 +55,17 @@ func init() {
 		panic(err)
 	}
 
+	// rules are validated against notifiers of sync command
+	err = httpCmd.NotifiersCommand.BindArgs(&httpCmd.Command)
+	if err != nil {
+		panic(err)
+	}
+
 	httpCmd.Flags().StringVarP(&httpCmd.HTTPAddress, "addr", "a", "localhost:8080", "Service address")
+	httpCmd.Flags().BoolVar(&httpCmd.Demo, "demo", false, "Demo mode: keeps balances in memory and syncs them in the same process, --db-url is not needed")
+	httpCmd.Flags().IntVarP(&httpCmd.SyncPeriod, "period", "p", 10, "Synchronization period in sec for demo mode")
+	httpCmd.Flags().IntVar(&httpCmd.UpdatesPeriod, "updates-period", 30, "Period in sec of checking new balances and order profit pushed to websocket clients")
+	httpCmd.Flags().StringVar(&httpCmd.TargetsPath, "targets", "", "YAML or TOML file of target weights of coins for rebalancing advice, e.g. 'BTC: 0.5'")
 
 	httpCmd.PreRunE = httpCmd.preRun
 	httpCmd.RunE = httpCmd.run
@@ -49,26 +73,120 @@ func init() {
 }
 
 func (c *HTTPCommand) preRun(_ *cobra.Command, _ []string) error {
-	return c.ExchangeAPICommand.CheckArgs()
+	if !c.Demo {
+		err := c.DBCommand.CheckArgs()
+		if err != nil {
+			return err
+		}
+	}
+	err := c.ExchangeAPICommand.CheckArgs()
+	if err != nil {
+		return err
+	}
+	return c.NotifiersCommand.CheckArgs()
 }
 
 func (c *HTTPCommand) run(_ *cobra.Command, _ []string) error {
-	exchange, err := c.CreateExchange()
+	targets, err := c.readTargets()
 	if err != nil {
 		return err
 	}
 
-	balanceStorage, err := c.CreateBalanceStorage()
+	// open orders are shown with live rates of their markets
+	exchange, stopStreaming, err := c.CreateStreamingExchange()
 	if err != nil {
 		return err
 	}
+	defer stopStreaming()
+
+	var (
+		balanceStorage  storage.BalanceStorage
+		tradeStorage    storage.TradeStorage
+		transferStorage storage.TransferStorage
+		alertStorage    storage.AlertStorage
+		marketStorage   storage.MarketStorage
+	)
+	if c.Demo {
+		balanceStorage = memory.NewBalanceStorage()
+		tradeStorage = memory.NewTradeStorage()
+		transferStorage = memory.NewTransferStorage()
+		alertStorage = memory.NewAlertStorage()
+		marketStorage = memory.NewMarketStorage()
+	} else {
+		balanceStorage, err = c.CreateBalanceStorage()
+		if err != nil {
+			return err
+		}
+		tradeStorage, err = c.CreateTradeStorage()
+		if err != nil {
+			return err
+		}
+		transferStorage, err = c.CreateTransferStorage()
+		if err != nil {
+			return err
+		}
+		alertStorage, err = c.CreateAlertStorage()
+		if err != nil {
+			return err
+		}
+		marketStorage, err = c.CreateMarketStorage()
+		if err != nil {
+			return err
+		}
+	}
 
 	ctx, ctxCancel := context.WithCancel(context.Background())
 
-	balanceUsecase := usecase.NewBalanceUsecase(exchange, balanceStorage)
-	orderUsecase := usecase.NewOrderUsecase(exchange)
+	balanceUsecase := usecase.NewBalanceUsecase(exchange, balanceStorage, transferStorage, marketStorage)
+	// realized profit is calculated from trades saved by sync command
+	orderUsecase := usecase.NewOrderUsecase(exchange, tradeStorage, balanceStorage)
+
+	if c.Demo {
+		// the first balance is available right after the start
+		err = balanceUsecase.SyncFromExchange()
+		if err != nil {
+			ctxCancel()
+			return err
+		}
+
+		stop, err := balanceUsecase.StartSyncFromExchangePeriodically(time.Second * time.Duration(c.SyncPeriod))
+		if err != nil {
+			ctxCancel()
+			return err
+		}
+		defer stop()
+
+		// performance and realized profit are calculated with transfers and trades known at the start,
+		// errors are logged by usecases
+		go usecase.NewTransferUsecase(exchange, transferStorage).SyncFromExchange()
+		go usecase.NewTradeUsecase(exchange, tradeStorage).SyncFromExchange()
+	}
 
-	server := http.NewServer(balanceUsecase, orderUsecase)
+	// orders are requested from the exchange only while clients are subscribed to updates
+	updateUsecase := usecase.NewUpdateUsecase(balanceUsecase, orderUsecase)
+	stopUpdates, err := updateUsecase.StartWatchingPeriodically(time.Second * time.Duration(c.UpdatesPeriod))
+	if err != nil {
+		ctxCancel()
+		return err
+	}
+	defer stopUpdates()
+
+	// alert rules are evaluated by sync command, the server only manages them except demo mode
+	alertRuleUsecase := usecase.NewAlertRuleUsecase(alertStorage, c.Channels())
+	if c.Demo {
+		alertUsecase := usecase.NewStoredAlertUsecase(exchange, alertStorage, c.CreateNotifier())
+		stopAlerts, err := alertUsecase.StartEvaluatingPeriodically(time.Second * time.Duration(c.SyncPeriod))
+		if err != nil {
+			ctxCancel()
+			return err
+		}
+		defer stopAlerts()
+	}
+
+	marketUsecase := usecase.NewMarketUsecase(exchange, marketStorage)
+	server := http.NewServer(balanceUsecase, orderUsecase, updateUsecase, alertRuleUsecase, marketUsecase,
+		usecase.NewAnalyticsUsecase(balanceUsecase), usecase.NewBenchmarkUsecase(balanceStorage, marketUsecase),
+		usecase.NewAllocationUsecase(exchange, balanceStorage, targets))
 
 	go func() {
 		defer ctxCancel()
@@ -92,3 +210,31 @@ func (c *HTTPCommand) run(_ *cobra.Command, _ []string) error {
 	log.Info("Server stopped")
 	return nil
 }
+
+// readTargets reads target weights of coins, they're positive and sum to 1. No weights without the file
+func (c *HTTPCommand) readTargets() (map[string]float64, error) {
+	if c.TargetsPath == "" {
+		return nil, nil
+	}
+
+	var targets map[string]float64
+	err := readConfigFile(c.TargetsPath, &targets)
+	if err != nil {
+		return nil, fmt.Errorf("can't read targets file: %s", err)
+	}
+	if len(targets) == 0 {
+		return nil, errors.New("targets file has no weights")
+	}
+
+	var sum float64
+	for currency, weight := range targets {
+		if weight <= 0 {
+			return nil, fmt.Errorf("targets file: weight of '%s' isn't positive", currency)
+		}
+		sum += weight
+	}
+	if math.Abs(sum-1) > targetsTolerance {
+		return nil, fmt.Errorf("targets file: weights sum to %v instead of 1", sum)
+	}
+	return targets, nil
+}
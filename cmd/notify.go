@@ -2,36 +2,90 @@ package cmd
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
 	"syscall"
 	"time"
 
-	"github.com/0xAX/notificator"
+	"github.com/BurntSushi/toml"
 	log "github.com/Sirupsen/logrus"
 	"github.com/pkg/errors"
 	"github.com/shopspring/decimal"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
 
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/notification"
 	"github.com/nawa/cryptoexchange-dashboard/storage"
-	"github.com/nawa/cryptoexchange-dashboard/storage/exchange"
+	"github.com/nawa/cryptoexchange-dashboard/storage/memory"
+	"github.com/nawa/cryptoexchange-dashboard/usecase"
 )
 
 type NotifyCommand struct {
 	cobra.Command
 	ExchangeAPICommand
+	DBCommand
 	Market        string
 	GreaterThan   float64
 	LessThan      float64
 	RefreshPeriod int
+	RulesFile     string
+
+	rules     []domain.AlertRule
+	notifiers map[string]notification.Notifier
+}
+
+type alertsConfig struct {
+	Notifiers []notifierConfig `yaml:"notifiers" toml:"notifiers"`
+	Alerts    []alertConfig    `yaml:"alerts" toml:"alerts"`
+}
+
+// notifiersConfig is the file with notifiers only, alert rules are managed by REST API.
+// Default is the name of the notifier of rules without notifiers, the desktop one if it's empty
+type notifiersConfig struct {
+	Default   string           `yaml:"default" toml:"default"`
+	Notifiers []notifierConfig `yaml:"notifiers" toml:"notifiers"`
+}
+
+type notifierConfig struct {
+	Name string `yaml:"name" toml:"name"`
+	Type string `yaml:"type" toml:"type"`
+	// webhook and slack
+	URL  string `yaml:"url" toml:"url"`
+	Body string `yaml:"body" toml:"body"`
+	// telegram
+	Token  string `yaml:"token" toml:"token"`
+	ChatID string `yaml:"chat_id" toml:"chat_id"`
+	// smtp
+	Addr     string   `yaml:"addr" toml:"addr"`
+	Username string   `yaml:"username" toml:"username"`
+	Password string   `yaml:"password" toml:"password"`
+	From     string   `yaml:"from" toml:"from"`
+	To       []string `yaml:"to" toml:"to"`
+}
+
+type alertConfig struct {
+	ID       string     `yaml:"id" toml:"id"`
+	Type     string     `yaml:"type" toml:"type"`
+	Market   string     `yaml:"market" toml:"market"`
+	Currency string     `yaml:"currency" toml:"currency"`
+	Quote    string     `yaml:"quote" toml:"quote"`
+	Value    alertValue `yaml:"value" toml:"value"`
+	Window   string     `yaml:"window" toml:"window"`
+	Cooldown string     `yaml:"cooldown" toml:"cooldown"`
+	Notify   []string   `yaml:"notify" toml:"notify"`
 }
 
 var (
 	notifyCmd = &NotifyCommand{
 		Command: cobra.Command{
 			Use:   "notify",
-			Short: "Notifies when price of coin is reached some value",
-			Long:  "Notifies when price of coin is reached some value. \nATTENTION: would be more secure is to generate keys with readonly permission",
+			Short: "Notifies when price of coin is reached some value or alert rules fire",
+			Long:  "Notifies when price of coin is reached some value or, with --rules, continuously evaluates alert rules. \nATTENTION: would be more secure is to generate keys with readonly permission",
 		},
 	}
 )
@@ -41,15 +95,15 @@ func init() {
 	if err != nil {
 		panic(err)
 	}
-	notifyCmd.Command.Flags().IntVarP(&notifyCmd.RefreshPeriod, "period", "p", 10, "Refresh period in sec")
-	notifyCmd.Command.Flags().StringVarP(&notifyCmd.Market, "market", "m", "", "Market name, for example 'BTC-ETH'")
-	notifyCmd.Command.Flags().Float64Var(&notifyCmd.GreaterThan, "gt", 0, "Notify when price is greater than value")
-	notifyCmd.Command.Flags().Float64Var(&notifyCmd.LessThan, "lt", 0, "Notify when price is less than value")
-
-	err = notifyCmd.MarkFlagRequired("market")
+	err = notifyCmd.DBCommand.BindArgs(&notifyCmd.Command)
 	if err != nil {
 		panic(err)
 	}
+	notifyCmd.Command.Flags().IntVarP(&notifyCmd.RefreshPeriod, "period", "p", 10, "Refresh period in sec for exchanges without streaming of market updates and evaluation period of alert rules")
+	notifyCmd.Command.Flags().StringVarP(&notifyCmd.Market, "market", "m", "", "Market name in the 'QUOTE-BASE' format, for example 'BTC-ETH'")
+	notifyCmd.Command.Flags().Float64Var(&notifyCmd.GreaterThan, "gt", 0, "Notify when price is greater than value")
+	notifyCmd.Command.Flags().Float64Var(&notifyCmd.LessThan, "lt", 0, "Notify when price is less than value")
+	notifyCmd.Command.Flags().StringVar(&notifyCmd.RulesFile, "rules", "", "Path to YAML or TOML file with alert rules, replaces --market, --gt and --lt. Alert states are kept in --db-url if it's provided")
 
 	notifyCmd.PreRunE = notifyCmd.preRun
 	notifyCmd.RunE = notifyCmd.run
@@ -62,6 +116,17 @@ func (c *NotifyCommand) preRun(_ *cobra.Command, _ []string) error {
 		return err
 	}
 
+	if c.RulesFile != "" {
+		if c.Market != "" || c.GreaterThan != 0 || c.LessThan != 0 {
+			return errors.New("--rules can't be used with --market, --gt or --lt")
+		}
+		return c.loadRules()
+	}
+
+	if c.Market == "" {
+		return errors.New("--market or --rules must be defined")
+	}
+
 	if c.GreaterThan == 0 && c.LessThan == 0 {
 		return errors.New("--gt or --lt must be defined")
 	}
@@ -79,30 +144,29 @@ func (c *NotifyCommand) preRun(_ *cobra.Command, _ []string) error {
 }
 
 func (c *NotifyCommand) run(_ *cobra.Command, _ []string) error {
-	exchange := exchange.NewBittrexExchange(c.APIKey, c.APISecret)
-	err := exchange.Ping()
+	exchange, stopStreaming, err := c.CreateStreamingExchange()
 	if err != nil {
 		return err
 	}
-	ticker := time.NewTicker(time.Second * time.Duration(c.RefreshPeriod))
+	defer stopStreaming()
 
-	resultCh := make(chan error, 1)
-	go func() {
-		for range ticker.C {
-			lastPrice, err := c.checkMarketLastPrice(exchange)
-			if err != nil {
-				log.Error(err)
-				continue
-			}
+	if len(c.rules) > 0 {
+		return c.runRules(exchange)
+	}
 
-			if lastPrice != nil {
-				ticker.Stop()
-				err = c.sendNotification(*lastPrice)
-				resultCh <- err
-				return
-			}
+	resultCh := make(chan error, 1)
+	if watcher, ok := exchange.(storage.MarketWatcher); ok {
+		updates, cancel, err := watcher.WatchMarket(c.Market)
+		if err == nil {
+			defer cancel()
+			go c.watchMarket(updates, resultCh)
+		} else {
+			log.WithError(err).Warn("market updates can't be streamed, polling")
+			go c.pollMarket(exchange, resultCh)
 		}
-	}()
+	} else {
+		go c.pollMarket(exchange, resultCh)
+	}
 
 	exitC := make(chan os.Signal, 1)
 	signal.Notify(exitC,
@@ -124,25 +188,309 @@ func (c *NotifyCommand) run(_ *cobra.Command, _ []string) error {
 	return nil
 }
 
+// runRules evaluates alert rules every refresh period until the command is stopped
+func (c *NotifyCommand) runRules(exchange storage.Exchange) error {
+	var (
+		alertStorage storage.AlertStorage
+		err          error
+	)
+	if c.DBURL != "" {
+		alertStorage, err = c.CreateAlertStorage()
+		if err != nil {
+			return err
+		}
+	} else {
+		log.Warn("--db-url isn't provided, alert states are kept in memory and rules can fire again after restart")
+		alertStorage = memory.NewAlertStorage()
+	}
+
+	notifier := notification.NewRouter(c.notifiers, c.notifiers[notification.DesktopChannel])
+	alertUsecase := usecase.NewAlertUsecase(c.rules, exchange, alertStorage, notifier)
+
+	// the first evaluation starts to collect prices of percent change rules right away
+	_ = alertUsecase.Evaluate()
+	stop, err := alertUsecase.StartEvaluatingPeriodically(time.Second * time.Duration(c.RefreshPeriod))
+	if err != nil {
+		return err
+	}
+	defer stop()
+
+	exitC := make(chan os.Signal, 1)
+	signal.Notify(exitC,
+		syscall.SIGHUP,
+		syscall.SIGINT,
+		syscall.SIGTERM,
+		syscall.SIGQUIT)
+
+	<-exitC
+	fmt.Println("Shutting down...")
+	return nil
+}
+
+// loadRules reads alert rules from the YAML file or the TOML one with .toml extension
+func (c *NotifyCommand) loadRules() error {
+	var config alertsConfig
+	err := readConfigFile(c.RulesFile, &config)
+	if err != nil {
+		return fmt.Errorf("can't read rules file: %s", err)
+	}
+
+	if len(config.Alerts) == 0 {
+		return errors.New("rules file doesn't contain any alert")
+	}
+
+	notifiers, err := createNotifiers(config.Notifiers)
+	if err != nil {
+		return err
+	}
+
+	ids := make(map[string]bool)
+	rules := make([]domain.AlertRule, len(config.Alerts))
+	for i, alert := range config.Alerts {
+		rule, err := alert.rule()
+		if err == nil {
+			err = rule.Validate()
+		}
+		if err != nil {
+			return fmt.Errorf("alert #%d: %s", i+1, err)
+		}
+
+		if ids[rule.ID] {
+			return fmt.Errorf("alert '%s': id is duplicated", rule.ID)
+		}
+		ids[rule.ID] = true
+
+		for _, name := range rule.Channels {
+			if _, ok := notifiers[name]; !ok {
+				return fmt.Errorf("alert '%s': notifier '%s' isn't defined", rule.ID, name)
+			}
+		}
+		rules[i] = *rule
+	}
+
+	c.rules = rules
+	c.notifiers = notifiers
+	return nil
+}
+
+// readConfigFile decodes the YAML file or the TOML one with .toml extension. Unknown fields are rejected
+func readConfigFile(path string, config interface{}) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if strings.ToLower(filepath.Ext(path)) == ".toml" {
+		meta, err := toml.Decode(string(data), config)
+		if err == nil && len(meta.Undecoded()) > 0 {
+			err = fmt.Errorf("unknown field '%s'", meta.Undecoded()[0])
+		}
+		return err
+	}
+	return yaml.UnmarshalStrict(data, config)
+}
+
+// NotifiersCommand reads notifiers of alert rules managed by REST API
+type NotifiersCommand struct {
+	NotifiersFile string
+
+	notifiers       map[string]notification.Notifier
+	defaultNotifier string
+}
+
+func (c *NotifiersCommand) BindArgs(cobraCmd *cobra.Command) error {
+	cobraCmd.Flags().StringVar(&c.NotifiersFile, "notifiers", "", "Path to YAML or TOML file with notifiers of alert rules, alerts without notifiers are sent to its 'default' notifier or to the desktop")
+	return nil
+}
+
+// CheckArgs reads notifiers from the file in the same format as the 'notifiers' section of alert rules of notify command
+func (c *NotifiersCommand) CheckArgs() error {
+	var config notifiersConfig
+	if c.NotifiersFile != "" {
+		err := readConfigFile(c.NotifiersFile, &config)
+		if err != nil {
+			return fmt.Errorf("can't read notifiers file: %s", err)
+		}
+	}
+
+	notifiers, err := createNotifiers(config.Notifiers)
+	if err != nil {
+		return err
+	}
+
+	c.defaultNotifier = notification.DesktopChannel
+	if config.Default != "" {
+		if _, ok := notifiers[config.Default]; !ok {
+			return fmt.Errorf("default notifier '%s' isn't defined", config.Default)
+		}
+		c.defaultNotifier = config.Default
+	}
+	c.notifiers = notifiers
+	return nil
+}
+
+// Channels returns names of notifiers rules can be sent to
+func (c *NotifiersCommand) Channels() []string {
+	channels := make([]string, 0, len(c.notifiers))
+	for name := range c.notifiers {
+		channels = append(channels, name)
+	}
+	sort.Strings(channels)
+	return channels
+}
+
+// CreateNotifier creates the notifier sending alerts to channels of their rules or to the default notifier
+func (c *NotifiersCommand) CreateNotifier() notification.Notifier {
+	return notification.NewRouter(c.notifiers, c.notifiers[c.defaultNotifier])
+}
+
+// createNotifiers creates configured notifiers by their names, the desktop one is always available
+func createNotifiers(configs []notifierConfig) (map[string]notification.Notifier, error) {
+	notifiers := map[string]notification.Notifier{
+		notification.DesktopChannel: notification.NewDesktopNotifier(),
+	}
+	for i, n := range configs {
+		if n.Name == "" {
+			return nil, fmt.Errorf("notifier #%d: name is empty", i+1)
+		}
+		if _, ok := notifiers[n.Name]; ok {
+			return nil, fmt.Errorf("notifier '%s': name is duplicated or reserved", n.Name)
+		}
+
+		notifier, err := n.notifier()
+		if err != nil {
+			return nil, fmt.Errorf("notifier '%s': %s", n.Name, err)
+		}
+		notifiers[n.Name] = notifier
+	}
+	return notifiers, nil
+}
+
+// notifier creates the notifier of the type. URLs, tokens and credentials can refer environment variables as $VAR or ${VAR}
+func (n notifierConfig) notifier() (notification.Notifier, error) {
+	switch n.Type {
+	case "webhook", "slack":
+		url := os.ExpandEnv(n.URL)
+		if url == "" {
+			return nil, errors.New("url is empty")
+		}
+		if n.Type == "slack" {
+			return notification.NewSlackNotifier(url), nil
+		}
+		return notification.NewWebhookNotifier(url, n.Body)
+	case "telegram":
+		token := os.ExpandEnv(n.Token)
+		if token == "" || n.ChatID == "" {
+			return nil, errors.New("token and chat_id must be provided")
+		}
+		return notification.NewTelegramNotifier(token, n.ChatID), nil
+	case "smtp":
+		return notification.NewSMTPNotifier(notification.SMTPConfig{
+			Addr:     n.Addr,
+			Username: os.ExpandEnv(n.Username),
+			Password: os.ExpandEnv(n.Password),
+			From:     n.From,
+			To:       n.To,
+		})
+	default:
+		return nil, fmt.Errorf("type '%s' is unknown, supported values: [webhook|slack|telegram|smtp]", n.Type)
+	}
+}
+
+// alertValue is a float accepting TOML integers, such as 'value = 1000'
+type alertValue float64
+
+func (v *alertValue) UnmarshalTOML(data interface{}) error {
+	switch value := data.(type) {
+	case int64:
+		*v = alertValue(value)
+	case float64:
+		*v = alertValue(value)
+	default:
+		return fmt.Errorf("value '%v' isn't a number", data)
+	}
+	return nil
+}
+
+func (a alertConfig) rule() (*domain.AlertRule, error) {
+	rule := &domain.AlertRule{
+		ID:       a.ID,
+		Type:     domain.AlertType(a.Type),
+		Market:   a.Market,
+		Currency: a.Currency,
+		Quote:    a.Quote,
+		Value:    float64(a.Value),
+		Channels: a.Notify,
+	}
+
+	var err error
+	if a.Window != "" {
+		rule.Window, err = time.ParseDuration(a.Window)
+		if err != nil {
+			return nil, fmt.Errorf("window is wrong: %s", err)
+		}
+	}
+	if a.Cooldown != "" {
+		rule.Cooldown, err = time.ParseDuration(a.Cooldown)
+		if err != nil {
+			return nil, fmt.Errorf("cooldown is wrong: %s", err)
+		}
+	}
+	return rule, nil
+}
+
+// watchMarket checks every streamed market state
+func (c *NotifyCommand) watchMarket(updates <-chan domain.MarketInfo, resultCh chan<- error) {
+	for marketInfo := range updates {
+		if c.isPriceReached(marketInfo) {
+			resultCh <- c.sendNotification(marketInfo.Last)
+			return
+		}
+	}
+	resultCh <- errors.New("market updates are stopped")
+}
+
+// pollMarket requests the market state every refresh period
+func (c *NotifyCommand) pollMarket(exchange storage.Exchange, resultCh chan<- error) {
+	ticker := time.NewTicker(time.Second * time.Duration(c.RefreshPeriod))
+	defer ticker.Stop()
+
+	for range ticker.C {
+		lastPrice, err := c.checkMarketLastPrice(exchange)
+		if err != nil {
+			log.Error(err)
+			continue
+		}
+
+		if lastPrice != nil {
+			resultCh <- c.sendNotification(*lastPrice)
+			return
+		}
+	}
+}
+
 func (c *NotifyCommand) checkMarketLastPrice(exchange storage.Exchange) (*float64, error) {
 	marketInfo, err := exchange.GetMarketInfo(c.Market)
 	if err != nil {
 		return nil, err
 	}
 
-	if (notifyCmd.GreaterThan > 0 && marketInfo.Last >= notifyCmd.GreaterThan) ||
-		(notifyCmd.LessThan > 0 && marketInfo.Last <= notifyCmd.LessThan) {
+	if c.isPriceReached(*marketInfo) {
 		return &marketInfo.Last, nil
 	}
 	return nil, nil
 }
 
+func (c *NotifyCommand) isPriceReached(marketInfo domain.MarketInfo) bool {
+	return (c.GreaterThan > 0 && marketInfo.Last >= c.GreaterThan) ||
+		(c.LessThan > 0 && marketInfo.Last <= c.LessThan)
+}
+
 func (c *NotifyCommand) sendNotification(lastPrice float64) error {
-	notifier := notificator.New(notificator.Options{
-		DefaultIcon: "",
-		AppName:     "cryptoexchange-dashboard",
+	return notification.NewDesktopNotifier().Notify(domain.AlertTrigger{
+		RuleID:  c.Market,
+		Time:    time.Now(),
+		Value:   lastPrice,
+		Message: fmt.Sprintf("%s is reached price %s", c.Market, decimal.NewFromFloat(lastPrice)),
 	})
-
-	msg := fmt.Sprintf("%s is reached price %s", notifyCmd.Market, decimal.NewFromFloat(lastPrice))
-	return notifier.Push("Coin price notifier", msg, "", notificator.UR_CRITICAL)
 }
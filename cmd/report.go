@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/usecase"
+	"github.com/nawa/cryptoexchange-dashboard/usecase/lots"
+)
+
+const (
+	reportFormatCSV  = "csv"
+	reportFormatJSON = "json"
+)
+
+type TaxReportCommand struct {
+	cobra.Command
+	DBCommand
+	Year   int
+	Method string
+	Format string
+
+	method lots.Method
+}
+
+var (
+	reportCmd = &cobra.Command{
+		Use:   "report",
+		Short: "Prints reports of the synced data",
+	}
+
+	taxReportCmd = &TaxReportCommand{
+		Command: cobra.Command{
+			Use:   "tax",
+			Short: "Prints gains of sells in the year for tax filing",
+			Long:  "Prints gains of sells in the year for tax filing. Sells are matched with buys before them from the trade history synced by 'sync' command. \nGains are in USDT at the rates of the trade day taken from the balance history",
+		},
+	}
+)
+
+func init() {
+	err := taxReportCmd.DBCommand.BindArgs(&taxReportCmd.Command)
+	if err != nil {
+		panic(err)
+	}
+	taxReportCmd.Command.Flags().IntVarP(&taxReportCmd.Year, "year", "y", time.Now().Year()-1, "Year of sells")
+	taxReportCmd.Command.Flags().StringVarP(&taxReportCmd.Method, "method", "m", string(lots.MethodFIFO), fmt.Sprintf("Which buys are sold first: [%s|%s|%s]", lots.MethodFIFO, lots.MethodLIFO, lots.MethodAverage))
+	taxReportCmd.Command.Flags().StringVarP(&taxReportCmd.Format, "format", "f", reportFormatCSV, fmt.Sprintf("Output format: [%s|%s]", reportFormatCSV, reportFormatJSON))
+
+	taxReportCmd.PreRunE = taxReportCmd.preRun
+	taxReportCmd.RunE = taxReportCmd.run
+	reportCmd.AddCommand(&taxReportCmd.Command)
+	rootCmd.AddCommand(reportCmd)
+}
+
+func (c *TaxReportCommand) preRun(_ *cobra.Command, _ []string) error {
+	err := c.DBCommand.CheckArgs()
+	if err != nil {
+		return err
+	}
+
+	c.method, err = lots.ParseMethod(c.Method)
+	if err != nil {
+		return fmt.Errorf("--method is wrong, %s", err)
+	}
+
+	if c.Format != reportFormatCSV && c.Format != reportFormatJSON {
+		return fmt.Errorf("--format is wrong, supported values: [%s|%s]", reportFormatCSV, reportFormatJSON)
+	}
+	return nil
+}
+
+func (c *TaxReportCommand) run(_ *cobra.Command, _ []string) error {
+	balanceStorage, err := c.CreateBalanceStorage()
+	if err != nil {
+		return err
+	}
+
+	tradeStorage, err := c.CreateTradeStorage()
+	if err != nil {
+		return err
+	}
+
+	disposals, err := usecase.NewTaxUsecase(tradeStorage, balanceStorage).GetDisposals(c.Year, c.method)
+	if err != nil {
+		return err
+	}
+
+	if c.Format == reportFormatJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(disposals)
+	}
+	return writeDisposalsCSV(os.Stdout, disposals)
+}
+
+func writeDisposalsCSV(out io.Writer, disposals []domain.Disposal) error {
+	w := csv.NewWriter(out)
+	err := w.Write([]string{"exchange", "account", "order_id", "market", "currency", "acquired", "disposed",
+		"amount", "unmatched_amount", "cost_usdt", "proceeds_usdt", "gain_usdt", "unpriced"})
+	if err != nil {
+		return err
+	}
+
+	formatFloat := func(f float64) string {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	for _, d := range disposals {
+		var acquired string
+		if !d.Acquired.IsZero() {
+			acquired = d.Acquired.UTC().Format(time.RFC3339)
+		}
+		err = w.Write([]string{string(d.Exchange), d.Account, d.OrderID, d.Market, d.Currency, acquired, d.Disposed.UTC().Format(time.RFC3339),
+			formatFloat(d.Amount), formatFloat(d.Unmatched), formatFloat(d.CostUSDT), formatFloat(d.ProceedsUSDT), formatFloat(d.GainUSDT),
+			strconv.FormatBool(d.Unpriced)})
+		if err != nil {
+			return err
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
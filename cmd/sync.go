@@ -7,16 +7,23 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/nawa/cryptoexchange-dashboard/notification"
 	"github.com/nawa/cryptoexchange-dashboard/usecase"
 
+	log "github.com/Sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
 type SyncCommand struct {
 	cobra.Command
 	ExchangeAPICommand
-	MongoCommand
-	SyncPeriod int
+	DBCommand
+	NotifiersCommand
+	SyncPeriod       int
+	TradesSyncPeriod int
+	AlertsPeriod     int
+	BackfillMarkets  []string
+	BackfillInterval time.Duration
 }
 
 var (
@@ -34,11 +41,21 @@ func init() {
 	if err != nil {
 		panic(err)
 	}
-	err = syncCmd.MongoCommand.BindArgs(&syncCmd.Command)
+	err = syncCmd.DBCommand.BindArgs(&syncCmd.Command)
+	if err != nil {
+		panic(err)
+	}
+	err = syncCmd.NotifiersCommand.BindArgs(&syncCmd.Command)
 	if err != nil {
 		panic(err)
 	}
 	syncCmd.Command.Flags().IntVarP(&syncCmd.SyncPeriod, "period", "p", 10, "Synchronization period in sec")
+	syncCmd.Command.Flags().IntVar(&syncCmd.TradesSyncPeriod, "trades-period", 600, "Synchronization period of trade, deposit and withdrawal history in sec")
+
+	syncCmd.Command.Flags().IntVar(&syncCmd.AlertsPeriod, "alerts-period", 30, "Evaluation period of alert rules managed by REST API in sec")
+
+	syncCmd.Command.Flags().StringSliceVar(&syncCmd.BackfillMarkets, "backfill-markets", nil, "Comma separated markets, e.g. BTC-ETH, whose past candles are saved at the start")
+	syncCmd.Command.Flags().DurationVar(&syncCmd.BackfillInterval, "backfill-interval", time.Hour, "Interval of backfilled candles: 1m, 5m, 30m, 1h or 24h")
 
 	syncCmd.PreRunE = syncCmd.preRun
 	syncCmd.RunE = syncCmd.run
@@ -46,27 +63,93 @@ func init() {
 }
 
 func (c *SyncCommand) preRun(_ *cobra.Command, _ []string) error {
-	return c.ExchangeAPICommand.CheckArgs()
+	err := c.DBCommand.CheckArgs()
+	if err != nil {
+		return err
+	}
+
+	err = c.ExchangeAPICommand.CheckArgs()
+	if err != nil {
+		return err
+	}
+	return c.NotifiersCommand.CheckArgs()
 }
 
 func (c *SyncCommand) run(_ *cobra.Command, _ []string) error {
-	exchange, err := c.CreateExchange()
+	// open positions and markets of alert rules are streamed instead of being requested by every evaluation
+	exchange, stopStreaming, err := c.CreateStreamingExchange()
 	if err != nil {
 		return err
 	}
+	defer stopStreaming()
 
 	balanceStorage, err := c.CreateBalanceStorage()
 	if err != nil {
 		return err
 	}
 
-	balanceUsecase := usecase.NewBalanceUsecase(exchange, balanceStorage)
+	tradeStorage, err := c.CreateTradeStorage()
+	if err != nil {
+		return err
+	}
+
+	transferStorage, err := c.CreateTransferStorage()
+	if err != nil {
+		return err
+	}
+
+	alertStorage, err := c.CreateAlertStorage()
+	if err != nil {
+		return err
+	}
+
+	marketStorage, err := c.CreateMarketStorage()
+	if err != nil {
+		return err
+	}
+
+	if len(c.BackfillMarkets) > 0 {
+		// errors are logged by the usecase, markets can be backfilled by the next start
+		go usecase.NewMarketUsecase(exchange, marketStorage).Backfill(c.BackfillMarkets, c.BackfillInterval)
+	}
+
+	balanceUsecase := usecase.NewBalanceUsecase(exchange, balanceStorage, transferStorage, marketStorage)
 	stop, err := balanceUsecase.StartSyncFromExchangePeriodically(time.Second * time.Duration(c.SyncPeriod))
 	if err != nil {
 		return err
 	}
 	defer stop()
 
+	// trade history is requested per market on some exchanges, so it is synced less often.
+	// The first sync doesn't wait for the period, errors are logged by the usecase
+	tradeUsecase := usecase.NewTradeUsecase(exchange, tradeStorage)
+	go tradeUsecase.SyncFromExchange()
+	stopTrades, err := tradeUsecase.StartSyncFromExchangePeriodically(time.Second * time.Duration(c.TradesSyncPeriod))
+	if err != nil {
+		return err
+	}
+	defer stopTrades()
+
+	transferUsecase := usecase.NewTransferUsecase(exchange, transferStorage)
+	go transferUsecase.SyncFromExchange()
+	stopTransfers, err := transferUsecase.StartSyncFromExchangePeriodically(time.Second * time.Duration(c.TradesSyncPeriod))
+	if err != nil {
+		return err
+	}
+	defer stopTransfers()
+
+	// rules are read from the database by every evaluation, so changes made by REST API are applied without restart
+	if c.defaultNotifier == notification.DesktopChannel {
+		log.Warn("alerts of rules without notifiers are sent to the desktop and aren't seen if sync runs on a server, " +
+			"set 'default' notifier in --notifiers file")
+	}
+	alertUsecase := usecase.NewStoredAlertUsecase(exchange, alertStorage, c.CreateNotifier())
+	stopAlerts, err := alertUsecase.StartEvaluatingPeriodically(time.Second * time.Duration(c.AlertsPeriod))
+	if err != nil {
+		return err
+	}
+	defer stopAlerts()
+
 	exitC := make(chan os.Signal, 1)
 	signal.Notify(exitC,
 		syscall.SIGHUP,
@@ -1,6 +1,8 @@
 package domain
 
 import (
+	"errors"
+	"fmt"
 	"time"
 )
 
@@ -8,15 +10,67 @@ type ExchangeType string
 
 const (
 	ExchangeTypeBittrex = ExchangeType("bittrex")
+	ExchangeTypeBinance = ExchangeType("binance")
 )
 
+// TotalCurrency is the pseudo currency of balances summed over all coins of all exchanges
+const TotalCurrency = "total"
+
+// TotalCurrency returns the pseudo currency of balances summed over all coins of the exchange
+func (t ExchangeType) TotalCurrency() string {
+	return TotalCurrency + "-" + string(t)
+}
+
+// CoinTotalCurrency returns the pseudo currency of balances of the coin summed over all accounts
+func CoinTotalCurrency(currency string) string {
+	return TotalCurrency + "-" + currency
+}
+
+// Fee returns the trading commission of the exchange as a fraction of the trade price
+func (t ExchangeType) Fee() float64 {
+	if t == ExchangeTypeBinance {
+		return 0.001
+	}
+	return 0.0025
+}
+
 type Balance struct {
-	Exchange   ExchangeType
+	Exchange      ExchangeType
+	Account       string
+	Currency      string
+	Amount        float64
+	BTCAmount     float64
+	USDTAmount    float64
+	Fiat          map[string]float64 // value in fiat currencies by codes, e.g. EUR; missing in balances saved before configuring
+	Reconstructed bool               // rebuilt by backfill from the trade and transfer history instead of synced
+	Time          time.Time
+}
+
+// OHLC is the first, the last, the minimal and the maximal value over a period
+type OHLC struct {
+	Open  float64
+	Close float64
+	Min   float64
+	Max   float64
+}
+
+// BalanceCandle is the balance of a currency aggregated over the period starting at Time
+type BalanceCandle struct {
 	Currency   string
-	Amount     float64
-	BTCAmount  float64
-	USDTAmount float64
 	Time       time.Time
+	Amount     OHLC
+	BTCAmount  OHLC
+	USDTAmount OHLC
+	Fiat       map[string]OHLC // value in fiat currencies of balances having it
+}
+
+// MarketCandle is the price of the market over the period starting at Time. The price saved
+// by the balance synchronization is the candle with all prices equal
+type MarketCandle struct {
+	Exchange ExchangeType
+	Market   string
+	Time     time.Time
+	Price    OHLC
 }
 
 type MarketInfo struct {
@@ -33,10 +87,378 @@ type Order struct {
 	//	|	Sell Price=SellNowPrice*Amount	|	Profit = BuyPrice+BuyPrice*0.0025 - (Sell Price - Sell Price*0.0025)
 	//	|	Profit BTC = Profit * BTCRate |	Profit USDT = Profit * USDTRate | Profit % = (Profit / Amount)*100
 	Exchange    ExchangeType
+	Account     string
 	Market      string
 	Time        time.Time
 	BuyRate     float64
 	Amount      float64
 	SellNowRate float64
+	BTCRate     float64
 	USDTRate    float64
 }
+
+type TradeType string
+
+const (
+	TradeTypeBuy  = TradeType("buy")
+	TradeTypeSell = TradeType("sell")
+)
+
+// Trade is the filled part of an order. Amount is in the base currency of the market,
+// Rate and Fee are in the quote currency. BTCRate and USDTRate are rates of the quote currency when the trade
+// was first synced, they're zero if it was synced long after the trade
+type Trade struct {
+	Exchange ExchangeType
+	Account  string
+	OrderID  string
+	Market   string
+	Type     TradeType
+	Time     time.Time
+	Amount   float64
+	Rate     float64
+	Fee      float64
+	BTCRate  float64
+	USDTRate float64
+}
+
+// Profit of a position. Cost, Value, Fee and Profit are in the quote currency of the market
+type Profit struct {
+	// Cost is the buy price plus the buy fee
+	Cost float64
+	// Value is the sell price minus the sell fee
+	Value      float64
+	Fee        float64
+	Profit     float64
+	ProfitBTC  float64
+	ProfitUSDT float64
+	// Percent is the profit relative to the cost
+	Percent float64
+}
+
+// OpenPosition is the active buy order valued at the current sell rate
+type OpenPosition struct {
+	Order
+	Profit
+}
+
+// ClosedPosition is the sell trade matched against the average rate of buys before it
+type ClosedPosition struct {
+	Exchange ExchangeType
+	Account  string
+	Market   string
+	Time     time.Time
+	Amount   float64
+	BuyRate  float64
+	SellRate float64
+	Profit
+}
+
+// Disposal is the sold amount of the currency matched with lots bought before.
+// The cost includes buy fees, the proceeds are net of the sell fee
+type Disposal struct {
+	Exchange ExchangeType
+	Account  string
+	OrderID  string
+	Market   string
+	Currency string
+	// Acquired is the time of the earliest matched lot
+	Acquired time.Time
+	Disposed time.Time
+	Amount   float64
+	// Unmatched is the sold amount exceeding bought lots, it isn't counted in the gain
+	Unmatched    float64
+	CostUSDT     float64
+	ProceedsUSDT float64
+	GainUSDT     float64
+	// Unpriced means the USDT rate of the disposal or of a matched lot is unknown, so the gain is wrong
+	Unpriced bool
+}
+
+// PnL is unrealized profit of open positions and realized profit of closed ones
+type PnL struct {
+	Open           []OpenPosition
+	Closed         []ClosedPosition
+	UnrealizedBTC  float64
+	UnrealizedUSDT float64
+	RealizedBTC    float64
+	RealizedUSDT   float64
+}
+
+type TransferType string
+
+const (
+	TransferTypeDeposit    = TransferType("deposit")
+	TransferTypeWithdrawal = TransferType("withdrawal")
+)
+
+// Transfer is a deposit to or a withdrawal from the account. Amount is the change of the balance
+// including the fee. BTCRate and USDTRate are the rates of the currency when the transfer was first synced,
+// they're zero if it was synced long after the transfer
+type Transfer struct {
+	Exchange ExchangeType
+	Account  string
+	ID       string
+	Type     TransferType
+	Currency string
+	Time     time.Time
+	Amount   float64
+	Fee      float64
+	BTCRate  float64
+	USDTRate float64
+}
+
+// PerformancePoint is the balance at the end of the period starting at Time,
+// net flow of transfers during the period and the time-weighted return since the first point
+type PerformancePoint struct {
+	Time        time.Time
+	BTCAmount   float64
+	USDTAmount  float64
+	NetFlowBTC  float64
+	NetFlowUSDT float64
+	TWRBTC      float64
+	TWRUSDT     float64
+}
+
+// Performance is the return of the balance net of deposits and withdrawals. TWR is the time-weighted return
+// that doesn't depend on timing of flows, MWR is the money-weighted return by Modified Dietz method
+type Performance struct {
+	Points  []PerformancePoint
+	TWRBTC  float64
+	TWRUSDT float64
+	MWRBTC  float64
+	MWRUSDT float64
+	// Unpriced is the number of transfers without known rates at their time, they aren't counted as flows
+	Unpriced int
+}
+
+// PeriodReturn is the return of the balance over the period ending with the bucket starting at Time
+type PeriodReturn struct {
+	Time   time.Time
+	Return float64
+}
+
+// ReturnStats are risk and return statistics of the balance in one quote currency by returns
+// of periods net of deposits and withdrawals. Ratios are annualised by 365 days as exchanges trade
+// every day, the risk-free rate is zero
+type ReturnStats struct {
+	Return      float64
+	MaxDrawdown float64 // the largest fall from a peak, 0.2 is 20%
+	// buckets of the peak and the bottom of the max drawdown, recovery is zero if the peak isn't reached again
+	DrawdownPeak     time.Time
+	DrawdownTrough   time.Time
+	DrawdownRecovery time.Time
+	Volatility       float64
+	Sharpe           float64
+	Sortino          float64
+	Best             PeriodReturn
+	Worst            PeriodReturn
+	Rolling          []PeriodReturn // returns over windows of periods, the latest first
+}
+
+// Analytics are statistics of the balance by returns of Periods periods in BTC and USDT
+type Analytics struct {
+	Periods int
+	BTC     ReturnStats
+	USDT    ReturnStats
+}
+
+const (
+	// BenchmarkHODL is the pseudo currency of the portfolio of the start snapshot held without trades
+	BenchmarkHODL = "hodl"
+	// BenchmarkHODLBTC is the pseudo currency of BTC bought by the whole portfolio at the start snapshot
+	BenchmarkHODLBTC = "hodl-btc"
+)
+
+// Benchmark is the value of doing nothing since the snapshot at Start. HODL keeps coins of the snapshot,
+// HODLBTC converts them to BTC at the start. Unpriced are coins valued in the snapshot without saved prices
+// at some times, HODL is missing at these times
+type Benchmark struct {
+	Start    time.Time
+	HODL     []Balance
+	HODLBTC  []Balance
+	Unpriced []string
+}
+
+// Rate is the current conversion rate of the currency to the quote one on the exchange by Trades markets,
+// more than one if there is no common market. Rates of the path are products of rates of its markets
+type Rate struct {
+	Exchange ExchangeType
+	Currency string
+	Quote    string
+	Last     float64
+	Bid      float64
+	Ask      float64
+	Trades   int
+}
+
+// Share is the part of the balance held in the coin or on the exchange by BTC value, 0.25 is 25%
+type Share struct {
+	Name       string
+	BTCAmount  float64
+	USDTAmount float64
+	Share      float64
+}
+
+// Allocation is the breakdown of the total balance at Time by coins and by exchanges, the largest share first
+type Allocation struct {
+	Time       time.Time
+	BTCAmount  float64
+	USDTAmount float64
+	Coins      []Share
+	Exchanges  []Share
+}
+
+// RebalanceTrade is the trade of the coin for BTC bringing it to the target weight. Price is the bid of sells
+// and the ask of buys in BTC, Fee is in BTC
+type RebalanceTrade struct {
+	Exchange     ExchangeType
+	Currency     string
+	Type         TradeType
+	Amount       float64
+	Price        float64
+	Fee          float64
+	Weight       float64
+	TargetWeight float64
+}
+
+// Rebalance is the advice bringing the allocation to target weights. BTC is the quote of all trades,
+// so its weight is the rest after them. Unpriced coins have no conversion to BTC and are left as is
+type Rebalance struct {
+	Time      time.Time
+	BTCAmount float64
+	// NetBTC is BTC got by sells minus BTC spent by buys after fees
+	NetBTC   float64
+	Trades   []RebalanceTrade
+	Unpriced []string
+}
+
+type UpdateType string
+
+const (
+	UpdateTypeBalance = UpdateType("balance")
+	UpdateTypePnL     = UpdateType("pnl")
+)
+
+// Update is the change pushed to dashboard clients: the new balance snapshot
+// of all currencies including totals or the changed profit of orders
+type Update struct {
+	Type     UpdateType
+	Balances []Balance
+	PnL      *PnL
+}
+
+type AlertType string
+
+const (
+	// AlertTypePriceAbove fires when the last price of the market is at or above the value
+	AlertTypePriceAbove = AlertType("price_above")
+	// AlertTypePriceBelow fires when the last price of the market is at or below the value
+	AlertTypePriceBelow = AlertType("price_below")
+	// AlertTypePercentChange fires when the last price of the market has changed by the value in percent
+	// over the window: rose by at least the positive value or dropped by at least the negative one
+	AlertTypePercentChange = AlertType("percent_change")
+	// AlertTypePortfolioAbove fires when the balance of the currency, usually a total one, is at or above the value in the quote currency
+	AlertTypePortfolioAbove = AlertType("portfolio_above")
+	// AlertTypePortfolioBelow fires when the balance of the currency is at or below the value in the quote currency
+	AlertTypePortfolioBelow = AlertType("portfolio_below")
+	// AlertTypeTrailingStop fires when the price of the held position has dropped by the value in percent
+	// from its peak since the purchase. The position is the order of the market or of any market if it's empty
+	AlertTypeTrailingStop = AlertType("trailing_stop")
+	// AlertTypePositionChange fires when the price of the held position has changed from its buy rate by the value in percent:
+	// rose by at least the positive value or dropped by at least the negative one
+	AlertTypePositionChange = AlertType("position_change")
+)
+
+// DefaultAlertCooldown is the cooldown of rules without one
+const DefaultAlertCooldown = time.Hour
+
+// AlertRule is the condition checked continuously. The rule fires when the condition holds
+// and re-arms after the cooldown, so the condition lasting longer fires again
+type AlertRule struct {
+	ID       string
+	Type     AlertType
+	Market   string
+	Currency string
+	// Quote is BTC or USDT, the currency of the portfolio value
+	Quote    string
+	Value    float64
+	Window   time.Duration
+	Cooldown time.Duration
+	// Channels are names of notifiers of the rule, the default one is used if they're empty
+	Channels []string
+}
+
+// Validate checks that the rule has everything its type needs
+func (r AlertRule) Validate() error {
+	if r.ID == "" {
+		return errors.New("id is empty")
+	}
+	if r.Cooldown < 0 {
+		return errors.New("cooldown is negative")
+	}
+
+	switch r.Type {
+	case AlertTypePriceAbove, AlertTypePriceBelow:
+		if r.Market == "" {
+			return errors.New("market is empty")
+		}
+		if r.Value <= 0 {
+			return errors.New("value must be (0, ∞)")
+		}
+	case AlertTypePercentChange:
+		if r.Market == "" {
+			return errors.New("market is empty")
+		}
+		if r.Value == 0 {
+			return errors.New("value is zero")
+		}
+		if r.Window <= 0 {
+			return errors.New("window must be positive")
+		}
+	case AlertTypePortfolioAbove, AlertTypePortfolioBelow:
+		if r.Currency == "" {
+			return errors.New("currency is empty")
+		}
+		if r.Quote != "BTC" && r.Quote != "USDT" {
+			return errors.New("quote must be BTC or USDT")
+		}
+		if r.Value <= 0 {
+			return errors.New("value must be (0, ∞)")
+		}
+	case AlertTypeTrailingStop:
+		if r.Value <= 0 || r.Value >= 100 {
+			return errors.New("value must be (0, 100)")
+		}
+	case AlertTypePositionChange:
+		if r.Value == 0 {
+			return errors.New("value is zero")
+		}
+	default:
+		return fmt.Errorf("type '%s' is unknown", r.Type)
+	}
+	return nil
+}
+
+// AlertState is what is remembered about the rule between evaluations. LastFired is zero if the rule hasn't fired yet.
+// Peaks are the highest prices of positions watched by the trailing stop rule by position keys
+type AlertState struct {
+	RuleID    string
+	LastFired time.Time
+	Peaks     map[string]float64
+}
+
+// AlertRuleInfo is the rule with the time it has fired last time, zero if it hasn't fired yet
+type AlertRuleInfo struct {
+	Rule      AlertRule
+	LastFired time.Time
+}
+
+// AlertTrigger is the fired rule with the observed value
+type AlertTrigger struct {
+	RuleID  string
+	Time    time.Time
+	Value   float64
+	Message string
+	// Channels are names of notifiers of the rule
+	Channels []string
+}
@@ -0,0 +1,181 @@
+package http
+
+import (
+	"github.com/kataras/iris"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/http/dto"
+	"github.com/nawa/cryptoexchange-dashboard/usecase"
+)
+
+const (
+	defaultTriggersLimit = 100
+	maxTriggersLimit     = 1000
+)
+
+type AlertHandler struct {
+	alertRuleUsecase usecase.AlertRuleUsecases
+}
+
+func NewAlertHandler(alertRuleUsecase usecase.AlertRuleUsecases) *AlertHandler {
+	return &AlertHandler{
+		alertRuleUsecase: alertRuleUsecase,
+	}
+}
+
+func (h *AlertHandler) List(ctx iris.Context) {
+	rules, err := h.alertRuleUsecase.GetRules()
+	if err != nil {
+		WriteInternalServerError(ctx, "internal error")
+		return
+	}
+
+	rulesDTO := make([]dto.AlertRuleDTO, len(rules))
+	for i, r := range rules {
+		rulesDTO[i] = *dto.NewAlertRuleDTO(r)
+	}
+	_, err = ctx.JSON(rulesDTO)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (h *AlertHandler) Get(ctx iris.Context) {
+	rule, err := h.alertRuleUsecase.GetRule(ctx.Params().Get("id"))
+	if err == usecase.ErrAlertRuleNotFound {
+		WriteCustomError(ctx, iris.StatusNotFound, "alert not found")
+		return
+	}
+	if err != nil {
+		WriteInternalServerError(ctx, "internal error")
+		return
+	}
+
+	_, err = ctx.JSON(dto.NewAlertRuleDTO(*rule))
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Create saves the new rule, the ID is generated if it isn't provided
+func (h *AlertHandler) Create(ctx iris.Context) {
+	rule, ok := readAlertRule(ctx)
+	if !ok {
+		return
+	}
+
+	created, err := h.alertRuleUsecase.CreateRule(*rule)
+	if validationErr, ok := err.(usecase.ValidationError); ok {
+		WriteBadRequest(ctx, validationErr.Error())
+		return
+	}
+	if err == usecase.ErrAlertRuleExists {
+		WriteCustomError(ctx, iris.StatusConflict, "alert already exists")
+		return
+	}
+	if err != nil {
+		WriteInternalServerError(ctx, "internal error")
+		return
+	}
+
+	ctx.StatusCode(iris.StatusCreated)
+	_, err = ctx.JSON(dto.NewAlertRuleDTO(domain.AlertRuleInfo{Rule: *created}))
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Update replaces the rule, the ID of the path is used
+func (h *AlertHandler) Update(ctx iris.Context) {
+	id := ctx.Params().Get("id")
+	rule, ok := readAlertRule(ctx)
+	if !ok {
+		return
+	}
+	if rule.ID != "" && rule.ID != id {
+		WriteBadRequest(ctx, "'id' differs from the path")
+		return
+	}
+	rule.ID = id
+
+	err := h.alertRuleUsecase.UpdateRule(*rule)
+	if validationErr, ok := err.(usecase.ValidationError); ok {
+		WriteBadRequest(ctx, validationErr.Error())
+		return
+	}
+	if err == usecase.ErrAlertRuleNotFound {
+		WriteCustomError(ctx, iris.StatusNotFound, "alert not found")
+		return
+	}
+	if err != nil {
+		WriteInternalServerError(ctx, "internal error")
+		return
+	}
+
+	_, err = ctx.JSON(dto.NewAlertRuleDTO(domain.AlertRuleInfo{Rule: *rule}))
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (h *AlertHandler) Delete(ctx iris.Context) {
+	err := h.alertRuleUsecase.DeleteRule(ctx.Params().Get("id"))
+	if err == usecase.ErrAlertRuleNotFound {
+		WriteCustomError(ctx, iris.StatusNotFound, "alert not found")
+		return
+	}
+	if err != nil {
+		WriteInternalServerError(ctx, "internal error")
+		return
+	}
+
+	ctx.StatusCode(iris.StatusNoContent)
+}
+
+// Triggers returns the latest triggers of the rule from the path or of all rules, the latest first.
+// The history of deleted rules is kept
+func (h *AlertHandler) Triggers(ctx iris.Context) {
+	limit := defaultTriggersLimit
+	if ctx.URLParamExists("limit") {
+		var err error
+		limit, err = ctx.URLParamInt("limit")
+		if err != nil || limit <= 0 {
+			WriteBadRequest(ctx, "'limit' is wrong")
+			return
+		}
+		if limit > maxTriggersLimit {
+			limit = maxTriggersLimit
+		}
+	}
+
+	triggers, err := h.alertRuleUsecase.FetchTriggers(ctx.Params().Get("id"), limit)
+	if err != nil {
+		WriteInternalServerError(ctx, "internal error")
+		return
+	}
+
+	triggersDTO := make([]dto.AlertTriggerDTO, len(triggers))
+	for i, t := range triggers {
+		triggersDTO[i] = *dto.NewAlertTriggerDTO(t)
+	}
+	_, err = ctx.JSON(triggersDTO)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func readAlertRule(ctx iris.Context) (*domain.AlertRule, bool) {
+	var ruleDTO dto.AlertRuleDTO
+	err := ctx.ReadJSON(&ruleDTO)
+	if err != nil {
+		WriteBadRequest(ctx, "body is wrong")
+		return nil, false
+	}
+
+	rule, err := ruleDTO.Rule()
+	if err != nil {
+		WriteBadRequest(ctx, err.Error())
+		return nil, false
+	}
+	return rule, true
+}
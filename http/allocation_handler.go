@@ -0,0 +1,120 @@
+package http
+
+import (
+	"time"
+
+	"github.com/kataras/iris"
+	"github.com/nawa/cryptoexchange-dashboard/http/dto"
+	"github.com/nawa/cryptoexchange-dashboard/usecase"
+)
+
+const (
+	// defaultAllocationSteps is the number of allocations returned if 'from' isn't set
+	defaultAllocationSteps = 30
+	// maxAllocationBuckets is lower than maxRangeBuckets because every bucket is a separate storage request
+	maxAllocationBuckets = 1000
+)
+
+type AllocationHandler struct {
+	allocationUsecase usecase.AllocationUsecases
+}
+
+func NewAllocationHandler(allocationUsecase usecase.AllocationUsecases) *AllocationHandler {
+	return &AllocationHandler{
+		allocationUsecase: allocationUsecase,
+	}
+}
+
+// Current returns shares of coins and exchanges in the last balance
+func (h *AllocationHandler) Current(ctx iris.Context) {
+	allocation, err := h.allocationUsecase.GetAllocation()
+	if err != nil {
+		WriteInternalServerError(ctx, "internal error")
+		return
+	}
+
+	_, err = ctx.JSON(dto.NewAllocationDTO(*allocation))
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Range returns allocations of the first balances of every 'step' (24h by default) between 'from' and 'to'
+// unix timestamps, the latest first. 'to' is now by default, 'from' is 30 steps before 'to'
+func (h *AllocationHandler) Range(ctx iris.Context) {
+	step := 24 * time.Hour
+	if ctx.URLParam("step") != "" {
+		var err error
+		step, err = time.ParseDuration(ctx.URLParam("step"))
+		if err != nil {
+			WriteBadRequest(ctx, "'step' is wrong")
+			return
+		}
+		if step < time.Minute {
+			WriteBadRequest(ctx, "'step' is < 1m")
+			return
+		}
+	}
+
+	to := time.Now()
+	if ctx.URLParam("to") != "" {
+		toUnix, err := ctx.URLParamInt64("to")
+		if err != nil {
+			WriteBadRequest(ctx, "'to' is wrong")
+			return
+		}
+		to = time.Unix(toUnix, 0)
+	}
+	from := to.Add(-defaultAllocationSteps * step)
+	if ctx.URLParam("from") != "" {
+		fromUnix, err := ctx.URLParamInt64("from")
+		if err != nil {
+			WriteBadRequest(ctx, "'from' is wrong")
+			return
+		}
+		from = time.Unix(fromUnix, 0)
+	}
+	if !from.Before(to) {
+		WriteBadRequest(ctx, "'from' is not before 'to'")
+		return
+	}
+	if to.Sub(from)/step > maxAllocationBuckets {
+		WriteBadRequest(ctx, "'step' is too small for the range")
+		return
+	}
+
+	allocations, err := h.allocationUsecase.FetchAllocations(from, to, step)
+	if err != nil {
+		WriteInternalServerError(ctx, "internal error")
+		return
+	}
+
+	allocationsDTO := []dto.AllocationDTO{}
+	for _, a := range allocations {
+		allocationsDTO = append(allocationsDTO, *dto.NewAllocationDTO(a))
+	}
+
+	_, err = ctx.JSON(allocationsDTO)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Rebalance returns trades bringing the last balance to configured target weights. It's only the advice,
+// orders aren't placed
+func (h *AllocationHandler) Rebalance(ctx iris.Context) {
+	rebalance, err := h.allocationUsecase.Rebalance()
+	if err == usecase.ErrNoTargetWeights {
+		WriteCustomError(ctx, iris.StatusNotFound, "target weights aren't configured")
+		return
+	}
+	if err != nil {
+		WriteInternalServerError(ctx, "internal error")
+		return
+	}
+
+	_, err = ctx.JSON(dto.NewRebalanceDTO(*rebalance))
+	if err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,62 @@
+package http
+
+import (
+	"time"
+
+	"github.com/kataras/iris"
+	"github.com/nawa/cryptoexchange-dashboard/http/dto"
+	"github.com/nawa/cryptoexchange-dashboard/usecase"
+)
+
+const (
+	// defaultAnalyticsStep makes best and worst periods days
+	defaultAnalyticsStep = 24 * time.Hour
+	// defaultAnalyticsWindow is the period of rolling returns if 'window' isn't set
+	defaultAnalyticsWindow = 7 * 24 * time.Hour
+)
+
+type AnalyticsHandler struct {
+	analyticsUsecase usecase.AnalyticsUsecases
+}
+
+func NewAnalyticsHandler(analyticsUsecase usecase.AnalyticsUsecases) *AnalyticsHandler {
+	return &AnalyticsHandler{
+		analyticsUsecase: analyticsUsecase,
+	}
+}
+
+// Analytics returns drawdown, volatility, Sharpe and Sortino ratios, rolling returns and best and worst periods
+// of the balance in BTC and USDT. It accepts the same parameters as Range, 'step' is 24h by default.
+// 'window' is the period of rolling returns, 7 days by default, it's rounded down to steps
+func (h *AnalyticsHandler) Analytics(ctx iris.Context) {
+	currency, from, to, step, ok := rangeParams(ctx, defaultAnalyticsStep)
+	if !ok {
+		return
+	}
+
+	window := defaultAnalyticsWindow
+	if ctx.URLParam("window") != "" {
+		var err error
+		window, err = time.ParseDuration(ctx.URLParam("window"))
+		if err != nil {
+			WriteBadRequest(ctx, "'window' is wrong")
+			return
+		}
+	}
+	if window < step {
+		WriteBadRequest(ctx, "'window' is shorter than 'step'")
+		return
+	}
+	window = window / step * step
+
+	analytics, err := h.analyticsUsecase.Analyze(currency, from, to, step, int(window/step))
+	if err != nil {
+		WriteInternalServerError(ctx, "internal error")
+		return
+	}
+
+	_, err = ctx.JSON(dto.NewAnalyticsDTO(currency, step, window, *analytics))
+	if err != nil {
+		panic(err)
+	}
+}
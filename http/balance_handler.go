@@ -1,18 +1,27 @@
 package http
 
 import (
+	"strings"
+	"time"
+
 	"github.com/kataras/iris"
+	"github.com/nawa/cryptoexchange-dashboard/domain"
 	"github.com/nawa/cryptoexchange-dashboard/http/dto"
 	"github.com/nawa/cryptoexchange-dashboard/usecase"
 )
 
+// maxRangeBuckets limits the number of buckets one range request can produce
+const maxRangeBuckets = 10000
+
 type BalanceHandler struct {
-	balanceUsecase usecase.BalanceUsecases
+	balanceUsecase   usecase.BalanceUsecases
+	benchmarkUsecase usecase.BenchmarkUsecases
 }
 
-func NewBalanceHandler(balanceUsecase usecase.BalanceUsecases) *BalanceHandler {
+func NewBalanceHandler(balanceUsecase usecase.BalanceUsecases, benchmarkUsecase usecase.BenchmarkUsecases) *BalanceHandler {
 	return &BalanceHandler{
-		balanceUsecase: balanceUsecase,
+		balanceUsecase:   balanceUsecase,
+		benchmarkUsecase: benchmarkUsecase,
 	}
 }
 
@@ -32,6 +41,11 @@ func (h *BalanceHandler) Hourly(ctx iris.Context) {
 		WriteBadRequest(ctx, "'currency' is empty")
 		return
 	}
+	quote := quoteParam(ctx)
+	benchmarkStart, ok := benchmarkParam(ctx, currency)
+	if !ok {
+		return
+	}
 
 	mBalances, err := h.balanceUsecase.FetchHourly(currency, hours)
 	if err != nil {
@@ -41,11 +55,16 @@ func (h *BalanceHandler) Hourly(ctx iris.Context) {
 
 	var curBalancesDTO []dto.BalanceDTO
 	for _, b := range mBalances {
-		curBalancesDTO = append(curBalancesDTO, *dto.NewBalanceDTO(b))
+		curBalancesDTO = append(curBalancesDTO, *dto.NewBalanceDTO(b, quote))
 	}
 
 	balanceDTO := dto.BalancesResponse{}
 	balanceDTO.Add(currency, curBalancesDTO...)
+	err = h.addBenchmarks(balanceDTO, benchmarkStart, mBalances, time.Minute)
+	if err != nil {
+		WriteInternalServerError(ctx, "internal error")
+		return
+	}
 
 	_, err = ctx.JSON(balanceDTO)
 	if err != nil {
@@ -59,6 +78,11 @@ func (h *BalanceHandler) Weekly(ctx iris.Context) {
 		WriteBadRequest(ctx, "'currency' is empty")
 		return
 	}
+	quote := quoteParam(ctx)
+	benchmarkStart, ok := benchmarkParam(ctx, currency)
+	if !ok {
+		return
+	}
 
 	mBalances, err := h.balanceUsecase.FetchWeekly(currency)
 	if err != nil {
@@ -68,11 +92,16 @@ func (h *BalanceHandler) Weekly(ctx iris.Context) {
 
 	var curBalancesDTO []dto.BalanceDTO
 	for _, b := range mBalances {
-		curBalancesDTO = append(curBalancesDTO, *dto.NewBalanceDTO(b))
+		curBalancesDTO = append(curBalancesDTO, *dto.NewBalanceDTO(b, quote))
 	}
 
 	balanceDTO := dto.BalancesResponse{}
 	balanceDTO.Add(currency, curBalancesDTO...)
+	err = h.addBenchmarks(balanceDTO, benchmarkStart, mBalances, 5*time.Minute)
+	if err != nil {
+		WriteInternalServerError(ctx, "internal error")
+		return
+	}
 
 	_, err = ctx.JSON(balanceDTO)
 	if err != nil {
@@ -86,6 +115,11 @@ func (h *BalanceHandler) Monthly(ctx iris.Context) {
 		WriteBadRequest(ctx, "'currency' is empty")
 		return
 	}
+	quote := quoteParam(ctx)
+	benchmarkStart, ok := benchmarkParam(ctx, currency)
+	if !ok {
+		return
+	}
 
 	mBalances, err := h.balanceUsecase.FetchMonthly(currency)
 	if err != nil {
@@ -95,11 +129,16 @@ func (h *BalanceHandler) Monthly(ctx iris.Context) {
 
 	var curBalancesDTO []dto.BalanceDTO
 	for _, b := range mBalances {
-		curBalancesDTO = append(curBalancesDTO, *dto.NewBalanceDTO(b))
+		curBalancesDTO = append(curBalancesDTO, *dto.NewBalanceDTO(b, quote))
 	}
 
 	balanceDTO := dto.BalancesResponse{}
 	balanceDTO.Add(currency, curBalancesDTO...)
+	err = h.addBenchmarks(balanceDTO, benchmarkStart, mBalances, time.Hour)
+	if err != nil {
+		WriteInternalServerError(ctx, "internal error")
+		return
+	}
 
 	_, err = ctx.JSON(balanceDTO)
 	if err != nil {
@@ -113,6 +152,11 @@ func (h *BalanceHandler) All(ctx iris.Context) {
 		WriteBadRequest(ctx, "'currency' is empty")
 		return
 	}
+	quote := quoteParam(ctx)
+	benchmarkStart, ok := benchmarkParam(ctx, currency)
+	if !ok {
+		return
+	}
 
 	mBalances, err := h.balanceUsecase.FetchAll(currency)
 	if err != nil {
@@ -122,11 +166,16 @@ func (h *BalanceHandler) All(ctx iris.Context) {
 
 	var curBalancesDTO []dto.BalanceDTO
 	for _, b := range mBalances {
-		curBalancesDTO = append(curBalancesDTO, *dto.NewBalanceDTO(b))
+		curBalancesDTO = append(curBalancesDTO, *dto.NewBalanceDTO(b, quote))
 	}
 
 	balanceDTO := dto.BalancesResponse{}
 	balanceDTO.Add(currency, curBalancesDTO...)
+	err = h.addBenchmarks(balanceDTO, benchmarkStart, mBalances, 24*time.Hour)
+	if err != nil {
+		WriteInternalServerError(ctx, "internal error")
+		return
+	}
 
 	_, err = ctx.JSON(balanceDTO)
 	if err != nil {
@@ -134,7 +183,157 @@ func (h *BalanceHandler) All(ctx iris.Context) {
 	}
 }
 
+// Range returns balances aggregated into buckets of 'step' (e.g. 5m, 1h) between
+// 'from' and 'to' unix timestamps, 'to' is now by default
+func (h *BalanceHandler) Range(ctx iris.Context) {
+	currency, from, to, step, ok := rangeParams(ctx, 0)
+	if !ok {
+		return
+	}
+	quote := quoteParam(ctx)
+
+	mCandles, err := h.balanceUsecase.FetchRange(currency, from, to, step)
+	if err != nil {
+		WriteInternalServerError(ctx, "internal error")
+		return
+	}
+
+	var curCandlesDTO []dto.BalanceCandleDTO
+	for _, c := range mCandles {
+		curCandlesDTO = append(curCandlesDTO, *dto.NewBalanceCandleDTO(c, quote))
+	}
+
+	candlesDTO := dto.BalanceCandlesResponse{}
+	candlesDTO.Add(currency, curCandlesDTO...)
+
+	_, err = ctx.JSON(candlesDTO)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Performance returns the balance with time-weighted and money-weighted returns net of deposits
+// and withdrawals. It accepts the same parameters as Range, every point is a bucket of 'step'
+func (h *BalanceHandler) Performance(ctx iris.Context) {
+	currency, from, to, step, ok := rangeParams(ctx, 0)
+	if !ok {
+		return
+	}
+
+	performance, err := h.balanceUsecase.FetchPerformance(currency, from, to, step)
+	if err != nil {
+		WriteInternalServerError(ctx, "internal error")
+		return
+	}
+
+	_, err = ctx.JSON(dto.NewPerformanceDTO(currency, *performance))
+	if err != nil {
+		panic(err)
+	}
+}
+
+// rangeParams parses 'currency', 'from', 'to' and 'step' of range requests, 'step' is required
+// if the default one is zero. It writes the bad request response and returns false if they are wrong
+func rangeParams(ctx iris.Context, defaultStep time.Duration) (currency string, from, to time.Time, step time.Duration, ok bool) {
+	currency = ctx.URLParam("currency")
+	if currency == "" {
+		WriteBadRequest(ctx, "'currency' is empty")
+		return
+	}
+
+	fromUnix, err := ctx.URLParamInt64("from")
+	if err != nil {
+		WriteBadRequest(ctx, "'from' is wrong")
+		return
+	}
+	from = time.Unix(fromUnix, 0)
+
+	to = time.Now()
+	if ctx.URLParam("to") != "" {
+		toUnix, err := ctx.URLParamInt64("to")
+		if err != nil {
+			WriteBadRequest(ctx, "'to' is wrong")
+			return
+		}
+		to = time.Unix(toUnix, 0)
+	}
+	if !from.Before(to) {
+		WriteBadRequest(ctx, "'from' is not before 'to'")
+		return
+	}
+
+	step = defaultStep
+	if defaultStep == 0 || ctx.URLParam("step") != "" {
+		step, err = time.ParseDuration(ctx.URLParam("step"))
+		if err != nil {
+			WriteBadRequest(ctx, "'step' is wrong")
+			return
+		}
+	}
+	if step < time.Second {
+		WriteBadRequest(ctx, "'step' is < 1s")
+		return
+	}
+	if to.Sub(from)/step > maxRangeBuckets {
+		WriteBadRequest(ctx, "'step' is too small for the range")
+		return
+	}
+
+	return currency, from, to, step, true
+}
+
+// quoteParam returns the fiat currency of 'quote', e.g. EUR. Balances get values in it if they have been valued
+func quoteParam(ctx iris.Context) string {
+	return strings.ToUpper(ctx.URLParam("quote"))
+}
+
+// benchmarkParam parses 'benchmark', the unix timestamp of the snapshot the total balance is compared with
+// doing nothing since. It writes the bad request response and returns false if it's wrong
+func benchmarkParam(ctx iris.Context, currency string) (start time.Time, ok bool) {
+	if ctx.URLParam("benchmark") == "" {
+		return time.Time{}, true
+	}
+	if currency != domain.TotalCurrency {
+		WriteBadRequest(ctx, "'benchmark' is only for 'total' currency")
+		return
+	}
+
+	startUnix, err := ctx.URLParamInt64("benchmark")
+	if err != nil {
+		WriteBadRequest(ctx, "'benchmark' is wrong")
+		return
+	}
+	return time.Unix(startUnix, 0), true
+}
+
+// addBenchmarks adds 'hodl' and 'hodl-btc' series at times of balances unless start is zero.
+// Prices are candles of the resolution
+func (h *BalanceHandler) addBenchmarks(response dto.BalancesResponse, start time.Time, balances []domain.Balance, resolution time.Duration) error {
+	if start.IsZero() || len(balances) == 0 {
+		return nil
+	}
+
+	times := make([]time.Time, len(balances))
+	for i, b := range balances {
+		times[i] = b.Time
+	}
+	benchmark, err := h.benchmarkUsecase.Compare(start, times, resolution)
+	if err != nil {
+		return err
+	}
+
+	for _, b := range benchmark.HODL {
+		response.Add(domain.BenchmarkHODL, *dto.NewBalanceDTO(b, ""))
+	}
+	for _, b := range benchmark.HODLBTC {
+		response.Add(domain.BenchmarkHODLBTC, *dto.NewBalanceDTO(b, ""))
+	}
+	return nil
+}
+
 func (h *BalanceHandler) ActiveCurrencies(ctx iris.Context) {
+	quote := quoteParam(ctx)
+
 	mBalances, err := h.balanceUsecase.GetActiveCurrencies()
 	if err != nil {
 		WriteInternalServerError(ctx, "internal error")
@@ -143,7 +342,7 @@ func (h *BalanceHandler) ActiveCurrencies(ctx iris.Context) {
 
 	balanceDTO := dto.BalancesResponse{}
 	for _, b := range mBalances {
-		balanceDTO.Add(b.Currency, *dto.NewBalanceDTO(b))
+		balanceDTO.Add(b.Currency, *dto.NewBalanceDTO(b, quote))
 	}
 	_, err = ctx.JSON(balanceDTO)
 	if err != nil {
@@ -0,0 +1,88 @@
+package dto
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+)
+
+// AlertRuleDTO is the alert rule with durations in Go format, e.g. '1h30m', and the time it has fired last time
+type AlertRuleDTO struct {
+	ID        string   `json:"id"`
+	Type      string   `json:"type"`
+	Market    string   `json:"market,omitempty"`
+	Currency  string   `json:"currency,omitempty"`
+	Quote     string   `json:"quote,omitempty"`
+	Value     float64  `json:"value"`
+	Window    string   `json:"window,omitempty"`
+	Cooldown  string   `json:"cooldown,omitempty"`
+	Notify    []string `json:"notify,omitempty"`
+	LastFired int64    `json:"last_fired,omitempty"`
+}
+
+func NewAlertRuleDTO(m domain.AlertRuleInfo) *AlertRuleDTO {
+	result := &AlertRuleDTO{
+		ID:       m.Rule.ID,
+		Type:     string(m.Rule.Type),
+		Market:   m.Rule.Market,
+		Currency: m.Rule.Currency,
+		Quote:    m.Rule.Quote,
+		Value:    m.Rule.Value,
+		Notify:   m.Rule.Channels,
+	}
+	if m.Rule.Window != 0 {
+		result.Window = m.Rule.Window.String()
+	}
+	if m.Rule.Cooldown != 0 {
+		result.Cooldown = m.Rule.Cooldown.String()
+	}
+	if !m.LastFired.IsZero() {
+		result.LastFired = m.LastFired.Unix()
+	}
+	return result
+}
+
+// Rule converts the DTO to the rule, the last fired time is ignored
+func (d *AlertRuleDTO) Rule() (*domain.AlertRule, error) {
+	rule := &domain.AlertRule{
+		ID:       d.ID,
+		Type:     domain.AlertType(d.Type),
+		Market:   d.Market,
+		Currency: d.Currency,
+		Quote:    d.Quote,
+		Value:    d.Value,
+		Channels: d.Notify,
+	}
+
+	var err error
+	if d.Window != "" {
+		rule.Window, err = time.ParseDuration(d.Window)
+		if err != nil {
+			return nil, fmt.Errorf("window is wrong: %s", err)
+		}
+	}
+	if d.Cooldown != "" {
+		rule.Cooldown, err = time.ParseDuration(d.Cooldown)
+		if err != nil {
+			return nil, fmt.Errorf("cooldown is wrong: %s", err)
+		}
+	}
+	return rule, nil
+}
+
+type AlertTriggerDTO struct {
+	RuleID  string  `json:"rule_id"`
+	Time    int64   `json:"time"`
+	Value   float64 `json:"value"`
+	Message string  `json:"message"`
+}
+
+func NewAlertTriggerDTO(m domain.AlertTrigger) *AlertTriggerDTO {
+	return &AlertTriggerDTO{
+		RuleID:  m.RuleID,
+		Time:    m.Time.Unix(),
+		Value:   m.Value,
+		Message: m.Message,
+	}
+}
@@ -0,0 +1,89 @@
+package dto
+
+import (
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+)
+
+type ShareDTO struct {
+	Name       string  `json:"name"`
+	BTCAmount  float64 `json:"btc_amount"`
+	USDTAmount float64 `json:"usdt_amount"`
+	Share      float64 `json:"share"`
+}
+
+type AllocationDTO struct {
+	BTCAmount  float64    `json:"btc_amount"`
+	USDTAmount float64    `json:"usdt_amount"`
+	Time       int64      `json:"time"`
+	Coins      []ShareDTO `json:"coins"`
+	Exchanges  []ShareDTO `json:"exchanges"`
+}
+
+type RebalanceTradeDTO struct {
+	Exchange     string  `json:"exchange"`
+	Currency     string  `json:"currency"`
+	Type         string  `json:"type"`
+	Amount       float64 `json:"amount"`
+	Price        float64 `json:"price"`
+	Fee          float64 `json:"fee"`
+	Weight       float64 `json:"weight"`
+	TargetWeight float64 `json:"target_weight"`
+}
+
+// RebalanceDTO is the advice, trades aren't placed. Unpriced coins are missing in trades and the balance value
+type RebalanceDTO struct {
+	BTCAmount float64             `json:"btc_amount"`
+	NetBTC    float64             `json:"net_btc"`
+	Time      int64               `json:"time,omitempty"`
+	Trades    []RebalanceTradeDTO `json:"trades"`
+	Unpriced  []string            `json:"unpriced"`
+}
+
+func NewAllocationDTO(model domain.Allocation) *AllocationDTO {
+	return &AllocationDTO{
+		BTCAmount:  model.BTCAmount,
+		USDTAmount: model.USDTAmount,
+		Time:       model.Time.Unix(),
+		Coins:      newShareDTOs(model.Coins),
+		Exchanges:  newShareDTOs(model.Exchanges),
+	}
+}
+
+func NewRebalanceDTO(model domain.Rebalance) *RebalanceDTO {
+	result := &RebalanceDTO{
+		BTCAmount: model.BTCAmount,
+		NetBTC:    model.NetBTC,
+		Trades:    []RebalanceTradeDTO{},
+		Unpriced:  []string{},
+	}
+	if !model.Time.IsZero() {
+		result.Time = model.Time.Unix()
+	}
+	for _, t := range model.Trades {
+		result.Trades = append(result.Trades, RebalanceTradeDTO{
+			Exchange:     string(t.Exchange),
+			Currency:     t.Currency,
+			Type:         string(t.Type),
+			Amount:       t.Amount,
+			Price:        t.Price,
+			Fee:          t.Fee,
+			Weight:       t.Weight,
+			TargetWeight: t.TargetWeight,
+		})
+	}
+	result.Unpriced = append(result.Unpriced, model.Unpriced...)
+	return result
+}
+
+func newShareDTOs(shares []domain.Share) []ShareDTO {
+	result := []ShareDTO{}
+	for _, s := range shares {
+		result = append(result, ShareDTO{
+			Name:       s.Name,
+			BTCAmount:  s.BTCAmount,
+			USDTAmount: s.USDTAmount,
+			Share:      s.Share,
+		})
+	}
+	return result
+}
@@ -0,0 +1,88 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+)
+
+type PeriodReturnDTO struct {
+	Return float64 `json:"return"`
+	Time   int64   `json:"time"`
+}
+
+// DrawdownDTO is the max drawdown, times of the peak and the bottom are missing if there is no drawdown,
+// the recovery time is missing if the peak isn't reached again
+type DrawdownDTO struct {
+	Value    float64 `json:"value"`
+	Peak     int64   `json:"peak,omitempty"`
+	Trough   int64   `json:"trough,omitempty"`
+	Recovery int64   `json:"recovery,omitempty"`
+}
+
+type ReturnStatsDTO struct {
+	Return      float64           `json:"return"`
+	MaxDrawdown DrawdownDTO       `json:"max_drawdown"`
+	Volatility  float64           `json:"volatility"`
+	Sharpe      float64           `json:"sharpe"`
+	Sortino     float64           `json:"sortino"`
+	Best        *PeriodReturnDTO  `json:"best,omitempty"`
+	Worst       *PeriodReturnDTO  `json:"worst,omitempty"`
+	Rolling     []PeriodReturnDTO `json:"rolling"`
+}
+
+type AnalyticsDTO struct {
+	Currency string         `json:"currency"`
+	Step     string         `json:"step"`
+	Window   string         `json:"window"`
+	Periods  int            `json:"periods"`
+	BTC      ReturnStatsDTO `json:"btc"`
+	USDT     ReturnStatsDTO `json:"usdt"`
+}
+
+func NewAnalyticsDTO(currency string, step, window time.Duration, model domain.Analytics) *AnalyticsDTO {
+	return &AnalyticsDTO{
+		Currency: currency,
+		Step:     step.String(),
+		Window:   window.String(),
+		Periods:  model.Periods,
+		BTC:      newReturnStatsDTO(model.BTC),
+		USDT:     newReturnStatsDTO(model.USDT),
+	}
+}
+
+func newReturnStatsDTO(model domain.ReturnStats) ReturnStatsDTO {
+	result := ReturnStatsDTO{
+		Return:     model.Return,
+		Volatility: model.Volatility,
+		Sharpe:     model.Sharpe,
+		Sortino:    model.Sortino,
+		MaxDrawdown: DrawdownDTO{
+			Value: model.MaxDrawdown,
+		},
+		Rolling: []PeriodReturnDTO{},
+	}
+	if model.MaxDrawdown > 0 {
+		result.MaxDrawdown.Peak = model.DrawdownPeak.Unix()
+		result.MaxDrawdown.Trough = model.DrawdownTrough.Unix()
+	}
+	if !model.DrawdownRecovery.IsZero() {
+		result.MaxDrawdown.Recovery = model.DrawdownRecovery.Unix()
+	}
+	// best and worst periods are missing without returns
+	if !model.Best.Time.IsZero() {
+		result.Best = newPeriodReturnDTO(model.Best)
+		result.Worst = newPeriodReturnDTO(model.Worst)
+	}
+	for _, r := range model.Rolling {
+		result.Rolling = append(result.Rolling, *newPeriodReturnDTO(r))
+	}
+	return result
+}
+
+func newPeriodReturnDTO(model domain.PeriodReturn) *PeriodReturnDTO {
+	return &PeriodReturnDTO{
+		Return: model.Return,
+		Time:   model.Time.Unix(),
+	}
+}
@@ -5,19 +5,28 @@ import "github.com/nawa/cryptoexchange-dashboard/domain"
 type BalancesResponse map[string][]BalanceDTO //currency/balances for time range
 
 type BalanceDTO struct {
-	Amount     float64 `json:"amount"`
-	BTCAmount  float64 `json:"btc"`
-	USDTAmount float64 `json:"usdt"`
-	Time       int64   `json:"time"`
+	Amount        float64  `json:"amount"`
+	BTCAmount     float64  `json:"btc"`
+	USDTAmount    float64  `json:"usdt"`
+	Fiat          *float64 `json:"fiat,omitempty"`
+	Reconstructed bool     `json:"reconstructed,omitempty"`
+	Time          int64    `json:"time"`
 }
 
-func NewBalanceDTO(model domain.Balance) *BalanceDTO {
-	return &BalanceDTO{
-		Amount:     model.Amount,
-		BTCAmount:  model.BTCAmount,
-		USDTAmount: model.USDTAmount,
-		Time:       model.Time.Unix(),
+// NewBalanceDTO creates DTO with the value in the fiat quote currency, e.g. EUR. The value is omitted
+// if the balance isn't valued in the quote currency
+func NewBalanceDTO(model domain.Balance, quote string) *BalanceDTO {
+	result := &BalanceDTO{
+		Amount:        model.Amount,
+		BTCAmount:     model.BTCAmount,
+		USDTAmount:    model.USDTAmount,
+		Reconstructed: model.Reconstructed,
+		Time:          model.Time.Unix(),
+	}
+	if value, ok := model.Fiat[quote]; ok {
+		result.Fiat = &value
 	}
+	return result
 }
 
 func (b BalancesResponse) Add(currency string, balance ...BalanceDTO) {
@@ -26,3 +35,88 @@ func (b BalancesResponse) Add(currency string, balance ...BalanceDTO) {
 	}
 	b[currency] = append(b[currency], balance...)
 }
+
+type BalanceCandlesResponse map[string][]BalanceCandleDTO //currency/candles for time range
+
+type OHLCDTO struct {
+	Open  float64 `json:"open"`
+	Close float64 `json:"close"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+}
+
+type BalanceCandleDTO struct {
+	Amount     OHLCDTO  `json:"amount"`
+	BTCAmount  OHLCDTO  `json:"btc"`
+	USDTAmount OHLCDTO  `json:"usdt"`
+	Fiat       *OHLCDTO `json:"fiat,omitempty"`
+	Time       int64    `json:"time"`
+}
+
+func NewBalanceCandleDTO(model domain.BalanceCandle, quote string) *BalanceCandleDTO {
+	result := &BalanceCandleDTO{
+		Amount:     OHLCDTO(model.Amount),
+		BTCAmount:  OHLCDTO(model.BTCAmount),
+		USDTAmount: OHLCDTO(model.USDTAmount),
+		Time:       model.Time.Unix(),
+	}
+	if value, ok := model.Fiat[quote]; ok {
+		fiat := OHLCDTO(value)
+		result.Fiat = &fiat
+	}
+	return result
+}
+
+func (b BalanceCandlesResponse) Add(currency string, candle ...BalanceCandleDTO) {
+	if len(candle) == 0 {
+		return
+	}
+	b[currency] = append(b[currency], candle...)
+}
+
+type PerformancePointDTO struct {
+	BTCAmount   float64 `json:"btc"`
+	USDTAmount  float64 `json:"usdt"`
+	NetFlowBTC  float64 `json:"net_flow_btc"`
+	NetFlowUSDT float64 `json:"net_flow_usdt"`
+	TWRBTC      float64 `json:"twr_btc"`
+	TWRUSDT     float64 `json:"twr_usdt"`
+	Time        int64   `json:"time"`
+}
+
+type ReturnDTO struct {
+	BTC  float64 `json:"btc"`
+	USDT float64 `json:"usdt"`
+}
+
+type PerformanceDTO struct {
+	Currency string                `json:"currency"`
+	Points   []PerformancePointDTO `json:"points"`
+	TWR      ReturnDTO             `json:"twr"`
+	MWR      ReturnDTO             `json:"mwr"`
+	// Unpriced is the number of transfers left out of flows because their rates are unknown
+	Unpriced int `json:"unpriced_transfers,omitempty"`
+}
+
+func NewPerformanceDTO(currency string, model domain.Performance) *PerformanceDTO {
+	points := []PerformancePointDTO{}
+	for _, p := range model.Points {
+		points = append(points, PerformancePointDTO{
+			BTCAmount:   p.BTCAmount,
+			USDTAmount:  p.USDTAmount,
+			NetFlowBTC:  p.NetFlowBTC,
+			NetFlowUSDT: p.NetFlowUSDT,
+			TWRBTC:      p.TWRBTC,
+			TWRUSDT:     p.TWRUSDT,
+			Time:        p.Time.Unix(),
+		})
+	}
+
+	return &PerformanceDTO{
+		Currency: currency,
+		Points:   points,
+		TWR:      ReturnDTO{BTC: model.TWRBTC, USDT: model.TWRUSDT},
+		MWR:      ReturnDTO{BTC: model.MWRBTC, USDT: model.MWRUSDT},
+		Unpriced: model.Unpriced,
+	}
+}
@@ -0,0 +1,17 @@
+package dto
+
+import "github.com/nawa/cryptoexchange-dashboard/domain"
+
+type MarketCandlesResponse map[string][]MarketCandleDTO //market/candles for time range
+
+type MarketCandleDTO struct {
+	Price OHLCDTO `json:"price"`
+	Time  int64   `json:"time"`
+}
+
+func NewMarketCandleDTO(model domain.MarketCandle) *MarketCandleDTO {
+	return &MarketCandleDTO{
+		Price: OHLCDTO(model.Price),
+		Time:  model.Time.Unix(),
+	}
+}
@@ -1,6 +1,10 @@
 package dto
 
-import "github.com/nawa/cryptoexchange-dashboard/domain"
+import (
+	"strings"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+)
 
 type OrderDTO struct {
 	Market      string  `json:"market"`
@@ -13,13 +17,9 @@ type OrderDTO struct {
 }
 
 func NewOrderDTO(m domain.Order) *OrderDTO {
-	var marketLink string
-	if m.Exchange == domain.ExchangeTypeBittrex {
-		marketLink = "https://bittrex.com/Market/Index?MarketName=" + m.Market
-	}
 	return &OrderDTO{
 		Market:      m.Market,
-		MarketLink:  marketLink,
+		MarketLink:  marketLink(m.Exchange, m.Market),
 		Time:        m.Time.Unix(),
 		BuyRate:     m.BuyRate,
 		Amount:      m.Amount,
@@ -27,3 +27,80 @@ func NewOrderDTO(m domain.Order) *OrderDTO {
 		USDTRate:    m.USDTRate,
 	}
 }
+
+type ProfitDTO struct {
+	Cost       float64 `json:"cost"`
+	Value      float64 `json:"value"`
+	Fee        float64 `json:"fee"`
+	Profit     float64 `json:"profit"`
+	ProfitBTC  float64 `json:"profit_btc"`
+	ProfitUSDT float64 `json:"profit_usdt"`
+	Percent    float64 `json:"profit_percent"`
+}
+
+type OpenPositionDTO struct {
+	OrderDTO
+	ProfitDTO
+}
+
+type ClosedPositionDTO struct {
+	Market     string  `json:"market"`
+	MarketLink string  `json:"market_link"`
+	Time       int64   `json:"time"`
+	Amount     float64 `json:"amount"`
+	BuyRate    float64 `json:"buy_rate"`
+	SellRate   float64 `json:"sell_rate"`
+	ProfitDTO
+}
+
+type ProfitTotalDTO struct {
+	BTC  float64 `json:"btc"`
+	USDT float64 `json:"usdt"`
+}
+
+type PnLDTO struct {
+	Open       []OpenPositionDTO   `json:"open"`
+	Closed     []ClosedPositionDTO `json:"closed"`
+	Unrealized ProfitTotalDTO      `json:"unrealized"`
+	Realized   ProfitTotalDTO      `json:"realized"`
+}
+
+func NewPnLDTO(m domain.PnL) *PnLDTO {
+	result := &PnLDTO{
+		Open:       make([]OpenPositionDTO, len(m.Open)),
+		Closed:     make([]ClosedPositionDTO, len(m.Closed)),
+		Unrealized: ProfitTotalDTO{BTC: m.UnrealizedBTC, USDT: m.UnrealizedUSDT},
+		Realized:   ProfitTotalDTO{BTC: m.RealizedBTC, USDT: m.RealizedUSDT},
+	}
+	for i, p := range m.Open {
+		result.Open[i] = OpenPositionDTO{
+			OrderDTO:  *NewOrderDTO(p.Order),
+			ProfitDTO: ProfitDTO(p.Profit),
+		}
+	}
+	for i, p := range m.Closed {
+		result.Closed[i] = ClosedPositionDTO{
+			Market:     p.Market,
+			MarketLink: marketLink(p.Exchange, p.Market),
+			Time:       p.Time.Unix(),
+			Amount:     p.Amount,
+			BuyRate:    p.BuyRate,
+			SellRate:   p.SellRate,
+			ProfitDTO:  ProfitDTO(p.Profit),
+		}
+	}
+	return result
+}
+
+func marketLink(exchange domain.ExchangeType, market string) string {
+	switch exchange {
+	case domain.ExchangeTypeBittrex:
+		return "https://bittrex.com/Market/Index?MarketName=" + market
+	case domain.ExchangeTypeBinance:
+		toFrom := strings.Split(market, "-")
+		if len(toFrom) == 2 {
+			return "https://www.binance.com/en/trade/" + toFrom[1] + "_" + toFrom[0]
+		}
+	}
+	return ""
+}
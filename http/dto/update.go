@@ -0,0 +1,36 @@
+package dto
+
+import "github.com/nawa/cryptoexchange-dashboard/domain"
+
+// UpdateTypeSubscription is the type of the message confirming the changed subscription with all its currencies
+const UpdateTypeSubscription = "subscription"
+
+// UpdateDTO is the message pushed to dashboard clients
+type UpdateDTO struct {
+	Type       string           `json:"type"`
+	Balances   BalancesResponse `json:"balances,omitempty"`
+	PnL        *PnLDTO          `json:"pnl,omitempty"`
+	Currencies []string         `json:"currencies,omitempty"`
+}
+
+func NewUpdateDTO(m domain.Update) *UpdateDTO {
+	result := &UpdateDTO{
+		Type: string(m.Type),
+	}
+	if len(m.Balances) > 0 {
+		result.Balances = BalancesResponse{}
+		for _, b := range m.Balances {
+			result.Balances.Add(b.Currency, *NewBalanceDTO(b, ""))
+		}
+	}
+	if m.PnL != nil {
+		result.PnL = NewPnLDTO(*m.PnL)
+	}
+	return result
+}
+
+// SubscriptionDTO is the message of websocket clients changing currencies they get balances of
+type SubscriptionDTO struct {
+	Subscribe   []string `json:"subscribe"`
+	Unsubscribe []string `json:"unsubscribe"`
+}
@@ -0,0 +1,103 @@
+package http
+
+import (
+	"strings"
+	"time"
+
+	"github.com/kataras/iris"
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/http/dto"
+	"github.com/nawa/cryptoexchange-dashboard/usecase"
+)
+
+// defaultMarketCandles is the number of candles returned if 'from' isn't set
+const defaultMarketCandles = 100
+
+type MarketHandler struct {
+	marketUsecase usecase.MarketUsecases
+}
+
+func NewMarketHandler(marketUsecase usecase.MarketUsecases) *MarketHandler {
+	return &MarketHandler{
+		marketUsecase: marketUsecase,
+	}
+}
+
+// Candles returns saved prices of the market in Bittrex notation, e.g. BTC-ETH, aggregated into candles
+// of 'interval' (1h by default) between 'from' and 'to' unix timestamps. 'to' is now by default,
+// 'from' is 100 intervals before 'to'. 'exchange' limits prices to one exchange
+func (h *MarketHandler) Candles(ctx iris.Context) {
+	market := strings.ToUpper(ctx.Params().Get("market"))
+	if market == "" {
+		WriteBadRequest(ctx, "':market' is empty")
+		return
+	}
+
+	exchange := domain.ExchangeType(strings.ToLower(ctx.URLParam("exchange")))
+	if exchange != "" && exchange != domain.ExchangeTypeBittrex && exchange != domain.ExchangeTypeBinance {
+		WriteBadRequest(ctx, "'exchange' is wrong")
+		return
+	}
+
+	interval := time.Hour
+	if ctx.URLParam("interval") != "" {
+		var err error
+		interval, err = time.ParseDuration(ctx.URLParam("interval"))
+		if err != nil {
+			WriteBadRequest(ctx, "'interval' is wrong")
+			return
+		}
+		if interval < time.Minute {
+			WriteBadRequest(ctx, "'interval' is < 1m")
+			return
+		}
+	}
+
+	to := time.Now()
+	if ctx.URLParam("to") != "" {
+		toUnix, err := ctx.URLParamInt64("to")
+		if err != nil {
+			WriteBadRequest(ctx, "'to' is wrong")
+			return
+		}
+		to = time.Unix(toUnix, 0)
+	}
+	from := to.Add(-defaultMarketCandles * interval)
+	if ctx.URLParam("from") != "" {
+		fromUnix, err := ctx.URLParamInt64("from")
+		if err != nil {
+			WriteBadRequest(ctx, "'from' is wrong")
+			return
+		}
+		from = time.Unix(fromUnix, 0)
+	}
+	if !from.Before(to) {
+		WriteBadRequest(ctx, "'from' is not before 'to'")
+		return
+	}
+	if to.Sub(from)/interval > maxRangeBuckets {
+		WriteBadRequest(ctx, "'interval' is too small for the range")
+		return
+	}
+
+	mCandles, err := h.marketUsecase.FetchCandles(exchange, market, from, to, interval)
+	if err != nil {
+		WriteInternalServerError(ctx, "internal error")
+		return
+	}
+
+	var curCandlesDTO []dto.MarketCandleDTO
+	for _, c := range mCandles {
+		curCandlesDTO = append(curCandlesDTO, *dto.NewMarketCandleDTO(c))
+	}
+
+	candlesDTO := dto.MarketCandlesResponse{}
+	if len(curCandlesDTO) > 0 {
+		candlesDTO[market] = curCandlesDTO
+	}
+
+	_, err = ctx.JSON(candlesDTO)
+	if err != nil {
+		panic(err)
+	}
+}
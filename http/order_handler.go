@@ -33,3 +33,16 @@ func (h *OrderHandler) GetActiveOrders(ctx iris.Context) {
 		panic(err)
 	}
 }
+
+func (h *OrderHandler) PnL(ctx iris.Context) {
+	pnl, err := h.orderUsecase.GetPnL()
+	if err != nil {
+		WriteInternalServerError(ctx, "internal error")
+		return
+	}
+
+	_, err = ctx.JSON(dto.NewPnLDTO(*pnl))
+	if err != nil {
+		panic(err)
+	}
+}
@@ -18,14 +18,22 @@ type Server struct {
 	log       *logrus.Entry
 }
 
-func NewServer(balanceUsecase usecase.BalanceUsecases, orderUsecase usecase.OrderUsecases) *Server {
+func NewServer(balanceUsecase usecase.BalanceUsecases, orderUsecase usecase.OrderUsecases, updateUsecase usecase.UpdateUsecases,
+	alertRuleUsecase usecase.AlertRuleUsecases, marketUsecase usecase.MarketUsecases, analyticsUsecase usecase.AnalyticsUsecases,
+	benchmarkUsecase usecase.BenchmarkUsecases, allocationUsecase usecase.AllocationUsecases) *Server {
 	app := iris.New()
 	app.Use(recover.New())
 	app.Use(cors.Default())
 
 	baseHandler := NewBaseHandler()
-	balanceHandler := NewBalanceHandler(balanceUsecase)
+	balanceHandler := NewBalanceHandler(balanceUsecase, benchmarkUsecase)
 	orderHandler := NewOrderHandler(orderUsecase)
+	wsHandler := NewWSHandler(updateUsecase)
+	streamHandler := NewStreamHandler(balanceUsecase, updateUsecase)
+	alertHandler := NewAlertHandler(alertRuleUsecase)
+	marketHandler := NewMarketHandler(marketUsecase)
+	analyticsHandler := NewAnalyticsHandler(analyticsUsecase)
+	allocationHandler := NewAllocationHandler(allocationUsecase)
 
 	app.Get("ping", baseHandler.Ping)
 
@@ -34,10 +42,34 @@ func NewServer(balanceUsecase usecase.BalanceUsecases, orderUsecase usecase.Orde
 	balanceGroup.Get("/period/weekly", balanceHandler.Weekly)
 	balanceGroup.Get("/period/monthly", balanceHandler.Monthly)
 	balanceGroup.Get("/period/all", balanceHandler.All)
+	balanceGroup.Get("/range", balanceHandler.Range)
+	balanceGroup.Get("/performance", balanceHandler.Performance)
+	balanceGroup.Get("/stream", streamHandler.Balance)
 
 	balanceGroup.Get("/active", balanceHandler.ActiveCurrencies)
 
 	app.Get("/order", orderHandler.GetActiveOrders)
+	app.Get("/order/pnl", orderHandler.PnL)
+
+	app.Get("/market/{market}/candles", marketHandler.Candles)
+
+	app.Get("/analytics", analyticsHandler.Analytics)
+
+	allocationGroup := app.Party("/allocation")
+	allocationGroup.Get("/", allocationHandler.Current)
+	allocationGroup.Get("/range", allocationHandler.Range)
+	allocationGroup.Get("/rebalance", allocationHandler.Rebalance)
+
+	app.Get("/ws", wsHandler.Serve)
+
+	alertGroup := app.Party("/alerts")
+	alertGroup.Get("/", alertHandler.List)
+	alertGroup.Post("/", alertHandler.Create)
+	alertGroup.Get("/triggers", alertHandler.Triggers)
+	alertGroup.Get("/{id}", alertHandler.Get)
+	alertGroup.Put("/{id}", alertHandler.Update)
+	alertGroup.Delete("/{id}", alertHandler.Delete)
+	alertGroup.Get("/{id}/triggers", alertHandler.Triggers)
 
 	server := &Server{
 		app: app,
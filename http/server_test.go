@@ -1,35 +1,63 @@
 package http
 
 import (
+	"bufio"
+	nethttp "net/http"
+	nethttptest "net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/gorilla/websocket"
 	"github.com/iris-contrib/httpexpect"
 	"github.com/kataras/iris/httptest"
 	"github.com/pkg/errors"
+	assert "github.com/stretchr/testify/require"
 
 	"github.com/nawa/cryptoexchange-dashboard/domain"
 	"github.com/nawa/cryptoexchange-dashboard/http/testdata"
+	"github.com/nawa/cryptoexchange-dashboard/storage/memory"
+	storagemocks "github.com/nawa/cryptoexchange-dashboard/storage/mocks"
+	"github.com/nawa/cryptoexchange-dashboard/usecase"
 	"github.com/nawa/cryptoexchange-dashboard/usecase/mocks"
 )
 
 type HTTPServerMock struct {
-	Server     *Server
-	BalanceUC  *mocks.MockBalanceUsecases
-	OrderUC    *mocks.MockOrderUsecases
-	HTTPExpect *httpexpect.Expect
+	Server       *Server
+	BalanceUC    *mocks.MockBalanceUsecases
+	OrderUC      *mocks.MockOrderUsecases
+	UpdateUC     *mocks.MockUpdateUsecases
+	AlertRuleUC  *mocks.MockAlertRuleUsecases
+	MarketUC     *mocks.MockMarketUsecases
+	AnalyticsUC  *mocks.MockAnalyticsUsecases
+	BenchmarkUC  *mocks.MockBenchmarkUsecases
+	AllocationUC *mocks.MockAllocationUsecases
+	HTTPExpect   *httpexpect.Expect
 }
 
 func NewHTTPServerMock(t *testing.T, ctrl *gomock.Controller) *HTTPServerMock {
 	balanceUC := mocks.NewMockBalanceUsecases(ctrl)
 	orderUC := mocks.NewMockOrderUsecases(ctrl)
-	server := NewServer(balanceUC, orderUC)
+	updateUC := mocks.NewMockUpdateUsecases(ctrl)
+	alertRuleUC := mocks.NewMockAlertRuleUsecases(ctrl)
+	marketUC := mocks.NewMockMarketUsecases(ctrl)
+	analyticsUC := mocks.NewMockAnalyticsUsecases(ctrl)
+	benchmarkUC := mocks.NewMockBenchmarkUsecases(ctrl)
+	allocationUC := mocks.NewMockAllocationUsecases(ctrl)
+	server := NewServer(balanceUC, orderUC, updateUC, alertRuleUC, marketUC, analyticsUC, benchmarkUC, allocationUC)
 
 	return &HTTPServerMock{
-		Server:     server,
-		HTTPExpect: httptest.New(t, server.app),
-		BalanceUC:  balanceUC,
-		OrderUC:    orderUC,
+		Server:       server,
+		HTTPExpect:   httptest.New(t, server.app),
+		BalanceUC:    balanceUC,
+		OrderUC:      orderUC,
+		UpdateUC:     updateUC,
+		AlertRuleUC:  alertRuleUC,
+		MarketUC:     marketUC,
+		AnalyticsUC:  analyticsUC,
+		BenchmarkUC:  benchmarkUC,
+		AllocationUC: allocationUC,
 	}
 }
 
@@ -60,6 +88,40 @@ func TestBalanceHandler_Hourly(t *testing.T) {
 				response.Status(httptest.StatusOK)
 				response.Body().Equal(`{"CUR1":[{"amount":1,"btc":1,"usdt":2,"time":0},{"amount":2,"btc":2,"usdt":4,"time":3600}]}`)
 			},
+		}, {
+			name: "correct with quote",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				balances := testdata.Balances()["CUR1"]
+				balances[1].Fiat = map[string]float64{"EUR": 3.2, "GBP": 3}
+				mock.BalanceUC.EXPECT().
+					FetchHourly("CUR1", 1).
+					Return(balances, nil)
+
+				response := mock.HTTPExpect.GET("/balance/period/hourly/1").
+					WithQuery("currency", "CUR1").
+					WithQuery("quote", "eur").
+					Expect()
+
+				// the balance saved before EUR has been configured has no fiat value
+				response.Status(httptest.StatusOK)
+				response.Body().Equal(`{"CUR1":[{"amount":1,"btc":1,"usdt":2,"time":0},{"amount":2,"btc":2,"usdt":4,"fiat":3.2,"time":3600}]}`)
+			},
+		}, {
+			name: "correct with reconstructed",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				balances := testdata.Balances()["CUR1"]
+				balances[0].Reconstructed = true
+				mock.BalanceUC.EXPECT().
+					FetchHourly("CUR1", 1).
+					Return(balances, nil)
+
+				response := mock.HTTPExpect.GET("/balance/period/hourly/1").
+					WithQuery("currency", "CUR1").
+					Expect()
+
+				response.Status(httptest.StatusOK)
+				response.Body().Equal(`{"CUR1":[{"amount":1,"btc":1,"usdt":2,"reconstructed":true,"time":0},{"amount":2,"btc":2,"usdt":4,"time":3600}]}`)
+			},
 		}, {
 			name: "correct with unknown currency",
 			test: func(t *testing.T, mock *HTTPServerMock) {
@@ -211,6 +273,62 @@ func TestBalanceHandler_Monthly(t *testing.T) {
 
 				response.Status(httptest.StatusBadRequest)
 			},
+		}, {
+			name: "correct with benchmark",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				balances := []domain.Balance{
+					{Currency: "total", BTCAmount: 2, USDTAmount: 4, Time: time.Unix(7200, 0)},
+					{Currency: "total", BTCAmount: 1, USDTAmount: 2, Time: time.Unix(3600, 0)},
+				}
+				mock.BalanceUC.EXPECT().
+					FetchMonthly("total").
+					Return(balances, nil)
+				mock.BenchmarkUC.EXPECT().
+					Compare(time.Unix(3600, 0), []time.Time{time.Unix(7200, 0), time.Unix(3600, 0)}, time.Hour).
+					Return(&domain.Benchmark{
+						Start:   time.Unix(3600, 0),
+						HODL:    []domain.Balance{{Currency: "hodl", BTCAmount: 1.5, USDTAmount: 3, Time: time.Unix(7200, 0)}},
+						HODLBTC: []domain.Balance{{Currency: "hodl-btc", Amount: 1, BTCAmount: 1, USDTAmount: 2.5, Time: time.Unix(7200, 0)}},
+					}, nil)
+
+				response := mock.HTTPExpect.GET("/balance/period/monthly").
+					WithQuery("currency", "total").
+					WithQuery("benchmark", 3600).
+					Expect()
+
+				response.Status(httptest.StatusOK)
+				response.Body().Equal(`{"hodl":[{"amount":0,"btc":1.5,"usdt":3,"time":7200}],` +
+					`"hodl-btc":[{"amount":1,"btc":1,"usdt":2.5,"time":7200}],` +
+					`"total":[{"amount":0,"btc":2,"usdt":4,"time":7200},{"amount":0,"btc":1,"usdt":2,"time":3600}]}`)
+			},
+		}, {
+			name: "incorrect request: 'benchmark' for a coin",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				response := mock.HTTPExpect.GET("/balance/period/monthly").
+					WithQuery("currency", "CUR1").
+					WithQuery("benchmark", 3600).
+					Expect()
+
+				response.Status(httptest.StatusBadRequest)
+				response.Body().Equal(`{"status":400,"message":"'benchmark' is only for 'total' currency"}`)
+			},
+		}, {
+			name: "error in benchmark",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				mock.BalanceUC.EXPECT().
+					FetchMonthly("total").
+					Return([]domain.Balance{{Currency: "total", Time: time.Unix(3600, 0)}}, nil)
+				mock.BenchmarkUC.EXPECT().
+					Compare(time.Unix(0, 0), []time.Time{time.Unix(3600, 0)}, time.Hour).
+					Return(nil, errors.New("unexpected error"))
+
+				response := mock.HTTPExpect.GET("/balance/period/monthly").
+					WithQuery("currency", "total").
+					WithQuery("benchmark", 0).
+					Expect()
+
+				response.Status(httptest.StatusInternalServerError)
+			},
 		}, {
 			name: "error from usecase level",
 			test: func(t *testing.T, mock *HTTPServerMock) {
@@ -283,6 +401,239 @@ func TestBalanceHandler_All(t *testing.T) {
 	})
 }
 
+func TestBalanceHandler_Range(t *testing.T) {
+	runTestCases(t, []testCase{
+		{
+			name: "correct",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				mock.BalanceUC.EXPECT().
+					FetchRange("CUR1", time.Unix(0, 0), time.Unix(7200, 0), time.Hour).
+					Return(testdata.BalanceCandles(), nil)
+
+				response := mock.HTTPExpect.GET("/balance/range").
+					WithQuery("currency", "CUR1").
+					WithQuery("from", 0).
+					WithQuery("to", 7200).
+					WithQuery("step", "1h").
+					Expect()
+
+				response.Status(httptest.StatusOK)
+				response.Body().Equal(`{"CUR1":[{"amount":{"open":2,"close":3,"min":1,"max":4},"btc":{"open":2,"close":3,"min":1,"max":4},"usdt":{"open":4,"close":6,"min":2,"max":8},"time":3600}]}`)
+			},
+		}, {
+			name: "correct with quote",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				candles := testdata.BalanceCandles()
+				candles[0].Fiat = map[string]domain.OHLC{"EUR": {Open: 3.2, Close: 4.8, Min: 1.6, Max: 6.4}}
+				mock.BalanceUC.EXPECT().
+					FetchRange("CUR1", time.Unix(0, 0), time.Unix(7200, 0), time.Hour).
+					Return(candles, nil)
+
+				response := mock.HTTPExpect.GET("/balance/range").
+					WithQuery("currency", "CUR1").
+					WithQuery("from", 0).
+					WithQuery("to", 7200).
+					WithQuery("step", "1h").
+					WithQuery("quote", "EUR").
+					Expect()
+
+				response.Status(httptest.StatusOK)
+				response.Body().Equal(`{"CUR1":[{"amount":{"open":2,"close":3,"min":1,"max":4},"btc":{"open":2,"close":3,"min":1,"max":4},"usdt":{"open":4,"close":6,"min":2,"max":8},"fiat":{"open":3.2,"close":4.8,"min":1.6,"max":6.4},"time":3600}]}`)
+			},
+		}, {
+			name: "correct with unknown currency",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				mock.BalanceUC.EXPECT().
+					FetchRange("CUR1", time.Unix(0, 0), time.Unix(7200, 0), time.Hour).
+					Return([]domain.BalanceCandle{}, nil)
+
+				response := mock.HTTPExpect.GET("/balance/range").
+					WithQuery("currency", "CUR1").
+					WithQuery("from", 0).
+					WithQuery("to", 7200).
+					WithQuery("step", "1h").
+					Expect()
+
+				response.Status(httptest.StatusOK)
+				response.Body().Equal(`{}`)
+			},
+		}, {
+			name: "correct without 'to'",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				from := time.Now().Add(-24 * time.Hour).Unix()
+				mock.BalanceUC.EXPECT().
+					FetchRange("CUR1", time.Unix(from, 0), gomock.Any(), time.Hour).
+					Return([]domain.BalanceCandle{}, nil)
+
+				response := mock.HTTPExpect.GET("/balance/range").
+					WithQuery("currency", "CUR1").
+					WithQuery("from", from).
+					WithQuery("step", "1h").
+					Expect()
+
+				response.Status(httptest.StatusOK)
+			},
+		}, {
+			name: "incorrect request: 'currency' is missing",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				response := mock.HTTPExpect.GET("/balance/range").
+					WithQuery("from", 0).
+					WithQuery("to", 7200).
+					WithQuery("step", "1h").
+					Expect()
+
+				response.Status(httptest.StatusBadRequest)
+			},
+		}, {
+			name: "incorrect request: 'from' is missing",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				response := mock.HTTPExpect.GET("/balance/range").
+					WithQuery("currency", "CUR1").
+					WithQuery("to", 7200).
+					WithQuery("step", "1h").
+					Expect()
+
+				response.Status(httptest.StatusBadRequest)
+			},
+		}, {
+			name: "incorrect request: 'to' is not int",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				response := mock.HTTPExpect.GET("/balance/range").
+					WithQuery("currency", "CUR1").
+					WithQuery("from", 0).
+					WithQuery("to", "s").
+					WithQuery("step", "1h").
+					Expect()
+
+				response.Status(httptest.StatusBadRequest)
+			},
+		}, {
+			name: "incorrect request: 'from' is after 'to'",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				response := mock.HTTPExpect.GET("/balance/range").
+					WithQuery("currency", "CUR1").
+					WithQuery("from", 7200).
+					WithQuery("to", 0).
+					WithQuery("step", "1h").
+					Expect()
+
+				response.Status(httptest.StatusBadRequest)
+			},
+		}, {
+			name: "incorrect request: 'step' is wrong",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				response := mock.HTTPExpect.GET("/balance/range").
+					WithQuery("currency", "CUR1").
+					WithQuery("from", 0).
+					WithQuery("to", 7200).
+					WithQuery("step", "1").
+					Expect()
+
+				response.Status(httptest.StatusBadRequest)
+			},
+		}, {
+			name: "incorrect request: too many buckets",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				response := mock.HTTPExpect.GET("/balance/range").
+					WithQuery("currency", "CUR1").
+					WithQuery("from", 0).
+					WithQuery("to", 3600*24*365).
+					WithQuery("step", "1m").
+					Expect()
+
+				response.Status(httptest.StatusBadRequest)
+			},
+		},
+		{
+			name: "error from usecase level",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				mock.BalanceUC.EXPECT().
+					FetchRange("CUR1", time.Unix(0, 0), time.Unix(7200, 0), time.Hour).
+					Return(nil, errors.New("unexpected error"))
+
+				response := mock.HTTPExpect.GET("/balance/range").
+					WithQuery("currency", "CUR1").
+					WithQuery("from", 0).
+					WithQuery("to", 7200).
+					WithQuery("step", "1h").
+					Expect()
+
+				response.Status(httptest.StatusInternalServerError)
+			},
+		},
+	})
+}
+
+func TestBalanceHandler_Performance(t *testing.T) {
+	runTestCases(t, []testCase{
+		{
+			name: "correct",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				mock.BalanceUC.EXPECT().
+					FetchPerformance("total", time.Unix(0, 0), time.Unix(7200, 0), time.Hour).
+					Return(testdata.Performance(), nil)
+
+				response := mock.HTTPExpect.GET("/balance/performance").
+					WithQuery("currency", "total").
+					WithQuery("from", 0).
+					WithQuery("to", 7200).
+					WithQuery("step", "1h").
+					Expect()
+
+				response.Status(httptest.StatusOK)
+				response.Body().Equal(`{"currency":"total","points":[` +
+					`{"btc":2,"usdt":4,"net_flow_btc":0.5,"net_flow_usdt":1,"twr_btc":0.25,"twr_usdt":0.5,"time":3600},` +
+					`{"btc":1,"usdt":2,"net_flow_btc":0,"net_flow_usdt":0,"twr_btc":0,"twr_usdt":0,"time":0}],` +
+					`"twr":{"btc":0.25,"usdt":0.5},"mwr":{"btc":0.2,"usdt":0.4}}`)
+			},
+		}, {
+			name: "correct with no balances",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				mock.BalanceUC.EXPECT().
+					FetchPerformance("CUR1", time.Unix(0, 0), time.Unix(7200, 0), time.Hour).
+					Return(&domain.Performance{}, nil)
+
+				response := mock.HTTPExpect.GET("/balance/performance").
+					WithQuery("currency", "CUR1").
+					WithQuery("from", 0).
+					WithQuery("to", 7200).
+					WithQuery("step", "1h").
+					Expect()
+
+				response.Status(httptest.StatusOK)
+				response.Body().Equal(`{"currency":"CUR1","points":[],"twr":{"btc":0,"usdt":0},"mwr":{"btc":0,"usdt":0}}`)
+			},
+		}, {
+			name: "incorrect request: 'step' is missing",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				response := mock.HTTPExpect.GET("/balance/performance").
+					WithQuery("currency", "CUR1").
+					WithQuery("from", 0).
+					WithQuery("to", 7200).
+					Expect()
+
+				response.Status(httptest.StatusBadRequest)
+			},
+		}, {
+			name: "error in usecase",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				mock.BalanceUC.EXPECT().
+					FetchPerformance("CUR1", time.Unix(0, 0), time.Unix(7200, 0), time.Hour).
+					Return(nil, errors.New("unexpected error"))
+
+				response := mock.HTTPExpect.GET("/balance/performance").
+					WithQuery("currency", "CUR1").
+					WithQuery("from", 0).
+					WithQuery("to", 7200).
+					WithQuery("step", "1h").
+					Expect()
+
+				response.Status(httptest.StatusInternalServerError)
+			},
+		},
+	})
+}
+
 func TestBalanceHandler_ActiveCurrencies(t *testing.T) {
 	runTestCases(t, []testCase{
 		{
@@ -298,6 +649,22 @@ func TestBalanceHandler_ActiveCurrencies(t *testing.T) {
 				response.Status(httptest.StatusOK)
 				response.Body().Equal(`{"CUR1":[{"amount":1,"btc":1,"usdt":2,"time":0},{"amount":2,"btc":2,"usdt":4,"time":3600}],"CUR2":[{"amount":3,"btc":3,"usdt":5,"time":7200}]}`)
 			},
+		}, {
+			name: "correct with quote",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				balances := testdata.Balances()["CUR2"]
+				balances[0].Fiat = map[string]float64{"EUR": 4, "GBP": 3.75}
+				mock.BalanceUC.EXPECT().
+					GetActiveCurrencies().
+					Return(balances, nil)
+
+				response := mock.HTTPExpect.GET("/balance/active").
+					WithQuery("quote", "GBP").
+					Expect()
+
+				response.Status(httptest.StatusOK)
+				response.Body().Equal(`{"CUR2":[{"amount":3,"btc":3,"usdt":5,"fiat":3.75,"time":7200}]}`)
+			},
 		}, {
 			name: "error from usecase level",
 			test: func(t *testing.T, mock *HTTPServerMock) {
@@ -314,42 +681,139 @@ func TestBalanceHandler_ActiveCurrencies(t *testing.T) {
 	})
 }
 
-func TestOrderHandler_GetActiveOrders(t *testing.T) {
+func TestBalanceHandler_MemoryStorage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	balanceStorage := memory.NewBalanceStorage()
+	server := NewServer(usecase.NewBalanceUsecase(storagemocks.NewMockExchange(ctrl), balanceStorage, storagemocks.NewMockTransferStorage(ctrl), memory.NewMarketStorage()),
+		mocks.NewMockOrderUsecases(ctrl), mocks.NewMockUpdateUsecases(ctrl), mocks.NewMockAlertRuleUsecases(ctrl), mocks.NewMockMarketUsecases(ctrl),
+		mocks.NewMockAnalyticsUsecases(ctrl), mocks.NewMockBenchmarkUsecases(ctrl), mocks.NewMockAllocationUsecases(ctrl))
+	e := httptest.New(t, server.app)
+
+	e.GET("/balance/active").Expect().Status(httptest.StatusInternalServerError)
+
+	now := time.Now().Truncate(time.Second)
+	for _, currency := range []string{"CUR1", "CUR2"} {
+		for _, b := range testdata.Balances()[currency] {
+			b.Time = now.Add(-90 * time.Minute).Add(b.Time.Sub(time.Unix(0, 0)))
+			assert.NoError(t, balanceStorage.Save(b))
+		}
+	}
+
+	e.GET("/balance/active").Expect().
+		Status(httptest.StatusOK).
+		JSON().Object().Keys().ContainsOnly("CUR2")
+
+	e.GET("/balance/period/hourly/1").
+		WithQuery("currency", "CUR1").
+		Expect().
+		Status(httptest.StatusOK).
+		JSON().Object().Value("CUR1").Array().Length().Equal(1)
+
+	candles := e.GET("/balance/range").
+		WithQuery("currency", "CUR1").
+		WithQuery("from", now.Add(-2*time.Hour).Unix()).
+		WithQuery("to", now.Unix()).
+		WithQuery("step", "1h").
+		Expect().
+		Status(httptest.StatusOK).
+		JSON().Object().Value("CUR1").Array()
+	candles.Length().Equal(2)
+	candles.Element(0).Object().ValueEqual("amount", map[string]float64{"open": 2, "close": 2, "min": 2, "max": 2})
+	candles.Element(1).Object().ValueEqual("amount", map[string]float64{"open": 1, "close": 1, "min": 1, "max": 1})
+}
+
+func TestMarketHandler_Candles(t *testing.T) {
+	candles := []domain.MarketCandle{{
+		Exchange: domain.ExchangeTypeBittrex,
+		Market:   "BTC-CUR1",
+		Time:     time.Unix(3600, 0),
+		Price:    domain.OHLC{Open: 0.2, Close: 0.3, Min: 0.1, Max: 0.4},
+	}}
 	runTestCases(t, []testCase{
 		{
 			name: "correct",
 			test: func(t *testing.T, mock *HTTPServerMock) {
-				mock.OrderUC.EXPECT().
-					GetActiveOrders().
-					Return(testdata.Orders(), nil)
+				mock.MarketUC.EXPECT().
+					FetchCandles(domain.ExchangeTypeBittrex, "BTC-CUR1", time.Unix(0, 0), time.Unix(7200, 0), 30*time.Minute).
+					Return(candles, nil)
 
-				response := mock.HTTPExpect.GET("/order").
+				response := mock.HTTPExpect.GET("/market/btc-cur1/candles").
+					WithQuery("exchange", "Bittrex").
+					WithQuery("interval", "30m").
+					WithQuery("from", 0).
+					WithQuery("to", 7200).
 					Expect()
 
 				response.Status(httptest.StatusOK)
-				response.Body().Equal(`[{"market":"market1","market_link":"https://bittrex.com/Market/Index?MarketName=market1","time":0,"buy_rate":1,"amount":2,"sellnow_rate":3,"usdt_rate":4},{"market":"market2","market_link":"https://bittrex.com/Market/Index?MarketName=market2","time":3600,"buy_rate":5,"amount":6,"sellnow_rate":7,"usdt_rate":8}]`)
+				response.Body().Equal(`{"BTC-CUR1":[{"price":{"open":0.2,"close":0.3,"min":0.1,"max":0.4},"time":3600}]}`)
 			},
 		}, {
-			name: "correct with no orders",
+			name: "correct with defaults",
 			test: func(t *testing.T, mock *HTTPServerMock) {
-				mock.OrderUC.EXPECT().
-					GetActiveOrders().
-					Return([]domain.Order{}, nil)
+				mock.MarketUC.EXPECT().
+					FetchCandles(domain.ExchangeType(""), "BTC-CUR1", time.Unix(7200, 0).Add(-100*time.Hour), time.Unix(7200, 0), time.Hour).
+					Return([]domain.MarketCandle{}, nil)
 
-				response := mock.HTTPExpect.GET("/order").
+				response := mock.HTTPExpect.GET("/market/BTC-CUR1/candles").
+					WithQuery("to", 7200).
 					Expect()
 
 				response.Status(httptest.StatusOK)
-				response.Body().Equal(`[]`)
+				response.Body().Equal(`{}`)
 			},
 		}, {
-			name: "error from usecase level",
+			name: "wrong 'exchange'",
 			test: func(t *testing.T, mock *HTTPServerMock) {
-				mock.OrderUC.EXPECT().
-					GetActiveOrders().
-					Return(nil, errors.New("unexpected error"))
+				response := mock.HTTPExpect.GET("/market/BTC-CUR1/candles").
+					WithQuery("exchange", "poloniex").
+					Expect()
 
-				response := mock.HTTPExpect.GET("/order").
+				response.Status(httptest.StatusBadRequest)
+			},
+		}, {
+			name: "wrong 'interval'",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				mock.HTTPExpect.GET("/market/BTC-CUR1/candles").
+					WithQuery("interval", "hour").
+					Expect().
+					Status(httptest.StatusBadRequest)
+
+				mock.HTTPExpect.GET("/market/BTC-CUR1/candles").
+					WithQuery("interval", "1s").
+					Expect().
+					Status(httptest.StatusBadRequest)
+
+				mock.HTTPExpect.GET("/market/BTC-CUR1/candles").
+					WithQuery("interval", "1m").
+					WithQuery("from", 0).
+					WithQuery("to", 7200000).
+					Expect().
+					Status(httptest.StatusBadRequest)
+			},
+		}, {
+			name: "wrong 'from'",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				mock.HTTPExpect.GET("/market/BTC-CUR1/candles").
+					WithQuery("from", "yesterday").
+					Expect().
+					Status(httptest.StatusBadRequest)
+
+				mock.HTTPExpect.GET("/market/BTC-CUR1/candles").
+					WithQuery("from", 7200).
+					WithQuery("to", 3600).
+					Expect().
+					Status(httptest.StatusBadRequest)
+			},
+		}, {
+			name: "internal error",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				mock.MarketUC.EXPECT().
+					FetchCandles(gomock.Any(), "BTC-CUR1", gomock.Any(), gomock.Any(), time.Hour).
+					Return(nil, errors.New("some error"))
+
+				response := mock.HTTPExpect.GET("/market/BTC-CUR1/candles").
 					Expect()
 
 				response.Status(httptest.StatusInternalServerError)
@@ -358,6 +822,787 @@ func TestOrderHandler_GetActiveOrders(t *testing.T) {
 	})
 }
 
+func TestAnalyticsHandler_Analytics(t *testing.T) {
+	day := 24 * time.Hour
+	analytics := &domain.Analytics{
+		Periods: 3,
+		USDT: domain.ReturnStats{
+			Return:           0.5,
+			MaxDrawdown:      0.2,
+			DrawdownPeak:     time.Unix(0, 0).Add(day),
+			DrawdownTrough:   time.Unix(0, 0).Add(2 * day),
+			DrawdownRecovery: time.Unix(0, 0).Add(3 * day),
+			Volatility:       7,
+			Sharpe:           2,
+			Sortino:          4,
+			Best:             domain.PeriodReturn{Time: time.Unix(0, 0).Add(3 * day), Return: 0.5},
+			Worst:            domain.PeriodReturn{Time: time.Unix(0, 0).Add(2 * day), Return: -0.2},
+			Rolling:          []domain.PeriodReturn{{Time: time.Unix(0, 0).Add(3 * day), Return: 0.2}},
+		},
+	}
+	runTestCases(t, []testCase{
+		{
+			name: "correct",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				mock.AnalyticsUC.EXPECT().
+					Analyze("total", time.Unix(0, 0), time.Unix(345600, 0), day, 2).
+					Return(analytics, nil)
+
+				response := mock.HTTPExpect.GET("/analytics").
+					WithQuery("currency", "total").
+					WithQuery("from", 0).
+					WithQuery("to", 345600).
+					WithQuery("window", "60h").
+					Expect()
+
+				response.Status(httptest.StatusOK)
+				response.Body().Equal(`{"currency":"total","step":"24h0m0s","window":"48h0m0s","periods":3,` +
+					`"btc":{"return":0,"max_drawdown":{"value":0},"volatility":0,"sharpe":0,"sortino":0,"rolling":[]},` +
+					`"usdt":{"return":0.5,"max_drawdown":{"value":0.2,"peak":86400,"trough":172800,"recovery":259200},` +
+					`"volatility":7,"sharpe":2,"sortino":4,"best":{"return":0.5,"time":259200},"worst":{"return":-0.2,"time":172800},` +
+					`"rolling":[{"return":0.2,"time":259200}]}}`)
+			},
+		}, {
+			name: "correct with step",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				mock.AnalyticsUC.EXPECT().
+					Analyze("CUR1", time.Unix(0, 0), time.Unix(7200, 0), time.Hour, 168).
+					Return(&domain.Analytics{}, nil)
+
+				response := mock.HTTPExpect.GET("/analytics").
+					WithQuery("currency", "CUR1").
+					WithQuery("from", 0).
+					WithQuery("to", 7200).
+					WithQuery("step", "1h").
+					Expect()
+
+				response.Status(httptest.StatusOK)
+			},
+		}, {
+			name: "incorrect request: 'window' is shorter than 'step'",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				response := mock.HTTPExpect.GET("/analytics").
+					WithQuery("currency", "CUR1").
+					WithQuery("from", 0).
+					WithQuery("to", 7200).
+					WithQuery("window", "1h").
+					Expect()
+
+				response.Status(httptest.StatusBadRequest)
+				response.Body().Equal(`{"status":400,"message":"'window' is shorter than 'step'"}`)
+			},
+		}, {
+			name: "incorrect request: 'from' is missing",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				response := mock.HTTPExpect.GET("/analytics").
+					WithQuery("currency", "CUR1").
+					Expect()
+
+				response.Status(httptest.StatusBadRequest)
+			},
+		}, {
+			name: "error in usecase",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				mock.AnalyticsUC.EXPECT().
+					Analyze("CUR1", time.Unix(0, 0), time.Unix(7200, 0), time.Hour, 168).
+					Return(nil, errors.New("unexpected error"))
+
+				response := mock.HTTPExpect.GET("/analytics").
+					WithQuery("currency", "CUR1").
+					WithQuery("from", 0).
+					WithQuery("to", 7200).
+					WithQuery("step", "1h").
+					Expect()
+
+				response.Status(httptest.StatusInternalServerError)
+			},
+		},
+	})
+}
+
+func TestAllocationHandler_Current(t *testing.T) {
+	runTestCases(t, []testCase{
+		{
+			name: "correct",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				mock.AllocationUC.EXPECT().
+					GetAllocation().
+					Return(&domain.Allocation{
+						Time:       time.Unix(3600, 0),
+						BTCAmount:  2,
+						USDTAmount: 20000,
+						Coins: []domain.Share{
+							{Name: "BTC", BTCAmount: 1.5, USDTAmount: 15000, Share: 0.75},
+							{Name: "CUR1", BTCAmount: 0.5, USDTAmount: 5000, Share: 0.25},
+						},
+						Exchanges: []domain.Share{
+							{Name: "bittrex", BTCAmount: 2, USDTAmount: 20000, Share: 1},
+						},
+					}, nil)
+
+				response := mock.HTTPExpect.GET("/allocation").Expect()
+
+				response.Status(httptest.StatusOK)
+				response.Body().Equal(`{"btc_amount":2,"usdt_amount":20000,"time":3600,` +
+					`"coins":[{"name":"BTC","btc_amount":1.5,"usdt_amount":15000,"share":0.75},` +
+					`{"name":"CUR1","btc_amount":0.5,"usdt_amount":5000,"share":0.25}],` +
+					`"exchanges":[{"name":"bittrex","btc_amount":2,"usdt_amount":20000,"share":1}]}`)
+			},
+		}, {
+			name: "error in usecase",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				mock.AllocationUC.EXPECT().
+					GetAllocation().
+					Return(nil, errors.New("unexpected error"))
+
+				response := mock.HTTPExpect.GET("/allocation").Expect()
+
+				response.Status(httptest.StatusInternalServerError)
+			},
+		},
+	})
+}
+
+func TestAllocationHandler_Range(t *testing.T) {
+	runTestCases(t, []testCase{
+		{
+			name: "correct",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				mock.AllocationUC.EXPECT().
+					FetchAllocations(time.Unix(0, 0), time.Unix(7200, 0), time.Hour).
+					Return([]domain.Allocation{
+						{Time: time.Unix(3600, 0), BTCAmount: 1, Coins: []domain.Share{{Name: "BTC", BTCAmount: 1, Share: 1}}},
+					}, nil)
+
+				response := mock.HTTPExpect.GET("/allocation/range").
+					WithQuery("from", 0).
+					WithQuery("to", 7200).
+					WithQuery("step", "1h").
+					Expect()
+
+				response.Status(httptest.StatusOK)
+				response.Body().Equal(`[{"btc_amount":1,"usdt_amount":0,"time":3600,` +
+					`"coins":[{"name":"BTC","btc_amount":1,"usdt_amount":0,"share":1}],"exchanges":[]}]`)
+			},
+		}, {
+			name: "correct with defaults",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				day := 24 * time.Hour
+				mock.AllocationUC.EXPECT().
+					FetchAllocations(time.Unix(0, 0), time.Unix(0, 0).Add(30*day), day).
+					Return(nil, nil)
+
+				response := mock.HTTPExpect.GET("/allocation/range").
+					WithQuery("to", 30*86400).
+					Expect()
+
+				response.Status(httptest.StatusOK)
+				response.Body().Equal(`[]`)
+			},
+		}, {
+			name: "incorrect request: 'step' is too small for the range",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				response := mock.HTTPExpect.GET("/allocation/range").
+					WithQuery("from", 0).
+					WithQuery("to", 86400).
+					WithQuery("step", "1m").
+					Expect()
+
+				response.Status(httptest.StatusBadRequest)
+				response.Body().Equal(`{"status":400,"message":"'step' is too small for the range"}`)
+			},
+		}, {
+			name: "incorrect request: 'from' is not before 'to'",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				response := mock.HTTPExpect.GET("/allocation/range").
+					WithQuery("from", 7200).
+					WithQuery("to", 3600).
+					Expect()
+
+				response.Status(httptest.StatusBadRequest)
+			},
+		}, {
+			name: "error in usecase",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				mock.AllocationUC.EXPECT().
+					FetchAllocations(time.Unix(0, 0), time.Unix(7200, 0), time.Hour).
+					Return(nil, errors.New("unexpected error"))
+
+				response := mock.HTTPExpect.GET("/allocation/range").
+					WithQuery("from", 0).
+					WithQuery("to", 7200).
+					WithQuery("step", "1h").
+					Expect()
+
+				response.Status(httptest.StatusInternalServerError)
+			},
+		},
+	})
+}
+
+func TestAllocationHandler_Rebalance(t *testing.T) {
+	runTestCases(t, []testCase{
+		{
+			name: "correct",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				mock.AllocationUC.EXPECT().
+					Rebalance().
+					Return(&domain.Rebalance{
+						Time:      time.Unix(3600, 0),
+						BTCAmount: 2,
+						NetBTC:    -0.25,
+						Trades: []domain.RebalanceTrade{
+							{Exchange: domain.ExchangeTypeBittrex, Currency: "CUR1", Type: domain.TradeTypeBuy,
+								Amount: 50, Price: 0.005, Fee: 0.0025, Weight: 0.25, TargetWeight: 0.5},
+						},
+						Unpriced: []string{"CUR2"},
+					}, nil)
+
+				response := mock.HTTPExpect.GET("/allocation/rebalance").Expect()
+
+				response.Status(httptest.StatusOK)
+				response.Body().Equal(`{"btc_amount":2,"net_btc":-0.25,"time":3600,` +
+					`"trades":[{"exchange":"bittrex","currency":"CUR1","type":"buy","amount":50,"price":0.005,` +
+					`"fee":0.0025,"weight":0.25,"target_weight":0.5}],"unpriced":["CUR2"]}`)
+			},
+		}, {
+			name: "target weights aren't configured",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				mock.AllocationUC.EXPECT().
+					Rebalance().
+					Return(nil, usecase.ErrNoTargetWeights)
+
+				response := mock.HTTPExpect.GET("/allocation/rebalance").Expect()
+
+				response.Status(httptest.StatusNotFound)
+				response.Body().Equal(`{"status":404,"message":"target weights aren't configured"}`)
+			},
+		}, {
+			name: "error in usecase",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				mock.AllocationUC.EXPECT().
+					Rebalance().
+					Return(nil, errors.New("unexpected error"))
+
+				response := mock.HTTPExpect.GET("/allocation/rebalance").Expect()
+
+				response.Status(httptest.StatusInternalServerError)
+			},
+		},
+	})
+}
+
+func TestOrderHandler_GetActiveOrders(t *testing.T) {
+	runTestCases(t, []testCase{
+		{
+			name: "correct",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				mock.OrderUC.EXPECT().
+					GetActiveOrders().
+					Return(testdata.Orders(), nil)
+
+				response := mock.HTTPExpect.GET("/order").
+					Expect()
+
+				response.Status(httptest.StatusOK)
+				response.Body().Equal(`[{"market":"market1","market_link":"https://bittrex.com/Market/Index?MarketName=market1","time":0,"buy_rate":1,"amount":2,"sellnow_rate":3,"usdt_rate":4},{"market":"market2","market_link":"https://bittrex.com/Market/Index?MarketName=market2","time":3600,"buy_rate":5,"amount":6,"sellnow_rate":7,"usdt_rate":8}]`)
+			},
+		}, {
+			name: "correct with no orders",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				mock.OrderUC.EXPECT().
+					GetActiveOrders().
+					Return([]domain.Order{}, nil)
+
+				response := mock.HTTPExpect.GET("/order").
+					Expect()
+
+				response.Status(httptest.StatusOK)
+				response.Body().Equal(`[]`)
+			},
+		}, {
+			name: "error from usecase level",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				mock.OrderUC.EXPECT().
+					GetActiveOrders().
+					Return(nil, errors.New("unexpected error"))
+
+				response := mock.HTTPExpect.GET("/order").
+					Expect()
+
+				response.Status(httptest.StatusInternalServerError)
+			},
+		},
+	})
+}
+
+func TestOrderHandler_PnL(t *testing.T) {
+	runTestCases(t, []testCase{
+		{
+			name: "correct",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				mock.OrderUC.EXPECT().
+					GetPnL().
+					Return(testdata.PnL(), nil)
+
+				response := mock.HTTPExpect.GET("/order/pnl").
+					Expect()
+
+				response.Status(httptest.StatusOK)
+				response.Body().Equal(`{"open":[{"market":"market1","market_link":"https://bittrex.com/Market/Index?MarketName=market1","time":0,"buy_rate":1,"amount":2,"sellnow_rate":3,"usdt_rate":4,"cost":2,"value":6,"fee":0.5,"profit":4,"profit_btc":8,"profit_usdt":16,"profit_percent":200}],` +
+					`"closed":[{"market":"BTC-CUR1","market_link":"https://www.binance.com/en/trade/CUR1_BTC","time":3600,"amount":10,"buy_rate":1,"sell_rate":2,"cost":10,"value":20,"fee":1,"profit":10,"profit_btc":10,"profit_usdt":20,"profit_percent":100}],` +
+					`"unrealized":{"btc":8,"usdt":16},"realized":{"btc":10,"usdt":20}}`)
+			},
+		}, {
+			name: "correct with no positions",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				mock.OrderUC.EXPECT().
+					GetPnL().
+					Return(&domain.PnL{}, nil)
+
+				response := mock.HTTPExpect.GET("/order/pnl").
+					Expect()
+
+				response.Status(httptest.StatusOK)
+				response.Body().Equal(`{"open":[],"closed":[],"unrealized":{"btc":0,"usdt":0},"realized":{"btc":0,"usdt":0}}`)
+			},
+		}, {
+			name: "error from usecase level",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				mock.OrderUC.EXPECT().
+					GetPnL().
+					Return(nil, errors.New("unexpected error"))
+
+				response := mock.HTTPExpect.GET("/order/pnl").
+					Expect()
+
+				response.Status(httptest.StatusInternalServerError)
+			},
+		},
+	})
+}
+
+func TestWSHandler_Serve(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mock := NewHTTPServerMock(t, ctrl)
+
+	updates := make(chan domain.Update, 1)
+	canceled := make(chan bool)
+	mock.UpdateUC.EXPECT().
+		Subscribe().
+		Return((<-chan domain.Update)(updates), func() { close(canceled) })
+
+	server := nethttptest.NewServer(mock.Server.app)
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws?currency=CUR1", nil)
+	assert.NoError(t, err)
+
+	snapshot := domain.Update{
+		Type:     domain.UpdateTypeBalance,
+		Balances: append(testdata.Balances()["CUR1"][:1], testdata.Balances()["CUR2"]...),
+	}
+
+	updates <- snapshot
+	_, message, err := conn.ReadMessage()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"type":"balance","balances":{"CUR1":[{"amount":1,"btc":1,"usdt":2,"time":0}]}}`, strings.TrimSpace(string(message)))
+
+	assert.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(`{"subscribe":["CUR2"],"unsubscribe":["CUR1"]}`)))
+	_, message, err = conn.ReadMessage()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"type":"subscription","currencies":["CUR2"]}`, strings.TrimSpace(string(message)))
+
+	updates <- snapshot
+	_, message, err = conn.ReadMessage()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"type":"balance","balances":{"CUR2":[{"amount":3,"btc":3,"usdt":5,"time":7200}]}}`, strings.TrimSpace(string(message)))
+
+	// profit is sent to everybody
+	updates <- domain.Update{Type: domain.UpdateTypePnL, PnL: &domain.PnL{}}
+	_, message, err = conn.ReadMessage()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"type":"pnl","pnl":{"open":[],"closed":[],"unrealized":{"btc":0,"usdt":0},"realized":{"btc":0,"usdt":0}}}`, strings.TrimSpace(string(message)))
+
+	assert.NoError(t, conn.Close())
+	select {
+	case <-canceled:
+	case <-time.After(time.Second * 5):
+		t.Fatal("subscription isn't canceled")
+	}
+}
+
+func TestStreamHandler_Balance(t *testing.T) {
+	runTestCases(t, []testCase{
+		{
+			name: "empty currency",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				response := mock.HTTPExpect.GET("/balance/stream").
+					Expect()
+
+				response.Status(httptest.StatusBadRequest)
+				response.Body().Equal(`{"status":400,"message":"'currency' is empty"}`)
+			},
+		}, {
+			name: "wrong Last-Event-ID",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				response := mock.HTTPExpect.GET("/balance/stream").
+					WithQuery("currency", "total").
+					WithHeader("Last-Event-ID", "abc").
+					Expect()
+
+				response.Status(httptest.StatusBadRequest)
+				response.Body().Equal(`{"status":400,"message":"'Last-Event-ID' is wrong"}`)
+			},
+		}, {
+			name: "error from usecase level",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				mock.UpdateUC.EXPECT().
+					Subscribe().
+					Return(make(<-chan domain.Update), func() {})
+				mock.BalanceUC.EXPECT().
+					FetchSince("total", time.Unix(0, 1001*int64(time.Millisecond)-1)).
+					Return(nil, errors.New("unexpected error"))
+
+				response := mock.HTTPExpect.GET("/balance/stream").
+					WithQuery("currency", "total").
+					WithHeader("Last-Event-ID", "1000").
+					Expect()
+
+				response.Status(httptest.StatusInternalServerError)
+			},
+		},
+	})
+}
+
+func TestStreamHandler_Balance_Stream(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mock := NewHTTPServerMock(t, ctrl)
+
+	updates := make(chan domain.Update, 1)
+	canceled := make(chan bool)
+	mock.UpdateUC.EXPECT().
+		Subscribe().
+		Return((<-chan domain.Update)(updates), func() { close(canceled) })
+
+	balance := func(account string, amount float64, t time.Time) domain.Balance {
+		return domain.Balance{Account: account, Currency: "total", Amount: amount, BTCAmount: amount, USDTAmount: amount, Time: t}
+	}
+	// two accounts in one snapshot are one event
+	mock.BalanceUC.EXPECT().
+		FetchSince("total", time.Unix(0, 1001*int64(time.Millisecond)-1)).
+		Return([]domain.Balance{
+			balance("main", 1, time.Unix(2, 0)),
+			balance("trading", 2, time.Unix(2, 0)),
+			balance("main", 3, time.Unix(3, 500*int64(time.Millisecond))),
+		}, nil)
+	mock.BalanceUC.EXPECT().
+		FetchSince("total", time.Unix(0, 3501*int64(time.Millisecond)-1)).
+		Return([]domain.Balance{balance("main", 4, time.Unix(4, 0))}, nil)
+
+	server := nethttptest.NewServer(mock.Server.app)
+	defer server.Close()
+
+	request, err := nethttp.NewRequest("GET", server.URL+"/balance/stream?currency=total", nil)
+	assert.NoError(t, err)
+	request.Header.Set("Last-Event-ID", "1000")
+	response, err := nethttp.DefaultClient.Do(request)
+	assert.NoError(t, err)
+	defer response.Body.Close()
+	assert.Contains(t, response.Header.Get("Content-Type"), "text/event-stream")
+
+	reader := bufio.NewReader(response.Body)
+	readEvent := func() string {
+		var lines []string
+		for {
+			line, err := reader.ReadString('\n')
+			assert.NoError(t, err)
+			if line == "\n" {
+				return strings.Join(lines, "")
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	assert.Equal(t, "id: 2000\nevent: balance\ndata: [{\"amount\":1,\"btc\":1,\"usdt\":1,\"time\":2},{\"amount\":2,\"btc\":2,\"usdt\":2,\"time\":2}]\n", readEvent())
+	assert.Equal(t, "id: 3500\nevent: balance\ndata: [{\"amount\":3,\"btc\":3,\"usdt\":3,\"time\":3}]\n", readEvent())
+	assert.Equal(t, ": connected\n", readEvent())
+
+	// snapshots without the currency are skipped, others are fetched since the last event
+	updates <- domain.Update{Type: domain.UpdateTypeBalance, Balances: []domain.Balance{{Currency: "BTC", Time: time.Unix(4, 0)}}}
+	updates <- domain.Update{Type: domain.UpdateTypeBalance, Balances: []domain.Balance{balance("main", 4, time.Unix(4, 0))}}
+	assert.Equal(t, "id: 4000\nevent: balance\ndata: [{\"amount\":4,\"btc\":4,\"usdt\":4,\"time\":4}]\n", readEvent())
+
+	assert.NoError(t, response.Body.Close())
+	select {
+	case <-canceled:
+	case <-time.After(time.Second * 5):
+		t.Fatal("subscription isn't canceled")
+	}
+}
+
+func TestAlertHandler_List(t *testing.T) {
+	runTestCases(t, []testCase{
+		{
+			name: "correct",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				mock.AlertRuleUC.EXPECT().
+					GetRules().
+					Return([]domain.AlertRuleInfo{
+						{
+							Rule: domain.AlertRule{ID: "eth", Type: domain.AlertTypePercentChange, Market: "BTC-ETH", Value: -5, Window: time.Hour, Channels: []string{"mail"}},
+						}, {
+							Rule:      domain.AlertRule{ID: "total", Type: domain.AlertTypePortfolioAbove, Currency: "total", Quote: "USDT", Value: 1000, Cooldown: time.Minute * 90},
+							LastFired: time.Unix(100, 0),
+						},
+					}, nil)
+
+				response := mock.HTTPExpect.GET("/alerts").Expect()
+
+				response.Status(httptest.StatusOK)
+				response.Body().Equal(`[{"id":"eth","type":"percent_change","market":"BTC-ETH","value":-5,"window":"1h0m0s","notify":["mail"]},` +
+					`{"id":"total","type":"portfolio_above","currency":"total","quote":"USDT","value":1000,"cooldown":"1h30m0s","last_fired":100}]`)
+			},
+		}, {
+			name: "empty",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				mock.AlertRuleUC.EXPECT().
+					GetRules().
+					Return([]domain.AlertRuleInfo{}, nil)
+
+				response := mock.HTTPExpect.GET("/alerts").Expect()
+
+				response.Status(httptest.StatusOK)
+				response.Body().Equal(`[]`)
+			},
+		}, {
+			name: "error",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				mock.AlertRuleUC.EXPECT().
+					GetRules().
+					Return(nil, errors.New("unexpected error"))
+
+				response := mock.HTTPExpect.GET("/alerts").Expect()
+
+				response.Status(httptest.StatusInternalServerError)
+				response.Body().Equal(`{"status":500,"message":"internal error"}`)
+			},
+		},
+	})
+}
+
+func TestAlertHandler_Get(t *testing.T) {
+	runTestCases(t, []testCase{
+		{
+			name: "correct",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				mock.AlertRuleUC.EXPECT().
+					GetRule("eth").
+					Return(&domain.AlertRuleInfo{Rule: domain.AlertRule{ID: "eth", Type: domain.AlertTypePriceAbove, Market: "BTC-ETH", Value: 0.08}}, nil)
+
+				response := mock.HTTPExpect.GET("/alerts/eth").Expect()
+
+				response.Status(httptest.StatusOK)
+				response.Body().Equal(`{"id":"eth","type":"price_above","market":"BTC-ETH","value":0.08}`)
+			},
+		}, {
+			name: "not found",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				mock.AlertRuleUC.EXPECT().
+					GetRule("eth").
+					Return(nil, usecase.ErrAlertRuleNotFound)
+
+				response := mock.HTTPExpect.GET("/alerts/eth").Expect()
+
+				response.Status(httptest.StatusNotFound)
+				response.Body().Equal(`{"status":404,"message":"alert not found"}`)
+			},
+		},
+	})
+}
+
+func TestAlertHandler_Create(t *testing.T) {
+	runTestCases(t, []testCase{
+		{
+			name: "correct",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				mock.AlertRuleUC.EXPECT().
+					CreateRule(domain.AlertRule{Type: domain.AlertTypePercentChange, Market: "BTC-ETH", Value: 5, Window: time.Minute * 30, Channels: []string{"chat"}}).
+					Return(&domain.AlertRule{ID: "generated", Type: domain.AlertTypePercentChange, Market: "BTC-ETH", Value: 5, Window: time.Minute * 30, Channels: []string{"chat"}}, nil)
+
+				response := mock.HTTPExpect.POST("/alerts").
+					WithBytes([]byte(`{"type":"percent_change","market":"BTC-ETH","value":5,"window":"30m","notify":["chat"]}`)).
+					Expect()
+
+				response.Status(httptest.StatusCreated)
+				response.Body().Equal(`{"id":"generated","type":"percent_change","market":"BTC-ETH","value":5,"window":"30m0s","notify":["chat"]}`)
+			},
+		}, {
+			name: "exists",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				mock.AlertRuleUC.EXPECT().
+					CreateRule(gomock.Any()).
+					Return(nil, usecase.ErrAlertRuleExists)
+
+				response := mock.HTTPExpect.POST("/alerts").
+					WithBytes([]byte(`{"id":"eth","type":"price_above","market":"BTC-ETH","value":0.08}`)).
+					Expect()
+
+				response.Status(httptest.StatusConflict)
+				response.Body().Equal(`{"status":409,"message":"alert already exists"}`)
+			},
+		}, {
+			name: "invalid rule",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				mock.AlertRuleUC.EXPECT().
+					CreateRule(gomock.Any()).
+					Return(nil, usecase.ValidationError{Err: errors.New("market is empty")})
+
+				response := mock.HTTPExpect.POST("/alerts").
+					WithBytes([]byte(`{"id":"eth","type":"price_above","value":0.08}`)).
+					Expect()
+
+				response.Status(httptest.StatusBadRequest)
+				response.Body().Equal(`{"status":400,"message":"market is empty"}`)
+			},
+		}, {
+			name: "wrong window",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				response := mock.HTTPExpect.POST("/alerts").
+					WithBytes([]byte(`{"id":"eth","type":"percent_change","market":"BTC-ETH","value":5,"window":"1x"}`)).
+					Expect()
+
+				response.Status(httptest.StatusBadRequest)
+			},
+		}, {
+			name: "wrong body",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				response := mock.HTTPExpect.POST("/alerts").
+					WithBytes([]byte(`{"id":`)).
+					Expect()
+
+				response.Status(httptest.StatusBadRequest)
+				response.Body().Equal(`{"status":400,"message":"body is wrong"}`)
+			},
+		},
+	})
+}
+
+func TestAlertHandler_Update(t *testing.T) {
+	runTestCases(t, []testCase{
+		{
+			name: "correct",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				mock.AlertRuleUC.EXPECT().
+					UpdateRule(domain.AlertRule{ID: "eth", Type: domain.AlertTypePriceAbove, Market: "BTC-ETH", Value: 0.09}).
+					Return(nil)
+
+				response := mock.HTTPExpect.PUT("/alerts/eth").
+					WithBytes([]byte(`{"type":"price_above","market":"BTC-ETH","value":0.09}`)).
+					Expect()
+
+				response.Status(httptest.StatusOK)
+				response.Body().Equal(`{"id":"eth","type":"price_above","market":"BTC-ETH","value":0.09}`)
+			},
+		}, {
+			name: "not found",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				mock.AlertRuleUC.EXPECT().
+					UpdateRule(gomock.Any()).
+					Return(usecase.ErrAlertRuleNotFound)
+
+				response := mock.HTTPExpect.PUT("/alerts/eth").
+					WithBytes([]byte(`{"id":"eth","type":"price_above","market":"BTC-ETH","value":0.09}`)).
+					Expect()
+
+				response.Status(httptest.StatusNotFound)
+			},
+		}, {
+			name: "other id",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				response := mock.HTTPExpect.PUT("/alerts/eth").
+					WithBytes([]byte(`{"id":"ltc","type":"price_above","market":"BTC-ETH","value":0.09}`)).
+					Expect()
+
+				response.Status(httptest.StatusBadRequest)
+				response.Body().Equal(`{"status":400,"message":"'id' differs from the path"}`)
+			},
+		},
+	})
+}
+
+func TestAlertHandler_Delete(t *testing.T) {
+	runTestCases(t, []testCase{
+		{
+			name: "correct",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				mock.AlertRuleUC.EXPECT().
+					DeleteRule("eth").
+					Return(nil)
+
+				response := mock.HTTPExpect.DELETE("/alerts/eth").Expect()
+
+				response.Status(httptest.StatusNoContent)
+			},
+		}, {
+			name: "not found",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				mock.AlertRuleUC.EXPECT().
+					DeleteRule("eth").
+					Return(usecase.ErrAlertRuleNotFound)
+
+				response := mock.HTTPExpect.DELETE("/alerts/eth").Expect()
+
+				response.Status(httptest.StatusNotFound)
+			},
+		},
+	})
+}
+
+func TestAlertHandler_Triggers(t *testing.T) {
+	runTestCases(t, []testCase{
+		{
+			name: "of all rules",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				mock.AlertRuleUC.EXPECT().
+					FetchTriggers("", 100).
+					Return([]domain.AlertTrigger{{RuleID: "eth", Time: time.Unix(100, 0), Value: 0.081, Message: "fired"}}, nil)
+
+				response := mock.HTTPExpect.GET("/alerts/triggers").Expect()
+
+				response.Status(httptest.StatusOK)
+				response.Body().Equal(`[{"rule_id":"eth","time":100,"value":0.081,"message":"fired"}]`)
+			},
+		}, {
+			name: "of the rule",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				mock.AlertRuleUC.EXPECT().
+					FetchTriggers("eth", 1000).
+					Return([]domain.AlertTrigger{}, nil)
+
+				response := mock.HTTPExpect.GET("/alerts/eth/triggers").
+					WithQuery("limit", 5000).
+					Expect()
+
+				response.Status(httptest.StatusOK)
+				response.Body().Equal(`[]`)
+			},
+		}, {
+			name: "wrong limit",
+			test: func(t *testing.T, mock *HTTPServerMock) {
+				response := mock.HTTPExpect.GET("/alerts/triggers").
+					WithQuery("limit", 0).
+					Expect()
+
+				response.Status(httptest.StatusBadRequest)
+				response.Body().Equal(`{"status":400,"message":"'limit' is wrong"}`)
+			},
+		},
+	})
+}
+
 type testCase struct {
 	name string
 	test func(t *testing.T, mock *HTTPServerMock)
@@ -0,0 +1,184 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/kataras/iris"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/http/dto"
+	"github.com/nawa/cryptoexchange-dashboard/usecase"
+)
+
+// sseKeepAlivePeriod is how often a comment is sent to keep idle connections open through proxies
+const sseKeepAlivePeriod = time.Second * 30
+
+type StreamHandler struct {
+	balanceUsecase usecase.BalanceUsecases
+	updateUsecase  usecase.UpdateUsecases
+	log            *logrus.Entry
+}
+
+func NewStreamHandler(balanceUsecase usecase.BalanceUsecases, updateUsecase usecase.UpdateUsecases) *StreamHandler {
+	return &StreamHandler{
+		balanceUsecase: balanceUsecase,
+		updateUsecase:  updateUsecase,
+		log:            logrus.WithField("component", "StreamHandler"),
+	}
+}
+
+// Balance streams new balances of the currency as server-sent events. Balances of one snapshot are sent
+// in one 'balance' event with the snapshot time in Unix milliseconds as the ID. The client reconnecting
+// with Last-Event-ID header gets balances saved after that ID first. 'quote' adds values in the fiat currency
+func (h *StreamHandler) Balance(ctx iris.Context) {
+	currency := ctx.URLParam("currency")
+	if currency == "" {
+		WriteBadRequest(ctx, "'currency' is empty")
+		return
+	}
+	quote := quoteParam(ctx)
+
+	var (
+		lastID    int64
+		hasLastID bool
+		err       error
+	)
+	if header := ctx.GetHeader("Last-Event-ID"); header != "" {
+		lastID, err = strconv.ParseInt(header, 10, 64)
+		if err != nil {
+			WriteBadRequest(ctx, "'Last-Event-ID' is wrong")
+			return
+		}
+		hasLastID = true
+	}
+
+	// subscribed before the replay not to miss snapshots saved meanwhile
+	updates, cancel := h.updateUsecase.Subscribe()
+	defer cancel()
+
+	ctx.ContentType("text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	// disables response buffering of nginx
+	ctx.Header("X-Accel-Buffering", "no")
+
+	// send writes balance events and the comment if it isn't empty
+	send := func(balances []domain.Balance, comment string) bool {
+		id, err := writeBalanceEvents(ctx, balances, quote)
+		if err == nil && comment != "" {
+			_, err = fmt.Fprintf(ctx, ": %s\n\n", comment)
+		}
+		if err != nil {
+			h.log.WithField("method", "Balance").WithError(err).Debug("client is gone")
+			return false
+		}
+		ctx.ResponseWriter().Flush()
+
+		if id > lastID {
+			lastID, hasLastID = id, true
+		}
+		return true
+	}
+
+	// fetchMissed fetches balances saved after the last event, the update tells only about the latest snapshot
+	fetchMissed := func() ([]domain.Balance, error) {
+		return h.balanceUsecase.FetchSince(currency, time.Unix(0, (lastID+1)*int64(time.Millisecond)-1))
+	}
+
+	var missed []domain.Balance
+	if hasLastID {
+		missed, err = fetchMissed()
+		if err != nil {
+			WriteInternalServerError(ctx, "internal error")
+			return
+		}
+	}
+	if !send(missed, "connected") {
+		return
+	}
+
+	keepAlive := time.NewTicker(sseKeepAlivePeriod)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case update, ok := <-updates:
+			if !ok {
+				return
+			}
+			if update.Type != domain.UpdateTypeBalance {
+				continue
+			}
+
+			balances := balancesOfCurrency(update.Balances, currency)
+			if len(balances) == 0 {
+				continue
+			}
+			if hasLastID {
+				balances, err = fetchMissed()
+				if err != nil {
+					return
+				}
+			}
+			if !send(balances, "") {
+				return
+			}
+		case <-keepAlive.C:
+			if !send(nil, "keep-alive") {
+				return
+			}
+		case <-ctx.Request().Context().Done():
+			return
+		}
+	}
+}
+
+func balancesOfCurrency(balances []domain.Balance, currency string) []domain.Balance {
+	var result []domain.Balance
+	for _, b := range balances {
+		if b.Currency == currency {
+			result = append(result, b)
+		}
+	}
+	return result
+}
+
+// eventID is the time in Unix milliseconds, the precision of all storages
+func eventID(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+// writeBalanceEvents writes balances as events, one per balance time, the oldest first. It returns the last event ID
+func writeBalanceEvents(w io.Writer, balances []domain.Balance, quote string) (lastID int64, err error) {
+	sorted := make([]domain.Balance, len(balances))
+	copy(sorted, balances)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Time.Before(sorted[j].Time)
+	})
+
+	for start := 0; start < len(sorted); {
+		id := eventID(sorted[start].Time)
+		end := start
+		var event []dto.BalanceDTO
+		for ; end < len(sorted) && eventID(sorted[end].Time) == id; end++ {
+			event = append(event, *dto.NewBalanceDTO(sorted[end], quote))
+		}
+
+		data, err := json.Marshal(event)
+		if err != nil {
+			return lastID, err
+		}
+		_, err = fmt.Fprintf(w, "id: %d\nevent: balance\ndata: %s\n\n", id, data)
+		if err != nil {
+			return lastID, err
+		}
+		lastID = id
+		start = end
+	}
+	return lastID, nil
+}
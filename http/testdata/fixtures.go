@@ -36,6 +36,43 @@ func Balances() map[string][]domain.Balance {
 	}
 }
 
+func BalanceCandles() []domain.BalanceCandle {
+	return []domain.BalanceCandle{
+		{
+			Currency:   "CUR1",
+			Time:       time.Unix(0, 0).UTC().Add(time.Hour),
+			Amount:     domain.OHLC{Open: 2, Close: 3, Min: 1, Max: 4},
+			BTCAmount:  domain.OHLC{Open: 2, Close: 3, Min: 1, Max: 4},
+			USDTAmount: domain.OHLC{Open: 4, Close: 6, Min: 2, Max: 8},
+		},
+	}
+}
+
+func Performance() *domain.Performance {
+	return &domain.Performance{
+		Points: []domain.PerformancePoint{
+			{
+				Time:        time.Unix(0, 0).UTC().Add(time.Hour),
+				BTCAmount:   2,
+				USDTAmount:  4,
+				NetFlowBTC:  0.5,
+				NetFlowUSDT: 1,
+				TWRBTC:      0.25,
+				TWRUSDT:     0.5,
+			},
+			{
+				Time:       time.Unix(0, 0).UTC(),
+				BTCAmount:  1,
+				USDTAmount: 2,
+			},
+		},
+		TWRBTC:  0.25,
+		TWRUSDT: 0.5,
+		MWRBTC:  0.2,
+		MWRUSDT: 0.4,
+	}
+}
+
 func Orders() []domain.Order {
 	return []domain.Order{
 		{
@@ -58,3 +95,29 @@ func Orders() []domain.Order {
 		},
 	}
 }
+
+func PnL() *domain.PnL {
+	return &domain.PnL{
+		Open: []domain.OpenPosition{
+			{
+				Order:  Orders()[0],
+				Profit: domain.Profit{Cost: 2, Value: 6, Fee: 0.5, Profit: 4, ProfitBTC: 8, ProfitUSDT: 16, Percent: 200},
+			},
+		},
+		Closed: []domain.ClosedPosition{
+			{
+				Exchange: domain.ExchangeTypeBinance,
+				Market:   "BTC-CUR1",
+				Time:     time.Unix(0, 0).UTC().Add(time.Hour),
+				Amount:   10,
+				BuyRate:  1,
+				SellRate: 2,
+				Profit:   domain.Profit{Cost: 10, Value: 20, Fee: 1, Profit: 10, ProfitBTC: 10, ProfitUSDT: 20, Percent: 100},
+			},
+		},
+		UnrealizedBTC:  8,
+		UnrealizedUSDT: 16,
+		RealizedBTC:    10,
+		RealizedUSDT:   20,
+	}
+}
@@ -0,0 +1,194 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/gorilla/websocket"
+	"github.com/kataras/iris"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/http/dto"
+	"github.com/nawa/cryptoexchange-dashboard/usecase"
+)
+
+const (
+	wsWriteTimeout = time.Second * 10
+	wsPingPeriod   = time.Second * 30
+	// wsReadTimeout is how long the client can be silent, pongs prolong it
+	wsReadTimeout = wsPingPeriod * 2
+)
+
+type WSHandler struct {
+	updateUsecase usecase.UpdateUsecases
+	upgrader      websocket.Upgrader
+	log           *logrus.Entry
+}
+
+func NewWSHandler(updateUsecase usecase.UpdateUsecases) *WSHandler {
+	return &WSHandler{
+		updateUsecase: updateUsecase,
+		upgrader: websocket.Upgrader{
+			// the same as CORS, any dashboard can connect
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+		log: logrus.WithField("component", "WSHandler"),
+	}
+}
+
+// Serve pushes new balance snapshots of currencies the client is subscribed to and every change of order profit.
+// Initial currencies are given by 'currency' query parameters, then the client changes them with subscription messages
+func (h *WSHandler) Serve(ctx iris.Context) {
+	conn, err := h.upgrader.Upgrade(ctx.ResponseWriter(), ctx.Request(), nil)
+	if err != nil {
+		// the upgrader has replied with the error already
+		h.log.WithField("method", "Serve").WithError(err).Debug()
+		return
+	}
+	defer conn.Close()
+
+	subscription := newWSSubscription(ctx.Request().URL.Query()["currency"])
+	updates, cancel := h.updateUsecase.Subscribe()
+	defer cancel()
+
+	// confirmations are sent by this goroutine, the connection doesn't support concurrent writers
+	confirmations := make(chan *dto.UpdateDTO, 1)
+	closed := make(chan struct{})
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		defer close(closed)
+		h.readSubscriptions(conn, subscription, confirmations, done)
+	}()
+
+	ping := time.NewTicker(wsPingPeriod)
+	defer ping.Stop()
+
+	for {
+		var message *dto.UpdateDTO
+		select {
+		case update, ok := <-updates:
+			if !ok {
+				return
+			}
+			message = subscription.filter(update)
+		case message = <-confirmations:
+		case <-ping.C:
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout))
+			if err != nil {
+				h.log.WithField("method", "Serve").WithError(err).Debug("client is gone")
+				return
+			}
+		case <-closed:
+			return
+		}
+
+		if message == nil {
+			continue
+		}
+		err = conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+		if err == nil {
+			err = conn.WriteJSON(message)
+		}
+		if err != nil {
+			h.log.WithField("method", "Serve").WithError(err).Debug("client is gone")
+			return
+		}
+	}
+}
+
+// readSubscriptions applies subscription messages of the client until the connection is closed or done
+func (h *WSHandler) readSubscriptions(conn *websocket.Conn, subscription *wsSubscription, confirmations chan<- *dto.UpdateDTO, done <-chan struct{}) {
+	extendDeadline := func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsReadTimeout))
+	}
+	conn.SetPongHandler(extendDeadline)
+
+	for {
+		err := extendDeadline("")
+		if err != nil {
+			return
+		}
+
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var message dto.SubscriptionDTO
+		err = json.Unmarshal(data, &message)
+		if err != nil {
+			h.log.WithField("method", "readSubscriptions").WithError(err).Warn("wrong subscription message")
+			continue
+		}
+		currencies := subscription.update(message.Subscribe, message.Unsubscribe)
+		select {
+		case confirmations <- &dto.UpdateDTO{Type: dto.UpdateTypeSubscription, Currencies: currencies}:
+		case <-done:
+			return
+		}
+	}
+}
+
+// wsSubscription is the set of currencies the client gets balances of
+type wsSubscription struct {
+	lock       sync.Mutex
+	currencies map[string]bool
+}
+
+func newWSSubscription(currencies []string) *wsSubscription {
+	s := &wsSubscription{
+		currencies: make(map[string]bool),
+	}
+	s.update(currencies, nil)
+	return s
+}
+
+// update changes the subscription and returns all its currencies
+func (s *wsSubscription) update(subscribe, unsubscribe []string) []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, currency := range subscribe {
+		if currency != "" {
+			s.currencies[currency] = true
+		}
+	}
+	for _, currency := range unsubscribe {
+		delete(s.currencies, currency)
+	}
+
+	currencies := make([]string, 0, len(s.currencies))
+	for currency := range s.currencies {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	return currencies
+}
+
+// filter returns the message of the update for the client or nil if the client isn't interested in it
+func (s *wsSubscription) filter(update domain.Update) *dto.UpdateDTO {
+	if update.Type != domain.UpdateTypeBalance {
+		return dto.NewUpdateDTO(update)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var balances []domain.Balance
+	for _, b := range update.Balances {
+		if s.currencies[b.Currency] {
+			balances = append(balances, b)
+		}
+	}
+	if len(balances) == 0 {
+		return nil
+	}
+
+	update.Balances = balances
+	return dto.NewUpdateDTO(update)
+}
@@ -0,0 +1,27 @@
+package notification
+
+import (
+	"github.com/0xAX/notificator"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+)
+
+const appName = "cryptoexchange-dashboard"
+
+// desktopNotifier pushes notifications to the desktop of the current user
+type desktopNotifier struct {
+	notificator *notificator.Notificator
+}
+
+func NewDesktopNotifier() Notifier {
+	return &desktopNotifier{
+		notificator: notificator.New(notificator.Options{
+			DefaultIcon: "",
+			AppName:     appName,
+		}),
+	}
+}
+
+func (n *desktopNotifier) Notify(trigger domain.AlertTrigger) error {
+	return n.notificator.Push(subject(trigger), trigger.Message, "", notificator.UR_CRITICAL)
+}
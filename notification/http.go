@@ -0,0 +1,135 @@
+package notification
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	neturl "net/url"
+	"text/template"
+	"time"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+)
+
+const (
+	httpTimeout = time.Second * 10
+
+	// DefaultWebhookBody is the body of webhook notifications without a template
+	DefaultWebhookBody = `{"rule_id":{{json .RuleID}},"time":{{json .Time}},"value":{{json .Value}},"message":{{json .Message}}}`
+
+	telegramAPIURL = "https://api.telegram.org"
+)
+
+// webhookNotifier posts the trigger to the URL as JSON rendered by the template
+type webhookNotifier struct {
+	url    string
+	body   *template.Template
+	client *http.Client
+}
+
+// NewWebhookNotifier creates the notifier posting the body template executed with domain.AlertTrigger.
+// The template has the 'json' function quoting values, e.g. {"text": {{json .Message}}}
+func NewWebhookNotifier(url, body string) (Notifier, error) {
+	if body == "" {
+		body = DefaultWebhookBody
+	}
+
+	tmpl, err := template.New("webhook").
+		Funcs(template.FuncMap{"json": toJSON}).
+		Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("body template is wrong: %s", err)
+	}
+
+	return &webhookNotifier{
+		url:    url,
+		body:   tmpl,
+		client: &http.Client{Timeout: httpTimeout},
+	}, nil
+}
+
+func (n *webhookNotifier) Notify(trigger domain.AlertTrigger) error {
+	var body bytes.Buffer
+	err := n.body.Execute(&body, trigger)
+	if err != nil {
+		return fmt.Errorf("webhook body error: %s", err)
+	}
+	return postJSON(n.client, n.url, &body)
+}
+
+func toJSON(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+// slackNotifier posts the message to the Slack incoming webhook or any service accepting its payload
+type slackNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func NewSlackNotifier(url string) Notifier {
+	return &slackNotifier{
+		url:    url,
+		client: &http.Client{Timeout: httpTimeout},
+	}
+}
+
+func (n *slackNotifier) Notify(trigger domain.AlertTrigger) error {
+	body, err := json.Marshal(map[string]string{
+		"text": trigger.Message,
+	})
+	if err != nil {
+		return err
+	}
+	return postJSON(n.client, n.url, bytes.NewReader(body))
+}
+
+// telegramNotifier sends the message to the chat by the Telegram bot
+type telegramNotifier struct {
+	apiURL string
+	token  string
+	chatID string
+	client *http.Client
+}
+
+func NewTelegramNotifier(token, chatID string) Notifier {
+	return &telegramNotifier{
+		apiURL: telegramAPIURL,
+		token:  token,
+		chatID: chatID,
+		client: &http.Client{Timeout: httpTimeout},
+	}
+}
+
+func (n *telegramNotifier) Notify(trigger domain.AlertTrigger) error {
+	body, err := json.Marshal(map[string]string{
+		"chat_id": n.chatID,
+		"text":    trigger.Message,
+	})
+	if err != nil {
+		return err
+	}
+	return postJSON(n.client, n.apiURL+"/bot"+n.token+"/sendMessage", bytes.NewReader(body))
+}
+
+func postJSON(client *http.Client, url string, body io.Reader) error {
+	response, err := client.Post(url, "application/json", body)
+	if err != nil {
+		if urlErr, ok := err.(*neturl.Error); ok {
+			// URLs of bots and webhooks contain secrets, they mustn't get to logs
+			return urlErr.Err
+		}
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
+		message, _ := ioutil.ReadAll(io.LimitReader(response.Body, 512))
+		return fmt.Errorf("notification isn't accepted, status %d: %s", response.StatusCode, message)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package notification
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/require"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+)
+
+var testTrigger = domain.AlertTrigger{
+	RuleID:  "eth-high",
+	Time:    time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	Value:   0.081,
+	Message: `BTC-ETH price 0.081 is above "0.08"`,
+}
+
+type receivedRequest struct {
+	path        string
+	contentType string
+	body        string
+}
+
+// newStandIn starts the server answering with the status and giving received requests to the channel
+func newStandIn(t *testing.T, status int) (*httptest.Server, <-chan receivedRequest) {
+	requests := make(chan receivedRequest, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		requests <- receivedRequest{
+			path:        r.URL.Path,
+			contentType: r.Header.Get("Content-Type"),
+			body:        string(body),
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte("stand-in answer"))
+	}))
+	return server, requests
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	server, requests := newStandIn(t, http.StatusOK)
+	defer server.Close()
+
+	notifier, err := NewWebhookNotifier(server.URL+"/hook", "")
+	assert.NoError(t, err)
+	assert.NoError(t, notifier.Notify(testTrigger))
+
+	request := <-requests
+	assert.Equal(t, "/hook", request.path)
+	assert.Equal(t, "application/json", request.contentType)
+	assert.JSONEq(t, `{
+		"rule_id": "eth-high",
+		"time": "2026-01-02T03:04:05Z",
+		"value": 0.081,
+		"message": "BTC-ETH price 0.081 is above \"0.08\""
+	}`, request.body)
+
+	notifier, err = NewWebhookNotifier(server.URL, `{"alert": {{json .RuleID}}, "text": {{json .Message}}, "raw": "{{.Value}}"}`)
+	assert.NoError(t, err)
+	assert.NoError(t, notifier.Notify(testTrigger))
+
+	request = <-requests
+	assert.JSONEq(t, `{"alert": "eth-high", "text": "BTC-ETH price 0.081 is above \"0.08\"", "raw": "0.081"}`, request.body)
+}
+
+func TestWebhookNotifier_Errors(t *testing.T) {
+	_, err := NewWebhookNotifier("http://localhost", `{"text": {{json .Message}`)
+	assert.Error(t, err)
+
+	notifier, err := NewWebhookNotifier("http://localhost", `{"text": {{.Unknown}}}`)
+	assert.NoError(t, err)
+	assert.Error(t, notifier.Notify(testTrigger))
+
+	server, requests := newStandIn(t, http.StatusBadRequest)
+	defer server.Close()
+
+	notifier, err = NewWebhookNotifier(server.URL, "")
+	assert.NoError(t, err)
+	err = notifier.Notify(testTrigger)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "400")
+	assert.Contains(t, err.Error(), "stand-in answer")
+	<-requests
+}
+
+func TestSlackNotifier(t *testing.T) {
+	server, requests := newStandIn(t, http.StatusOK)
+	defer server.Close()
+
+	assert.NoError(t, NewSlackNotifier(server.URL+"/services/T000/B000/XXX").Notify(testTrigger))
+
+	request := <-requests
+	assert.Equal(t, "/services/T000/B000/XXX", request.path)
+	assert.JSONEq(t, `{"text": "BTC-ETH price 0.081 is above \"0.08\""}`, request.body)
+}
+
+func TestTelegramNotifier(t *testing.T) {
+	server, requests := newStandIn(t, http.StatusOK)
+	defer server.Close()
+
+	notifier := NewTelegramNotifier("123:secret", "-100500").(*telegramNotifier)
+	notifier.apiURL = server.URL
+	assert.NoError(t, notifier.Notify(testTrigger))
+
+	request := <-requests
+	assert.Equal(t, "/bot123:secret/sendMessage", request.path)
+
+	var payload map[string]string
+	assert.NoError(t, json.Unmarshal([]byte(request.body), &payload))
+	assert.Equal(t, map[string]string{"chat_id": "-100500", "text": testTrigger.Message}, payload)
+}
+
+func TestTelegramNotifier_HidesToken(t *testing.T) {
+	server, _ := newStandIn(t, http.StatusOK)
+	server.Close()
+
+	notifier := NewTelegramNotifier("123:secret", "-100500").(*telegramNotifier)
+	notifier.apiURL = server.URL
+	err := notifier.Notify(testTrigger)
+	assert.Error(t, err)
+	assert.NotContains(t, err.Error(), "secret")
+}
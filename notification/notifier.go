@@ -0,0 +1,8 @@
+package notification
+
+import "github.com/nawa/cryptoexchange-dashboard/domain"
+
+// Notifier delivers fired alerts to the user
+type Notifier interface {
+	Notify(trigger domain.AlertTrigger) error
+}
@@ -0,0 +1,61 @@
+package notification
+
+import (
+	"fmt"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/hashicorp/go-multierror"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+)
+
+// DesktopChannel is the name of the desktop notifier available without configuration
+const DesktopChannel = "desktop"
+
+// router sends the trigger to notifiers of its channels or to the default notifier if the trigger has no channels
+type router struct {
+	channels map[string]Notifier
+	fallback Notifier
+	log      *logrus.Entry
+}
+
+func NewRouter(channels map[string]Notifier, fallback Notifier) Notifier {
+	return &router{
+		channels: channels,
+		fallback: fallback,
+		log:      logrus.WithField("component", "notificationRouter"),
+	}
+}
+
+// Notify sends the trigger to all its channels. It fails only if no channel has got the trigger,
+// otherwise the alert would be sent again to channels which have got it
+func (r *router) Notify(trigger domain.AlertTrigger) error {
+	if len(trigger.Channels) == 0 {
+		return r.fallback.Notify(trigger)
+	}
+
+	var (
+		result error
+		sent   bool
+	)
+	for _, name := range trigger.Channels {
+		notifier, ok := r.channels[name]
+		if !ok {
+			result = multierror.Append(result, fmt.Errorf("notifier '%s' not found", name))
+			continue
+		}
+
+		err := notifier.Notify(trigger)
+		if err != nil {
+			result = multierror.Append(result, fmt.Errorf("notifier '%s': %s", name, err))
+			continue
+		}
+		sent = true
+	}
+
+	if sent && result != nil {
+		r.log.WithField("method", "Notify").WithField("rule", trigger.RuleID).WithError(result).Error()
+		return nil
+	}
+	return result
+}
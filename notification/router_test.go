@@ -0,0 +1,46 @@
+package notification
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	assert "github.com/stretchr/testify/require"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/notification/mocks"
+)
+
+func TestRouter_Notify(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	fallback := mocks.NewMockNotifier(ctrl)
+	mail := mocks.NewMockNotifier(ctrl)
+	chat := mocks.NewMockNotifier(ctrl)
+	router := NewRouter(map[string]Notifier{
+		"mail": mail,
+		"chat": chat,
+	}, fallback)
+
+	trigger := domain.AlertTrigger{RuleID: "rule"}
+	fallback.EXPECT().Notify(trigger).Return(nil)
+	assert.NoError(t, router.Notify(trigger))
+
+	trigger = domain.AlertTrigger{RuleID: "rule", Channels: []string{"mail", "chat"}}
+	mail.EXPECT().Notify(trigger).Return(nil)
+	chat.EXPECT().Notify(trigger).Return(nil)
+	assert.NoError(t, router.Notify(trigger))
+
+	// the alert has reached one channel at least
+	mail.EXPECT().Notify(trigger).Return(errors.New("unexpected error"))
+	chat.EXPECT().Notify(trigger).Return(nil)
+	assert.NoError(t, router.Notify(trigger))
+
+	mail.EXPECT().Notify(trigger).Return(errors.New("unexpected error"))
+	chat.EXPECT().Notify(trigger).Return(errors.New("unexpected error"))
+	assert.Error(t, router.Notify(trigger))
+
+	trigger = domain.AlertTrigger{RuleID: "rule", Channels: []string{"unknown"}}
+	assert.Error(t, router.Notify(trigger))
+}
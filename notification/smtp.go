@@ -0,0 +1,77 @@
+package notification
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+)
+
+// SMTPConfig is the mail server and addresses of alert emails
+type SMTPConfig struct {
+	// Addr is the server address as host:port
+	Addr string
+	// Username and Password are used for PLAIN authentication if the username is set
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+// smtpNotifier sends alerts by email. The connection is upgraded with STARTTLS if the server supports it
+type smtpNotifier struct {
+	config SMTPConfig
+	auth   smtp.Auth
+}
+
+func NewSMTPNotifier(config SMTPConfig) (Notifier, error) {
+	host, _, err := net.SplitHostPort(config.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("smtp address is wrong: %s", err)
+	}
+	if config.From == "" {
+		return nil, errors.New("smtp sender is empty")
+	}
+	if len(config.To) == 0 {
+		return nil, errors.New("smtp recipients are empty")
+	}
+
+	var auth smtp.Auth
+	if config.Username != "" {
+		auth = smtp.PlainAuth("", config.Username, config.Password, host)
+	}
+
+	return &smtpNotifier{
+		config: config,
+		auth:   auth,
+	}, nil
+}
+
+func (n *smtpNotifier) Notify(trigger domain.AlertTrigger) error {
+	return smtp.SendMail(n.config.Addr, n.auth, n.config.From, n.config.To, n.message(trigger))
+}
+
+func (n *smtpNotifier) message(trigger domain.AlertTrigger) []byte {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", n.config.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(n.config.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject(trigger))
+	fmt.Fprintf(&msg, "Date: %s\r\n", trigger.Time.Format("Mon, 02 Jan 2006 15:04:05 -0700"))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(trigger.Message)
+	msg.WriteString("\r\n")
+	return msg.Bytes()
+}
+
+func subject(trigger domain.AlertTrigger) string {
+	// a header can't contain line breaks
+	id := strings.NewReplacer("\r", " ", "\n", " ").Replace(trigger.RuleID)
+	return "Alert '" + id + "'"
+}
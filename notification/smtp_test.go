@@ -0,0 +1,146 @@
+package notification
+
+import (
+	"bufio"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+type receivedMail struct {
+	from string
+	to   []string
+	data string
+}
+
+// smtpStandIn is the minimal SMTP server accepting all mails without authentication
+type smtpStandIn struct {
+	listener net.Listener
+	mails    chan receivedMail
+}
+
+func newSMTPStandIn(t *testing.T) *smtpStandIn {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	s := &smtpStandIn{
+		listener: listener,
+		mails:    make(chan receivedMail, 10),
+	}
+	go s.serve()
+	return s
+}
+
+func (s *smtpStandIn) Addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *smtpStandIn) Close() {
+	_ = s.listener.Close()
+}
+
+func (s *smtpStandIn) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *smtpStandIn) handle(conn net.Conn) {
+	defer conn.Close()
+
+	text := textproto.NewConn(conn)
+	reply := func(line string) bool {
+		return text.PrintfLine("%s", line) == nil
+	}
+
+	var mail receivedMail
+	if !reply("220 localhost stand-in") {
+		return
+	}
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+
+		command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch command {
+		case "EHLO", "HELO":
+			reply("250 localhost")
+		case "MAIL":
+			mail = receivedMail{from: addressOf(line)}
+			reply("250 OK")
+		case "RCPT":
+			mail.to = append(mail.to, addressOf(line))
+			reply("250 OK")
+		case "DATA":
+			reply("354 go ahead")
+			data, err := bufio.NewReader(text.DotReader()).ReadString(0)
+			if err != nil && data == "" {
+				return
+			}
+			mail.data = data
+			s.mails <- mail
+			reply("250 OK")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func addressOf(line string) string {
+	start := strings.Index(line, "<")
+	end := strings.Index(line, ">")
+	if start < 0 || end < start {
+		return ""
+	}
+	return line[start+1 : end]
+}
+
+func TestSMTPNotifier(t *testing.T) {
+	server := newSMTPStandIn(t)
+	defer server.Close()
+
+	notifier, err := NewSMTPNotifier(SMTPConfig{
+		Addr: server.Addr(),
+		From: "crexd@example.com",
+		To:   []string{"me@example.com", "ops@example.com"},
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, notifier.Notify(testTrigger))
+
+	mail := <-server.mails
+	assert.Equal(t, "crexd@example.com", mail.from)
+	assert.Equal(t, []string{"me@example.com", "ops@example.com"}, mail.to)
+	assert.Contains(t, mail.data, "To: me@example.com, ops@example.com\n")
+	assert.Contains(t, mail.data, "Subject: Alert 'eth-high'\n")
+	assert.Contains(t, mail.data, "\n\n"+testTrigger.Message+"\n")
+}
+
+func TestSMTPNotifier_Errors(t *testing.T) {
+	_, err := NewSMTPNotifier(SMTPConfig{Addr: "localhost", From: "a@example.com", To: []string{"b@example.com"}})
+	assert.Error(t, err)
+
+	_, err = NewSMTPNotifier(SMTPConfig{Addr: "localhost:25", To: []string{"b@example.com"}})
+	assert.Error(t, err)
+
+	_, err = NewSMTPNotifier(SMTPConfig{Addr: "localhost:25", From: "a@example.com"})
+	assert.Error(t, err)
+
+	server := newSMTPStandIn(t)
+	server.Close()
+
+	notifier, err := NewSMTPNotifier(SMTPConfig{Addr: server.Addr(), From: "a@example.com", To: []string{"b@example.com"}})
+	assert.NoError(t, err)
+	assert.Error(t, notifier.Notify(testTrigger))
+}
@@ -0,0 +1,32 @@
+package storage
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+)
+
+// ErrNotFound is returned when the requested record doesn't exist
+var ErrNotFound = errors.New("not found")
+
+type AlertStorage interface {
+	// Init initializes the storage, such as prepares indexes and another
+	Init() error
+	// SaveState inserts new states and replaces already saved ones of the same rules
+	SaveState(state ...domain.AlertState) error
+	// FetchStates returns states of all rules
+	FetchStates() ([]domain.AlertState, error)
+	// SaveRule inserts the new rule or replaces the saved one with the same ID
+	SaveRule(rule domain.AlertRule) error
+	// FetchRule returns ErrNotFound if the rule doesn't exist
+	FetchRule(id string) (*domain.AlertRule, error)
+	// FetchRules returns all rules ordered by IDs
+	FetchRules() ([]domain.AlertRule, error)
+	// DeleteRule removes the rule with its state, the history of its triggers is kept.
+	// It returns ErrNotFound if the rule doesn't exist
+	DeleteRule(id string) error
+	// SaveTrigger adds fired alerts to the history
+	SaveTrigger(trigger ...domain.AlertTrigger) error
+	// FetchTriggers returns at most limit latest triggers of the rule or of all rules if ruleID is empty, the latest first
+	FetchTriggers(ruleID string, limit int) ([]domain.AlertTrigger, error)
+}
@@ -1,6 +1,14 @@
 package storage
 
-import "github.com/nawa/cryptoexchange-dashboard/domain"
+import (
+	"time"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+)
+
+// FetchAllDailyPeriod is the period FetchAll returns one balance per day for,
+// older balances are returned one per week
+const FetchAllDailyPeriod = time.Hour * 24 * 365
 
 type BalanceStorage interface {
 	// Init initializes the storage, such as prepares indexes and another
@@ -10,5 +18,11 @@ type BalanceStorage interface {
 	FetchWeekly(currency string) ([]domain.Balance, error)
 	FetchMonthly(currency string) ([]domain.Balance, error)
 	FetchAll(currency string) ([]domain.Balance, error)
+	// FetchRange returns balances in [from, to) grouped into buckets of the resolution
+	// aligned to the Unix epoch, the latest bucket first
+	FetchRange(currency string, from, to time.Time, resolution time.Duration) ([]domain.BalanceCandle, error)
 	GetActiveCurrencies() ([]domain.Balance, error)
+	// FetchSnapshot returns balances of all currencies saved at the earliest time at or after the time,
+	// totals included. It returns nothing if there are no balances since the time
+	FetchSnapshot(at time.Time) ([]domain.Balance, error)
 }
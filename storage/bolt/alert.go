@@ -0,0 +1,320 @@
+package bolt
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage"
+)
+
+var (
+	alertStateBucket   = []byte("alert_state")
+	alertRuleBucket    = []byte("alert_rule")
+	alertTriggerBucket = []byte("alert_trigger")
+)
+
+// alertStorage keeps alert rules and states in the file by rule IDs and triggers by sequence numbers
+type alertStorage struct {
+	baseStorage
+}
+
+type alertState struct {
+	RuleID    string             `json:"rule_id"`
+	LastFired time.Time          `json:"last_fired"`
+	Peaks     map[string]float64 `json:"peaks,omitempty"`
+}
+
+type alertRule struct {
+	ID       string        `json:"id"`
+	Type     string        `json:"type"`
+	Market   string        `json:"market,omitempty"`
+	Currency string        `json:"currency,omitempty"`
+	Quote    string        `json:"quote,omitempty"`
+	Value    float64       `json:"value"`
+	Window   time.Duration `json:"window,omitempty"`
+	Cooldown time.Duration `json:"cooldown,omitempty"`
+	Channels []string      `json:"channels,omitempty"`
+}
+
+type alertTrigger struct {
+	RuleID  string    `json:"rule_id"`
+	Time    time.Time `json:"time"`
+	Value   float64   `json:"value"`
+	Message string    `json:"message"`
+}
+
+func NewAlertStorage(db *bbolt.DB) storage.AlertStorage {
+	return &alertStorage{
+		baseStorage{
+			db: db,
+		},
+	}
+}
+
+// Init creates buckets if they don't exist
+func (s *alertStorage) Init() error {
+	return s.update(func(tx *bbolt.Tx) error {
+		for _, name := range [][]byte{alertStateBucket, alertRuleBucket, alertTriggerBucket} {
+			_, err := tx.CreateBucketIfNotExists(name)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *alertStorage) SaveState(state ...domain.AlertState) error {
+	return s.update(func(tx *bbolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(alertStateBucket)
+		if err != nil {
+			return err
+		}
+
+		for _, st := range state {
+			value, err := json.Marshal(alertState{
+				RuleID:    st.RuleID,
+				LastFired: st.LastFired,
+				Peaks:     st.Peaks,
+			})
+			if err != nil {
+				return err
+			}
+
+			err = bucket.Put([]byte(st.RuleID), value)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// FetchStates returns states ordered by rule IDs as keys of the bucket are
+func (s *alertStorage) FetchStates() (result []domain.AlertState, err error) {
+	result = []domain.AlertState{}
+	err = s.view(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(alertStateBucket)
+		if bucket == nil {
+			return nil
+		}
+
+		return bucket.ForEach(func(_, value []byte) error {
+			var st alertState
+			err := json.Unmarshal(value, &st)
+			if err != nil {
+				return err
+			}
+
+			result = append(result, domain.AlertState{
+				RuleID:    st.RuleID,
+				LastFired: st.LastFired,
+				Peaks:     st.Peaks,
+			})
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *alertStorage) SaveRule(rule domain.AlertRule) error {
+	return s.update(func(tx *bbolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(alertRuleBucket)
+		if err != nil {
+			return err
+		}
+
+		value, err := json.Marshal(convertAlertRuleFromModel(rule))
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(rule.ID), value)
+	})
+}
+
+func (s *alertStorage) FetchRule(id string) (result *domain.AlertRule, err error) {
+	err = s.view(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(alertRuleBucket)
+		if bucket == nil {
+			return nil
+		}
+
+		value := bucket.Get([]byte(id))
+		if value == nil {
+			return nil
+		}
+
+		var r alertRule
+		err := json.Unmarshal(value, &r)
+		if err != nil {
+			return err
+		}
+		rule := convertAlertRuleToModel(r)
+		result = &rule
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, storage.ErrNotFound
+	}
+	return result, nil
+}
+
+// FetchRules returns rules ordered by IDs as keys of the bucket are
+func (s *alertStorage) FetchRules() (result []domain.AlertRule, err error) {
+	result = []domain.AlertRule{}
+	err = s.view(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(alertRuleBucket)
+		if bucket == nil {
+			return nil
+		}
+
+		return bucket.ForEach(func(_, value []byte) error {
+			var r alertRule
+			err := json.Unmarshal(value, &r)
+			if err != nil {
+				return err
+			}
+
+			result = append(result, convertAlertRuleToModel(r))
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *alertStorage) DeleteRule(id string) error {
+	return s.update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(alertRuleBucket)
+		if bucket == nil || bucket.Get([]byte(id)) == nil {
+			return storage.ErrNotFound
+		}
+
+		err := bucket.Delete([]byte(id))
+		if err != nil {
+			return err
+		}
+
+		if states := tx.Bucket(alertStateBucket); states != nil {
+			return states.Delete([]byte(id))
+		}
+		return nil
+	})
+}
+
+func (s *alertStorage) SaveTrigger(trigger ...domain.AlertTrigger) error {
+	return s.update(func(tx *bbolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(alertTriggerBucket)
+		if err != nil {
+			return err
+		}
+
+		for _, t := range trigger {
+			value, err := json.Marshal(alertTrigger{
+				RuleID:  t.RuleID,
+				Time:    t.Time,
+				Value:   t.Value,
+				Message: t.Message,
+			})
+			if err != nil {
+				return err
+			}
+
+			seq, err := bucket.NextSequence()
+			if err != nil {
+				return err
+			}
+			key := make([]byte, 8)
+			binary.BigEndian.PutUint64(key, seq)
+
+			err = bucket.Put(key, value)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// FetchTriggers scans triggers from the last saved one, they are saved mostly in the order of time
+func (s *alertStorage) FetchTriggers(ruleID string, limit int) (result []domain.AlertTrigger, err error) {
+	result = []domain.AlertTrigger{}
+	err = s.view(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(alertTriggerBucket)
+		if bucket == nil {
+			return nil
+		}
+
+		cursor := bucket.Cursor()
+		for key, value := cursor.Last(); key != nil; key, value = cursor.Prev() {
+			var t alertTrigger
+			err := json.Unmarshal(value, &t)
+			if err != nil {
+				return err
+			}
+
+			if ruleID != "" && t.RuleID != ruleID {
+				continue
+			}
+			result = append(result, domain.AlertTrigger{
+				RuleID:  t.RuleID,
+				Time:    t.Time,
+				Value:   t.Value,
+				Message: t.Message,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Time.After(result[j].Time)
+	})
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+func convertAlertRuleFromModel(r domain.AlertRule) alertRule {
+	return alertRule{
+		ID:       r.ID,
+		Type:     string(r.Type),
+		Market:   r.Market,
+		Currency: r.Currency,
+		Quote:    r.Quote,
+		Value:    r.Value,
+		Window:   r.Window,
+		Cooldown: r.Cooldown,
+		Channels: r.Channels,
+	}
+}
+
+func convertAlertRuleToModel(r alertRule) domain.AlertRule {
+	return domain.AlertRule{
+		ID:       r.ID,
+		Type:     domain.AlertType(r.Type),
+		Market:   r.Market,
+		Currency: r.Currency,
+		Quote:    r.Quote,
+		Value:    r.Value,
+		Window:   r.Window,
+		Cooldown: r.Cooldown,
+		Channels: r.Channels,
+	}
+}
@@ -0,0 +1,25 @@
+package bolt
+
+import (
+	"testing"
+
+	assert "github.com/stretchr/testify/require"
+
+	"github.com/nawa/cryptoexchange-dashboard/storage/testdata"
+)
+
+func TestAlertStorage(t *testing.T) {
+	db, closeDB := openTestDB(t)
+	defer closeDB()
+
+	alertStorage := NewAlertStorage(db)
+	assert.NoError(t, alertStorage.Init())
+
+	testdata.RunAlertStorageSuite(t, alertStorage, func() error {
+		err := clearDB(db)
+		if err != nil {
+			return err
+		}
+		return alertStorage.Init()
+	})
+}
@@ -0,0 +1,299 @@
+package bolt
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.etcd.io/bbolt"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage"
+	"github.com/nawa/cryptoexchange-dashboard/storage/downsample"
+)
+
+var balanceBucket = []byte("balance")
+
+// balanceStorage keeps balances in the file. Every currency has own bucket of balances
+// with keys ordered by time, so range queries are cursor scans
+type balanceStorage struct {
+	baseStorage
+}
+
+type balance struct {
+	Exchange      string             `json:"exchange"`
+	Account       string             `json:"account,omitempty"`
+	Currency      string             `json:"currency"`
+	Amount        float64            `json:"amount"`
+	BTCAmount     float64            `json:"btc_amount"`
+	USDTAmount    float64            `json:"usdt_amount"`
+	Fiat          map[string]float64 `json:"fiat,omitempty"`
+	Reconstructed bool               `json:"reconstructed,omitempty"`
+	Time          time.Time          `json:"time"`
+}
+
+func NewBalanceStorage(db *bbolt.DB) storage.BalanceStorage {
+	return &balanceStorage{
+		baseStorage{
+			db: db,
+		},
+	}
+}
+
+// Init creates the bucket if it doesn't exist
+func (s *balanceStorage) Init() error {
+	return s.update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(balanceBucket)
+		return err
+	})
+}
+
+func (s *balanceStorage) Save(balance ...domain.Balance) error {
+	return s.update(func(tx *bbolt.Tx) error {
+		root, err := tx.CreateBucketIfNotExists(balanceBucket)
+		if err != nil {
+			return err
+		}
+
+		for _, b := range balance {
+			bucket, err := root.CreateBucketIfNotExists([]byte(b.Currency))
+			if err != nil {
+				return err
+			}
+
+			// the sequence keeps balances with the same time
+			seq, err := bucket.NextSequence()
+			if err != nil {
+				return err
+			}
+
+			value, err := json.Marshal(convertBalanceFromModel(b))
+			if err != nil {
+				return err
+			}
+
+			err = bucket.Put(balanceKey(b.Time, seq), value)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *balanceStorage) FetchHourly(currency string, hours int) ([]domain.Balance, error) {
+	period := time.Now().Add(-1 * time.Hour * time.Duration(hours))
+
+	balances, err := s.fetch(currency, period, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+
+	// the latest first
+	for i, j := 0, len(balances)-1; i < j; i, j = i+1, j-1 {
+		balances[i], balances[j] = balances[j], balances[i]
+	}
+	return balances, nil
+}
+
+func (s *balanceStorage) FetchWeekly(currency string) ([]domain.Balance, error) {
+	period := time.Now().Add(-1 * time.Hour * 24 * 7)
+
+	balances, err := s.fetch(currency, period, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+
+	return downsample.First(balances, downsample.Every(5*time.Minute)), nil
+}
+
+func (s *balanceStorage) FetchMonthly(currency string) ([]domain.Balance, error) {
+	period := time.Now().Add(-1 * time.Hour * 24 * 30)
+
+	balances, err := s.fetch(currency, period, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+
+	return downsample.First(balances, downsample.Every(time.Hour)), nil
+}
+
+func (s *balanceStorage) FetchAll(currency string) ([]domain.Balance, error) {
+	dailyPeriod := time.Now().Add(-storage.FetchAllDailyPeriod)
+
+	balances, err := s.fetch(currency, time.Time{}, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+
+	return downsample.First(balances, downsample.DailyThenWeekly(dailyPeriod)), nil
+}
+
+func (s *balanceStorage) FetchRange(currency string, from, to time.Time, resolution time.Duration) ([]domain.BalanceCandle, error) {
+	if resolution < time.Millisecond {
+		return nil, errors.Errorf("resolution %s is less than 1ms", resolution)
+	}
+
+	balances, err := s.fetch(currency, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return downsample.Candles(currency, balances, resolution), nil
+}
+
+func (s *balanceStorage) GetActiveCurrencies() ([]domain.Balance, error) {
+	var result []domain.Balance
+	err := s.view(func(tx *bbolt.Tx) error {
+		root := tx.Bucket(balanceBucket)
+		if root == nil {
+			return nil
+		}
+
+		var lastTime []byte
+		err := root.ForEach(func(currency, _ []byte) error {
+			k, _ := root.Bucket(currency).Cursor().Last()
+			if k != nil && string(k[:8]) > string(lastTime) {
+				lastTime = k[:8]
+			}
+			return nil
+		})
+		if err != nil || lastTime == nil {
+			return err
+		}
+
+		return root.ForEach(func(currency, _ []byte) error {
+			c := root.Bucket(currency).Cursor()
+			for k, v := c.Seek(lastTime); k != nil && string(k[:8]) == string(lastTime); k, v = c.Next() {
+				b, err := convertBalanceToModel(v)
+				if err != nil {
+					return err
+				}
+				result = append(result, b)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result) == 0 {
+		return nil, errors.New("last time not found for currency")
+	}
+	return result, nil
+}
+
+func (s *balanceStorage) FetchSnapshot(at time.Time) ([]domain.Balance, error) {
+	var result []domain.Balance
+	err := s.view(func(tx *bbolt.Tx) error {
+		root := tx.Bucket(balanceBucket)
+		if root == nil {
+			return nil
+		}
+
+		from := balanceKey(at, 0)
+		var snapshotTime []byte
+		err := root.ForEach(func(currency, _ []byte) error {
+			k, _ := root.Bucket(currency).Cursor().Seek(from)
+			if k != nil && (snapshotTime == nil || string(k[:8]) < string(snapshotTime)) {
+				snapshotTime = k[:8]
+			}
+			return nil
+		})
+		if err != nil || snapshotTime == nil {
+			return err
+		}
+
+		return root.ForEach(func(currency, _ []byte) error {
+			c := root.Bucket(currency).Cursor()
+			for k, v := c.Seek(snapshotTime); k != nil && string(k[:8]) == string(snapshotTime); k, v = c.Next() {
+				b, err := convertBalanceToModel(v)
+				if err != nil {
+					return err
+				}
+				result = append(result, b)
+			}
+			return nil
+		})
+	})
+	return result, err
+}
+
+// fetch returns balances of the currency in [from, to) sorted by time in ascending order.
+// Zero to means no upper bound
+func (s *balanceStorage) fetch(currency string, from, to time.Time) (result []domain.Balance, err error) {
+	err = s.view(func(tx *bbolt.Tx) error {
+		root := tx.Bucket(balanceBucket)
+		if root == nil {
+			return nil
+		}
+		bucket := root.Bucket([]byte(currency))
+		if bucket == nil {
+			return nil
+		}
+
+		c := bucket.Cursor()
+		max := balanceKey(to, 0)
+		for k, v := c.Seek(balanceKey(from, 0)); k != nil; k, v = c.Next() {
+			if !to.IsZero() && string(k) >= string(max) {
+				break
+			}
+			b, err := convertBalanceToModel(v)
+			if err != nil {
+				return err
+			}
+			result = append(result, b)
+		}
+		return nil
+	})
+	return result, err
+}
+
+// balanceKey is the time in nanoseconds followed by the sequence, both are big endian to keep keys ordered by time.
+// The sign bit of the time is flipped to place times before 1970 first
+func balanceKey(t time.Time, seq uint64) []byte {
+	key := make([]byte, 16)
+	var nano uint64
+	if !t.IsZero() {
+		nano = uint64(t.UnixNano()) ^ (1 << 63)
+	}
+	binary.BigEndian.PutUint64(key, nano)
+	binary.BigEndian.PutUint64(key[8:], seq)
+	return key
+}
+
+func convertBalanceFromModel(b domain.Balance) balance {
+	return balance{
+		Exchange:      string(b.Exchange),
+		Account:       b.Account,
+		Currency:      b.Currency,
+		Amount:        b.Amount,
+		BTCAmount:     b.BTCAmount,
+		USDTAmount:    b.USDTAmount,
+		Fiat:          b.Fiat,
+		Time:          b.Time,
+		Reconstructed: b.Reconstructed,
+	}
+}
+
+func convertBalanceToModel(value []byte) (domain.Balance, error) {
+	var b balance
+	err := json.Unmarshal(value, &b)
+	if err != nil {
+		return domain.Balance{}, err
+	}
+
+	return domain.Balance{
+		Exchange:      domain.ExchangeType(b.Exchange),
+		Account:       b.Account,
+		Currency:      b.Currency,
+		Amount:        b.Amount,
+		BTCAmount:     b.BTCAmount,
+		USDTAmount:    b.USDTAmount,
+		Fiat:          b.Fiat,
+		Time:          b.Time,
+		Reconstructed: b.Reconstructed,
+	}, nil
+}
@@ -0,0 +1,59 @@
+package bolt
+
+import (
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/require"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage/testdata"
+)
+
+func TestBalanceStorage(t *testing.T) {
+	db, closeDB := openTestDB(t)
+	defer closeDB()
+
+	balanceStorage := NewBalanceStorage(db)
+	assert.NoError(t, balanceStorage.Init())
+
+	testdata.RunBalanceStorageSuite(t, balanceStorage, func() error {
+		err := clearDB(db)
+		if err != nil {
+			return err
+		}
+		return balanceStorage.Init()
+	})
+}
+
+func TestBalanceStorage_NotInitialized(t *testing.T) {
+	db, closeDB := openTestDB(t)
+	defer closeDB()
+
+	balanceStorage := NewBalanceStorage(db)
+
+	balances, err := balanceStorage.FetchHourly("total", 1)
+	assert.NoError(t, err)
+	assert.Empty(t, balances)
+
+	_, err = balanceStorage.GetActiveCurrencies()
+	assert.Error(t, err)
+
+	assert.NoError(t, balanceStorage.Save(domain.Balance{Currency: "total", BTCAmount: 1, Time: time.Now()}))
+
+	balances, err = balanceStorage.GetActiveCurrencies()
+	assert.NoError(t, err)
+	assert.Len(t, balances, 1)
+}
+
+func TestBalanceKey(t *testing.T) {
+	before1970 := balanceKey(time.Unix(-10, 0), 0)
+	epoch := balanceKey(time.Unix(0, 0), 0)
+	now := balanceKey(time.Now(), 0)
+	nowNext := balanceKey(time.Now(), 1)
+
+	assert.True(t, string(balanceKey(time.Time{}, 0)) < string(before1970))
+	assert.True(t, string(before1970) < string(epoch))
+	assert.True(t, string(epoch) < string(now))
+	assert.True(t, string(now) < string(nowNext))
+}
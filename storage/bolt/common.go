@@ -0,0 +1,37 @@
+package bolt
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"go.etcd.io/bbolt"
+)
+
+// OpenTimeout is the time to wait for the file lock held by another process
+const OpenTimeout = time.Second * 10
+
+// ErrLocked is returned by Open if another process keeps the file open
+var ErrLocked = errors.New("the file is used by another process, it can be opened by one process at a time")
+
+// Open opens the file creating it if it doesn't exist. The file is locked while it's open, so one process
+// at a time can use it, and storages of the process share the returned handle
+func Open(path string) (*bbolt.DB, error) {
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: OpenTimeout})
+	if err == bbolt.ErrTimeout {
+		return nil, errors.Wrap(ErrLocked, path)
+	}
+	return db, err
+}
+
+// baseStorage keeps the handle of the file shared by all storages of the process
+type baseStorage struct {
+	db *bbolt.DB
+}
+
+func (s *baseStorage) update(f func(tx *bbolt.Tx) error) error {
+	return s.db.Update(f)
+}
+
+func (s *baseStorage) view(f func(tx *bbolt.Tx) error) error {
+	return s.db.View(f)
+}
@@ -0,0 +1,45 @@
+package bolt
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	assert "github.com/stretchr/testify/require"
+	"go.etcd.io/bbolt"
+)
+
+// openTestDB opens the file in the temporary directory removed by the returned function
+func openTestDB(t *testing.T) (*bbolt.DB, func()) {
+	dir, err := ioutil.TempDir("", "crexd")
+	assert.NoError(t, err)
+
+	db, err := Open(filepath.Join(dir, "crexd.db"))
+	assert.NoError(t, err)
+	return db, func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+// clearDB deletes all buckets, the file stays open
+func clearDB(db *bbolt.DB) error {
+	return db.Update(func(tx *bbolt.Tx) error {
+		var names [][]byte
+		err := tx.ForEach(func(name []byte, _ *bbolt.Bucket) error {
+			names = append(names, append([]byte{}, name...))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			err = tx.DeleteBucket(name)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
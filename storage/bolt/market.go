@@ -0,0 +1,142 @@
+package bolt
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.etcd.io/bbolt"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage"
+	"github.com/nawa/cryptoexchange-dashboard/storage/downsample"
+)
+
+var marketBucket = []byte("market")
+
+// marketStorage keeps candles in the file. Every market has own bucket of candles with keys
+// ordered by time, the candle of the same exchange and time has the same key and is replaced
+type marketStorage struct {
+	baseStorage
+}
+
+type marketCandle struct {
+	Exchange string    `json:"exchange"`
+	Market   string    `json:"market"`
+	Time     time.Time `json:"time"`
+	Open     float64   `json:"open"`
+	Close    float64   `json:"close"`
+	Min      float64   `json:"min"`
+	Max      float64   `json:"max"`
+}
+
+func NewMarketStorage(db *bbolt.DB) storage.MarketStorage {
+	return &marketStorage{
+		baseStorage{
+			db: db,
+		},
+	}
+}
+
+// Init creates the bucket if it doesn't exist
+func (s *marketStorage) Init() error {
+	return s.update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(marketBucket)
+		return err
+	})
+}
+
+func (s *marketStorage) Save(candle ...domain.MarketCandle) error {
+	return s.update(func(tx *bbolt.Tx) error {
+		root, err := tx.CreateBucketIfNotExists(marketBucket)
+		if err != nil {
+			return err
+		}
+
+		for _, c := range candle {
+			bucket, err := root.CreateBucketIfNotExists([]byte(c.Market))
+			if err != nil {
+				return err
+			}
+
+			value, err := json.Marshal(convertMarketCandleFromModel(c))
+			if err != nil {
+				return err
+			}
+
+			err = bucket.Put(marketKey(c.Time, c.Exchange), value)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *marketStorage) FetchCandles(exchange domain.ExchangeType, market string, from, to time.Time, resolution time.Duration) ([]domain.MarketCandle, error) {
+	if resolution < time.Millisecond {
+		return nil, errors.Errorf("resolution %s is less than 1ms", resolution)
+	}
+
+	var candles []domain.MarketCandle
+	err := s.view(func(tx *bbolt.Tx) error {
+		root := tx.Bucket(marketBucket)
+		if root == nil {
+			return nil
+		}
+		bucket := root.Bucket([]byte(market))
+		if bucket == nil {
+			return nil
+		}
+
+		c := bucket.Cursor()
+		max := marketKey(to, "")
+		for k, v := c.Seek(marketKey(from, "")); k != nil && string(k) < string(max); k, v = c.Next() {
+			var candle marketCandle
+			err := json.Unmarshal(v, &candle)
+			if err != nil {
+				return err
+			}
+			if exchange == "" || domain.ExchangeType(candle.Exchange) == exchange {
+				candles = append(candles, convertMarketCandleToModel(candle))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return downsample.MarketCandles(exchange, market, candles, resolution), nil
+}
+
+// marketKey is the time as in balanceKey followed by the exchange
+func marketKey(t time.Time, exchange domain.ExchangeType) []byte {
+	return append(balanceKey(t, 0)[:8], exchange...)
+}
+
+func convertMarketCandleFromModel(c domain.MarketCandle) marketCandle {
+	return marketCandle{
+		Exchange: string(c.Exchange),
+		Market:   c.Market,
+		Time:     c.Time,
+		Open:     c.Price.Open,
+		Close:    c.Price.Close,
+		Min:      c.Price.Min,
+		Max:      c.Price.Max,
+	}
+}
+
+func convertMarketCandleToModel(c marketCandle) domain.MarketCandle {
+	return domain.MarketCandle{
+		Exchange: domain.ExchangeType(c.Exchange),
+		Market:   c.Market,
+		Time:     c.Time,
+		Price: domain.OHLC{
+			Open:  c.Open,
+			Close: c.Close,
+			Min:   c.Min,
+			Max:   c.Max,
+		},
+	}
+}
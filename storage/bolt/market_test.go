@@ -0,0 +1,28 @@
+package bolt
+
+import (
+	"testing"
+
+	assert "github.com/stretchr/testify/require"
+
+	"github.com/nawa/cryptoexchange-dashboard/storage/testdata"
+)
+
+func TestMarketStorage(t *testing.T) {
+	db, closeDB := openTestDB(t)
+	defer closeDB()
+
+	marketStorage := NewMarketStorage(db)
+	assert.NoError(t, marketStorage.Init())
+
+	// balances and markets share the file
+	assert.NoError(t, NewBalanceStorage(db).Init())
+
+	testdata.RunMarketStorageSuite(t, marketStorage, func() error {
+		err := clearDB(db)
+		if err != nil {
+			return err
+		}
+		return marketStorage.Init()
+	})
+}
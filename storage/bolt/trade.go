@@ -0,0 +1,145 @@
+package bolt
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage"
+)
+
+var tradeBucket = []byte("trade")
+
+// tradeStorage keeps trades in the file by exchange and order ID. Trade history is short,
+// so range queries scan all trades
+type tradeStorage struct {
+	baseStorage
+}
+
+type trade struct {
+	Exchange string    `json:"exchange"`
+	Account  string    `json:"account,omitempty"`
+	OrderID  string    `json:"order_id"`
+	Market   string    `json:"market"`
+	Type     string    `json:"type"`
+	Time     time.Time `json:"time"`
+	Amount   float64   `json:"amount"`
+	Rate     float64   `json:"rate"`
+	Fee      float64   `json:"fee"`
+	BTCRate  float64   `json:"btc_rate"`
+	USDTRate float64   `json:"usdt_rate"`
+}
+
+func NewTradeStorage(db *bbolt.DB) storage.TradeStorage {
+	return &tradeStorage{
+		baseStorage{
+			db: db,
+		},
+	}
+}
+
+// Init creates the bucket if it doesn't exist
+func (s *tradeStorage) Init() error {
+	return s.update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(tradeBucket)
+		return err
+	})
+}
+
+func (s *tradeStorage) Save(trades ...domain.Trade) error {
+	return s.update(func(tx *bbolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(tradeBucket)
+		if err != nil {
+			return err
+		}
+
+		for _, t := range trades {
+			key := []byte(string(t.Exchange) + "/" + t.OrderID)
+			if savedValue := bucket.Get(key); savedValue != nil {
+				var saved trade
+				err = json.Unmarshal(savedValue, &saved)
+				if err != nil {
+					return err
+				}
+				t.BTCRate, t.USDTRate = saved.BTCRate, saved.USDTRate
+			}
+
+			value, err := json.Marshal(convertTradeFromModel(t))
+			if err != nil {
+				return err
+			}
+
+			err = bucket.Put(key, value)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *tradeStorage) Fetch(from, to time.Time) (result []domain.Trade, err error) {
+	err = s.view(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(tradeBucket)
+		if bucket == nil {
+			return nil
+		}
+
+		return bucket.ForEach(func(_, value []byte) error {
+			var t trade
+			err := json.Unmarshal(value, &t)
+			if err != nil {
+				return err
+			}
+
+			if t.Time.Before(from) || (!to.IsZero() && !t.Time.Before(to)) {
+				return nil
+			}
+			result = append(result, convertTradeToModel(t))
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Time.After(result[j].Time)
+	})
+	return result, nil
+}
+
+func convertTradeFromModel(t domain.Trade) trade {
+	return trade{
+		Exchange: string(t.Exchange),
+		Account:  t.Account,
+		OrderID:  t.OrderID,
+		Market:   t.Market,
+		Type:     string(t.Type),
+		Time:     t.Time,
+		Amount:   t.Amount,
+		Rate:     t.Rate,
+		Fee:      t.Fee,
+		BTCRate:  t.BTCRate,
+		USDTRate: t.USDTRate,
+	}
+}
+
+func convertTradeToModel(t trade) domain.Trade {
+	return domain.Trade{
+		Exchange: domain.ExchangeType(t.Exchange),
+		Account:  t.Account,
+		OrderID:  t.OrderID,
+		Market:   t.Market,
+		Type:     domain.TradeType(t.Type),
+		Time:     t.Time,
+		Amount:   t.Amount,
+		Rate:     t.Rate,
+		Fee:      t.Fee,
+		BTCRate:  t.BTCRate,
+		USDTRate: t.USDTRate,
+	}
+}
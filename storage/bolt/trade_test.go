@@ -0,0 +1,28 @@
+package bolt
+
+import (
+	"testing"
+
+	assert "github.com/stretchr/testify/require"
+
+	"github.com/nawa/cryptoexchange-dashboard/storage/testdata"
+)
+
+func TestTradeStorage(t *testing.T) {
+	db, closeDB := openTestDB(t)
+	defer closeDB()
+
+	tradeStorage := NewTradeStorage(db)
+	assert.NoError(t, tradeStorage.Init())
+
+	// balances and trades share the file
+	assert.NoError(t, NewBalanceStorage(db).Init())
+
+	testdata.RunTradeStorageSuite(t, tradeStorage, func() error {
+		err := clearDB(db)
+		if err != nil {
+			return err
+		}
+		return tradeStorage.Init()
+	})
+}
@@ -0,0 +1,142 @@
+package bolt
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage"
+)
+
+var transferBucket = []byte("transfer")
+
+// transferStorage keeps transfers in the file by exchange and ID. Transfers are rare,
+// so range queries scan all of them
+type transferStorage struct {
+	baseStorage
+}
+
+type transfer struct {
+	Exchange string    `json:"exchange"`
+	Account  string    `json:"account,omitempty"`
+	ID       string    `json:"id"`
+	Type     string    `json:"type"`
+	Currency string    `json:"currency"`
+	Time     time.Time `json:"time"`
+	Amount   float64   `json:"amount"`
+	Fee      float64   `json:"fee"`
+	BTCRate  float64   `json:"btc_rate"`
+	USDTRate float64   `json:"usdt_rate"`
+}
+
+func NewTransferStorage(db *bbolt.DB) storage.TransferStorage {
+	return &transferStorage{
+		baseStorage{
+			db: db,
+		},
+	}
+}
+
+// Init creates the bucket if it doesn't exist
+func (s *transferStorage) Init() error {
+	return s.update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(transferBucket)
+		return err
+	})
+}
+
+func (s *transferStorage) Save(transfers ...domain.Transfer) error {
+	return s.update(func(tx *bbolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(transferBucket)
+		if err != nil {
+			return err
+		}
+
+		for _, t := range transfers {
+			key := []byte(string(t.Exchange) + "/" + t.ID)
+			if savedValue := bucket.Get(key); savedValue != nil {
+				var saved transfer
+				err = json.Unmarshal(savedValue, &saved)
+				if err != nil {
+					return err
+				}
+				t.BTCRate, t.USDTRate = saved.BTCRate, saved.USDTRate
+			}
+
+			value, err := json.Marshal(convertTransferFromModel(t))
+			if err != nil {
+				return err
+			}
+
+			err = bucket.Put(key, value)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *transferStorage) Fetch(from, to time.Time) (result []domain.Transfer, err error) {
+	err = s.view(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(transferBucket)
+		if bucket == nil {
+			return nil
+		}
+
+		return bucket.ForEach(func(_, value []byte) error {
+			var t transfer
+			err := json.Unmarshal(value, &t)
+			if err != nil {
+				return err
+			}
+
+			if t.Time.Before(from) || (!to.IsZero() && !t.Time.Before(to)) {
+				return nil
+			}
+			result = append(result, convertTransferToModel(t))
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Time.After(result[j].Time)
+	})
+	return result, nil
+}
+
+func convertTransferFromModel(t domain.Transfer) transfer {
+	return transfer{
+		Exchange: string(t.Exchange),
+		Account:  t.Account,
+		ID:       t.ID,
+		Type:     string(t.Type),
+		Currency: t.Currency,
+		Time:     t.Time,
+		Amount:   t.Amount,
+		Fee:      t.Fee,
+		BTCRate:  t.BTCRate,
+		USDTRate: t.USDTRate,
+	}
+}
+
+func convertTransferToModel(t transfer) domain.Transfer {
+	return domain.Transfer{
+		Exchange: domain.ExchangeType(t.Exchange),
+		Account:  t.Account,
+		ID:       t.ID,
+		Type:     domain.TransferType(t.Type),
+		Currency: t.Currency,
+		Time:     t.Time,
+		Amount:   t.Amount,
+		Fee:      t.Fee,
+		BTCRate:  t.BTCRate,
+		USDTRate: t.USDTRate,
+	}
+}
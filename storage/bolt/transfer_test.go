@@ -0,0 +1,25 @@
+package bolt
+
+import (
+	"testing"
+
+	assert "github.com/stretchr/testify/require"
+
+	"github.com/nawa/cryptoexchange-dashboard/storage/testdata"
+)
+
+func TestTransferStorage(t *testing.T) {
+	db, closeDB := openTestDB(t)
+	defer closeDB()
+
+	transferStorage := NewTransferStorage(db)
+	assert.NoError(t, transferStorage.Init())
+
+	testdata.RunTransferStorageSuite(t, transferStorage, func() error {
+		err := clearDB(db)
+		if err != nil {
+			return err
+		}
+		return transferStorage.Init()
+	})
+}
@@ -0,0 +1,178 @@
+// Package downsample groups balances into time buckets the same way as the aggregation
+// pipelines of MongoDB storage do. It is used by storages that can't aggregate data themselves
+package downsample
+
+import (
+	"time"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+)
+
+// BucketFunc returns the start of the bucket the time belongs to
+type BucketFunc func(t time.Time) time.Time
+
+// Every returns buckets of the duration aligned to the Unix epoch
+func Every(d time.Duration) BucketFunc {
+	step := int64(d)
+	return func(t time.Time) time.Time {
+		nano := t.UnixNano()
+		mod := nano % step
+		if mod < 0 {
+			mod += step
+		}
+		return time.Unix(0, nano-mod).UTC()
+	}
+}
+
+// Day returns buckets of UTC days
+func Day(t time.Time) time.Time {
+	year, month, day := t.UTC().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// ISOWeek returns buckets of ISO weeks starting on Monday in UTC
+func ISOWeek(t time.Time) time.Time {
+	day := Day(t)
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
+
+// DailyThenWeekly returns daily buckets for times since dailyFrom and weekly buckets for older ones
+func DailyThenWeekly(dailyFrom time.Time) BucketFunc {
+	return func(t time.Time) time.Time {
+		if t.Before(dailyFrom) {
+			return ISOWeek(t)
+		}
+		return Day(t)
+	}
+}
+
+// First returns the first balance of every bucket, the latest bucket first.
+// Balances must be sorted by time in ascending order
+func First(balances []domain.Balance, bucket BucketFunc) []domain.Balance {
+	var result []domain.Balance
+	var current time.Time
+	for i, b := range balances {
+		start := bucket(b.Time)
+		if i == 0 || !start.Equal(current) {
+			current = start
+			result = append(result, b)
+		}
+	}
+
+	reverse := make([]domain.Balance, len(result))
+	for i, b := range result {
+		reverse[len(result)-1-i] = b
+	}
+	return reverse
+}
+
+// Candles aggregates balances into candles of the resolution aligned to the Unix epoch,
+// the latest candle first. Balances must be sorted by time in ascending order
+func Candles(currency string, balances []domain.Balance, resolution time.Duration) []domain.BalanceCandle {
+	bucket := Every(resolution)
+
+	var result []domain.BalanceCandle
+	for i, b := range balances {
+		start := bucket(b.Time)
+		if i == 0 || !start.Equal(result[len(result)-1].Time) {
+			result = append(result, domain.BalanceCandle{
+				Currency:   currency,
+				Time:       start,
+				Amount:     newOHLC(b.Amount),
+				BTCAmount:  newOHLC(b.BTCAmount),
+				USDTAmount: newOHLC(b.USDTAmount),
+			})
+			addFiat(&result[len(result)-1], b.Fiat)
+			continue
+		}
+
+		candle := &result[len(result)-1]
+		addToOHLC(&candle.Amount, b.Amount)
+		addToOHLC(&candle.BTCAmount, b.BTCAmount)
+		addToOHLC(&candle.USDTAmount, b.USDTAmount)
+		addFiat(candle, b.Fiat)
+	}
+
+	reverse := make([]domain.BalanceCandle, len(result))
+	for i, c := range result {
+		reverse[len(result)-1-i] = c
+	}
+	return reverse
+}
+
+// MarketCandles aggregates candles into candles of the resolution aligned to the Unix epoch, the latest candle first.
+// Aggregated candles get the exchange, it's empty for candles of several exchanges. Candles must be sorted by time
+// in ascending order
+func MarketCandles(exchange domain.ExchangeType, market string, candles []domain.MarketCandle, resolution time.Duration) []domain.MarketCandle {
+	bucket := Every(resolution)
+
+	var result []domain.MarketCandle
+	for i, c := range candles {
+		start := bucket(c.Time)
+		if i == 0 || !start.Equal(result[len(result)-1].Time) {
+			result = append(result, domain.MarketCandle{
+				Exchange: exchange,
+				Market:   market,
+				Time:     start,
+				Price:    c.Price,
+			})
+			continue
+		}
+
+		price := &result[len(result)-1].Price
+		price.Close = c.Price.Close
+		if c.Price.Min < price.Min {
+			price.Min = c.Price.Min
+		}
+		if c.Price.Max > price.Max {
+			price.Max = c.Price.Max
+		}
+	}
+
+	reverse := make([]domain.MarketCandle, len(result))
+	for i, c := range result {
+		reverse[len(result)-1-i] = c
+	}
+	return reverse
+}
+
+// addFiat adds fiat values to the candle. The candle of a currency starts with the first balance having it,
+// balances saved before the currency has been configured are skipped
+func addFiat(candle *domain.BalanceCandle, fiat map[string]float64) {
+	for currency, value := range fiat {
+		if candle.Fiat == nil {
+			candle.Fiat = make(map[string]domain.OHLC)
+		}
+		ohlc, ok := candle.Fiat[currency]
+		if !ok {
+			candle.Fiat[currency] = newOHLC(value)
+			continue
+		}
+		addToOHLC(&ohlc, value)
+		candle.Fiat[currency] = ohlc
+	}
+}
+
+// Fiat aggregates fiat values of balances sorted by time. It is used by storages that aggregate
+// other values themselves
+func Fiat(values []map[string]float64) map[string]domain.OHLC {
+	var candle domain.BalanceCandle
+	for _, fiat := range values {
+		addFiat(&candle, fiat)
+	}
+	return candle.Fiat
+}
+
+func newOHLC(value float64) domain.OHLC {
+	return domain.OHLC{Open: value, Close: value, Min: value, Max: value}
+}
+
+func addToOHLC(ohlc *domain.OHLC, value float64) {
+	ohlc.Close = value
+	if value < ohlc.Min {
+		ohlc.Min = value
+	}
+	if value > ohlc.Max {
+		ohlc.Max = value
+	}
+}
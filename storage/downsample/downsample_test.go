@@ -0,0 +1,137 @@
+package downsample
+
+import (
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/require"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+)
+
+func TestEvery(t *testing.T) {
+	bucket := Every(40 * time.Minute)
+
+	start := time.Date(2018, 2, 6, 10, 0, 0, 0, time.UTC)
+	assert.Equal(t, start, bucket(start))
+	assert.Equal(t, start, bucket(start.Add(39*time.Minute)))
+	assert.Equal(t, start.Add(40*time.Minute), bucket(start.Add(40*time.Minute)))
+
+	local := time.FixedZone("UTC+3", 3*60*60)
+	assert.Equal(t, start, bucket(start.In(local).Add(time.Second)))
+
+	assert.Equal(t, time.Unix(-2400, 0).UTC(), bucket(time.Unix(-1, 0)))
+}
+
+func TestISOWeek(t *testing.T) {
+	monday := time.Date(2018, 2, 5, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, monday, ISOWeek(monday))
+	assert.Equal(t, monday, ISOWeek(monday.Add(6*24*time.Hour+23*time.Hour)))
+	assert.Equal(t, monday.Add(7*24*time.Hour), ISOWeek(monday.Add(7*24*time.Hour)))
+}
+
+func TestDailyThenWeekly(t *testing.T) {
+	monday := time.Date(2018, 2, 5, 0, 0, 0, 0, time.UTC)
+	bucket := DailyThenWeekly(monday.Add(3 * 24 * time.Hour))
+
+	assert.Equal(t, monday, bucket(monday.Add(2*24*time.Hour+time.Hour)))
+	assert.Equal(t, monday.Add(3*24*time.Hour), bucket(monday.Add(3*24*time.Hour+time.Hour)))
+	assert.Equal(t, monday.Add(4*24*time.Hour), bucket(monday.Add(4*24*time.Hour)))
+}
+
+func TestFirst(t *testing.T) {
+	start := time.Date(2018, 2, 6, 10, 0, 0, 0, time.UTC)
+	balances := []domain.Balance{
+		{Amount: 1, Time: start},
+		{Amount: 2, Time: start.Add(30 * time.Minute)},
+		{Amount: 3, Time: start.Add(time.Hour)},
+		{Amount: 4, Time: start.Add(3 * time.Hour)},
+		{Amount: 5, Time: start.Add(3*time.Hour + time.Minute)},
+	}
+
+	assert.Equal(t, []domain.Balance{balances[3], balances[2], balances[0]}, First(balances, Every(time.Hour)))
+	assert.Empty(t, First(nil, Every(time.Hour)))
+}
+
+func TestCandles(t *testing.T) {
+	start := time.Date(2018, 2, 6, 10, 0, 0, 0, time.UTC)
+	balances := []domain.Balance{
+		{Amount: 5, BTCAmount: 50, USDTAmount: 500, Time: start},
+		{Amount: 3, BTCAmount: 30, USDTAmount: 300, Time: start.Add(20 * time.Minute)},
+		{Amount: 8, BTCAmount: 80, USDTAmount: 800, Time: start.Add(40 * time.Minute)},
+		{Amount: 6, BTCAmount: 60, USDTAmount: 600, Time: start.Add(2 * time.Hour)},
+	}
+
+	assert.Equal(t, []domain.BalanceCandle{
+		{
+			Currency:   "CUR1",
+			Time:       start.Add(2 * time.Hour),
+			Amount:     domain.OHLC{Open: 6, Close: 6, Min: 6, Max: 6},
+			BTCAmount:  domain.OHLC{Open: 60, Close: 60, Min: 60, Max: 60},
+			USDTAmount: domain.OHLC{Open: 600, Close: 600, Min: 600, Max: 600},
+		},
+		{
+			Currency:   "CUR1",
+			Time:       start,
+			Amount:     domain.OHLC{Open: 5, Close: 8, Min: 3, Max: 8},
+			BTCAmount:  domain.OHLC{Open: 50, Close: 80, Min: 30, Max: 80},
+			USDTAmount: domain.OHLC{Open: 500, Close: 800, Min: 300, Max: 800},
+		},
+	}, Candles("CUR1", balances, time.Hour))
+	assert.Empty(t, Candles("CUR1", nil, time.Hour))
+}
+
+func TestCandles_Fiat(t *testing.T) {
+	start := time.Date(2018, 2, 6, 10, 0, 0, 0, time.UTC)
+	balances := []domain.Balance{
+		{Amount: 5, Time: start},
+		{Amount: 3, Fiat: map[string]float64{"EUR": 240}, Time: start.Add(20 * time.Minute)},
+		{Amount: 8, Fiat: map[string]float64{"EUR": 640, "GBP": 600}, Time: start.Add(40 * time.Minute)},
+		{Amount: 7, Fiat: map[string]float64{"EUR": 560, "GBP": 525}, Time: start.Add(50 * time.Minute)},
+		{Amount: 6, Time: start.Add(2 * time.Hour)},
+	}
+
+	candles := Candles("CUR1", balances, time.Hour)
+	assert.Len(t, candles, 2)
+	assert.Nil(t, candles[0].Fiat)
+	assert.Equal(t, map[string]domain.OHLC{
+		"EUR": {Open: 240, Close: 560, Min: 240, Max: 640},
+		"GBP": {Open: 600, Close: 525, Min: 525, Max: 600},
+	}, candles[1].Fiat)
+}
+
+func TestFiat(t *testing.T) {
+	assert.Equal(t, map[string]domain.OHLC{
+		"EUR": {Open: 240, Close: 560, Min: 240, Max: 640},
+		"GBP": {Open: 600, Close: 600, Min: 600, Max: 600},
+	}, Fiat([]map[string]float64{nil, {"EUR": 240}, {"EUR": 640, "GBP": 600}, {"EUR": 560}}))
+	assert.Nil(t, Fiat(nil))
+}
+
+func TestMarketCandles(t *testing.T) {
+	start := time.Date(2018, 2, 6, 10, 0, 0, 0, time.UTC)
+	candles := []domain.MarketCandle{
+		// the hourly candle of the exchange
+		{Time: start, Price: domain.OHLC{Open: 5, Close: 4, Min: 3, Max: 6}},
+		// prices saved by synchronization
+		{Time: start.Add(2 * time.Hour), Price: domain.OHLC{Open: 7, Close: 7, Min: 7, Max: 7}},
+		{Time: start.Add(2*time.Hour + 20*time.Minute), Price: domain.OHLC{Open: 9, Close: 9, Min: 9, Max: 9}},
+		{Time: start.Add(2*time.Hour + 40*time.Minute), Price: domain.OHLC{Open: 8, Close: 8, Min: 8, Max: 8}},
+	}
+
+	assert.Equal(t, []domain.MarketCandle{
+		{
+			Exchange: domain.ExchangeTypeBittrex,
+			Market:   "BTC-ETH",
+			Time:     start.Add(2 * time.Hour),
+			Price:    domain.OHLC{Open: 7, Close: 8, Min: 7, Max: 9},
+		},
+		{
+			Exchange: domain.ExchangeTypeBittrex,
+			Market:   "BTC-ETH",
+			Time:     start,
+			Price:    domain.OHLC{Open: 5, Close: 4, Min: 3, Max: 6},
+		},
+	}, MarketCandles(domain.ExchangeTypeBittrex, "BTC-ETH", candles, time.Hour))
+	assert.Empty(t, MarketCandles("", "BTC-ETH", nil, time.Hour))
+}
@@ -1,10 +1,54 @@
 package storage
 
-import "github.com/nawa/cryptoexchange-dashboard/domain"
+import (
+	"time"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+)
 
 type Exchange interface {
 	GetBalance() ([]domain.Balance, error)
 	GetMarketInfo(market string) (*domain.MarketInfo, error)
 	GetOrders() ([]domain.Order, error)
+	// GetTrades returns filled buy and sell orders, the latest first
+	GetTrades() ([]domain.Trade, error)
+	// GetTransfers returns deposits and withdrawals, the latest first
+	GetTransfers() ([]domain.Transfer, error)
 	Ping() error
 }
+
+// MarketWatcher is implemented by exchanges streaming market updates
+type MarketWatcher interface {
+	// WatchMarket returns the channel of market states sent on every change, a slow reader gets only the latest one.
+	// cancel stops watching and closes the channel
+	WatchMarket(market string) (updates <-chan domain.MarketInfo, cancel func(), err error)
+}
+
+// CurrencyRates is implemented by exchanges converting currencies by their markets
+type CurrencyRates interface {
+	// GetRates returns current rates of currencies to the quote currency by the common market or the path through
+	// other markets, in the order of currencies. Currencies without conversion are missing
+	GetRates(currencies []string, quote string) ([]domain.Rate, error)
+}
+
+// MarketHistory is implemented by exchanges giving prices of all markets and their past candles
+type MarketHistory interface {
+	// LastMarketPrices returns prices of all markets downloaded for conversion by the last GetBalance, at its time
+	LastMarketPrices() []domain.MarketCandle
+	// GetMarketCandles returns past candles of the market of the interval as far as the exchange keeps them,
+	// the oldest first. Supported intervals are 1m, 5m, 30m, 1h and 24h
+	GetMarketCandles(market string, interval time.Duration) ([]domain.MarketCandle, error)
+}
+
+// MultiAccount is implemented by exchanges of several configured accounts
+type MultiAccount interface {
+	// ExchangeTypes returns exchanges of accounts in the configured order without repeats
+	ExchangeTypes() []domain.ExchangeType
+}
+
+// TradeScanner is implemented by exchanges returning orders only per market. Their GetTrades looks only through
+// markets of held coins and markets traded before, so coins bought and sold out between calls are missed
+type TradeScanner interface {
+	// ScanTrades returns trades of all markets like GetTrades. It's slow and rate limited, so only the trade sync calls it
+	ScanTrades() ([]domain.Trade, error)
+}
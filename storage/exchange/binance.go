@@ -0,0 +1,693 @@
+package exchange
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/hashicorp/go-multierror"
+	"github.com/shopspring/decimal"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage"
+	"github.com/nawa/cryptoexchange-dashboard/storage/exchange/binance"
+	"github.com/nawa/cryptoexchange-dashboard/utils"
+)
+
+// binanceQuoteAssets are quote currencies of markets where orders and trades are looked for
+var binanceQuoteAssets = []string{"BTC", "ETH", "USDT"}
+
+const (
+	// binanceOrderBurst is the number of markets whose orders or trades are requested at once, more markets are throttled
+	binanceOrderBurst = 20
+	// binanceOrderRequestInterval keeps requests of all markets under Binance weight limit of a minute
+	binanceOrderRequestInterval = 250 * time.Millisecond
+)
+
+type binanceExchange struct {
+	binance *binance.Binance
+	log     *logrus.Entry
+	// preference is the path of conversion of currencies without a common market
+	preference ConversionPreference
+
+	symbolsLock sync.Mutex
+	symbols     map[string]binance.Symbol
+
+	// lastPrices are prices of markets at the last balance
+	lastPrices lastMarketPrices
+
+	tradesLock sync.Mutex
+	// tradedSymbols are markets where the account had trades, they're looked through after coins are sold out
+	tradedSymbols map[string]bool
+	// orderRequestInterval throttles requests of more than binanceOrderBurst markets
+	orderRequestInterval time.Duration
+}
+
+// binanceKlineIntervals are intervals of Binance candles
+var binanceKlineIntervals = map[time.Duration]string{
+	time.Minute:      "1m",
+	5 * time.Minute:  "5m",
+	30 * time.Minute: "30m",
+	time.Hour:        "1h",
+	24 * time.Hour:   "1d",
+}
+
+func NewBinanceExchange(apiKey, apiSecret string, preference ConversionPreference) storage.Exchange {
+	log := logrus.WithField("component", "BinanceExchange")
+	return &binanceExchange{
+		binance:              binance.New(apiKey, apiSecret),
+		log:                  log,
+		preference:           preference,
+		orderRequestInterval: binanceOrderRequestInterval,
+	}
+}
+
+func (be *binanceExchange) GetBalance() ([]domain.Balance, error) {
+	var (
+		account   *binance.Account
+		converter *currencyConverter
+	)
+
+	errs := utils.ExecuteConcurrently([]func() error{
+		func() (err error) {
+			converter, err = be.createCurrencyConverter()
+			return
+		},
+		func() (err error) {
+			account, err = be.binance.GetAccount()
+			return
+		},
+	})
+
+	var err error
+	for _, e := range errs {
+		err = multierror.Append(err, e)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	be.lastPrices.set(domain.ExchangeTypeBinance, converter)
+
+	var result []domain.Balance
+	for _, b := range account.Balances {
+		amount := b.Free.Add(b.Locked)
+		if amount.GreaterThan(decimal.NewFromFloat(0)) {
+			btcBalance, usdtBalance := convertBalance(converter, b.Asset, amount, be.log)
+			result = append(result, domain.Balance{
+				Exchange:   domain.ExchangeTypeBinance,
+				Currency:   b.Asset,
+				Amount:     utils.DecimalToFloatQuiet(amount),
+				BTCAmount:  utils.DecimalToFloatQuiet(btcBalance),
+				USDTAmount: utils.DecimalToFloatQuiet(usdtBalance),
+				Time:       converter.syncTime,
+			})
+		}
+	}
+	return result, nil
+}
+
+// GetMarketInfo accepts market in Bittrex notation 'QUOTE-BASE', for example 'BTC-ETH'
+func (be *binanceExchange) GetMarketInfo(market string) (*domain.MarketInfo, error) {
+	toFrom := strings.Split(strings.ToUpper(market), "-")
+	if len(toFrom) != 2 {
+		return nil, fmt.Errorf("market name '%s' can't be parsed to QUOTE-BASE format", market)
+	}
+
+	ticker, err := be.binance.GetTicker(toFrom[1] + toFrom[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.MarketInfo{
+		MarketName: strings.ToUpper(market),
+		Last:       utils.DecimalToFloatQuiet(ticker.LastPrice),
+		Bid:        utils.DecimalToFloatQuiet(ticker.BidPrice),
+		Ask:        utils.DecimalToFloatQuiet(ticker.AskPrice),
+		High:       utils.DecimalToFloatQuiet(ticker.HighPrice),
+		Low:        utils.DecimalToFloatQuiet(ticker.LowPrice),
+	}, nil
+}
+
+// LastMarketPrices returns prices of all markets at the time of the last balance
+func (be *binanceExchange) LastMarketPrices() []domain.MarketCandle {
+	return be.lastPrices.get()
+}
+
+// GetRates converts currencies by current markets
+func (be *binanceExchange) GetRates(currencies []string, quote string) ([]domain.Rate, error) {
+	converter, err := be.createCurrencyConverter()
+	if err != nil {
+		return nil, err
+	}
+	return converter.rates(domain.ExchangeTypeBinance, currencies, quote), nil
+}
+
+// GetMarketCandles accepts market in Bittrex notation 'QUOTE-BASE' and returns up to 1000 last candles, the oldest first
+func (be *binanceExchange) GetMarketCandles(market string, interval time.Duration) ([]domain.MarketCandle, error) {
+	klineInterval, ok := binanceKlineIntervals[interval]
+	if !ok {
+		return nil, fmt.Errorf("candle interval %s isn't supported", interval)
+	}
+
+	toFrom := strings.Split(strings.ToUpper(market), "-")
+	if len(toFrom) != 2 {
+		return nil, fmt.Errorf("market name '%s' can't be parsed to QUOTE-BASE format", market)
+	}
+
+	klines, err := be.binance.GetKlines(toFrom[1]+toFrom[0], klineInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]domain.MarketCandle, len(klines))
+	for i, k := range klines {
+		result[i] = domain.MarketCandle{
+			Exchange: domain.ExchangeTypeBinance,
+			Market:   strings.ToUpper(market),
+			Time:     time.Unix(0, k.OpenTime*int64(time.Millisecond)).UTC(),
+			Price: domain.OHLC{
+				Open:  utils.DecimalToFloatQuiet(k.Open),
+				Close: utils.DecimalToFloatQuiet(k.Close),
+				Min:   utils.DecimalToFloatQuiet(k.Low),
+				Max:   utils.DecimalToFloatQuiet(k.High),
+			},
+		}
+	}
+	return result, nil
+}
+
+func (be *binanceExchange) GetOrders() ([]domain.Order, error) {
+	orders, symbols, converter, err := be.getOrders(heldSymbols)
+	if err != nil {
+		return nil, err
+	}
+
+	sold := make(map[string]bool)
+	filtered := make([]binance.Order, 0)
+
+	// leave only last buy orders in each active market
+	for _, order := range orders {
+		if order.Status != "FILLED" && order.Status != "PARTIALLY_FILLED" {
+			continue
+		}
+		if !sold[order.Symbol] {
+			if order.Side == "BUY" {
+				filtered = append(filtered, order)
+			} else {
+				sold[order.Symbol] = true
+			}
+		}
+	}
+
+	return be.convertOrders(filtered, symbols, converter), nil
+}
+
+func (be *binanceExchange) convertOrders(binanceOrders []binance.Order, symbols map[string]binance.Symbol, converter *currencyConverter) []domain.Order {
+	orders := []domain.Order{}
+	for _, order := range binanceOrders {
+		symbol, ok := symbols[order.Symbol]
+		if !ok {
+			be.log.WithField("method", "convertOrders").Warnf("unknown symbol - %s", order.Symbol)
+			continue
+		}
+
+		if order.ExecutedQty.Equal(decimal.Zero) {
+			continue
+		}
+
+		_, bidRate, _, err := converter.MarketRate(symbol.BaseAsset, symbol.QuoteAsset)
+		if err != nil {
+			be.log.WithField("method", "convertOrders").Warnf("market rate can't be found")
+			continue
+		}
+
+		btcRate, err := converter.ConvertToBTC(symbol.QuoteAsset, decimal.NewFromFloat(1))
+		if err != nil {
+			be.log.WithField("method", "convertOrders").Warnf("market convert to BTC")
+			continue
+		}
+
+		usdtRate, err := converter.ConvertToUSDT(symbol.QuoteAsset, decimal.NewFromFloat(1))
+		if err != nil {
+			be.log.WithField("method", "convertOrders").Warnf("market convert to USDT")
+			continue
+		}
+
+		orders = append(orders, domain.Order{
+			Exchange:    domain.ExchangeTypeBinance,
+			Market:      binanceMarketName(symbol),
+			Time:        time.Unix(0, order.Time*int64(time.Millisecond)).UTC(),
+			Amount:      utils.DecimalToFloatQuiet(order.ExecutedQty),
+			BuyRate:     utils.DecimalToFloatQuiet(order.CummulativeQuoteQty.Div(order.ExecutedQty)),
+			SellNowRate: utils.DecimalToFloatQuiet(bidRate),
+			BTCRate:     utils.DecimalToFloatQuiet(btcRate),
+			USDTRate:    utils.DecimalToFloatQuiet(usdtRate),
+		})
+	}
+	return orders
+}
+
+// GetTrades returns trades in markets of held coins and markets which had trades before, see tradeSymbols.
+// Trades are joined from account fills with their actual commissions
+func (be *binanceExchange) GetTrades() ([]domain.Trade, error) {
+	return be.getTrades(be.tradeSymbols)
+}
+
+// ScanTrades returns trades in all markets of quote assets to find coins bought and sold out between calls of GetTrades.
+// It makes a request per market, about 1500 ones throttled under the weight limit
+func (be *binanceExchange) ScanTrades() ([]domain.Trade, error) {
+	return be.getTrades(func(_ *binance.Account, symbols map[string]binance.Symbol) []string {
+		return quoteSymbols(symbols)
+	})
+}
+
+// getTrades returns trades in markets chosen by marketsOf and remembers markets with trades
+func (be *binanceExchange) getTrades(marketsOf func(*binance.Account, map[string]binance.Symbol) []string) ([]domain.Trade, error) {
+	account, symbols, converter, err := be.getMarkets()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		lock  sync.Mutex
+		fills []binance.Trade
+	)
+	err = be.forEachMarket(marketsOf(account, symbols), func(symbol string) error {
+		symbolFills, err := be.binance.GetMyTrades(symbol)
+		if err != nil {
+			return err
+		}
+		lock.Lock()
+		fills = append(fills, symbolFills...)
+		lock.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	be.tradesLock.Lock()
+	if be.tradedSymbols == nil {
+		be.tradedSymbols = make(map[string]bool)
+	}
+	for _, fill := range fills {
+		be.tradedSymbols[fill.Symbol] = true
+	}
+	be.tradesLock.Unlock()
+
+	return be.convertTrades(fills, symbols, converter), nil
+}
+
+// tradeSymbols returns markets of held coins and markets where trades were found before
+func (be *binanceExchange) tradeSymbols(account *binance.Account, symbols map[string]binance.Symbol) []string {
+	result := heldSymbols(account, symbols)
+
+	be.tradesLock.Lock()
+	defer be.tradesLock.Unlock()
+
+	held := make(map[string]bool, len(result))
+	for _, name := range result {
+		held[name] = true
+	}
+	var traded []string
+	for name := range be.tradedSymbols {
+		if !held[name] {
+			traded = append(traded, name)
+		}
+	}
+	sort.Strings(traded)
+	return append(result, traded...)
+}
+
+// heldSymbols returns markets of coins we hold with quote assets
+func heldSymbols(account *binance.Account, symbols map[string]binance.Symbol) []string {
+	var result []string
+	for _, b := range account.Balances {
+		if !b.Free.Add(b.Locked).GreaterThan(decimal.NewFromFloat(0)) {
+			continue
+		}
+		for _, quote := range binanceQuoteAssets {
+			if symbol, ok := symbols[b.Asset+quote]; ok {
+				result = append(result, symbol.Symbol)
+			}
+		}
+	}
+	return result
+}
+
+// quoteSymbols returns all markets of quote assets
+func quoteSymbols(symbols map[string]binance.Symbol) []string {
+	var result []string
+	for name, symbol := range symbols {
+		for _, quote := range binanceQuoteAssets {
+			if symbol.QuoteAsset == quote {
+				result = append(result, name)
+			}
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// convertTrades joins fills of each order into the trade. The order is filled at the time of its last fill
+func (be *binanceExchange) convertTrades(fills []binance.Trade, symbols map[string]binance.Symbol, converter *currencyConverter) []domain.Trade {
+	var (
+		orderIDs []string
+		byOrder  = make(map[string][]binance.Trade)
+	)
+	for _, fill := range fills {
+		orderID := fmt.Sprintf("%s:%d", fill.Symbol, fill.OrderID)
+		if _, ok := byOrder[orderID]; !ok {
+			orderIDs = append(orderIDs, orderID)
+		}
+		byOrder[orderID] = append(byOrder[orderID], fill)
+	}
+
+	trades := []domain.Trade{}
+	for _, orderID := range orderIDs {
+		orderFills := byOrder[orderID]
+		symbol, ok := symbols[orderFills[0].Symbol]
+		if !ok {
+			be.log.WithField("method", "convertTrades").Warnf("unknown symbol - %s", orderFills[0].Symbol)
+			continue
+		}
+
+		btcRate, err := converter.ConvertToBTC(symbol.QuoteAsset, decimal.NewFromFloat(1))
+		if err != nil {
+			be.log.WithField("method", "convertTrades").Warnf("market convert to BTC")
+			continue
+		}
+
+		usdtRate, err := converter.ConvertToUSDT(symbol.QuoteAsset, decimal.NewFromFloat(1))
+		if err != nil {
+			be.log.WithField("method", "convertTrades").Warnf("market convert to USDT")
+			continue
+		}
+
+		var (
+			amount, quoteAmount, fee decimal.Decimal
+			filled                   int64
+		)
+		for _, fill := range orderFills {
+			amount = amount.Add(fill.Qty)
+			quoteAmount = quoteAmount.Add(fill.QuoteQty)
+			fee = fee.Add(be.quoteCommission(fill, symbol, converter, btcRate))
+			if fill.Time > filled {
+				filled = fill.Time
+			}
+		}
+		if !amount.GreaterThan(decimal.Zero) {
+			continue
+		}
+
+		tradeType := domain.TradeTypeSell
+		if orderFills[0].IsBuyer {
+			tradeType = domain.TradeTypeBuy
+		}
+
+		trades = append(trades, domain.Trade{
+			Exchange: domain.ExchangeTypeBinance,
+			OrderID:  orderID,
+			Market:   binanceMarketName(symbol),
+			Type:     tradeType,
+			Time:     time.Unix(0, filled*int64(time.Millisecond)).UTC(),
+			Amount:   utils.DecimalToFloatQuiet(amount),
+			Rate:     utils.DecimalToFloatQuiet(quoteAmount.Div(amount)),
+			Fee:      utils.DecimalToFloatQuiet(fee),
+			BTCRate:  utils.DecimalToFloatQuiet(btcRate),
+			USDTRate: utils.DecimalToFloatQuiet(usdtRate),
+		})
+	}
+
+	// the latest trades first as Bittrex returns them
+	sort.SliceStable(trades, func(i, j int) bool {
+		return trades[i].Time.After(trades[j].Time)
+	})
+	return trades
+}
+
+// quoteCommission returns the commission of the fill in the quote asset of the market. The commission in the base asset
+// is converted at the fill price, in other assets like BNB at current rates. If the asset can't be converted,
+// the commission is estimated with the standard fee
+func (be *binanceExchange) quoteCommission(fill binance.Trade, symbol binance.Symbol, converter *currencyConverter, btcRate decimal.Decimal) decimal.Decimal {
+	switch fill.CommissionAsset {
+	case symbol.QuoteAsset:
+		return fill.Commission
+	case symbol.BaseAsset:
+		return fill.Commission.Mul(fill.Price)
+	}
+
+	btcCommission, err := converter.ConvertToBTC(fill.CommissionAsset, fill.Commission)
+	if err != nil || !btcRate.GreaterThan(decimal.Zero) {
+		be.log.WithField("method", "quoteCommission").Warnf("commission in %s is estimated", fill.CommissionAsset)
+		return fill.QuoteQty.Mul(decimal.NewFromFloat(domain.ExchangeTypeBinance.Fee()))
+	}
+	return btcCommission.Div(btcRate)
+}
+
+// getOrders returns orders of the account, the latest first, with trading symbols and the converter of current rates.
+// Binance returns orders only per symbol, so they are looked for in markets chosen by marketsOf
+func (be *binanceExchange) getOrders(marketsOf func(*binance.Account, map[string]binance.Symbol) []string) ([]binance.Order, map[string]binance.Symbol, *currencyConverter, error) {
+	account, symbols, converter, err := be.getMarkets()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var (
+		lock   sync.Mutex
+		orders []binance.Order
+	)
+	err = be.forEachMarket(marketsOf(account, symbols), func(symbol string) error {
+		symbolOrders, err := be.binance.GetAllOrders(symbol)
+		if err != nil {
+			return err
+		}
+		lock.Lock()
+		orders = append(orders, symbolOrders...)
+		lock.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// the latest orders first as Bittrex returns them
+	sort.SliceStable(orders, func(i, j int) bool {
+		return orders[i].Time > orders[j].Time
+	})
+	return orders, symbols, converter, nil
+}
+
+// getMarkets returns the account with trading symbols and the converter of current rates
+func (be *binanceExchange) getMarkets() (*binance.Account, map[string]binance.Symbol, *currencyConverter, error) {
+	var (
+		account   *binance.Account
+		converter *currencyConverter
+	)
+
+	errs := utils.ExecuteConcurrently([]func() error{
+		func() (err error) {
+			converter, err = be.createCurrencyConverter()
+			return
+		},
+		func() (err error) {
+			account, err = be.binance.GetAccount()
+			return
+		},
+	})
+
+	var err error
+	for _, e := range errs {
+		err = multierror.Append(err, e)
+	}
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	symbols, err := be.getSymbols()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return account, symbols, converter, nil
+}
+
+// forEachMarket calls fetch for markets concurrently. Binance answers only per symbol, so requests of many markets
+// are throttled under the weight limit
+func (be *binanceExchange) forEachMarket(markets []string, fetch func(symbol string) error) error {
+	var throttle <-chan time.Time
+	if len(markets) > binanceOrderBurst && be.orderRequestInterval > 0 {
+		ticker := time.NewTicker(be.orderRequestInterval)
+		defer ticker.Stop()
+		throttle = ticker.C
+	}
+
+	var tasks []func() error
+	for _, symbolName := range markets {
+		symbolName := symbolName
+		tasks = append(tasks, func() error {
+			if throttle != nil {
+				<-throttle
+			}
+			return fetch(symbolName)
+		})
+	}
+
+	var err error
+	for _, e := range utils.ExecuteConcurrently(tasks) {
+		err = multierror.Append(err, e)
+	}
+	return err
+}
+
+// GetTransfers returns successful deposits and completed withdrawals. Binance keeps only 90 days
+// of the history available by default, older transfers are known only if they were synced before
+func (be *binanceExchange) GetTransfers() ([]domain.Transfer, error) {
+	var (
+		deposits    []binance.Deposit
+		withdrawals []binance.Withdrawal
+		converter   *currencyConverter
+	)
+
+	errs := utils.ExecuteConcurrently([]func() error{
+		func() (err error) {
+			converter, err = be.createCurrencyConverter()
+			return
+		},
+		func() (err error) {
+			deposits, err = be.binance.GetDepositHistory()
+			return
+		},
+		func() (err error) {
+			withdrawals, err = be.binance.GetWithdrawalHistory()
+			return
+		},
+	})
+
+	var err error
+	for _, e := range errs {
+		err = multierror.Append(err, e)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	transfers := []domain.Transfer{}
+	for _, d := range deposits {
+		if d.Status != binance.DepositStatusSuccess {
+			continue
+		}
+		transfers = append(transfers, domain.Transfer{
+			Exchange: domain.ExchangeTypeBinance,
+			ID:       d.ID,
+			Type:     domain.TransferTypeDeposit,
+			Currency: d.Coin,
+			Time:     time.Unix(0, d.InsertTime*int64(time.Millisecond)).UTC(),
+			Amount:   utils.DecimalToFloatQuiet(d.Amount),
+		})
+	}
+	for _, w := range withdrawals {
+		if w.Status != binance.WithdrawalStatusCompleted {
+			continue
+		}
+		applyTime, err := time.Parse(binance.WithdrawTimeLayout, w.ApplyTime)
+		if err != nil {
+			be.log.WithField("method", "GetTransfers").Warnf("withdrawal time can't be parsed - %s", w.ApplyTime)
+			continue
+		}
+		// the amount doesn't include the fee, but both are taken from the balance
+		transfers = append(transfers, domain.Transfer{
+			Exchange: domain.ExchangeTypeBinance,
+			ID:       w.ID,
+			Type:     domain.TransferTypeWithdrawal,
+			Currency: w.Coin,
+			Time:     applyTime,
+			Amount:   utils.DecimalToFloatQuiet(w.Amount.Add(w.TransactionFee)),
+			Fee:      utils.DecimalToFloatQuiet(w.TransactionFee),
+		})
+	}
+
+	return setTransferRates(transfers, converter, be.log), nil
+}
+
+func (be *binanceExchange) Ping() error {
+	_, err := be.binance.GetAccount()
+	return err
+}
+
+func (be *binanceExchange) createCurrencyConverter() (*currencyConverter, error) {
+	var (
+		tickers []binance.Ticker
+		symbols map[string]binance.Symbol
+	)
+
+	errs := utils.ExecuteConcurrently([]func() error{
+		func() (err error) {
+			symbols, err = be.getSymbols()
+			return
+		},
+		func() (err error) {
+			tickers, err = be.binance.GetTickers()
+			return
+		},
+	})
+
+	var err error
+	for _, e := range errs {
+		err = multierror.Append(err, e)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	markets := make([]market, 0, len(tickers))
+	for _, ticker := range tickers {
+		symbol, ok := symbols[ticker.Symbol]
+		if !ok {
+			continue
+		}
+		markets = append(markets, market{
+			MarketName: binanceMarketName(symbol),
+			Last:       ticker.LastPrice,
+			Bid:        ticker.BidPrice,
+			Ask:        ticker.AskPrice,
+		})
+	}
+
+	return newCurrencyConverter(markets, be.preference), nil
+}
+
+// getSymbols returns trading symbols by name. They rarely change, so they are requested only once
+func (be *binanceExchange) getSymbols() (map[string]binance.Symbol, error) {
+	be.symbolsLock.Lock()
+	defer be.symbolsLock.Unlock()
+
+	if be.symbols != nil {
+		return be.symbols, nil
+	}
+
+	info, err := be.binance.GetExchangeInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	symbols := make(map[string]binance.Symbol)
+	for _, symbol := range info.Symbols {
+		if symbol.Status == "TRADING" {
+			symbols[symbol.Symbol] = symbol
+		}
+	}
+	be.symbols = symbols
+	return symbols, nil
+}
+
+// binanceMarketName converts Binance symbol to Bittrex notation, for example 'ETHBTC' to 'BTC-ETH'
+func binanceMarketName(symbol binance.Symbol) string {
+	return symbol.QuoteAsset + "-" + symbol.BaseAsset
+}
@@ -0,0 +1,297 @@
+// Package binance is a minimal client for the Binance REST API
+// covering only the endpoints required by the dashboard
+package binance
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	APIURL = "https://api.binance.com"
+
+	defaultTimeout    = 30 * time.Second
+	defaultRecvWindow = 5000
+	maxKlines         = 1000
+	maxTrades         = 1000
+)
+
+type Binance struct {
+	apiKey     string
+	apiSecret  string
+	baseURL    string
+	httpClient *http.Client
+}
+
+type Balance struct {
+	Asset  string          `json:"asset"`
+	Free   decimal.Decimal `json:"free"`
+	Locked decimal.Decimal `json:"locked"`
+}
+
+type Account struct {
+	Balances []Balance `json:"balances"`
+}
+
+type Symbol struct {
+	Symbol     string `json:"symbol"`
+	Status     string `json:"status"`
+	BaseAsset  string `json:"baseAsset"`
+	QuoteAsset string `json:"quoteAsset"`
+}
+
+type ExchangeInfo struct {
+	Symbols []Symbol `json:"symbols"`
+}
+
+type Ticker struct {
+	Symbol      string          `json:"symbol"`
+	LastPrice   decimal.Decimal `json:"lastPrice"`
+	BidPrice    decimal.Decimal `json:"bidPrice"`
+	AskPrice    decimal.Decimal `json:"askPrice"`
+	HighPrice   decimal.Decimal `json:"highPrice"`
+	LowPrice    decimal.Decimal `json:"lowPrice"`
+	Volume      decimal.Decimal `json:"volume"`
+	QuoteVolume decimal.Decimal `json:"quoteVolume"`
+}
+
+type Order struct {
+	Symbol              string          `json:"symbol"`
+	OrderID             int64           `json:"orderId"`
+	Price               decimal.Decimal `json:"price"`
+	OrigQty             decimal.Decimal `json:"origQty"`
+	ExecutedQty         decimal.Decimal `json:"executedQty"`
+	CummulativeQuoteQty decimal.Decimal `json:"cummulativeQuoteQty"`
+	Status              string          `json:"status"`
+	Type                string          `json:"type"`
+	Side                string          `json:"side"`
+	Time                int64           `json:"time"`
+	UpdateTime          int64           `json:"updateTime"`
+}
+
+// Trade is the fill of an account order. The commission is charged in CommissionAsset:
+// the received asset or BNB if paying fees with BNB is enabled
+type Trade struct {
+	Symbol          string          `json:"symbol"`
+	ID              int64           `json:"id"`
+	OrderID         int64           `json:"orderId"`
+	Price           decimal.Decimal `json:"price"`
+	Qty             decimal.Decimal `json:"qty"`
+	QuoteQty        decimal.Decimal `json:"quoteQty"`
+	Commission      decimal.Decimal `json:"commission"`
+	CommissionAsset string          `json:"commissionAsset"`
+	Time            int64           `json:"time"`
+	IsBuyer         bool            `json:"isBuyer"`
+}
+
+// Kline is the candle of the symbol. Binance returns it as the array
+// [openTime, open, high, low, close, volume, closeTime, ...]
+type Kline struct {
+	OpenTime int64
+	Open     decimal.Decimal
+	High     decimal.Decimal
+	Low      decimal.Decimal
+	Close    decimal.Decimal
+	Volume   decimal.Decimal
+}
+
+func (k *Kline) UnmarshalJSON(data []byte) error {
+	var fields []json.RawMessage
+	err := json.Unmarshal(data, &fields)
+	if err != nil {
+		return err
+	}
+	if len(fields) < 6 {
+		return fmt.Errorf("kline has %d fields instead of 6 at least", len(fields))
+	}
+
+	targets := []interface{}{&k.OpenTime, &k.Open, &k.High, &k.Low, &k.Close, &k.Volume}
+	for i, target := range targets {
+		err = json.Unmarshal(fields[i], target)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Deposit statuses: 0 is pending, 6 is credited but can't be withdrawn yet, 1 is success
+const DepositStatusSuccess = 1
+
+type Deposit struct {
+	ID         string          `json:"id"`
+	Coin       string          `json:"coin"`
+	Amount     decimal.Decimal `json:"amount"`
+	Status     int             `json:"status"`
+	TxID       string          `json:"txId"`
+	InsertTime int64           `json:"insertTime"`
+}
+
+// Withdrawal statuses: 0 is email sent, 1 is cancelled, 2 is awaiting approval, 3 is rejected,
+// 4 is processing, 5 is failure, 6 is completed
+const WithdrawalStatusCompleted = 6
+
+// WithdrawTimeLayout is the layout of the withdrawal apply time in UTC
+const WithdrawTimeLayout = "2006-01-02 15:04:05"
+
+type Withdrawal struct {
+	ID             string          `json:"id"`
+	Coin           string          `json:"coin"`
+	Amount         decimal.Decimal `json:"amount"`
+	TransactionFee decimal.Decimal `json:"transactionFee"`
+	Status         int             `json:"status"`
+	TxID           string          `json:"txId"`
+	ApplyTime      string          `json:"applyTime"`
+}
+
+// Error is returned when Binance responds with an error payload
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"msg"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("binance error %d: %s", e.Code, e.Message)
+}
+
+func New(apiKey, apiSecret string) *Binance {
+	return &Binance{
+		apiKey:     apiKey,
+		apiSecret:  apiSecret,
+		baseURL:    APIURL,
+		httpClient: &http.Client{Timeout: defaultTimeout},
+	}
+}
+
+func (b *Binance) GetAccount() (*Account, error) {
+	var account Account
+	err := b.do("/api/v3/account", url.Values{}, true, &account)
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (b *Binance) GetExchangeInfo() (*ExchangeInfo, error) {
+	var info ExchangeInfo
+	err := b.do("/api/v3/exchangeInfo", url.Values{}, false, &info)
+	if err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// GetTickers returns 24hr price statistics for all symbols
+func (b *Binance) GetTickers() ([]Ticker, error) {
+	var tickers []Ticker
+	err := b.do("/api/v3/ticker/24hr", url.Values{}, false, &tickers)
+	return tickers, err
+}
+
+// GetTicker returns 24hr price statistics for one symbol, for example 'ETHBTC'
+func (b *Binance) GetTicker(symbol string) (*Ticker, error) {
+	var ticker Ticker
+	err := b.do("/api/v3/ticker/24hr", url.Values{"symbol": {symbol}}, false, &ticker)
+	if err != nil {
+		return nil, err
+	}
+	return &ticker, nil
+}
+
+// GetKlines returns up to 1000 last candles of the symbol for the interval, e.g. '1h', the oldest first
+func (b *Binance) GetKlines(symbol, interval string) ([]Kline, error) {
+	var klines []Kline
+	params := url.Values{
+		"symbol":   {symbol},
+		"interval": {interval},
+		"limit":    {strconv.Itoa(maxKlines)},
+	}
+	err := b.do("/api/v3/klines", params, false, &klines)
+	return klines, err
+}
+
+// GetAllOrders returns all account orders of the symbol: active, canceled or filled
+func (b *Binance) GetAllOrders(symbol string) ([]Order, error) {
+	var orders []Order
+	err := b.do("/api/v3/allOrders", url.Values{"symbol": {symbol}}, true, &orders)
+	return orders, err
+}
+
+// GetMyTrades returns the latest account fills of the symbol, the oldest first
+func (b *Binance) GetMyTrades(symbol string) ([]Trade, error) {
+	var trades []Trade
+	err := b.do("/api/v3/myTrades", url.Values{"symbol": {symbol}, "limit": {strconv.Itoa(maxTrades)}}, true, &trades)
+	return trades, err
+}
+
+// GetDepositHistory returns deposits of the last 90 days, the latest first
+func (b *Binance) GetDepositHistory() ([]Deposit, error) {
+	var deposits []Deposit
+	err := b.do("/sapi/v1/capital/deposit/hisrec", url.Values{}, true, &deposits)
+	return deposits, err
+}
+
+// GetWithdrawalHistory returns withdrawals of the last 90 days, the latest first.
+// Amount of a withdrawal doesn't include the transaction fee
+func (b *Binance) GetWithdrawalHistory() ([]Withdrawal, error) {
+	var withdrawals []Withdrawal
+	err := b.do("/sapi/v1/capital/withdraw/history", url.Values{}, true, &withdrawals)
+	return withdrawals, err
+}
+
+func (b *Binance) do(path string, params url.Values, signed bool, result interface{}) error {
+	if signed {
+		params.Set("recvWindow", strconv.Itoa(defaultRecvWindow))
+		params.Set("timestamp", strconv.FormatInt(time.Now().UnixNano()/int64(time.Millisecond), 10))
+	}
+	query := params.Encode()
+	if signed {
+		// signature must be the last parameter and is calculated over all the others
+		query += "&signature=" + b.sign(query)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, b.baseURL+path+"?"+query, nil)
+	if err != nil {
+		return err
+	}
+	if signed {
+		req.Header.Set("X-MBX-APIKEY", b.apiKey)
+	}
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		apiErr := &Error{}
+		if json.Unmarshal(body, apiErr) == nil && apiErr.Message != "" {
+			return apiErr
+		}
+		return fmt.Errorf("binance responded with status %d", resp.StatusCode)
+	}
+
+	return json.Unmarshal(body, result)
+}
+
+func (b *Binance) sign(query string) string {
+	mac := hmac.New(sha256.New, []byte(b.apiSecret))
+	_, _ = mac.Write([]byte(query))
+	return hex.EncodeToString(mac.Sum(nil))
+}
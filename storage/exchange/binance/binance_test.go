@@ -0,0 +1,145 @@
+package binance
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/h2non/gock"
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestBinance_SignedRequest(t *testing.T) {
+	defer gock.Off()
+
+	var rawQuery string
+	gock.New(APIURL).
+		Get("/api/v3/account").
+		MatchHeader("X-MBX-APIKEY", "key").
+		// own matcher, AddMatcher would change the default one shared by all mocks
+		SetMatcher(gock.NewMatcher()).
+		AddMatcher(func(req *http.Request, _ *gock.Request) (bool, error) {
+			rawQuery = req.URL.RawQuery
+			return true, nil
+		}).
+		Reply(200).
+		JSON(Account{})
+
+	_, err := New("key", "secret").GetAccount()
+	assert.NoError(t, err)
+
+	idx := strings.LastIndex(rawQuery, "&signature=")
+	assert.True(t, idx > 0, "signature must be the last parameter")
+	assert.Contains(t, rawQuery[:idx], "timestamp=")
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	_, _ = mac.Write([]byte(rawQuery[:idx]))
+	assert.Equal(t, hex.EncodeToString(mac.Sum(nil)), rawQuery[idx+len("&signature="):])
+}
+
+func TestBinance_PublicRequest(t *testing.T) {
+	defer gock.Off()
+
+	gock.New(APIURL).
+		Get("/api/v3/ticker/24hr").
+		MatchParam("symbol", "ETHBTC").
+		// own matcher, AddMatcher would change the default one shared by all mocks
+		SetMatcher(gock.NewMatcher()).
+		AddMatcher(func(req *http.Request, _ *gock.Request) (bool, error) {
+			return req.URL.Query().Get("signature") == "" && req.Header.Get("X-MBX-APIKEY") == "", nil
+		}).
+		Reply(200).
+		BodyString(`{"symbol":"ETHBTC","lastPrice":"0.03","bidPrice":"0.029","askPrice":"0.031"}`)
+
+	ticker, err := New("key", "secret").GetTicker("ETHBTC")
+	assert.NoError(t, err)
+	assert.Equal(t, "ETHBTC", ticker.Symbol)
+	assert.Equal(t, "0.03", ticker.LastPrice.String())
+}
+
+func TestBinance_Error(t *testing.T) {
+	defer gock.Off()
+
+	gock.New(APIURL).
+		Get("/api/v3/ticker/24hr").
+		Reply(400).
+		BodyString(`{"code":-1121,"msg":"Invalid symbol."}`)
+
+	_, err := New("key", "secret").GetTicker("UNKNOWN")
+	assert.Equal(t, &Error{Code: -1121, Message: "Invalid symbol."}, err)
+
+	gock.New(APIURL).
+		Get("/api/v3/ticker/24hr").
+		Reply(502).
+		BodyString(`Bad Gateway`)
+
+	_, err = New("key", "secret").GetTicker("ETHBTC")
+	assert.EqualError(t, err, "binance responded with status 502")
+}
+
+func TestBinance_GetTransferHistory(t *testing.T) {
+	defer gock.Off()
+
+	gock.New(APIURL).
+		Get("/sapi/v1/capital/deposit/hisrec").
+		MatchHeader("X-MBX-APIKEY", "key").
+		Reply(200).
+		BodyString(`[{"id":"d1","coin":"BTC","amount":"0.5","status":1,"txId":"tx1","insertTime":1517904000000}]`)
+
+	gock.New(APIURL).
+		Get("/sapi/v1/capital/withdraw/history").
+		MatchHeader("X-MBX-APIKEY", "key").
+		Reply(200).
+		BodyString(`[{"id":"w1","coin":"ETH","amount":"2","transactionFee":"0.01","status":6,"txId":"tx2","applyTime":"2018-02-06 10:00:00"}]`)
+
+	client := New("key", "secret")
+
+	deposits, err := client.GetDepositHistory()
+	assert.NoError(t, err)
+	assert.Len(t, deposits, 1)
+	assert.Equal(t, "d1", deposits[0].ID)
+	assert.Equal(t, "0.5", deposits[0].Amount.String())
+	assert.Equal(t, DepositStatusSuccess, deposits[0].Status)
+	assert.Equal(t, int64(1517904000000), deposits[0].InsertTime)
+
+	withdrawals, err := client.GetWithdrawalHistory()
+	assert.NoError(t, err)
+	assert.Len(t, withdrawals, 1)
+	assert.Equal(t, "w1", withdrawals[0].ID)
+	assert.Equal(t, "0.01", withdrawals[0].TransactionFee.String())
+	assert.Equal(t, WithdrawalStatusCompleted, withdrawals[0].Status)
+	assert.Equal(t, "2018-02-06 10:00:00", withdrawals[0].ApplyTime)
+}
+
+func TestBinance_GetKlines(t *testing.T) {
+	defer gock.Off()
+
+	gock.New(APIURL).
+		Get("/api/v3/klines").
+		MatchParam("symbol", "ETHBTC").
+		MatchParam("interval", "1h").
+		MatchParam("limit", "1000").
+		Reply(200).
+		BodyString(`[[1517904000000,"0.1","0.15","0.05","0.12","100",1517907599999,"12",10,"50","6","0"]]`)
+
+	klines, err := New("key", "secret").GetKlines("ETHBTC", "1h")
+	assert.NoError(t, err)
+	assert.Len(t, klines, 1)
+	assert.Equal(t, int64(1517904000000), klines[0].OpenTime)
+	assert.Equal(t, "0.1", klines[0].Open.String())
+	assert.Equal(t, "0.15", klines[0].High.String())
+	assert.Equal(t, "0.05", klines[0].Low.String())
+	assert.Equal(t, "0.12", klines[0].Close.String())
+	assert.Equal(t, "100", klines[0].Volume.String())
+
+	gock.New(APIURL).
+		Get("/api/v3/klines").
+		Reply(200).
+		BodyString(`[[1517904000000,"0.1"]]`)
+
+	_, err = New("key", "secret").GetKlines("ETHBTC", "1h")
+	assert.Error(t, err)
+}
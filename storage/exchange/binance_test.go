@@ -0,0 +1,562 @@
+package exchange
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/h2non/gock"
+	"github.com/shopspring/decimal"
+	assert "github.com/stretchr/testify/require"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage/exchange/binance"
+	"github.com/nawa/cryptoexchange-dashboard/storage/exchange/testdata"
+	"github.com/nawa/cryptoexchange-dashboard/utils"
+)
+
+func TestNewBinanceExchange(t *testing.T) {
+	exchange := NewBinanceExchange(testAPIKey, testAPISecret, ConversionLiquidity)
+	assert.IsType(t, &binanceExchange{}, exchange)
+	assert.NotNil(t, exchange.(*binanceExchange).binance)
+	assert.NotNil(t, exchange.(*binanceExchange).log)
+}
+
+func TestBinanceExchange_Ping(t *testing.T) {
+	defer gock.Off()
+
+	be := &binanceExchange{
+		binance: binance.New(testAPIKey, testAPISecret),
+		log:     utils.NewDevNullLog(),
+	}
+
+	gock.New(binance.APIURL).
+		Get("/api/v3/account").
+		MatchHeader("X-MBX-APIKEY", testAPIKey).
+		Reply(200).
+		JSON(testdata.BinanceAccount())
+
+	err := be.Ping()
+	assert.NoError(t, err)
+
+	gock.New(binance.APIURL).
+		Get("/api/v3/account").
+		Reply(401).
+		JSON(testdata.BinanceErrorResponse)
+
+	err = be.Ping()
+	assert.Error(t, err)
+	assert.IsType(t, &binance.Error{}, err)
+}
+
+func TestBinanceExchange_GetBalance(t *testing.T) {
+	type fields struct {
+		binance *binance.Binance
+		log     *logrus.Entry
+	}
+	tests := []struct {
+		name    string
+		fieldsF func() fields
+		want    []domain.Balance
+		wantErr bool
+	}{
+		{
+			name: "correct",
+			fieldsF: func() fields {
+				mockBinanceConverter()
+
+				gock.New(binance.APIURL).
+					Get("/api/v3/account").
+					Reply(200).
+					JSON(testdata.BinanceAccount())
+
+				return fields{
+					binance: binance.New(testAPIKey, testAPISecret),
+					log:     utils.NewDevNullLog(),
+				}
+			},
+			want:    testdata.BinanceModelBalances(),
+			wantErr: false,
+		},
+		{
+			name: "error in binance 'account'",
+			fieldsF: func() fields {
+				mockBinanceConverter()
+
+				gock.New(binance.APIURL).
+					Get("/api/v3/account").
+					Reply(401).
+					JSON(testdata.BinanceErrorResponse)
+
+				return fields{
+					binance: binance.New(testAPIKey, testAPISecret),
+					log:     utils.NewDevNullLog(),
+				}
+			},
+			wantErr: true,
+		},
+		{
+			name: "error in binance 'ticker/24hr'",
+			fieldsF: func() fields {
+				gock.New(binance.APIURL).
+					Get("/api/v3/exchangeInfo").
+					Reply(200).
+					JSON(testdata.BinanceExchangeInfo())
+
+				gock.New(binance.APIURL).
+					Get("/api/v3/ticker/24hr").
+					Reply(500)
+
+				gock.New(binance.APIURL).
+					Get("/api/v3/account").
+					Reply(200).
+					JSON(testdata.BinanceAccount())
+
+				return fields{
+					binance: binance.New(testAPIKey, testAPISecret),
+					log:     utils.NewDevNullLog(),
+				}
+			},
+			wantErr: true,
+		},
+		{
+			name: "BTC market is missing for currency, balances are kept without BTC and USDT amounts",
+			fieldsF: func() fields {
+				gock.New(binance.APIURL).
+					Get("/api/v3/exchangeInfo").
+					Reply(200).
+					JSON(testdata.BinanceExchangeInfo())
+
+				gock.New(binance.APIURL).
+					Get("/api/v3/ticker/24hr").
+					Reply(200).
+					JSON([]binance.Ticker{})
+
+				gock.New(binance.APIURL).
+					Get("/api/v3/account").
+					Reply(200).
+					JSON(testdata.BinanceAccount())
+
+				return fields{
+					binance: binance.New(testAPIKey, testAPISecret),
+					log:     utils.NewDevNullLog(),
+				}
+			},
+			want:    withoutConversion(testdata.BinanceModelBalances()),
+			wantErr: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer gock.Off()
+
+			fields := tt.fieldsF()
+			be := &binanceExchange{
+				binance: fields.binance,
+				log:     fields.log,
+			}
+			got, err := be.GetBalance()
+			if err != nil {
+				if !tt.wantErr {
+					t.Errorf("binanceExchange.GetBalance() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Errorf("binanceExchange.GetBalance() error is expected")
+			}
+			if !modelBalancesEqual(got, tt.want) {
+				t.Errorf("binanceExchange.GetBalance() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBinanceExchange_GetMarketInfo(t *testing.T) {
+	type fields struct {
+		binance *binance.Binance
+		log     *logrus.Entry
+	}
+	type args struct {
+		market string
+	}
+	tests := []struct {
+		name    string
+		fieldsF func() fields
+		args    args
+		want    *domain.MarketInfo
+		wantErr bool
+	}{
+		{
+			name: "correct",
+			fieldsF: func() fields {
+				gock.New(binance.APIURL).
+					Get("/api/v3/ticker/24hr").
+					MatchParam("symbol", "CUR1BTC").
+					Reply(200).
+					JSON(testdata.BinanceTickers()[0])
+
+				return fields{
+					binance: binance.New(testAPIKey, testAPISecret),
+					log:     utils.NewDevNullLog(),
+				}
+			},
+			args:    args{market: "btc-cur1"},
+			want:    testdata.BinanceModelMarketInfo(),
+			wantErr: false,
+		},
+		{
+			name: "error in binance 'ticker/24hr'",
+			fieldsF: func() fields {
+				gock.New(binance.APIURL).
+					Get("/api/v3/ticker/24hr").
+					Reply(400).
+					JSON(binance.Error{Code: -1121, Message: "Invalid symbol."})
+
+				return fields{
+					binance: binance.New(testAPIKey, testAPISecret),
+					log:     utils.NewDevNullLog(),
+				}
+			},
+			args:    args{market: "BTC-CUR5"},
+			wantErr: true,
+		},
+		{
+			name: "error when market name is incorrect",
+			fieldsF: func() fields {
+				return fields{
+					binance: binance.New(testAPIKey, testAPISecret),
+					log:     utils.NewDevNullLog(),
+				}
+			},
+			args:    args{market: "CUR1BTC"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer gock.Off()
+
+			fields := tt.fieldsF()
+			be := &binanceExchange{
+				binance: fields.binance,
+				log:     fields.log,
+			}
+			got, err := be.GetMarketInfo(tt.args.market)
+			if err != nil {
+				if !tt.wantErr {
+					t.Errorf("binanceExchange.GetMarketInfo() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Errorf("binanceExchange.GetMarketInfo() error is expected")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("binanceExchange.GetMarketInfo() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBinanceExchange_GetOrders(t *testing.T) {
+	type fields struct {
+		binance *binance.Binance
+		log     *logrus.Entry
+	}
+	tests := []struct {
+		name    string
+		fieldsF func() fields
+		want    []domain.Order
+		wantErr bool
+	}{
+		{
+			name: "correct",
+			fieldsF: func() fields {
+				mockBinanceConverter()
+
+				gock.New(binance.APIURL).
+					Get("/api/v3/account").
+					Reply(200).
+					JSON(testdata.BinanceAccount())
+
+				for symbol, orders := range testdata.BinanceOrders() {
+					gock.New(binance.APIURL).
+						Get("/api/v3/allOrders").
+						MatchParam("symbol", symbol).
+						Reply(200).
+						JSON(orders)
+				}
+
+				return fields{
+					binance: binance.New(testAPIKey, testAPISecret),
+					log:     utils.NewDevNullLog(),
+				}
+			},
+			want:    testdata.BinanceModelOrders(),
+			wantErr: false,
+		},
+		{
+			name: "correct if account has no coins",
+			fieldsF: func() fields {
+				mockBinanceConverter()
+
+				gock.New(binance.APIURL).
+					Get("/api/v3/account").
+					Reply(200).
+					JSON(binance.Account{})
+
+				return fields{
+					binance: binance.New(testAPIKey, testAPISecret),
+					log:     utils.NewDevNullLog(),
+				}
+			},
+			want:    []domain.Order{},
+			wantErr: false,
+		},
+		{
+			name: "error in binance 'allOrders'",
+			fieldsF: func() fields {
+				mockBinanceConverter()
+
+				gock.New(binance.APIURL).
+					Get("/api/v3/account").
+					Reply(200).
+					JSON(testdata.BinanceAccount())
+
+				gock.New(binance.APIURL).
+					Get("/api/v3/allOrders").
+					Persist().
+					Reply(429).
+					JSON(binance.Error{Code: -1003, Message: "Too many requests."})
+
+				return fields{
+					binance: binance.New(testAPIKey, testAPISecret),
+					log:     utils.NewDevNullLog(),
+				}
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer gock.Off()
+
+			fields := tt.fieldsF()
+			be := &binanceExchange{
+				binance: fields.binance,
+				log:     fields.log,
+			}
+			got, err := be.GetOrders()
+			if err != nil {
+				if !tt.wantErr {
+					t.Errorf("binanceExchange.GetOrders() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Errorf("binanceExchange.GetOrders() error is expected")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("binanceExchange.GetOrders() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBinanceExchange_GetTrades(t *testing.T) {
+	defer gock.Off()
+
+	mockBinanceConverter()
+
+	gock.New(binance.APIURL).
+		Get("/api/v3/account").
+		Reply(200).
+		JSON(testdata.BinanceAccount())
+
+	for symbol, fills := range testdata.BinanceMyTrades() {
+		gock.New(binance.APIURL).
+			Get("/api/v3/myTrades").
+			MatchParam("symbol", symbol).
+			Reply(200).
+			JSON(fills)
+	}
+
+	be := &binanceExchange{
+		binance: binance.New(testAPIKey, testAPISecret),
+		log:     utils.NewDevNullLog(),
+	}
+	trades, err := be.GetTrades()
+	assert.NoError(t, err)
+	assert.Equal(t, testdata.BinanceModelTrades(), trades)
+}
+
+func TestBinanceExchange_GetTrades_SoldOut(t *testing.T) {
+	defer gock.Off()
+
+	mockBinanceConverter()
+
+	// CUR1 is sold out, it's found by trades of the previous scan
+	gock.New(binance.APIURL).
+		Get("/api/v3/account").
+		Reply(200).
+		JSON(&binance.Account{Balances: []binance.Balance{{Asset: "BTC", Free: decimal.NewFromFloat(1)}}})
+
+	for _, symbol := range []string{"CUR1BTC", "BTCUSDT"} {
+		gock.New(binance.APIURL).
+			Get("/api/v3/myTrades").
+			MatchParam("symbol", symbol).
+			Reply(200).
+			JSON(testdata.BinanceMyTrades()[symbol])
+	}
+
+	be := &binanceExchange{
+		binance:       binance.New(testAPIKey, testAPISecret),
+		log:           utils.NewDevNullLog(),
+		tradedSymbols: map[string]bool{"CUR1BTC": true},
+	}
+	trades, err := be.GetTrades()
+	assert.NoError(t, err)
+	assert.True(t, gock.IsDone())
+
+	var want []domain.Trade
+	for _, trade := range testdata.BinanceModelTrades() {
+		if trade.Market == "BTC-CUR1" {
+			want = append(want, trade)
+		}
+	}
+	assert.Equal(t, want, trades)
+}
+
+func TestBinanceExchange_ScanTrades(t *testing.T) {
+	defer gock.Off()
+
+	mockBinanceConverter()
+
+	gock.New(binance.APIURL).
+		Get("/api/v3/account").
+		Reply(200).
+		JSON(&binance.Account{})
+
+	// markets of quote assets only, CUR2CUR1 isn't looked through
+	for _, symbol := range []string{"CUR1BTC", "CUR2BTC", "BTCUSDT"} {
+		gock.New(binance.APIURL).
+			Get("/api/v3/myTrades").
+			MatchParam("symbol", symbol).
+			Reply(200).
+			JSON(testdata.BinanceMyTrades()[symbol])
+	}
+
+	be := &binanceExchange{
+		binance: binance.New(testAPIKey, testAPISecret),
+		log:     utils.NewDevNullLog(),
+	}
+	trades, err := be.ScanTrades()
+	assert.NoError(t, err)
+	assert.True(t, gock.IsDone())
+	assert.Equal(t, testdata.BinanceModelTrades(), trades)
+	assert.Equal(t, map[string]bool{"CUR1BTC": true, "CUR2BTC": true}, be.tradedSymbols)
+}
+
+func mockBinanceConverter() {
+	gock.New(binance.APIURL).
+		Get("/api/v3/exchangeInfo").
+		Reply(200).
+		JSON(testdata.BinanceExchangeInfo())
+
+	gock.New(binance.APIURL).
+		Get("/api/v3/ticker/24hr").
+		Reply(200).
+		JSON(testdata.BinanceTickers())
+}
+
+func TestBinanceExchange_GetTransfers(t *testing.T) {
+	defer gock.Off()
+
+	mockBinanceConverter()
+
+	gock.New(binance.APIURL).
+		Get("/sapi/v1/capital/deposit/hisrec").
+		Reply(200).
+		JSON(testdata.BinanceDeposits())
+
+	gock.New(binance.APIURL).
+		Get("/sapi/v1/capital/withdraw/history").
+		Reply(200).
+		JSON(testdata.BinanceWithdrawals())
+
+	be := &binanceExchange{
+		binance: binance.New(testAPIKey, testAPISecret),
+		log:     utils.NewDevNullLog(),
+	}
+	transfers, err := be.GetTransfers()
+	assert.NoError(t, err)
+	assert.Equal(t, testdata.BinanceModelTransfers(), transfers)
+
+	mockBinanceConverter()
+
+	gock.New(binance.APIURL).
+		Get("/sapi/v1/capital/deposit/hisrec").
+		Reply(401).
+		JSON(testdata.BinanceErrorResponse)
+
+	gock.New(binance.APIURL).
+		Get("/sapi/v1/capital/withdraw/history").
+		Reply(200).
+		JSON(testdata.BinanceWithdrawals())
+
+	_, err = be.GetTransfers()
+	assert.Error(t, err)
+}
+
+func TestBinanceExchange_MarketHistory(t *testing.T) {
+	defer gock.Off()
+
+	mockBinanceConverter()
+
+	gock.New(binance.APIURL).
+		Get("/api/v3/account").
+		Reply(200).
+		JSON(testdata.BinanceAccount())
+
+	be := &binanceExchange{
+		binance: binance.New(testAPIKey, testAPISecret),
+		log:     utils.NewDevNullLog(),
+	}
+	assert.Empty(t, be.LastMarketPrices())
+
+	balances, err := be.GetBalance()
+	assert.NoError(t, err)
+
+	prices := be.LastMarketPrices()
+	assert.Len(t, prices, 4)
+	assert.Equal(t, domain.MarketCandle{
+		Exchange: domain.ExchangeTypeBinance,
+		Market:   "BTC-CUR1",
+		Time:     balances[0].Time,
+		Price:    domain.OHLC{Open: 10, Close: 10, Min: 10, Max: 10},
+	}, prices[0])
+	assert.Equal(t, "USDT-BTC", prices[3].Market)
+
+	gock.New(binance.APIURL).
+		Get("/api/v3/klines").
+		MatchParam("symbol", "CUR1BTC").
+		MatchParam("interval", "30m").
+		Reply(200).
+		BodyString(`[[1517904000000,"10","15","5","12","100",1517905799999,"1200",10,"50","600","0"]]`)
+
+	candles, err := be.GetMarketCandles("btc-cur1", 30*time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.MarketCandle{{
+		Exchange: domain.ExchangeTypeBinance,
+		Market:   "BTC-CUR1",
+		Time:     time.Date(2018, 2, 6, 8, 0, 0, 0, time.UTC),
+		Price:    domain.OHLC{Open: 10, Close: 12, Min: 5, Max: 15},
+	}}, candles)
+
+	_, err = be.GetMarketCandles("BTC-CUR1", 2*time.Hour)
+	assert.Error(t, err)
+	_, err = be.GetMarketCandles("CUR1BTC", time.Hour)
+	assert.Error(t, err)
+}
@@ -1,7 +1,7 @@
 package exchange
 
 import (
-	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -19,22 +19,47 @@ import (
 type bittrexExchange struct {
 	bittrex *bittrex.Bittrex
 	log     *logrus.Entry
+	// preference is the path of conversion of currencies without a common market
+	preference ConversionPreference
+	// feed is used for market rates if the exchange is streaming
+	feed *marketFeed
+	// lastPrices are prices of markets at the last balance
+	lastPrices lastMarketPrices
 }
 
-type currencyConverter struct {
-	marketSummaries []bittrex.MarketSummary
-	syncTime        time.Time
+// bittrexCandleIntervals are tick intervals of Bittrex candles
+var bittrexCandleIntervals = map[time.Duration]string{
+	time.Minute:      "oneMin",
+	5 * time.Minute:  "fiveMin",
+	30 * time.Minute: "thirtyMin",
+	time.Hour:        "hour",
+	24 * time.Hour:   "day",
 }
 
-func NewBittrexExchange(apiKey, apiSecret string) storage.Exchange {
+func NewBittrexExchange(apiKey, apiSecret string, preference ConversionPreference) storage.Exchange {
 	log := logrus.WithField("component", "BittrexExchange")
 	bittrex := bittrex.New(apiKey, apiSecret)
 	return &bittrexExchange{
-		bittrex: bittrex,
-		log:     log,
+		bittrex:    bittrex,
+		log:        log,
+		preference: preference,
 	}
 }
 
+// NewBittrexStreamingExchange creates the exchange that keeps live state of markets it has been asked for
+// over the Bittrex SignalR API instead of downloading them on every call. The returned function stops streaming
+func NewBittrexStreamingExchange(apiKey, apiSecret string, preference ConversionPreference) (storage.Exchange, func()) {
+	log := logrus.WithField("component", "BittrexExchange")
+	bittrex := bittrex.New(apiKey, apiSecret)
+	feed := newMarketFeed(bittrex, logrus.WithField("component", "BittrexMarketFeed"))
+	return &bittrexExchange{
+		bittrex:    bittrex,
+		log:        log,
+		preference: preference,
+		feed:       feed,
+	}, feed.Stop
+}
+
 func (be *bittrexExchange) GetBalance() ([]domain.Balance, error) {
 	var (
 		balances  []bittrex.Balance
@@ -60,18 +85,12 @@ func (be *bittrexExchange) GetBalance() ([]domain.Balance, error) {
 	if err != nil {
 		return nil, err
 	}
+	be.lastPrices.set(domain.ExchangeTypeBittrex, converter)
 
 	var result []domain.Balance
 	for _, b := range balances {
 		if b.Balance.GreaterThan(decimal.NewFromFloat(0)) {
-			btcBalance, err := converter.ConvertToBTC(b.Currency, b.Balance)
-			if err != nil {
-				return nil, err
-			}
-			usdtBalance, err := converter.ConvertToUSDT("BTC", btcBalance)
-			if err != nil {
-				return nil, err
-			}
+			btcBalance, usdtBalance := convertBalance(converter, b.Currency, b.Balance, be.log)
 
 			result = append(result, domain.Balance{
 				Exchange:   domain.ExchangeTypeBittrex,
@@ -87,6 +106,11 @@ func (be *bittrexExchange) GetBalance() ([]domain.Balance, error) {
 }
 
 func (be *bittrexExchange) GetMarketInfo(market string) (*domain.MarketInfo, error) {
+	if be.feed != nil {
+		be.feed.Subscribe(market)
+		return be.feed.MarketInfo(market)
+	}
+
 	marketSummary, err := be.bittrex.GetMarketSummary(market)
 	if err != nil {
 		return nil, err
@@ -107,27 +131,7 @@ func (be *bittrexExchange) GetMarketInfo(market string) (*domain.MarketInfo, err
 }
 
 func (be *bittrexExchange) GetOrders() ([]domain.Order, error) {
-	var (
-		orders    []bittrex.Order
-		converter *currencyConverter
-	)
-
-	errs := utils.ExecuteConcurrently([]func() error{
-		func() (err error) {
-			converter, err = be.createCurrencyConverter()
-			return
-		},
-		func() (err error) {
-			orders, err = be.bittrex.GetOrderHistory("all")
-			return
-		},
-	})
-
-	var err error
-	for _, e := range errs {
-		err = multierror.Append(err, e)
-	}
-
+	orders, converter, err := be.getOrderHistory()
 	if err != nil {
 		return nil, err
 	}
@@ -146,9 +150,68 @@ func (be *bittrexExchange) GetOrders() ([]domain.Order, error) {
 		}
 	}
 
+	if be.feed != nil {
+		// sell now rates of open positions become live from the next call
+		for _, order := range filtered {
+			be.feed.Subscribe(order.Exchange)
+		}
+	}
+
 	return be.convertOrders(filtered, converter), nil
 }
 
+// WatchMarket streams states of the market. It fails if the exchange isn't streaming
+func (be *bittrexExchange) WatchMarket(market string) (<-chan domain.MarketInfo, func(), error) {
+	if be.feed == nil {
+		return nil, nil, errors.New("market streaming isn't enabled")
+	}
+	updates, cancel := be.feed.Watch(market)
+	return updates, cancel, nil
+}
+
+// LastMarketPrices returns prices of all markets at the time of the last balance
+func (be *bittrexExchange) LastMarketPrices() []domain.MarketCandle {
+	return be.lastPrices.get()
+}
+
+// GetRates converts currencies by current markets, streamed ones if the exchange is streaming
+func (be *bittrexExchange) GetRates(currencies []string, quote string) ([]domain.Rate, error) {
+	converter, err := be.createCurrencyConverter()
+	if err != nil {
+		return nil, err
+	}
+	return converter.rates(domain.ExchangeTypeBittrex, currencies, quote), nil
+}
+
+// GetMarketCandles returns candles of the market Bittrex keeps for the interval, the oldest first
+func (be *bittrexExchange) GetMarketCandles(market string, interval time.Duration) ([]domain.MarketCandle, error) {
+	tickInterval, ok := bittrexCandleIntervals[interval]
+	if !ok {
+		return nil, errors.Errorf("candle interval %s isn't supported", interval)
+	}
+
+	candles, err := be.bittrex.GetTicks(market, tickInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]domain.MarketCandle, len(candles))
+	for i, c := range candles {
+		result[i] = domain.MarketCandle{
+			Exchange: domain.ExchangeTypeBittrex,
+			Market:   strings.ToUpper(market),
+			Time:     c.TimeStamp.Time.UTC(),
+			Price: domain.OHLC{
+				Open:  utils.DecimalToFloatQuiet(c.Open),
+				Close: utils.DecimalToFloatQuiet(c.Close),
+				Min:   utils.DecimalToFloatQuiet(c.Low),
+				Max:   utils.DecimalToFloatQuiet(c.High),
+			},
+		}
+	}
+	return result, nil
+}
+
 func (be *bittrexExchange) convertOrders(bittrexOrders []bittrex.Order, converter *currencyConverter) []domain.Order {
 	orders := []domain.Order{} //don't change me
 	for _, order := range bittrexOrders {
@@ -164,6 +227,12 @@ func (be *bittrexExchange) convertOrders(bittrexOrders []bittrex.Order, converte
 			continue
 		}
 
+		btcRate, err := converter.ConvertToBTC(toFrom[0], decimal.NewFromFloat(1))
+		if err != nil {
+			be.log.WithField("method", "convertOrders").Warnf("market convert to BTC")
+			continue
+		}
+
 		usdtRate, err := converter.ConvertToUSDT(toFrom[0], decimal.NewFromFloat(1))
 		if err != nil {
 			be.log.WithField("method", "convertOrders").Warnf("market convert to USDT")
@@ -177,86 +246,187 @@ func (be *bittrexExchange) convertOrders(bittrexOrders []bittrex.Order, converte
 			Amount:      utils.DecimalToFloatQuiet(order.Quantity),
 			BuyRate:     utils.DecimalToFloatQuiet(order.Price.Div(order.Quantity)),
 			SellNowRate: utils.DecimalToFloatQuiet(bidRate),
+			BTCRate:     utils.DecimalToFloatQuiet(btcRate),
 			USDTRate:    utils.DecimalToFloatQuiet(usdtRate),
 		})
 	}
 	return orders
 }
 
-func (be *bittrexExchange) Ping() error {
-	_, err := be.bittrex.GetBalances()
-	return err
-}
-
-func (be *bittrexExchange) createCurrencyConverter() (*currencyConverter, error) {
-	marketSummaries, err := be.bittrex.GetMarketSummaries()
+func (be *bittrexExchange) GetTrades() ([]domain.Trade, error) {
+	orders, converter, err := be.getOrderHistory()
 	if err != nil {
 		return nil, err
 	}
 
-	return &currencyConverter{
-		marketSummaries: marketSummaries,
-		syncTime:        time.Now().UTC(),
-	}, nil
+	return be.convertTrades(orders, converter), nil
 }
 
-func (c *currencyConverter) ConvertToBTC(currency string, amount decimal.Decimal) (decimal.Decimal, error) {
-	return c.ConvertCurrency(currency, "BTC", amount)
-}
+func (be *bittrexExchange) convertTrades(bittrexOrders []bittrex.Order, converter *currencyConverter) []domain.Trade {
+	trades := []domain.Trade{}
+	for _, order := range bittrexOrders {
+		amount := order.Quantity.Sub(order.QuantityRemaining)
+		if !amount.GreaterThan(decimal.Zero) {
+			continue
+		}
+
+		toFrom := strings.Split(order.Exchange, "-")
+		if len(toFrom) != 2 {
+			be.log.WithField("method", "convertTrades").Warnf("exchange name can't be parsed to from-to format - %s", order.Exchange)
+			continue
+		}
+
+		btcRate, err := converter.ConvertToBTC(toFrom[0], decimal.NewFromFloat(1))
+		if err != nil {
+			be.log.WithField("method", "convertTrades").Warnf("market convert to BTC")
+			continue
+		}
 
-func (c *currencyConverter) ConvertToUSDT(currency string, amount decimal.Decimal) (decimal.Decimal, error) {
-	return c.ConvertCurrency(currency, "USDT", amount)
+		usdtRate, err := converter.ConvertToUSDT(toFrom[0], decimal.NewFromFloat(1))
+		if err != nil {
+			be.log.WithField("method", "convertTrades").Warnf("market convert to USDT")
+			continue
+		}
+
+		tradeType := domain.TradeTypeBuy
+		if strings.HasSuffix(order.OrderType, "_SELL") {
+			tradeType = domain.TradeTypeSell
+		}
+
+		trades = append(trades, domain.Trade{
+			Exchange: domain.ExchangeTypeBittrex,
+			OrderID:  order.OrderUuid,
+			Market:   order.Exchange,
+			Type:     tradeType,
+			Time:     order.TimeStamp.Time,
+			Amount:   utils.DecimalToFloatQuiet(amount),
+			Rate:     utils.DecimalToFloatQuiet(order.Price.Div(amount)),
+			Fee:      utils.DecimalToFloatQuiet(order.Commission),
+			BTCRate:  utils.DecimalToFloatQuiet(btcRate),
+			USDTRate: utils.DecimalToFloatQuiet(usdtRate),
+		})
+	}
+	return trades
 }
 
-func (c *currencyConverter) ConvertCurrency(fromCurrency, toCurrency string, amount decimal.Decimal) (decimal.Decimal, error) {
-	last, _, _, err := c.MarketRate(fromCurrency, toCurrency)
+// getOrderHistory returns all orders of the account, the latest first, and the converter of current rates
+func (be *bittrexExchange) getOrderHistory() ([]bittrex.Order, *currencyConverter, error) {
+	var (
+		orders    []bittrex.Order
+		converter *currencyConverter
+	)
+
+	errs := utils.ExecuteConcurrently([]func() error{
+		func() (err error) {
+			converter, err = be.createCurrencyConverter()
+			return
+		},
+		func() (err error) {
+			orders, err = be.bittrex.GetOrderHistory("all")
+			return
+		},
+	})
+
+	var err error
+	for _, e := range errs {
+		err = multierror.Append(err, e)
+	}
+
 	if err != nil {
-		return decimal.Decimal{}, err
+		return nil, nil, err
 	}
-	return amount.Mul(last), nil
+	return orders, converter, nil
 }
 
-func (c *currencyConverter) MarketRate(fromCurrency, toCurrency string) (last, bid, ask decimal.Decimal, err error) {
-	if fromCurrency == toCurrency {
-		return decimal.NewFromFloat(1), decimal.NewFromFloat(1), decimal.NewFromFloat(1), nil
-	}
-	for _, market := range c.marketSummaries {
-		if strings.ToUpper(market.MarketName) == strings.ToUpper(fmt.Sprintf("%s-%s", toCurrency, fromCurrency)) {
-			last = market.Last
-			bid = market.Bid
-			ask = market.Ask
+func (be *bittrexExchange) GetTransfers() ([]domain.Transfer, error) {
+	var (
+		deposits    []bittrex.Deposit
+		withdrawals []bittrex.Withdrawal
+		converter   *currencyConverter
+	)
+
+	errs := utils.ExecuteConcurrently([]func() error{
+		func() (err error) {
+			converter, err = be.createCurrencyConverter()
 			return
-		}
-		if strings.ToUpper(market.MarketName) == strings.ToUpper(fmt.Sprintf("%s-%s", fromCurrency, toCurrency)) {
-			last = decimal.NewFromFloat(1).Div(market.Last)
-			bid = decimal.NewFromFloat(1).Div(market.Bid)
-			ask = decimal.NewFromFloat(1).Div(market.Ask)
+		},
+		func() (err error) {
+			deposits, err = be.bittrex.GetDepositHistory("all")
 			return
+		},
+		func() (err error) {
+			withdrawals, err = be.bittrex.GetWithdrawalHistory("all")
+			return
+		},
+	})
+
+	var err error
+	for _, e := range errs {
+		err = multierror.Append(err, e)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	transfers := []domain.Transfer{}
+	for _, d := range deposits {
+		transfers = append(transfers, domain.Transfer{
+			Exchange: domain.ExchangeTypeBittrex,
+			ID:       strconv.FormatInt(d.Id, 10),
+			Type:     domain.TransferTypeDeposit,
+			Currency: d.Currency,
+			Time:     d.LastUpdated.Time,
+			Amount:   utils.DecimalToFloatQuiet(d.Amount),
+		})
+	}
+	for _, w := range withdrawals {
+		if w.Canceled {
+			continue
 		}
+		// the amount is taken from the balance, the recipient gets it minus the transaction cost
+		transfers = append(transfers, domain.Transfer{
+			Exchange: domain.ExchangeTypeBittrex,
+			ID:       w.PaymentUuid,
+			Type:     domain.TransferTypeWithdrawal,
+			Currency: w.Currency,
+			Time:     w.Opened.Time,
+			Amount:   utils.DecimalToFloatQuiet(w.Amount),
+			Fee:      utils.DecimalToFloatQuiet(w.TxCost),
+		})
 	}
-	return last, bid, ask, fmt.Errorf("neither market '%s-%s' nor '%s-%s' found in markets", fromCurrency, toCurrency, toCurrency, fromCurrency)
+
+	return setTransferRates(transfers, converter, be.log), nil
 }
 
-// func (c *currencyConverter) MarketRateWorkaround(fromCurrency /* CUR3 */, toCurrency /* BTC */ string) (last decimal.Decimal, bid decimal.Decimal, ask decimal.Decimal, err error) {
-// 	for _, market := range c.marketSummaries {
-// 		toFrom := strings.Split(strings.ToUpper(market.MarketName), "-")
-// 		if len(toFrom) != 2 {
-// 			continue
-// 		}
-// 		if toFrom[0] == fromCurrency { /* CUR3-ETH */
-// 			last, bid, ask, err := c.MarketRate(toFrom[1] /* ETH */, toCurrency /* BTC */)
-// 			if err == nil {
-// 				return last.Mul(market.Last), bid.Mul(market.Bid), ask.Mul(market.Ask), nil
-// 			}
-// 		}
-
-// 		if toFrom[1] == fromCurrency { /* ETH-CUR3 */
-// 			last, bid, ask, err := c.MarketRate(toFrom[0] /* ETH */, toCurrency /* BTC */)
-// 			if err == nil {
-// 				return last.Mul(market.Last), bid.Mul(market.Bid), ask.Mul(market.Ask), nil
-// 			}
-// 		}
-// 	}
-// 	err = fmt.Errorf("neither market '%s-%s' nor '%s-%s' found in markets", fromCurrency, toCurrency, toCurrency, fromCurrency)
-// 	return
-// }
+func (be *bittrexExchange) Ping() error {
+	_, err := be.bittrex.GetBalances()
+	return err
+}
+
+func (be *bittrexExchange) createCurrencyConverter() (*currencyConverter, error) {
+	if be.feed != nil {
+		markets, err := be.feed.Markets()
+		if err != nil {
+			return nil, err
+		}
+		return newCurrencyConverter(markets, be.preference), nil
+	}
+
+	marketSummaries, err := be.bittrex.GetMarketSummaries()
+	if err != nil {
+		return nil, err
+	}
+
+	markets := make([]market, len(marketSummaries))
+	for i, summary := range marketSummaries {
+		markets[i] = market{
+			MarketName: summary.MarketName,
+			Last:       summary.Last,
+			Bid:        summary.Bid,
+			Ask:        summary.Ask,
+		}
+	}
+
+	return newCurrencyConverter(markets, be.preference), nil
+}
@@ -0,0 +1,488 @@
+package exchange
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"github.com/toorop/go-bittrex"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/utils"
+)
+
+const (
+	// summariesTTL is how long market summaries are reused. Subscribed markets are live anyway,
+	// the rest of summaries is needed for conversion rates only
+	summariesTTL = time.Minute
+
+	// subscriptionTTL is how long the market stays subscribed after the last request without watchers.
+	// It is longer than periods of syncs and alert evaluations requesting held and watched markets
+	subscriptionTTL = time.Minute * 10
+
+	minReconnectDelay = time.Second
+	maxReconnectDelay = time.Minute
+
+	// updatesBuffer absorbs bursts of order book deltas, the client drops updates when the buffer is full
+	updatesBuffer = 256
+)
+
+// orderUpdateRemove is the type of order book update removing the rate, others add or update it
+const orderUpdateRemove = 1
+
+// bittrexStreamClient is the part of the Bittrex client used by the market feed
+type bittrexStreamClient interface {
+	GetMarketSummaries() ([]bittrex.MarketSummary, error)
+	SubscribeExchangeUpdate(market string, dataCh chan<- bittrex.ExchangeState, stop <-chan bool) error
+}
+
+// marketFeed keeps the continuously updated state of subscribed Bittrex markets.
+// Every subscribed market has its own SignalR connection which is restored after failures, so markets
+// which aren't requested for subscriptionTTL and have no watchers are unsubscribed to close their connections.
+// The state of other markets comes from market summaries downloaded at most once per summariesTTL
+type marketFeed struct {
+	client            bittrexStreamClient
+	log               *logrus.Entry
+	summariesTTL      time.Duration
+	subscriptionTTL   time.Duration
+	minReconnectDelay time.Duration
+	maxReconnectDelay time.Duration
+
+	// refreshLock prevents concurrent downloads of the same summaries
+	refreshLock sync.Mutex
+
+	lock          sync.RWMutex
+	summaries     map[string]bittrex.MarketSummary
+	summariesTime time.Time
+	books         map[string]*orderBook
+	subscriptions map[string]*subscription
+	watchers      map[string]map[chan domain.MarketInfo]bool
+	stopped       bool
+}
+
+func newMarketFeed(client bittrexStreamClient, log *logrus.Entry) *marketFeed {
+	return &marketFeed{
+		client:            client,
+		log:               log,
+		summariesTTL:      summariesTTL,
+		subscriptionTTL:   subscriptionTTL,
+		minReconnectDelay: minReconnectDelay,
+		maxReconnectDelay: maxReconnectDelay,
+		summaries:         make(map[string]bittrex.MarketSummary),
+		books:             make(map[string]*orderBook),
+		subscriptions:     make(map[string]*subscription),
+		watchers:          make(map[string]map[chan domain.MarketInfo]bool),
+	}
+}
+
+// subscription is the streamed market, requested is the time of the last Subscribe call
+type subscription struct {
+	stop      chan bool
+	requested time.Time
+}
+
+// Subscribe starts streaming of the market or prolongs the existing subscription.
+// Subscriptions of other markets which are no longer requested are closed
+func (f *marketFeed) Subscribe(market string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.stopped {
+		return
+	}
+
+	now := time.Now()
+	if s := f.subscriptions[market]; s != nil {
+		s.requested = now
+	} else {
+		s = &subscription{stop: make(chan bool), requested: now}
+		f.subscriptions[market] = s
+		go f.follow(market, s.stop)
+	}
+	f.unsubscribeIdle(now)
+}
+
+// unsubscribeIdle closes subscriptions without watchers requested more than subscriptionTTL ago. It must be called under the lock
+func (f *marketFeed) unsubscribeIdle(now time.Time) {
+	for market, s := range f.subscriptions {
+		if len(f.watchers[market]) == 0 && now.Sub(s.requested) >= f.subscriptionTTL {
+			close(s.stop)
+			delete(f.subscriptions, market)
+			delete(f.books, market)
+		}
+	}
+}
+
+// Stop closes all subscriptions and watchers
+func (f *marketFeed) Stop() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.stopped {
+		return
+	}
+	f.stopped = true
+
+	for _, s := range f.subscriptions {
+		close(s.stop)
+	}
+	for market, watchers := range f.watchers {
+		for ch := range watchers {
+			close(ch)
+		}
+		delete(f.watchers, market)
+	}
+}
+
+// Markets returns all markets with live rates of subscribed ones
+func (f *marketFeed) Markets() ([]market, error) {
+	err := f.refreshSummaries()
+	if err != nil {
+		return nil, err
+	}
+
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	markets := make([]market, 0, len(f.summaries))
+	for _, summary := range f.summaries {
+		markets = append(markets, f.liveMarket(summary))
+	}
+	sort.Slice(markets, func(i, j int) bool {
+		return markets[i].MarketName < markets[j].MarketName
+	})
+	return markets, nil
+}
+
+// MarketInfo returns the current state of the market
+func (f *marketFeed) MarketInfo(market string) (*domain.MarketInfo, error) {
+	err := f.refreshSummaries()
+	if err != nil {
+		return nil, err
+	}
+
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	info, ok := f.marketInfo(market)
+	if !ok {
+		return nil, errors.Errorf("market '%s' not found", market)
+	}
+	return info, nil
+}
+
+// Watch subscribes the market and returns the channel of its states sent after every order book update.
+// A slow reader gets only the latest state. The channel is closed by cancel, the market stays subscribed for subscriptionTTL after it
+func (f *marketFeed) Watch(market string) (updates <-chan domain.MarketInfo, cancel func()) {
+	ch := make(chan domain.MarketInfo, 1)
+
+	f.lock.Lock()
+	if f.stopped {
+		close(ch)
+	} else {
+		if f.watchers[market] == nil {
+			f.watchers[market] = make(map[chan domain.MarketInfo]bool)
+		}
+		f.watchers[market][ch] = true
+	}
+	f.lock.Unlock()
+
+	f.Subscribe(market)
+
+	cancel = func() {
+		f.lock.Lock()
+		defer f.lock.Unlock()
+
+		if f.watchers[market][ch] {
+			delete(f.watchers[market], ch)
+			close(ch)
+			if s := f.subscriptions[market]; s != nil {
+				s.requested = time.Now()
+			}
+		}
+	}
+	return ch, cancel
+}
+
+func (f *marketFeed) refreshSummaries() error {
+	f.refreshLock.Lock()
+	defer f.refreshLock.Unlock()
+
+	f.lock.RLock()
+	fresh := time.Since(f.summariesTime) < f.summariesTTL
+	f.lock.RUnlock()
+	if fresh {
+		return nil
+	}
+
+	summaries, err := f.client.GetMarketSummaries()
+	if err != nil {
+		return err
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.summaries = make(map[string]bittrex.MarketSummary, len(summaries))
+	for _, summary := range summaries {
+		f.summaries[summary.MarketName] = summary
+	}
+	f.summariesTime = time.Now()
+	return nil
+}
+
+// marketInfo merges the market summary with the live order book. It must be called under the lock
+func (f *marketFeed) marketInfo(name string) (*domain.MarketInfo, bool) {
+	summary, hasSummary := f.summaries[name]
+	book := f.books[name]
+	live := book != nil && book.synced
+	if !hasSummary && !live {
+		return nil, false
+	}
+
+	summary.MarketName = name
+	m := f.liveMarket(summary)
+	info := &domain.MarketInfo{
+		MarketName: name,
+		Last:       utils.DecimalToFloatQuiet(m.Last),
+		Bid:        utils.DecimalToFloatQuiet(m.Bid),
+		Ask:        utils.DecimalToFloatQuiet(m.Ask),
+		High:       utils.DecimalToFloatQuiet(summary.High),
+		Low:        utils.DecimalToFloatQuiet(summary.Low),
+	}
+	if live {
+		if info.Last > info.High {
+			info.High = info.Last
+		}
+		if info.Low == 0 || info.Last < info.Low {
+			info.Low = info.Last
+		}
+	}
+	return info, true
+}
+
+// liveMarket replaces summary rates of the subscribed market by rates of its order book. It must be called under the lock
+func (f *marketFeed) liveMarket(summary bittrex.MarketSummary) market {
+	m := market{
+		MarketName: summary.MarketName,
+		Last:       summary.Last,
+		Bid:        summary.Bid,
+		Ask:        summary.Ask,
+	}
+
+	book := f.books[summary.MarketName]
+	if book == nil || !book.synced {
+		return m
+	}
+	if !book.last.Equal(decimal.Zero) {
+		m.Last = book.last
+	}
+	if bid, ok := book.bestBid(); ok {
+		m.Bid = bid
+	}
+	if ask, ok := book.bestAsk(); ok {
+		m.Ask = ask
+	}
+	return m
+}
+
+// follow keeps the market subscribed until the subscription or the feed is stopped
+func (f *marketFeed) follow(market string, stop chan bool) {
+	delay := f.minReconnectDelay
+	for {
+		synced := f.stream(market, stop)
+
+		f.lock.Lock()
+		// the book of the closed subscription may belong to the next subscription of the market already
+		if f.subscribed(market, stop) {
+			delete(f.books, market)
+		}
+		f.lock.Unlock()
+
+		if synced {
+			delay = f.minReconnectDelay
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(delay):
+		}
+
+		if !synced {
+			delay *= 2
+			if delay > f.maxReconnectDelay {
+				delay = f.maxReconnectDelay
+			}
+		}
+	}
+}
+
+// stream applies market updates until the connection is lost, the order book misses a delta or the feed is stopped.
+// It reports whether the initial state has been received
+func (f *marketFeed) stream(market string, stop chan bool) (synced bool) {
+	log := f.log.WithField("method", "stream").WithField("market", market)
+
+	updates := make(chan bittrex.ExchangeState, updatesBuffer)
+	subscriptionStop := make(chan bool)
+	defer close(subscriptionStop)
+
+	// buffered, the subscription can finish after stream returns
+	done := make(chan error, 1)
+	go func() {
+		done <- f.client.SubscribeExchangeUpdate(market, updates, subscriptionStop)
+	}()
+
+	for {
+		select {
+		case state := <-updates:
+			if state.Initial {
+				synced = true
+			}
+			if !f.apply(market, stop, state) {
+				log.Warn("order book update is missed, resubscribing")
+				return
+			}
+		case err := <-done:
+			if err != nil {
+				log.WithError(err).Error("subscription error")
+			} else {
+				log.Warn("connection is closed, resubscribing")
+			}
+			return
+		case <-stop:
+			return
+		}
+	}
+}
+
+// subscribed reports whether the subscription of the market is still open. It must be called under the lock
+func (f *marketFeed) subscribed(market string, stop chan bool) bool {
+	s := f.subscriptions[market]
+	return s != nil && s.stop == stop
+}
+
+// apply updates the order book and notifies watchers. It returns false when the update can't be applied
+func (f *marketFeed) apply(market string, stop chan bool, state bittrex.ExchangeState) bool {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if !f.subscribed(market, stop) {
+		// the subscription is closed, stream returns by the stop channel
+		return true
+	}
+
+	book := f.books[market]
+	if book == nil {
+		book = &orderBook{}
+		f.books[market] = book
+	}
+	if !book.apply(state) {
+		return false
+	}
+	if !book.synced {
+		return true
+	}
+
+	info, _ := f.marketInfo(market)
+	for ch := range f.watchers[market] {
+		// replace the state a slow reader hasn't got yet
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- *info
+	}
+	return true
+}
+
+// orderBook is the market state restored from the initial state and deltas numbered by nounce
+type orderBook struct {
+	synced bool
+	nounce int
+	bids   map[string]bittrex.Orderb
+	asks   map[string]bittrex.Orderb
+	last   decimal.Decimal
+	// pending are deltas received before the initial state
+	pending []bittrex.ExchangeState
+}
+
+func (b *orderBook) apply(state bittrex.ExchangeState) bool {
+	if state.Initial {
+		b.synced = true
+		b.nounce = state.Nounce
+		b.bids = make(map[string]bittrex.Orderb)
+		b.asks = make(map[string]bittrex.Orderb)
+		b.update(state)
+
+		pending := b.pending
+		b.pending = nil
+		for _, delta := range pending {
+			if !b.apply(delta) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if !b.synced {
+		b.pending = append(b.pending, state)
+		return true
+	}
+
+	if state.Nounce <= b.nounce {
+		// already in the initial state
+		return true
+	}
+	if state.Nounce != b.nounce+1 {
+		return false
+	}
+	b.nounce = state.Nounce
+	b.update(state)
+	return true
+}
+
+func (b *orderBook) update(state bittrex.ExchangeState) {
+	updateOrders(b.bids, state.Buys)
+	updateOrders(b.asks, state.Sells)
+
+	var lastTime time.Time
+	for _, fill := range state.Fills {
+		if !fill.Timestamp.Time.Before(lastTime) {
+			lastTime = fill.Timestamp.Time
+			b.last = fill.Rate
+		}
+	}
+}
+
+func updateOrders(orders map[string]bittrex.Orderb, updates []bittrex.OrderUpdate) {
+	for _, u := range updates {
+		key := u.Rate.String()
+		if u.Type == orderUpdateRemove || !u.Quantity.GreaterThan(decimal.Zero) {
+			delete(orders, key)
+			continue
+		}
+		orders[key] = u.Orderb
+	}
+}
+
+func (b *orderBook) bestBid() (rate decimal.Decimal, ok bool) {
+	for _, o := range b.bids {
+		if !ok || o.Rate.GreaterThan(rate) {
+			rate, ok = o.Rate, true
+		}
+	}
+	return
+}
+
+func (b *orderBook) bestAsk() (rate decimal.Decimal, ok bool) {
+	for _, o := range b.asks {
+		if !ok || o.Rate.LessThan(rate) {
+			rate, ok = o.Rate, true
+		}
+	}
+	return
+}
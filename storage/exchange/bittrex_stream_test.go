@@ -0,0 +1,315 @@
+package exchange
+
+import (
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	assert "github.com/stretchr/testify/require"
+	"github.com/toorop/go-bittrex"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/utils"
+)
+
+const streamTestTimeout = time.Second * 5
+
+type fakeSubscription struct {
+	market  string
+	updates chan<- bittrex.ExchangeState
+	close   chan error
+}
+
+// fakeStreamClient gives every subscription to the test that sends updates and closes it
+type fakeStreamClient struct {
+	lock           sync.Mutex
+	summaries      []bittrex.MarketSummary
+	summariesErr   error
+	summariesCalls int
+	subscriptions  chan fakeSubscription
+}
+
+func newFakeStreamClient(summaries ...bittrex.MarketSummary) *fakeStreamClient {
+	return &fakeStreamClient{
+		summaries:     summaries,
+		subscriptions: make(chan fakeSubscription, 10),
+	}
+}
+
+func (c *fakeStreamClient) GetMarketSummaries() ([]bittrex.MarketSummary, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.summariesCalls++
+	return c.summaries, c.summariesErr
+}
+
+func (c *fakeStreamClient) SubscribeExchangeUpdate(market string, dataCh chan<- bittrex.ExchangeState, stop <-chan bool) error {
+	s := fakeSubscription{market: market, updates: dataCh, close: make(chan error, 1)}
+	c.subscriptions <- s
+	select {
+	case <-stop:
+		return nil
+	case err := <-s.close:
+		return err
+	}
+}
+
+func (c *fakeStreamClient) nextSubscription(t *testing.T) fakeSubscription {
+	select {
+	case s := <-c.subscriptions:
+		return s
+	case <-time.After(streamTestTimeout):
+		t.Fatal("no subscription")
+		return fakeSubscription{}
+	}
+}
+
+func newTestMarketFeed(client bittrexStreamClient) *marketFeed {
+	feed := newMarketFeed(client, utils.NewDevNullLog())
+	feed.minReconnectDelay = time.Millisecond
+	feed.maxReconnectDelay = time.Millisecond * 10
+	return feed
+}
+
+func nextMarketInfo(t *testing.T, updates <-chan domain.MarketInfo) domain.MarketInfo {
+	select {
+	case info := <-updates:
+		return info
+	case <-time.After(streamTestTimeout):
+		t.Fatal("no market update")
+		return domain.MarketInfo{}
+	}
+}
+
+func order(rate, quantity float64, updateType int) bittrex.OrderUpdate {
+	return bittrex.OrderUpdate{
+		Orderb: bittrex.Orderb{Rate: decimal.NewFromFloat(rate), Quantity: decimal.NewFromFloat(quantity)},
+		Type:   updateType,
+	}
+}
+
+func fill(rate float64, t time.Time) bittrex.Fill {
+	f := bittrex.Fill{Orderb: bittrex.Orderb{Rate: decimal.NewFromFloat(rate), Quantity: decimal.NewFromFloat(1)}}
+	f.Timestamp.Time = t
+	return f
+}
+
+func initialState(nounce int) bittrex.ExchangeState {
+	return bittrex.ExchangeState{
+		MarketName: "BTC-ETH",
+		Nounce:     nounce,
+		Buys:       []bittrex.OrderUpdate{order(0.09, 1, 0), order(0.095, 2, 0)},
+		Sells:      []bittrex.OrderUpdate{order(0.11, 1, 0), order(0.105, 2, 0)},
+		Fills:      []bittrex.Fill{fill(0.1, time.Unix(2, 0)), fill(0.08, time.Unix(1, 0))},
+		Initial:    true,
+	}
+}
+
+func TestMarketFeed_Watch(t *testing.T) {
+	client := newFakeStreamClient(bittrex.MarketSummary{
+		MarketName: "BTC-ETH",
+		Last:       decimal.NewFromFloat(0.2),
+		High:       decimal.NewFromFloat(0.3),
+		Low:        decimal.NewFromFloat(0.099),
+	})
+	feed := newTestMarketFeed(client)
+	defer feed.Stop()
+
+	_, err := feed.MarketInfo("BTC-ETH")
+	assert.NoError(t, err)
+
+	updates, cancel := feed.Watch("BTC-ETH")
+	s := client.nextSubscription(t)
+	assert.Equal(t, "BTC-ETH", s.market)
+
+	s.updates <- initialState(10)
+	info := nextMarketInfo(t, updates)
+	assert.Equal(t, domain.MarketInfo{MarketName: "BTC-ETH", Last: 0.1, Bid: 0.095, Ask: 0.105, High: 0.3, Low: 0.099}, info)
+
+	s.updates <- bittrex.ExchangeState{
+		MarketName: "BTC-ETH",
+		Nounce:     11,
+		Buys:       []bittrex.OrderUpdate{order(0.095, 0, orderUpdateRemove), order(0.098, 1, 0)},
+		Sells:      []bittrex.OrderUpdate{order(0.105, 0, orderUpdateRemove)},
+		Fills:      []bittrex.Fill{fill(0.098, time.Unix(3, 0))},
+	}
+	info = nextMarketInfo(t, updates)
+	assert.Equal(t, domain.MarketInfo{MarketName: "BTC-ETH", Last: 0.098, Bid: 0.098, Ask: 0.11, High: 0.3, Low: 0.098}, info)
+
+	marketInfo, err := feed.MarketInfo("BTC-ETH")
+	assert.NoError(t, err)
+	assert.Equal(t, info, *marketInfo)
+
+	cancel()
+	_, ok := <-updates
+	assert.False(t, ok)
+
+	// summaries are reused
+	assert.Equal(t, 1, client.summariesCalls)
+}
+
+func TestMarketFeed_PendingDeltas(t *testing.T) {
+	client := newFakeStreamClient()
+	feed := newTestMarketFeed(client)
+	defer feed.Stop()
+
+	updates, cancel := feed.Watch("BTC-ETH")
+	defer cancel()
+	s := client.nextSubscription(t)
+
+	// the first delta is in the initial state already, the second isn't
+	s.updates <- bittrex.ExchangeState{MarketName: "BTC-ETH", Nounce: 10, Fills: []bittrex.Fill{fill(0.5, time.Unix(2, 0))}}
+	s.updates <- bittrex.ExchangeState{MarketName: "BTC-ETH", Nounce: 11, Fills: []bittrex.Fill{fill(0.2, time.Unix(3, 0))}}
+	s.updates <- initialState(10)
+
+	info := nextMarketInfo(t, updates)
+	assert.Equal(t, 0.2, info.Last)
+}
+
+func TestMarketFeed_Resubscribe(t *testing.T) {
+	client := newFakeStreamClient()
+	feed := newTestMarketFeed(client)
+	defer feed.Stop()
+
+	updates, cancel := feed.Watch("BTC-ETH")
+	defer cancel()
+
+	// missed delta
+	s := client.nextSubscription(t)
+	s.updates <- initialState(10)
+	nextMarketInfo(t, updates)
+	s.updates <- bittrex.ExchangeState{MarketName: "BTC-ETH", Nounce: 12}
+
+	// subscription error
+	s = client.nextSubscription(t)
+	s.close <- errors.New("connection error")
+
+	// closed connection
+	s = client.nextSubscription(t)
+	s.updates <- initialState(20)
+	info := nextMarketInfo(t, updates)
+	assert.Equal(t, 0.1, info.Last)
+	s.close <- nil
+
+	s = client.nextSubscription(t)
+	assert.Equal(t, "BTC-ETH", s.market)
+}
+
+func subscribedMarkets(feed *marketFeed) []string {
+	feed.lock.RLock()
+	defer feed.lock.RUnlock()
+
+	markets := make([]string, 0, len(feed.subscriptions))
+	for market := range feed.subscriptions {
+		markets = append(markets, market)
+	}
+	sort.Strings(markets)
+	return markets
+}
+
+func TestMarketFeed_UnsubscribeIdle(t *testing.T) {
+	client := newFakeStreamClient()
+	feed := newTestMarketFeed(client)
+	feed.subscriptionTTL = time.Millisecond * 50
+	defer feed.Stop()
+
+	feed.Subscribe("BTC-ETH")
+	client.nextSubscription(t)
+	_, cancel := feed.Watch("BTC-LTC")
+	client.nextSubscription(t)
+
+	// the watched market stays subscribed
+	time.Sleep(feed.subscriptionTTL)
+	feed.Subscribe("BTC-XRP")
+	client.nextSubscription(t)
+	assert.Equal(t, []string{"BTC-LTC", "BTC-XRP"}, subscribedMarkets(feed))
+
+	// the requested market is prolonged, the market without watchers is closed
+	cancel()
+	time.Sleep(feed.subscriptionTTL)
+	feed.Subscribe("BTC-XRP")
+	assert.Equal(t, []string{"BTC-XRP"}, subscribedMarkets(feed))
+
+	feed.Subscribe("BTC-ETH")
+	assert.Equal(t, "BTC-ETH", client.nextSubscription(t).market)
+}
+
+func TestMarketFeed_Markets(t *testing.T) {
+	client := newFakeStreamClient(
+		bittrex.MarketSummary{MarketName: "USDT-BTC", Last: decimal.NewFromFloat(10000), Bid: decimal.NewFromFloat(9999), Ask: decimal.NewFromFloat(10001)},
+		bittrex.MarketSummary{MarketName: "BTC-ETH", Last: decimal.NewFromFloat(0.2), Bid: decimal.NewFromFloat(0.19), Ask: decimal.NewFromFloat(0.21)},
+	)
+	feed := newTestMarketFeed(client)
+	defer feed.Stop()
+
+	updates, cancel := feed.Watch("BTC-ETH")
+	defer cancel()
+	s := client.nextSubscription(t)
+	s.updates <- initialState(1)
+	nextMarketInfo(t, updates)
+
+	markets, err := feed.Markets()
+	assert.NoError(t, err)
+	assert.Len(t, markets, 2)
+	assert.Equal(t, "BTC-ETH", markets[0].MarketName)
+	assert.Equal(t, 0.1, utils.DecimalToFloatQuiet(markets[0].Last))
+	assert.Equal(t, 0.095, utils.DecimalToFloatQuiet(markets[0].Bid))
+	assert.Equal(t, 0.105, utils.DecimalToFloatQuiet(markets[0].Ask))
+	assert.Equal(t, "USDT-BTC", markets[1].MarketName)
+	assert.Equal(t, 10000.0, utils.DecimalToFloatQuiet(markets[1].Last))
+
+	client.summariesErr = errors.New("unexpected error")
+	feed.summariesTTL = 0
+	_, err = feed.Markets()
+	assert.Error(t, err)
+}
+
+func TestMarketFeed_MarketInfo_NotFound(t *testing.T) {
+	feed := newTestMarketFeed(newFakeStreamClient())
+	defer feed.Stop()
+
+	_, err := feed.MarketInfo("BTC-ETH")
+	assert.Error(t, err)
+}
+
+func TestMarketFeed_Stop(t *testing.T) {
+	client := newFakeStreamClient()
+	feed := newTestMarketFeed(client)
+
+	updates, cancel := feed.Watch("BTC-ETH")
+	client.nextSubscription(t)
+
+	feed.Stop()
+	_, ok := <-updates
+	assert.False(t, ok)
+	// no panic on the closed channel
+	cancel()
+
+	updates, _ = feed.Watch("BTC-LTC")
+	_, ok = <-updates
+	assert.False(t, ok)
+}
+
+func TestBittrexExchange_WatchMarket(t *testing.T) {
+	be := &bittrexExchange{
+		bittrex: bittrex.New(testAPIKey, testAPISecret),
+		log:     utils.NewDevNullLog(),
+	}
+	_, _, err := be.WatchMarket("BTC-ETH")
+	assert.Error(t, err)
+
+	client := newFakeStreamClient()
+	be.feed = newTestMarketFeed(client)
+	defer be.feed.Stop()
+
+	updates, cancel, err := be.WatchMarket("BTC-ETH")
+	assert.NoError(t, err)
+	defer cancel()
+
+	s := client.nextSubscription(t)
+	s.updates <- initialState(1)
+	assert.Equal(t, 0.1, nextMarketInfo(t, updates).Last)
+}
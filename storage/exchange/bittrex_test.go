@@ -19,12 +19,20 @@ const testAPIKey = "aaapppiiiKey"
 const testAPISecret = "aaapppiiiSecret"
 
 func TestNewBittrexExchange(t *testing.T) {
-	exchange := NewBittrexExchange(testAPIKey, testAPISecret)
+	exchange := NewBittrexExchange(testAPIKey, testAPISecret, ConversionLiquidity)
 	assert.IsType(t, &bittrexExchange{}, exchange)
 	assert.NotNil(t, exchange.(*bittrexExchange).bittrex)
 	assert.NotNil(t, exchange.(*bittrexExchange).log)
 }
 
+func TestNewBittrexStreamingExchange(t *testing.T) {
+	exchange, stop := NewBittrexStreamingExchange(testAPIKey, testAPISecret, ConversionLiquidity)
+	defer stop()
+	assert.IsType(t, &bittrexExchange{}, exchange)
+	assert.NotNil(t, exchange.(*bittrexExchange).bittrex)
+	assert.NotNil(t, exchange.(*bittrexExchange).feed)
+}
+
 func TestBittrexExchange_Ping(t *testing.T) {
 	defer gock.Off()
 
@@ -131,7 +139,7 @@ func TestBittrexExchange_GetBalance(t *testing.T) {
 			wantErr: true,
 		},
 		{
-			name: "BTC market is missing for currency",
+			name: "BTC market is missing for currency, the balance is kept without BTC and USDT amounts",
 			fieldsF: func() fields {
 				response := testdata.BittrexResponseSuccess([]bittrex.MarketSummary{})
 				gock.New("https://bittrex.com").
@@ -151,7 +159,8 @@ func TestBittrexExchange_GetBalance(t *testing.T) {
 					log:     utils.NewDevNullLog(),
 				}
 			},
-			wantErr: true,
+			want:    withoutConversion(testdata.ModelBalances()[1:2]),
+			wantErr: false,
 		},
 		{
 			name: "USDT market is missing for BTC somehow",
@@ -174,7 +183,8 @@ func TestBittrexExchange_GetBalance(t *testing.T) {
 					log:     utils.NewDevNullLog(),
 				}
 			},
-			wantErr: true,
+			want:    withoutConversion(testdata.ModelBalances()[0:1]),
+			wantErr: false,
 		},
 	}
 	for _, tt := range tests {
@@ -200,6 +210,16 @@ func TestBittrexExchange_GetBalance(t *testing.T) {
 	}
 }
 
+// withoutConversion returns copies of balances with zero BTC and USDT amounts
+func withoutConversion(balances []domain.Balance) []domain.Balance {
+	result := make([]domain.Balance, len(balances))
+	for i, b := range balances {
+		b.BTCAmount, b.USDTAmount = 0, 0
+		result[i] = b
+	}
+	return result
+}
+
 func modelBalancesEqual(got []domain.Balance, want []domain.Balance) bool {
 	var zeroTime time.Time
 	for i := range got {
@@ -402,3 +422,259 @@ func TestBittrexExchange_GetOrders(t *testing.T) {
 		})
 	}
 }
+
+func TestBittrexExchange_GetTrades(t *testing.T) {
+	type fields struct {
+		bittrex *bittrex.Bittrex
+		log     *logrus.Entry
+	}
+	tests := []struct {
+		name    string
+		fieldsF func() fields
+		want    []domain.Trade
+		wantErr bool
+	}{
+		{
+			name: "correct",
+			fieldsF: func() fields {
+				response := testdata.BittrexResponseSuccess(testdata.BittrexMarketSummaries())
+
+				gock.New("https://bittrex.com").
+					Get("api/v1.1/public/getmarketsummaries").
+					Reply(200).
+					JSON(response)
+
+				response = testdata.BittrexResponseSuccess(testdata.BittrexTradeOrders())
+
+				gock.New("https://bittrex.com").
+					Get("api/v1.1/account/getorderhistory").
+					Reply(200).
+					JSON(response)
+
+				return fields{
+					bittrex: bittrex.New(testAPIKey, testAPISecret),
+					log:     utils.NewDevNullLog(),
+				}
+			},
+			want:    testdata.ModelTrades(),
+			wantErr: false,
+		},
+		{
+			name: "error in bittrex 'account|getorderhistory'",
+			fieldsF: func() fields {
+				response := testdata.BittrexResponseSuccess(testdata.BittrexMarketSummaries())
+
+				gock.New("https://bittrex.com").
+					Get("api/v1.1/public/getmarketsummaries").
+					Reply(200).
+					JSON(response)
+
+				gock.New("https://bittrex.com").
+					Get("api/v1.1/account/getorderhistory").
+					Reply(200).
+					JSON(testdata.BittrexResponseFailure())
+
+				return fields{
+					bittrex: bittrex.New(testAPIKey, testAPISecret),
+					log:     utils.NewDevNullLog(),
+				}
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer gock.Off()
+
+			fields := tt.fieldsF()
+			be := &bittrexExchange{
+				bittrex: fields.bittrex,
+				log:     fields.log,
+			}
+			got, err := be.GetTrades()
+			if err != nil {
+				if !tt.wantErr {
+					t.Errorf("bittrexExchange.GetTrades() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Errorf("bittrexExchange.GetTrades() error is expected")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("bittrexExchange.GetTrades() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBittrexExchange_GetTransfers(t *testing.T) {
+	type fields struct {
+		bittrex *bittrex.Bittrex
+		log     *logrus.Entry
+	}
+	tests := []struct {
+		name    string
+		fieldsF func() fields
+		want    []domain.Transfer
+		wantErr bool
+	}{
+		{
+			name: "correct",
+			fieldsF: func() fields {
+				gock.New("https://bittrex.com").
+					Get("api/v1.1/public/getmarketsummaries").
+					Reply(200).
+					JSON(testdata.BittrexResponseSuccess(testdata.BittrexMarketSummaries()))
+
+				gock.New("https://bittrex.com").
+					Get("api/v1.1/account/getdeposithistory").
+					Reply(200).
+					JSON(testdata.BittrexResponseSuccess(testdata.BittrexDeposits()))
+
+				gock.New("https://bittrex.com").
+					Get("api/v1.1/account/getwithdrawalhistory").
+					Reply(200).
+					JSON(testdata.BittrexResponseSuccess(testdata.BittrexWithdrawals()))
+
+				return fields{
+					bittrex: bittrex.New(testAPIKey, testAPISecret),
+					log:     utils.NewDevNullLog(),
+				}
+			},
+			want:    testdata.ModelTransfers(),
+			wantErr: false,
+		},
+		{
+			name: "error in bittrex 'account|getwithdrawalhistory'",
+			fieldsF: func() fields {
+				gock.New("https://bittrex.com").
+					Get("api/v1.1/public/getmarketsummaries").
+					Reply(200).
+					JSON(testdata.BittrexResponseSuccess(testdata.BittrexMarketSummaries()))
+
+				gock.New("https://bittrex.com").
+					Get("api/v1.1/account/getdeposithistory").
+					Reply(200).
+					JSON(testdata.BittrexResponseSuccess(testdata.BittrexDeposits()))
+
+				gock.New("https://bittrex.com").
+					Get("api/v1.1/account/getwithdrawalhistory").
+					Reply(200).
+					JSON(testdata.BittrexResponseFailure())
+
+				return fields{
+					bittrex: bittrex.New(testAPIKey, testAPISecret),
+					log:     utils.NewDevNullLog(),
+				}
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer gock.Off()
+
+			fields := tt.fieldsF()
+			be := &bittrexExchange{
+				bittrex: fields.bittrex,
+				log:     fields.log,
+			}
+			got, err := be.GetTransfers()
+			if err != nil {
+				if !tt.wantErr {
+					t.Errorf("bittrexExchange.GetTransfers() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Errorf("bittrexExchange.GetTransfers() error is expected")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("bittrexExchange.GetTransfers() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBittrexExchange_MarketHistory(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("https://bittrex.com").
+		Get("api/v1.1/public/getmarketsummaries").
+		Reply(200).
+		JSON(testdata.BittrexResponseSuccess(testdata.BittrexMarketSummaries()))
+
+	gock.New("https://bittrex.com").
+		Get("api/v1.1/account/getbalances").
+		Reply(200).
+		JSON(testdata.BittrexResponseSuccess(testdata.BittrexBalances()))
+
+	be := &bittrexExchange{
+		bittrex: bittrex.New(testAPIKey, testAPISecret),
+		log:     utils.NewDevNullLog(),
+	}
+	assert.Empty(t, be.LastMarketPrices())
+
+	balances, err := be.GetBalance()
+	assert.NoError(t, err)
+
+	prices := be.LastMarketPrices()
+	assert.Len(t, prices, 4)
+	assert.Equal(t, domain.MarketCandle{
+		Exchange: domain.ExchangeTypeBittrex,
+		Market:   "BTC-CUR2",
+		Time:     balances[0].Time,
+		Price:    domain.OHLC{Open: 40, Close: 40, Min: 40, Max: 40},
+	}, prices[1])
+
+	gock.New("https://bittrex.com").
+		Get("Api/v2.0/pub/market/GetTicks").
+		MatchParam("tickInterval", "hour").
+		MatchParam("marketName", "BTC-CUR1").
+		Reply(200).
+		BodyString(`{"success":true,"message":"","result":[{"T":"2018-02-06T10:00:00","O":10,"C":12,"H":15,"L":5,"V":100,"BV":1200}]}`)
+
+	candles, err := be.GetMarketCandles("btc-cur1", time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.MarketCandle{{
+		Exchange: domain.ExchangeTypeBittrex,
+		Market:   "BTC-CUR1",
+		Time:     time.Date(2018, 2, 6, 10, 0, 0, 0, time.UTC),
+		Price:    domain.OHLC{Open: 10, Close: 12, Min: 5, Max: 15},
+	}}, candles)
+
+	_, err = be.GetMarketCandles("BTC-CUR1", 2*time.Hour)
+	assert.Error(t, err)
+}
+
+func TestBittrexExchange_GetRates(t *testing.T) {
+	defer gock.Off()
+
+	gock.New("https://bittrex.com").
+		Get("api/v1.1/public/getmarketsummaries").
+		Reply(200).
+		JSON(testdata.BittrexResponseSuccess(testdata.BittrexMarketSummaries()))
+
+	be := &bittrexExchange{
+		bittrex: bittrex.New(testAPIKey, testAPISecret),
+		log:     utils.NewDevNullLog(),
+	}
+	rates, err := be.GetRates([]string{"CUR2", "CUR3"}, "BTC")
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.Rate{{
+		Exchange: domain.ExchangeTypeBittrex,
+		Currency: "CUR2",
+		Quote:    "BTC",
+		Last:     40,
+		Bid:      50,
+		Ask:      60,
+		Trades:   1,
+	}}, rates)
+
+	gock.New("https://bittrex.com").
+		Get("api/v1.1/public/getmarketsummaries").
+		Reply(500)
+	_, err = be.GetRates([]string{"CUR2"}, "BTC")
+	assert.Error(t, err)
+}
@@ -0,0 +1,282 @@
+package exchange
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/shopspring/decimal"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/utils"
+)
+
+// market is the exchange independent representation of a market
+// named in Bittrex notation 'QUOTE-BASE', for example 'BTC-ETH'
+type market struct {
+	MarketName string
+	Last       decimal.Decimal
+	Bid        decimal.Decimal
+	Ask        decimal.Decimal
+}
+
+// ConversionPreference chooses the path of the currency conversion through markets
+// if the currencies have no common market
+type ConversionPreference string
+
+const (
+	// ConversionLiquidity prefers the path losing the least on bid-ask spreads of its markets
+	ConversionLiquidity = ConversionPreference("liquidity")
+	// ConversionShortest prefers the path through the fewest markets, paths of the same length are compared by spreads
+	ConversionShortest = ConversionPreference("shortest")
+)
+
+// conversion is the edge of the conversion graph: the currency is converted to another one by the market,
+// from its base currency to the quote one or backwards if it's inverse
+type conversion struct {
+	to      string
+	market  market
+	inverse bool
+	// spread is the relative bid-ask spread of the market, the part of the amount lost on the conversion
+	spread float64
+}
+
+// pathCost is the cost of conversion path, what is less depends on the preference
+type pathCost struct {
+	hops   int
+	spread float64
+}
+
+type currencyConverter struct {
+	markets    []market
+	syncTime   time.Time
+	preference ConversionPreference
+	// graph is conversions of every currency in upper case
+	graph map[string][]conversion
+
+	lock sync.Mutex
+	// paths are found paths by 'FROM-TO' keys, nil if there is no path
+	paths map[string][]conversion
+}
+
+// newCurrencyConverter builds the conversion graph of markets. Markets without the last price can't convert anything
+func newCurrencyConverter(markets []market, preference ConversionPreference) *currencyConverter {
+	graph := make(map[string][]conversion)
+	for _, m := range markets {
+		toFrom := strings.Split(strings.ToUpper(m.MarketName), "-")
+		if len(toFrom) != 2 || !m.Last.GreaterThan(decimal.Zero) {
+			continue
+		}
+
+		spread := 1.0
+		bid, ask := utils.DecimalToFloatQuiet(m.Bid), utils.DecimalToFloatQuiet(m.Ask)
+		if bid > 0 && ask >= bid {
+			spread = (ask - bid) / ask
+		}
+		graph[toFrom[1]] = append(graph[toFrom[1]], conversion{to: toFrom[0], market: m, spread: spread})
+		graph[toFrom[0]] = append(graph[toFrom[0]], conversion{to: toFrom[1], market: m, inverse: true, spread: spread})
+	}
+
+	return &currencyConverter{
+		markets:    markets,
+		syncTime:   time.Now().UTC(),
+		preference: preference,
+		graph:      graph,
+		paths:      make(map[string][]conversion),
+	}
+}
+
+func (c *currencyConverter) ConvertToBTC(currency string, amount decimal.Decimal) (decimal.Decimal, error) {
+	return c.ConvertCurrency(currency, "BTC", amount)
+}
+
+func (c *currencyConverter) ConvertToUSDT(currency string, amount decimal.Decimal) (decimal.Decimal, error) {
+	return c.ConvertCurrency(currency, "USDT", amount)
+}
+
+func (c *currencyConverter) ConvertCurrency(fromCurrency, toCurrency string, amount decimal.Decimal) (decimal.Decimal, error) {
+	last, _, _, err := c.MarketRate(fromCurrency, toCurrency)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	return amount.Mul(last), nil
+}
+
+// MarketRate returns rates of the conversion by the common market of currencies or, if there is no such market,
+// by the best path through other markets, for example XYZ→ETH→BTC→USDT. Rates of the path are products of rates of its markets
+func (c *currencyConverter) MarketRate(fromCurrency, toCurrency string) (last, bid, ask decimal.Decimal, err error) {
+	one := decimal.NewFromFloat(1)
+	if strings.ToUpper(fromCurrency) == strings.ToUpper(toCurrency) {
+		return one, one, one, nil
+	}
+
+	path := c.path(strings.ToUpper(fromCurrency), strings.ToUpper(toCurrency))
+	if path == nil {
+		return last, bid, ask, fmt.Errorf("no conversion from '%s' to '%s' found in markets", fromCurrency, toCurrency)
+	}
+
+	last, bid, ask = one, one, one
+	for _, step := range path {
+		m := step.market
+		if !step.inverse {
+			last, bid, ask = last.Mul(m.Last), bid.Mul(m.Bid), ask.Mul(m.Ask)
+			continue
+		}
+		last, bid, ask = last.Mul(one.Div(m.Last)), bid.Mul(inverseRate(m.Bid)), ask.Mul(inverseRate(m.Ask))
+	}
+	return last, bid, ask, nil
+}
+
+// inverseRate returns 1/rate or zero for the zero rate, the market has no orders on that side
+func inverseRate(rate decimal.Decimal) decimal.Decimal {
+	if !rate.GreaterThan(decimal.Zero) {
+		return decimal.Zero
+	}
+	return decimal.NewFromFloat(1).Div(rate)
+}
+
+// path returns the common market of currencies or the cheapest conversion path between them by Dijkstra's algorithm.
+// Paths are cached, the converter lives only during one request
+func (c *currencyConverter) path(from, to string) []conversion {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	key := from + "-" + to
+	if path, ok := c.paths[key]; ok {
+		return path
+	}
+
+	// the common market is the real rate, the preference is only for paths through other currencies
+	for _, step := range c.graph[from] {
+		if step.to == to {
+			c.paths[key] = []conversion{step}
+			return c.paths[key]
+		}
+	}
+
+	costs := map[string]pathCost{from: {}}
+	previous := make(map[string]conversion)
+	visited := make(map[string]bool)
+	for {
+		// the graph is small, hundreds of currencies, so the closest one is found by the scan
+		current, found := "", false
+		for currency, cost := range costs {
+			if !visited[currency] && (!found || c.less(cost, costs[current]) || (!c.less(costs[current], cost) && currency < current)) {
+				current, found = currency, true
+			}
+		}
+		if !found || current == to {
+			break
+		}
+		visited[current] = true
+
+		for _, step := range c.graph[current] {
+			if visited[step.to] {
+				continue
+			}
+			cost := pathCost{hops: costs[current].hops + 1, spread: costs[current].spread + step.spread}
+			if existing, ok := costs[step.to]; !ok || c.less(cost, existing) {
+				costs[step.to] = cost
+				previous[step.to] = step
+			}
+		}
+	}
+
+	var path []conversion
+	if _, ok := costs[to]; ok {
+		for currency := to; currency != from; {
+			step := previous[currency]
+			path = append([]conversion{step}, path...)
+			currency = c.from(step)
+		}
+	}
+	c.paths[key] = path
+	return path
+}
+
+// from returns the currency converted by the step
+func (c *currencyConverter) from(step conversion) string {
+	toFrom := strings.Split(strings.ToUpper(step.market.MarketName), "-")
+	if step.inverse {
+		return toFrom[0]
+	}
+	return toFrom[1]
+}
+
+func (c *currencyConverter) less(a, b pathCost) bool {
+	if c.preference == ConversionShortest && a.hops != b.hops {
+		return a.hops < b.hops
+	}
+	if a.spread != b.spread {
+		return a.spread < b.spread
+	}
+	return a.hops < b.hops
+}
+
+// rates returns rates of currencies to the quote currency on the exchange, currencies without conversion are skipped
+func (c *currencyConverter) rates(exchange domain.ExchangeType, currencies []string, quote string) []domain.Rate {
+	var result []domain.Rate
+	for _, currency := range currencies {
+		last, bid, ask, err := c.MarketRate(currency, quote)
+		if err != nil {
+			continue
+		}
+
+		var trades int
+		if strings.ToUpper(currency) != strings.ToUpper(quote) {
+			trades = len(c.path(strings.ToUpper(currency), strings.ToUpper(quote)))
+		}
+		result = append(result, domain.Rate{
+			Exchange: exchange,
+			Currency: currency,
+			Quote:    quote,
+			Last:     utils.DecimalToFloatQuiet(last),
+			Bid:      utils.DecimalToFloatQuiet(bid),
+			Ask:      utils.DecimalToFloatQuiet(ask),
+			Trades:   trades,
+		})
+	}
+	return result
+}
+
+// convertBalance converts the amount to BTC and USDT. The currency without conversion path gets zero amounts
+// not to fail the whole balance
+func convertBalance(converter *currencyConverter, currency string, amount decimal.Decimal, log *logrus.Entry) (btcAmount, usdtAmount decimal.Decimal) {
+	btcAmount, err := converter.ConvertToBTC(currency, amount)
+	if err == nil {
+		usdtAmount, err = converter.ConvertToUSDT("BTC", btcAmount)
+	}
+	if err != nil {
+		log.WithField("method", "convertBalance").WithError(err).Warnf("balance of %s can't be converted", currency)
+		return decimal.Zero, decimal.Zero
+	}
+	return btcAmount, usdtAmount
+}
+
+// setTransferRates sets the current BTC and USDT rates of transfer currencies and sorts transfers by time, the latest first.
+// Transfers of currencies without a market keep zero rates. Current rates suit only recent transfers,
+// usecases drop them for older ones
+func setTransferRates(transfers []domain.Transfer, converter *currencyConverter, log *logrus.Entry) []domain.Transfer {
+	for i, t := range transfers {
+		btcRate, err := converter.ConvertToBTC(t.Currency, decimal.NewFromFloat(1))
+		if err != nil {
+			log.WithField("method", "setTransferRates").Warnf("market convert to BTC - %s", t.Currency)
+			continue
+		}
+		usdtRate, err := converter.ConvertToUSDT("BTC", btcRate)
+		if err != nil {
+			log.WithField("method", "setTransferRates").Warnf("market convert to USDT - %s", t.Currency)
+			continue
+		}
+		transfers[i].BTCRate = utils.DecimalToFloatQuiet(btcRate)
+		transfers[i].USDTRate = utils.DecimalToFloatQuiet(usdtRate)
+	}
+
+	sort.SliceStable(transfers, func(i, j int) bool {
+		return transfers[i].Time.After(transfers[j].Time)
+	})
+	return transfers
+}
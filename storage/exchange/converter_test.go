@@ -0,0 +1,108 @@
+package exchange
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	assert "github.com/stretchr/testify/require"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/utils"
+)
+
+func testMarket(name string, last, bid, ask float64) market {
+	return market{
+		MarketName: name,
+		Last:       decimal.NewFromFloat(last),
+		Bid:        decimal.NewFromFloat(bid),
+		Ask:        decimal.NewFromFloat(ask),
+	}
+}
+
+// testMarkets have two paths from XYZ to BTC: the short one through the market with the wide spread
+// and the long one through ETH with narrow spreads
+func testMarkets() []market {
+	return []market{
+		testMarket("USDT-BTC", 10000, 9990, 10000),
+		testMarket("BTC-ETH", 0.05, 0.0499, 0.05),
+		testMarket("ETH-XYZ", 0.2, 0.199, 0.2),
+		testMarket("BTC-XYZ", 0.02, 0.01, 0.02),
+		testMarket("BTC-DEAD", 0, 0, 0),
+	}
+}
+
+func TestCurrencyConverter_MarketRate(t *testing.T) {
+	converter := newCurrencyConverter(testMarkets(), ConversionLiquidity)
+
+	// the common market
+	last, bid, ask, err := converter.MarketRate("ETH", "BTC")
+	assert.NoError(t, err)
+	assert.Equal(t, 0.05, utils.DecimalToFloatQuiet(last))
+	assert.Equal(t, 0.0499, utils.DecimalToFloatQuiet(bid))
+	assert.Equal(t, 0.05, utils.DecimalToFloatQuiet(ask))
+
+	last, _, _, err = converter.MarketRate("BTC", "USDT")
+	assert.NoError(t, err)
+	assert.Equal(t, 10000.0, utils.DecimalToFloatQuiet(last))
+
+	// the common market is used even if the path through other markets has narrower spreads
+	last, _, _, err = converter.MarketRate("xyz", "btc")
+	assert.NoError(t, err)
+	assert.Equal(t, 0.02, utils.DecimalToFloatQuiet(last))
+
+	last, bid, ask, err = converter.MarketRate("USDT", "USDT")
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, utils.DecimalToFloatQuiet(last))
+	assert.Equal(t, 1.0, utils.DecimalToFloatQuiet(bid))
+	assert.Equal(t, 1.0, utils.DecimalToFloatQuiet(ask))
+
+	// markets without the last price can't convert
+	_, _, _, err = converter.MarketRate("DEAD", "BTC")
+	assert.Error(t, err)
+	_, _, _, err = converter.MarketRate("BTC", "ABC")
+	assert.Error(t, err)
+}
+
+func TestCurrencyConverter_MarketRate_Path(t *testing.T) {
+	// XYZ→ETH→BTC→USDT loses less on spreads than XYZ→BTC→USDT
+	converter := newCurrencyConverter(testMarkets(), ConversionLiquidity)
+	last, bid, ask, err := converter.MarketRate("XYZ", "USDT")
+	assert.NoError(t, err)
+	assert.InDelta(t, 100, utils.DecimalToFloatQuiet(last), 1e-9)
+	assert.InDelta(t, 0.199*0.0499*9990, utils.DecimalToFloatQuiet(bid), 1e-9)
+	assert.InDelta(t, 100, utils.DecimalToFloatQuiet(ask), 1e-9)
+	assert.Len(t, converter.paths["XYZ-USDT"], 3)
+
+	// backwards
+	last, _, _, err = converter.MarketRate("USDT", "XYZ")
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.01, utils.DecimalToFloatQuiet(last), 1e-9)
+
+	converter = newCurrencyConverter(testMarkets(), ConversionShortest)
+	last, _, _, err = converter.MarketRate("XYZ", "USDT")
+	assert.NoError(t, err)
+	assert.InDelta(t, 200, utils.DecimalToFloatQuiet(last), 1e-9)
+	assert.Len(t, converter.paths["XYZ-USDT"], 2)
+
+	// the converted amount
+	amount, err := converter.ConvertToUSDT("XYZ", decimal.NewFromFloat(3))
+	assert.NoError(t, err)
+	assert.InDelta(t, 600, utils.DecimalToFloatQuiet(amount), 1e-9)
+}
+
+func TestCurrencyConverter_Rates(t *testing.T) {
+	converter := newCurrencyConverter(testMarkets(), ConversionLiquidity)
+
+	rates := converter.rates(domain.ExchangeTypeBittrex, []string{"ETH", "BTC", "XYZ", "ABC"}, "BTC")
+	assert.Equal(t, []domain.Rate{
+		{Exchange: domain.ExchangeTypeBittrex, Currency: "ETH", Quote: "BTC", Last: 0.05, Bid: 0.0499, Ask: 0.05, Trades: 1},
+		{Exchange: domain.ExchangeTypeBittrex, Currency: "BTC", Quote: "BTC", Last: 1, Bid: 1, Ask: 1},
+		{Exchange: domain.ExchangeTypeBittrex, Currency: "XYZ", Quote: "BTC", Last: 0.02, Bid: 0.01, Ask: 0.02, Trades: 1},
+	}, rates)
+
+	// through ETH and BTC
+	rates = converter.rates(domain.ExchangeTypeBittrex, []string{"XYZ"}, "USDT")
+	assert.Len(t, rates, 1)
+	assert.Equal(t, 3, rates[0].Trades)
+	assert.InDelta(t, 100, rates[0].Last, 1e-9)
+}
@@ -0,0 +1,117 @@
+package exchange
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/pkg/errors"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage"
+)
+
+type fiatExchange struct {
+	storage.Exchange
+	source     storage.PriceSource
+	currencies []string
+	log        *logrus.Entry
+}
+
+// NewFiatExchange creates the exchange valuing balances in fiat currencies, e.g. EUR, by rates of the source.
+// The USDT value is taken as USD
+func NewFiatExchange(exchange storage.Exchange, source storage.PriceSource, currencies []string) storage.Exchange {
+	upper := make([]string, len(currencies))
+	for i, currency := range currencies {
+		upper[i] = strings.ToUpper(currency)
+	}
+	return &fiatExchange{
+		Exchange:   exchange,
+		source:     source,
+		currencies: upper,
+		log:        logrus.WithField("component", "FiatExchange"),
+	}
+}
+
+// GetBalance returns balances with fiat values. Balances are returned without them if rates aren't available,
+// so the source being down doesn't stop syncing
+func (fe *fiatExchange) GetBalance() ([]domain.Balance, error) {
+	balances, err := fe.Exchange.GetBalance()
+	if err != nil || len(balances) == 0 {
+		return balances, err
+	}
+
+	rates, err := fe.source.GetUSDRates(fe.currencies)
+	if err != nil {
+		fe.log.WithField("method", "GetBalance").WithError(err).Warn("balances aren't valued in fiat currencies")
+		return balances, nil
+	}
+
+	for i := range balances {
+		balances[i].Fiat = make(map[string]float64, len(rates))
+		for currency, rate := range rates {
+			balances[i].Fiat[currency] = roundFiat(balances[i].USDTAmount * rate)
+		}
+	}
+	return balances, nil
+}
+
+// WatchMarket streams the market if the wrapped exchange can do it
+func (fe *fiatExchange) WatchMarket(market string) (<-chan domain.MarketInfo, func(), error) {
+	watcher, ok := fe.Exchange.(storage.MarketWatcher)
+	if !ok {
+		return nil, nil, errors.New("exchange doesn't stream market updates")
+	}
+	return watcher.WatchMarket(market)
+}
+
+// LastMarketPrices returns prices of the wrapped exchange if it gives them
+func (fe *fiatExchange) LastMarketPrices() []domain.MarketCandle {
+	history, ok := fe.Exchange.(storage.MarketHistory)
+	if !ok {
+		return nil
+	}
+	return history.LastMarketPrices()
+}
+
+// GetMarketCandles returns candles of the wrapped exchange if it gives them
+func (fe *fiatExchange) GetMarketCandles(market string, interval time.Duration) ([]domain.MarketCandle, error) {
+	history, ok := fe.Exchange.(storage.MarketHistory)
+	if !ok {
+		return nil, errors.New("exchange doesn't give market candles")
+	}
+	return history.GetMarketCandles(market, interval)
+}
+
+// ExchangeTypes returns exchanges of accounts of the wrapped exchange if it has several ones
+func (fe *fiatExchange) ExchangeTypes() []domain.ExchangeType {
+	accounts, ok := fe.Exchange.(storage.MultiAccount)
+	if !ok {
+		return nil
+	}
+	return accounts.ExchangeTypes()
+}
+
+// ScanTrades scans trades of all markets if the wrapped exchange can do it, otherwise it returns its trades
+func (fe *fiatExchange) ScanTrades() ([]domain.Trade, error) {
+	scanner, ok := fe.Exchange.(storage.TradeScanner)
+	if !ok {
+		return fe.Exchange.GetTrades()
+	}
+	return scanner.ScanTrades()
+}
+
+// GetRates returns rates of the wrapped exchange if it gives them
+func (fe *fiatExchange) GetRates(currencies []string, quote string) ([]domain.Rate, error) {
+	converter, ok := fe.Exchange.(storage.CurrencyRates)
+	if !ok {
+		return nil, errors.New("exchange doesn't give rates")
+	}
+	return converter.GetRates(currencies, quote)
+}
+
+// roundFiat rounds to hundredths of a cent
+func roundFiat(amount float64) float64 {
+	return math.Round(amount*10000) / 10000
+}
@@ -0,0 +1,102 @@
+package exchange
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	assert "github.com/stretchr/testify/require"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage"
+	"github.com/nawa/cryptoexchange-dashboard/storage/fx"
+	"github.com/nawa/cryptoexchange-dashboard/storage/mocks"
+)
+
+func TestFiatExchange_GetBalance(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ex := mocks.NewMockExchange(ctrl)
+	fe := NewFiatExchange(ex, fx.NewFilePriceSource("testdata/rates.yml"), []string{"eur", "GBP"})
+
+	ex.EXPECT().GetBalance().Return([]domain.Balance{
+		{Currency: "BTC", Amount: 0.5, BTCAmount: 0.5, USDTAmount: 5000.1},
+		{Currency: "ETH", Amount: 0, BTCAmount: 0, USDTAmount: 0},
+	}, nil)
+
+	balances, err := fe.GetBalance()
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.Balance{
+		{Currency: "BTC", Amount: 0.5, BTCAmount: 0.5, USDTAmount: 5000.1, Fiat: map[string]float64{"EUR": 4000.08, "GBP": 3750.075}},
+		{Currency: "ETH", Amount: 0, BTCAmount: 0, USDTAmount: 0, Fiat: map[string]float64{"EUR": 0, "GBP": 0}},
+	}, balances)
+
+	ex.EXPECT().GetBalance().Return(nil, errors.New("exchange error"))
+	_, err = fe.GetBalance()
+	assert.Error(t, err)
+
+	// balances are returned without fiat values if rates are unknown
+	ex.EXPECT().GetBalance().Return([]domain.Balance{{Currency: "BTC", Amount: 0.5, BTCAmount: 0.5, USDTAmount: 5000}}, nil)
+	balances, err = NewFiatExchange(ex, fx.NewFilePriceSource("testdata/rates.yml"), []string{"JPY"}).GetBalance()
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.Balance{{Currency: "BTC", Amount: 0.5, BTCAmount: 0.5, USDTAmount: 5000}}, balances)
+}
+
+func TestFiatExchange_WatchMarket(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	watcher := mocks.NewMockMarketWatcher(ctrl)
+	updates := make(<-chan domain.MarketInfo)
+	watcher.EXPECT().WatchMarket("BTC-ETH").Return(updates, func() {}, nil)
+
+	fe := NewFiatExchange(watchingExchange{mocks.NewMockExchange(ctrl), watcher}, fx.NewFilePriceSource("testdata/rates.yml"), []string{"EUR"})
+	result, _, err := fe.(storage.MarketWatcher).WatchMarket("BTC-ETH")
+	assert.NoError(t, err)
+	assert.Equal(t, updates, result)
+
+	fe = NewFiatExchange(mocks.NewMockExchange(ctrl), fx.NewFilePriceSource("testdata/rates.yml"), []string{"EUR"})
+	_, _, err = fe.(storage.MarketWatcher).WatchMarket("BTC-ETH")
+	assert.Error(t, err)
+}
+
+func TestFiatExchange_MarketHistory(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	history := mocks.NewMockMarketHistory(ctrl)
+	candles := []domain.MarketCandle{{Exchange: domain.ExchangeTypeBittrex, Market: "BTC-ETH"}}
+	history.EXPECT().LastMarketPrices().Return(candles)
+	history.EXPECT().GetMarketCandles("BTC-ETH", time.Hour).Return(candles, nil)
+
+	fe := NewFiatExchange(historyExchange{mocks.NewMockExchange(ctrl), history}, fx.NewFilePriceSource("testdata/rates.yml"), []string{"EUR"})
+	assert.Equal(t, candles, fe.(storage.MarketHistory).LastMarketPrices())
+	result, err := fe.(storage.MarketHistory).GetMarketCandles("BTC-ETH", time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, candles, result)
+
+	fe = NewFiatExchange(mocks.NewMockExchange(ctrl), fx.NewFilePriceSource("testdata/rates.yml"), []string{"EUR"})
+	assert.Empty(t, fe.(storage.MarketHistory).LastMarketPrices())
+	_, err = fe.(storage.MarketHistory).GetMarketCandles("BTC-ETH", time.Hour)
+	assert.Error(t, err)
+}
+
+func TestFiatExchange_GetRates(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	converter := mocks.NewMockCurrencyRates(ctrl)
+	rates := []domain.Rate{{Exchange: domain.ExchangeTypeBittrex, Currency: "ETH", Quote: "BTC", Last: 0.05}}
+	converter.EXPECT().GetRates([]string{"ETH"}, "BTC").Return(rates, nil)
+
+	fe := NewFiatExchange(ratesExchange{mocks.NewMockExchange(ctrl), converter}, fx.NewFilePriceSource("testdata/rates.yml"), []string{"EUR"})
+	result, err := fe.(storage.CurrencyRates).GetRates([]string{"ETH"}, "BTC")
+	assert.NoError(t, err)
+	assert.Equal(t, rates, result)
+
+	fe = NewFiatExchange(mocks.NewMockExchange(ctrl), fx.NewFilePriceSource("testdata/rates.yml"), []string{"EUR"})
+	_, err = fe.(storage.CurrencyRates).GetRates([]string{"ETH"}, "BTC")
+	assert.Error(t, err)
+}
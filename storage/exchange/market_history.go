@@ -0,0 +1,45 @@
+package exchange
+
+import (
+	"sync"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/utils"
+)
+
+// lastMarketPrices keeps prices of markets downloaded for conversion by the last balance request
+type lastMarketPrices struct {
+	lock    sync.Mutex
+	candles []domain.MarketCandle
+}
+
+// set keeps last prices of converter markets at its sync time, markets without the last price are skipped
+func (l *lastMarketPrices) set(exchange domain.ExchangeType, converter *currencyConverter) {
+	candles := make([]domain.MarketCandle, 0, len(converter.markets))
+	for _, m := range converter.markets {
+		if !m.Last.GreaterThan(decimal.Zero) {
+			continue
+		}
+		last := utils.DecimalToFloatQuiet(m.Last)
+		candles = append(candles, domain.MarketCandle{
+			Exchange: exchange,
+			Market:   m.MarketName,
+			Time:     converter.syncTime,
+			Price:    domain.OHLC{Open: last, Close: last, Min: last, Max: last},
+		})
+	}
+
+	l.lock.Lock()
+	l.candles = candles
+	l.lock.Unlock()
+}
+
+func (l *lastMarketPrices) get() []domain.MarketCandle {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	result := make([]domain.MarketCandle, len(l.candles))
+	copy(result, l.candles)
+	return result
+}
@@ -0,0 +1,343 @@
+package exchange
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage"
+	"github.com/nawa/cryptoexchange-dashboard/utils"
+)
+
+// Account is the exchange of one configured key pair labeled by the name, Type is the type of the exchange
+type Account struct {
+	Name     string
+	Type     domain.ExchangeType
+	Exchange storage.Exchange
+}
+
+type multiExchange struct {
+	accounts []Account
+	log      *logrus.Entry
+}
+
+// NewMultiExchange creates the exchange that fans out requests to all accounts concurrently
+// and merges their results tagged by account names
+func NewMultiExchange(accounts ...Account) storage.Exchange {
+	log := logrus.WithField("component", "MultiExchange")
+	return &multiExchange{
+		accounts: accounts,
+		log:      log,
+	}
+}
+
+// GetBalance returns balances of all accounts. It fails if any account fails,
+// because partial balance would look like the loss of funds.
+// All balances get the same time to be stored as one snapshot
+func (me *multiExchange) GetBalance() ([]domain.Balance, error) {
+	syncTime := time.Now().UTC()
+
+	var (
+		lock   sync.Mutex
+		result []domain.Balance
+	)
+	err := me.forEachAccount(func(account Account) error {
+		balances, err := account.Exchange.GetBalance()
+		if err != nil {
+			return err
+		}
+
+		for i := range balances {
+			balances[i].Account = account.Name
+			balances[i].Time = syncTime
+		}
+
+		lock.Lock()
+		result = append(result, balances...)
+		lock.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// restore the configured order of accounts broken by concurrent execution
+	positions := make(map[string]int, len(me.accounts))
+	for i, account := range me.accounts {
+		positions[account.Name] = i
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return positions[result[i].Account] < positions[result[j].Account]
+	})
+	return result, nil
+}
+
+func (me *multiExchange) ExchangeTypes() []domain.ExchangeType {
+	var (
+		seen   = make(map[domain.ExchangeType]bool)
+		result []domain.ExchangeType
+	)
+	for _, account := range me.accounts {
+		if !seen[account.Type] {
+			seen[account.Type] = true
+			result = append(result, account.Type)
+		}
+	}
+	return result
+}
+
+// GetMarketInfo returns market info from the first account that has the market
+func (me *multiExchange) GetMarketInfo(market string) (*domain.MarketInfo, error) {
+	var err error
+	for _, account := range me.accounts {
+		marketInfo, e := account.Exchange.GetMarketInfo(market)
+		if e == nil {
+			return marketInfo, nil
+		}
+		err = multierror.Append(err, errors.Wrapf(e, "account '%s'", account.Name))
+	}
+	if err == nil {
+		err = errors.New("no accounts configured")
+	}
+	return nil, err
+}
+
+// WatchMarket streams the market from the first account that can do it
+func (me *multiExchange) WatchMarket(market string) (<-chan domain.MarketInfo, func(), error) {
+	var err error
+	for _, account := range me.accounts {
+		watcher, ok := account.Exchange.(storage.MarketWatcher)
+		if !ok {
+			continue
+		}
+		updates, cancel, e := watcher.WatchMarket(market)
+		if e == nil {
+			return updates, cancel, nil
+		}
+		err = multierror.Append(err, errors.Wrapf(e, "account '%s'", account.Name))
+	}
+	if err == nil {
+		err = errors.New("no accounts streaming market updates")
+	}
+	return nil, nil, err
+}
+
+// LastMarketPrices returns prices of all accounts, markets of the same exchange are taken from the first account
+func (me *multiExchange) LastMarketPrices() []domain.MarketCandle {
+	var (
+		seen   = make(map[string]bool)
+		result []domain.MarketCandle
+	)
+	for _, account := range me.accounts {
+		history, ok := account.Exchange.(storage.MarketHistory)
+		if !ok {
+			continue
+		}
+		for _, candle := range history.LastMarketPrices() {
+			key := string(candle.Exchange) + "|" + candle.Market
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			result = append(result, candle)
+		}
+	}
+	return result
+}
+
+// GetMarketCandles returns candles from the first account that has the market
+func (me *multiExchange) GetMarketCandles(market string, interval time.Duration) ([]domain.MarketCandle, error) {
+	var err error
+	for _, account := range me.accounts {
+		history, ok := account.Exchange.(storage.MarketHistory)
+		if !ok {
+			continue
+		}
+		candles, e := history.GetMarketCandles(market, interval)
+		if e == nil {
+			return candles, nil
+		}
+		err = multierror.Append(err, errors.Wrapf(e, "account '%s'", account.Name))
+	}
+	if err == nil {
+		err = errors.New("no accounts giving market candles")
+	}
+	return nil, err
+}
+
+// GetRates returns rates of every currency from the first account converting it
+func (me *multiExchange) GetRates(currencies []string, quote string) ([]domain.Rate, error) {
+	var (
+		err   error
+		given bool
+		rates = make(map[string]domain.Rate)
+	)
+	for _, account := range me.accounts {
+		converter, ok := account.Exchange.(storage.CurrencyRates)
+		if !ok {
+			continue
+		}
+		accountRates, e := converter.GetRates(currencies, quote)
+		if e != nil {
+			err = multierror.Append(err, errors.Wrapf(e, "account '%s'", account.Name))
+			continue
+		}
+		given = true
+		for _, rate := range accountRates {
+			if _, ok := rates[rate.Currency]; !ok {
+				rates[rate.Currency] = rate
+			}
+		}
+	}
+	if !given {
+		if err == nil {
+			err = errors.New("no accounts giving rates")
+		}
+		return nil, err
+	}
+
+	var result []domain.Rate
+	for _, currency := range currencies {
+		if rate, ok := rates[currency]; ok {
+			result = append(result, rate)
+		}
+	}
+	return result, nil
+}
+
+func (me *multiExchange) GetOrders() ([]domain.Order, error) {
+	var (
+		lock   sync.Mutex
+		result = []domain.Order{}
+	)
+	err := me.forEachAccount(func(account Account) error {
+		orders, err := account.Exchange.GetOrders()
+		if err != nil {
+			return err
+		}
+
+		for i := range orders {
+			orders[i].Account = account.Name
+		}
+
+		lock.Lock()
+		result = append(result, orders...)
+		lock.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Time.After(result[j].Time)
+	})
+	return result, nil
+}
+
+func (me *multiExchange) GetTrades() ([]domain.Trade, error) {
+	return me.getTrades(func(account Account) ([]domain.Trade, error) {
+		return account.Exchange.GetTrades()
+	})
+}
+
+// ScanTrades returns trades of all markets of accounts scanning them, trades of other accounts are got by GetTrades
+func (me *multiExchange) ScanTrades() ([]domain.Trade, error) {
+	return me.getTrades(func(account Account) ([]domain.Trade, error) {
+		if scanner, ok := account.Exchange.(storage.TradeScanner); ok {
+			return scanner.ScanTrades()
+		}
+		return account.Exchange.GetTrades()
+	})
+}
+
+func (me *multiExchange) getTrades(tradesOf func(account Account) ([]domain.Trade, error)) ([]domain.Trade, error) {
+	var (
+		lock   sync.Mutex
+		result = []domain.Trade{}
+	)
+	err := me.forEachAccount(func(account Account) error {
+		trades, err := tradesOf(account)
+		if err != nil {
+			return err
+		}
+
+		for i := range trades {
+			trades[i].Account = account.Name
+		}
+
+		lock.Lock()
+		result = append(result, trades...)
+		lock.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Time.After(result[j].Time)
+	})
+	return result, nil
+}
+
+func (me *multiExchange) Ping() error {
+	return me.forEachAccount(func(account Account) error {
+		return account.Exchange.Ping()
+	})
+}
+
+func (me *multiExchange) forEachAccount(f func(account Account) error) error {
+	tasks := make([]func() error, len(me.accounts))
+	for i, account := range me.accounts {
+		account := account
+		tasks[i] = func() error {
+			err := f(account)
+			if err != nil {
+				return errors.Wrapf(err, "account '%s'", account.Name)
+			}
+			return nil
+		}
+	}
+
+	var err error
+	for _, e := range utils.ExecuteConcurrently(tasks) {
+		err = multierror.Append(err, e)
+	}
+	return err
+}
+
+func (me *multiExchange) GetTransfers() ([]domain.Transfer, error) {
+	var (
+		lock   sync.Mutex
+		result = []domain.Transfer{}
+	)
+	err := me.forEachAccount(func(account Account) error {
+		transfers, err := account.Exchange.GetTransfers()
+		if err != nil {
+			return err
+		}
+
+		for i := range transfers {
+			transfers[i].Account = account.Name
+		}
+
+		lock.Lock()
+		result = append(result, transfers...)
+		lock.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Time.After(result[j].Time)
+	})
+	return result, nil
+}
@@ -0,0 +1,382 @@
+package exchange
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	assert "github.com/stretchr/testify/require"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage"
+	"github.com/nawa/cryptoexchange-dashboard/storage/mocks"
+)
+
+func TestMultiExchange_GetBalance(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	bittrex := mocks.NewMockExchange(ctrl)
+	binance := mocks.NewMockExchange(ctrl)
+
+	bittrex.EXPECT().GetBalance().Return([]domain.Balance{
+		{Exchange: domain.ExchangeTypeBittrex, Currency: "BTC", Amount: 1, Time: time.Unix(1, 0)},
+		{Exchange: domain.ExchangeTypeBittrex, Currency: "CUR1", Amount: 2, Time: time.Unix(1, 0)},
+	}, nil)
+	binance.EXPECT().GetBalance().Return([]domain.Balance{
+		{Exchange: domain.ExchangeTypeBinance, Currency: "BTC", Amount: 3, Time: time.Unix(2, 0)},
+	}, nil)
+
+	me := NewMultiExchange(Account{Name: "main", Exchange: bittrex}, Account{Name: "trading", Exchange: binance})
+	balances, err := me.GetBalance()
+	assert.NoError(t, err)
+	assert.Len(t, balances, 3)
+
+	assert.Equal(t, "main", balances[0].Account)
+	assert.Equal(t, "BTC", balances[0].Currency)
+	assert.Equal(t, "main", balances[1].Account)
+	assert.Equal(t, "CUR1", balances[1].Currency)
+	assert.Equal(t, "trading", balances[2].Account)
+	assert.Equal(t, domain.ExchangeTypeBinance, balances[2].Exchange)
+
+	// one snapshot has one time
+	assert.Equal(t, balances[0].Time, balances[1].Time)
+	assert.Equal(t, balances[0].Time, balances[2].Time)
+}
+
+func TestMultiExchange_ExchangeTypes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	me := NewMultiExchange(
+		Account{Name: "main", Type: domain.ExchangeTypeBinance, Exchange: mocks.NewMockExchange(ctrl)},
+		Account{Name: "savings", Type: domain.ExchangeTypeBittrex, Exchange: mocks.NewMockExchange(ctrl)},
+		Account{Name: "trading", Type: domain.ExchangeTypeBinance, Exchange: mocks.NewMockExchange(ctrl)},
+	)
+	assert.Equal(t, []domain.ExchangeType{domain.ExchangeTypeBinance, domain.ExchangeTypeBittrex},
+		me.(storage.MultiAccount).ExchangeTypes())
+}
+
+func TestMultiExchange_GetBalance_Error(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	bittrex := mocks.NewMockExchange(ctrl)
+	binance := mocks.NewMockExchange(ctrl)
+
+	bittrex.EXPECT().GetBalance().Return([]domain.Balance{{Currency: "BTC"}}, nil)
+	binance.EXPECT().GetBalance().Return(nil, errors.New("some error"))
+
+	me := NewMultiExchange(Account{Name: "main", Exchange: bittrex}, Account{Name: "trading", Exchange: binance})
+	_, err := me.GetBalance()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "account 'trading': some error")
+}
+
+func TestMultiExchange_GetOrders(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	bittrex := mocks.NewMockExchange(ctrl)
+	binance := mocks.NewMockExchange(ctrl)
+
+	bittrex.EXPECT().GetOrders().Return([]domain.Order{
+		{Market: "BTC-CUR1", Time: time.Unix(3, 0)},
+		{Market: "BTC-CUR2", Time: time.Unix(1, 0)},
+	}, nil)
+	binance.EXPECT().GetOrders().Return([]domain.Order{
+		{Market: "BTC-CUR3", Time: time.Unix(2, 0)},
+	}, nil)
+
+	me := NewMultiExchange(Account{Name: "main", Exchange: bittrex}, Account{Name: "trading", Exchange: binance})
+	orders, err := me.GetOrders()
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.Order{
+		{Account: "main", Market: "BTC-CUR1", Time: time.Unix(3, 0)},
+		{Account: "trading", Market: "BTC-CUR3", Time: time.Unix(2, 0)},
+		{Account: "main", Market: "BTC-CUR2", Time: time.Unix(1, 0)},
+	}, orders)
+
+	bittrex.EXPECT().GetOrders().Return(nil, errors.New("some error"))
+	binance.EXPECT().GetOrders().Return([]domain.Order{}, nil)
+
+	_, err = me.GetOrders()
+	assert.Error(t, err)
+}
+
+func TestMultiExchange_GetTrades(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	bittrex := mocks.NewMockExchange(ctrl)
+	binance := mocks.NewMockExchange(ctrl)
+
+	bittrex.EXPECT().GetTrades().Return([]domain.Trade{
+		{Market: "BTC-CUR1", Time: time.Unix(3, 0)},
+		{Market: "BTC-CUR2", Time: time.Unix(1, 0)},
+	}, nil)
+	binance.EXPECT().GetTrades().Return([]domain.Trade{
+		{Market: "BTC-CUR3", Time: time.Unix(2, 0)},
+	}, nil)
+
+	me := NewMultiExchange(Account{Name: "main", Exchange: bittrex}, Account{Name: "trading", Exchange: binance})
+	trades, err := me.GetTrades()
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.Trade{
+		{Account: "main", Market: "BTC-CUR1", Time: time.Unix(3, 0)},
+		{Account: "trading", Market: "BTC-CUR3", Time: time.Unix(2, 0)},
+		{Account: "main", Market: "BTC-CUR2", Time: time.Unix(1, 0)},
+	}, trades)
+
+	bittrex.EXPECT().GetTrades().Return(nil, errors.New("some error"))
+	binance.EXPECT().GetTrades().Return([]domain.Trade{}, nil)
+
+	_, err = me.GetTrades()
+	assert.Error(t, err)
+}
+
+type scanningExchange struct {
+	*mocks.MockExchange
+	*mocks.MockTradeScanner
+}
+
+func TestMultiExchange_ScanTrades(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	bittrex := mocks.NewMockExchange(ctrl)
+	binance := mocks.NewMockTradeScanner(ctrl)
+
+	// the account without scanning returns its trades
+	bittrex.EXPECT().GetTrades().Return([]domain.Trade{{Market: "BTC-CUR1", Time: time.Unix(1, 0)}}, nil)
+	binance.EXPECT().ScanTrades().Return([]domain.Trade{{Market: "BTC-CUR2", Time: time.Unix(2, 0)}}, nil)
+
+	me := NewMultiExchange(
+		Account{Name: "main", Exchange: bittrex},
+		Account{Name: "trading", Exchange: scanningExchange{mocks.NewMockExchange(ctrl), binance}},
+	)
+	trades, err := me.(storage.TradeScanner).ScanTrades()
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.Trade{
+		{Account: "trading", Market: "BTC-CUR2", Time: time.Unix(2, 0)},
+		{Account: "main", Market: "BTC-CUR1", Time: time.Unix(1, 0)},
+	}, trades)
+
+	bittrex.EXPECT().GetTrades().Return([]domain.Trade{}, nil)
+	binance.EXPECT().ScanTrades().Return(nil, errors.New("some error"))
+	_, err = me.(storage.TradeScanner).ScanTrades()
+	assert.Error(t, err)
+}
+
+func TestMultiExchange_GetMarketInfo(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	bittrex := mocks.NewMockExchange(ctrl)
+	binance := mocks.NewMockExchange(ctrl)
+
+	bittrex.EXPECT().GetMarketInfo("BTC-CUR1").Return(nil, errors.New("market not found"))
+	binance.EXPECT().GetMarketInfo("BTC-CUR1").Return(&domain.MarketInfo{MarketName: "BTC-CUR1", Last: 1}, nil)
+
+	me := NewMultiExchange(Account{Name: "main", Exchange: bittrex}, Account{Name: "trading", Exchange: binance})
+	marketInfo, err := me.GetMarketInfo("BTC-CUR1")
+	assert.NoError(t, err)
+	assert.Equal(t, &domain.MarketInfo{MarketName: "BTC-CUR1", Last: 1}, marketInfo)
+
+	bittrex.EXPECT().GetMarketInfo("BTC-CUR2").Return(nil, errors.New("market not found"))
+	binance.EXPECT().GetMarketInfo("BTC-CUR2").Return(nil, errors.New("market not found"))
+
+	_, err = me.GetMarketInfo("BTC-CUR2")
+	assert.Error(t, err)
+
+	_, err = NewMultiExchange().GetMarketInfo("BTC-CUR2")
+	assert.Error(t, err)
+}
+
+type watchingExchange struct {
+	*mocks.MockExchange
+	*mocks.MockMarketWatcher
+}
+
+func TestMultiExchange_WatchMarket(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	binance := mocks.NewMockExchange(ctrl)
+	bittrex1 := mocks.NewMockMarketWatcher(ctrl)
+	bittrex2 := mocks.NewMockMarketWatcher(ctrl)
+
+	updates := make(<-chan domain.MarketInfo)
+	bittrex1.EXPECT().WatchMarket("BTC-CUR1").Return(nil, nil, errors.New("streaming error"))
+	bittrex2.EXPECT().WatchMarket("BTC-CUR1").Return(updates, func() {}, nil)
+
+	me := NewMultiExchange(
+		Account{Name: "trading", Exchange: binance},
+		Account{Name: "main", Exchange: watchingExchange{mocks.NewMockExchange(ctrl), bittrex1}},
+		Account{Name: "second", Exchange: watchingExchange{mocks.NewMockExchange(ctrl), bittrex2}},
+	)
+	result, cancel, err := me.(storage.MarketWatcher).WatchMarket("BTC-CUR1")
+	assert.NoError(t, err)
+	assert.NotNil(t, cancel)
+	assert.Equal(t, updates, result)
+
+	bittrex1.EXPECT().WatchMarket("BTC-CUR2").Return(nil, nil, errors.New("streaming error"))
+	bittrex2.EXPECT().WatchMarket("BTC-CUR2").Return(nil, nil, errors.New("streaming error"))
+	_, _, err = me.(storage.MarketWatcher).WatchMarket("BTC-CUR2")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "account 'second': streaming error")
+
+	_, _, err = NewMultiExchange(Account{Name: "trading", Exchange: binance}).(storage.MarketWatcher).WatchMarket("BTC-CUR1")
+	assert.Error(t, err)
+}
+
+type historyExchange struct {
+	*mocks.MockExchange
+	*mocks.MockMarketHistory
+}
+
+func TestMultiExchange_LastMarketPrices(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	binance := mocks.NewMockMarketHistory(ctrl)
+	bittrex1 := mocks.NewMockMarketHistory(ctrl)
+	bittrex2 := mocks.NewMockMarketHistory(ctrl)
+
+	price := func(exchange domain.ExchangeType, market string, last float64) domain.MarketCandle {
+		return domain.MarketCandle{Exchange: exchange, Market: market, Price: domain.OHLC{Open: last, Close: last, Min: last, Max: last}}
+	}
+	binance.EXPECT().LastMarketPrices().Return([]domain.MarketCandle{price(domain.ExchangeTypeBinance, "BTC-CUR1", 1)})
+	bittrex1.EXPECT().LastMarketPrices().Return([]domain.MarketCandle{price(domain.ExchangeTypeBittrex, "BTC-CUR1", 2)})
+	bittrex2.EXPECT().LastMarketPrices().Return([]domain.MarketCandle{
+		price(domain.ExchangeTypeBittrex, "BTC-CUR1", 3),
+		price(domain.ExchangeTypeBittrex, "BTC-CUR2", 4),
+	})
+
+	me := NewMultiExchange(
+		Account{Name: "plain", Exchange: mocks.NewMockExchange(ctrl)},
+		Account{Name: "trading", Exchange: historyExchange{mocks.NewMockExchange(ctrl), binance}},
+		Account{Name: "main", Exchange: historyExchange{mocks.NewMockExchange(ctrl), bittrex1}},
+		Account{Name: "second", Exchange: historyExchange{mocks.NewMockExchange(ctrl), bittrex2}},
+	)
+	assert.Equal(t, []domain.MarketCandle{
+		price(domain.ExchangeTypeBinance, "BTC-CUR1", 1),
+		price(domain.ExchangeTypeBittrex, "BTC-CUR1", 2),
+		price(domain.ExchangeTypeBittrex, "BTC-CUR2", 4),
+	}, me.(storage.MarketHistory).LastMarketPrices())
+}
+
+func TestMultiExchange_GetMarketCandles(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	bittrex1 := mocks.NewMockMarketHistory(ctrl)
+	bittrex2 := mocks.NewMockMarketHistory(ctrl)
+
+	candles := []domain.MarketCandle{{Exchange: domain.ExchangeTypeBittrex, Market: "BTC-CUR1"}}
+	bittrex1.EXPECT().GetMarketCandles("BTC-CUR1", time.Hour).Return(nil, errors.New("market error"))
+	bittrex2.EXPECT().GetMarketCandles("BTC-CUR1", time.Hour).Return(candles, nil)
+
+	me := NewMultiExchange(
+		Account{Name: "plain", Exchange: mocks.NewMockExchange(ctrl)},
+		Account{Name: "main", Exchange: historyExchange{mocks.NewMockExchange(ctrl), bittrex1}},
+		Account{Name: "second", Exchange: historyExchange{mocks.NewMockExchange(ctrl), bittrex2}},
+	)
+	result, err := me.(storage.MarketHistory).GetMarketCandles("BTC-CUR1", time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, candles, result)
+
+	_, err = NewMultiExchange(Account{Name: "plain", Exchange: mocks.NewMockExchange(ctrl)}).(storage.MarketHistory).GetMarketCandles("BTC-CUR1", time.Hour)
+	assert.Error(t, err)
+}
+
+type ratesExchange struct {
+	*mocks.MockExchange
+	*mocks.MockCurrencyRates
+}
+
+func TestMultiExchange_GetRates(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	binance := mocks.NewMockCurrencyRates(ctrl)
+	bittrex1 := mocks.NewMockCurrencyRates(ctrl)
+	bittrex2 := mocks.NewMockCurrencyRates(ctrl)
+
+	currencies := []string{"CUR1", "CUR2", "CUR3"}
+	binance.EXPECT().GetRates(currencies, "BTC").Return(nil, errors.New("rates error"))
+	bittrex1.EXPECT().GetRates(currencies, "BTC").Return([]domain.Rate{
+		{Exchange: domain.ExchangeTypeBittrex, Currency: "CUR2", Last: 2},
+	}, nil)
+	bittrex2.EXPECT().GetRates(currencies, "BTC").Return([]domain.Rate{
+		{Exchange: domain.ExchangeTypeBittrex, Currency: "CUR1", Last: 1},
+		{Exchange: domain.ExchangeTypeBittrex, Currency: "CUR2", Last: 3},
+	}, nil)
+
+	me := NewMultiExchange(
+		Account{Name: "plain", Exchange: mocks.NewMockExchange(ctrl)},
+		Account{Name: "trading", Exchange: ratesExchange{mocks.NewMockExchange(ctrl), binance}},
+		Account{Name: "main", Exchange: ratesExchange{mocks.NewMockExchange(ctrl), bittrex1}},
+		Account{Name: "second", Exchange: ratesExchange{mocks.NewMockExchange(ctrl), bittrex2}},
+	)
+	rates, err := me.(storage.CurrencyRates).GetRates(currencies, "BTC")
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.Rate{
+		{Exchange: domain.ExchangeTypeBittrex, Currency: "CUR1", Last: 1},
+		{Exchange: domain.ExchangeTypeBittrex, Currency: "CUR2", Last: 2},
+	}, rates)
+
+	_, err = NewMultiExchange(Account{Name: "plain", Exchange: mocks.NewMockExchange(ctrl)}).(storage.CurrencyRates).GetRates(currencies, "BTC")
+	assert.Error(t, err)
+}
+
+func TestMultiExchange_Ping(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	bittrex := mocks.NewMockExchange(ctrl)
+	binance := mocks.NewMockExchange(ctrl)
+
+	bittrex.EXPECT().Ping().Return(nil).Times(2)
+	binance.EXPECT().Ping().Return(nil)
+	binance.EXPECT().Ping().Return(errors.New("invalid key"))
+
+	me := NewMultiExchange(Account{Name: "main", Exchange: bittrex}, Account{Name: "trading", Exchange: binance})
+	assert.NoError(t, me.Ping())
+
+	err := me.Ping()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "account 'trading': invalid key")
+}
+
+func TestMultiExchange_GetTransfers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	bittrex := mocks.NewMockExchange(ctrl)
+	binance := mocks.NewMockExchange(ctrl)
+
+	bittrex.EXPECT().GetTransfers().Return([]domain.Transfer{
+		{ID: "1", Time: time.Unix(3, 0)},
+		{ID: "2", Time: time.Unix(1, 0)},
+	}, nil)
+	binance.EXPECT().GetTransfers().Return([]domain.Transfer{
+		{ID: "3", Time: time.Unix(2, 0)},
+	}, nil)
+
+	me := NewMultiExchange(Account{Name: "main", Exchange: bittrex}, Account{Name: "trading", Exchange: binance})
+	transfers, err := me.GetTransfers()
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.Transfer{
+		{Account: "main", ID: "1", Time: time.Unix(3, 0)},
+		{Account: "trading", ID: "3", Time: time.Unix(2, 0)},
+		{Account: "main", ID: "2", Time: time.Unix(1, 0)},
+	}, transfers)
+
+	bittrex.EXPECT().GetTransfers().Return(nil, errors.New("some error"))
+	binance.EXPECT().GetTransfers().Return([]domain.Transfer{}, nil)
+
+	_, err = me.GetTransfers()
+	assert.Error(t, err)
+}
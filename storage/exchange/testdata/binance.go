@@ -0,0 +1,379 @@
+package testdata
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage/exchange/binance"
+	"github.com/nawa/cryptoexchange-dashboard/utils"
+)
+
+var (
+	binanceUSDTTicker = binance.Ticker{
+		Symbol:    "BTCUSDT",
+		LastPrice: decimal.NewFromFloat(130),
+		BidPrice:  decimal.NewFromFloat(140),
+		AskPrice:  decimal.NewFromFloat(150),
+	}
+
+	BinanceErrorResponse = binance.Error{
+		Code:    -2015,
+		Message: "Invalid API-key, IP, or permissions for action.",
+	}
+)
+
+func BinanceExchangeInfo() *binance.ExchangeInfo {
+	return &binance.ExchangeInfo{
+		Symbols: []binance.Symbol{
+			{Symbol: "CUR1BTC", Status: "TRADING", BaseAsset: "CUR1", QuoteAsset: "BTC"},
+			{Symbol: "CUR2BTC", Status: "TRADING", BaseAsset: "CUR2", QuoteAsset: "BTC"},
+			{Symbol: "CUR2CUR1", Status: "TRADING", BaseAsset: "CUR2", QuoteAsset: "CUR1"},
+			{Symbol: "CUR3BTC", Status: "BREAK", BaseAsset: "CUR3", QuoteAsset: "BTC"},
+			{Symbol: "BTCUSDT", Status: "TRADING", BaseAsset: "BTC", QuoteAsset: "USDT"},
+		},
+	}
+}
+
+func BinanceTickers() []binance.Ticker {
+	return []binance.Ticker{
+		{
+			Symbol:    "CUR1BTC",
+			LastPrice: decimal.NewFromFloat(10),
+			BidPrice:  decimal.NewFromFloat(20),
+			AskPrice:  decimal.NewFromFloat(30),
+			HighPrice: decimal.NewFromFloat(35),
+			LowPrice:  decimal.NewFromFloat(5),
+		},
+		{
+			Symbol:    "CUR2BTC",
+			LastPrice: decimal.NewFromFloat(40),
+			BidPrice:  decimal.NewFromFloat(50),
+			AskPrice:  decimal.NewFromFloat(60),
+		},
+		{
+			Symbol:    "CUR2CUR1",
+			LastPrice: decimal.NewFromFloat(100),
+			BidPrice:  decimal.NewFromFloat(110),
+			AskPrice:  decimal.NewFromFloat(120),
+		},
+		binanceUSDTTicker,
+	}
+}
+
+func BinanceAccount() *binance.Account {
+	return &binance.Account{
+		Balances: []binance.Balance{
+			{
+				Asset:  "BTC",
+				Free:   decimal.NewFromFloat(900),
+				Locked: decimal.NewFromFloat(100),
+			},
+			{
+				Asset: "CUR1",
+				Free:  decimal.NewFromFloat(2000),
+			},
+			{
+				Asset:  "CUR2",
+				Locked: decimal.NewFromFloat(3000),
+			},
+			{
+				Asset: "CUR3",
+			},
+		},
+	}
+}
+
+func BinanceModelBalances() []domain.Balance {
+	usdtRate := binanceUSDTTicker.LastPrice
+	return []domain.Balance{
+		{
+			Exchange:   domain.ExchangeTypeBinance,
+			Amount:     1000,
+			BTCAmount:  1000,
+			Currency:   "BTC",
+			USDTAmount: utils.DecimalToFloatQuiet(decimal.NewFromFloat(1000).Mul(usdtRate)),
+		},
+		{
+			Exchange:   domain.ExchangeTypeBinance,
+			Amount:     2000,
+			BTCAmount:  20000,
+			Currency:   "CUR1",
+			USDTAmount: utils.DecimalToFloatQuiet(decimal.NewFromFloat(20000).Mul(usdtRate)),
+		},
+		{
+			Exchange:   domain.ExchangeTypeBinance,
+			Amount:     3000,
+			BTCAmount:  120000,
+			Currency:   "CUR2",
+			USDTAmount: utils.DecimalToFloatQuiet(decimal.NewFromFloat(120000).Mul(usdtRate)),
+		},
+	}
+}
+
+func BinanceModelMarketInfo() *domain.MarketInfo {
+	ticker := BinanceTickers()[0]
+	return &domain.MarketInfo{
+		MarketName: "BTC-CUR1",
+		Last:       utils.DecimalToFloatQuiet(ticker.LastPrice),
+		Bid:        utils.DecimalToFloatQuiet(ticker.BidPrice),
+		Ask:        utils.DecimalToFloatQuiet(ticker.AskPrice),
+		High:       utils.DecimalToFloatQuiet(ticker.HighPrice),
+		Low:        utils.DecimalToFloatQuiet(ticker.LowPrice),
+	}
+}
+
+// BinanceOrders returns orders by symbol in the order Binance returns them - the oldest first
+func BinanceOrders() map[string][]binance.Order {
+	return map[string][]binance.Order{
+		"CUR1BTC": {
+			{
+				Symbol:              "CUR1BTC",
+				OrderID:             1,
+				Side:                "BUY",
+				Status:              "FILLED",
+				ExecutedQty:         decimal.NewFromFloat(300),
+				CummulativeQuoteQty: decimal.NewFromFloat(30),
+				Time:                1000,
+			},
+			{
+				Symbol:              "CUR1BTC",
+				OrderID:             2,
+				Side:                "SELL",
+				Status:              "FILLED",
+				ExecutedQty:         decimal.NewFromFloat(300),
+				CummulativeQuoteQty: decimal.NewFromFloat(60),
+				Time:                2000,
+			},
+			{
+				Symbol:              "CUR1BTC",
+				OrderID:             3,
+				Side:                "BUY",
+				Status:              "FILLED",
+				ExecutedQty:         decimal.NewFromFloat(100),
+				CummulativeQuoteQty: decimal.NewFromFloat(10),
+				Time:                3000,
+			},
+			{
+				Symbol:              "CUR1BTC",
+				OrderID:             4,
+				Side:                "BUY",
+				Status:              "CANCELED",
+				ExecutedQty:         decimal.NewFromFloat(0),
+				CummulativeQuoteQty: decimal.NewFromFloat(0),
+				Time:                4000,
+			},
+			{
+				Symbol:              "CUR1BTC",
+				OrderID:             5,
+				Side:                "BUY",
+				Status:              "FILLED",
+				ExecutedQty:         decimal.NewFromFloat(200),
+				CummulativeQuoteQty: decimal.NewFromFloat(4),
+				Time:                5000,
+			},
+		},
+		"CUR2BTC": {
+			{
+				Symbol:              "CUR2BTC",
+				OrderID:             1,
+				Side:                "BUY",
+				Status:              "FILLED",
+				ExecutedQty:         decimal.NewFromFloat(50),
+				CummulativeQuoteQty: decimal.NewFromFloat(5),
+				Time:                1500,
+			},
+		},
+		"BTCUSDT": {},
+	}
+}
+
+// BinanceMyTrades returns fills of BinanceOrders by symbol in the order Binance returns them - the oldest first.
+// Commissions are charged in the quote asset, the base asset or other assets
+func BinanceMyTrades() map[string][]binance.Trade {
+	return map[string][]binance.Trade{
+		"CUR1BTC": {
+			{
+				Symbol: "CUR1BTC", ID: 1, OrderID: 1, IsBuyer: true, Time: 1000,
+				Price: decimal.NewFromFloat(0.1), Qty: decimal.NewFromFloat(100), QuoteQty: decimal.NewFromFloat(10),
+				Commission: decimal.NewFromFloat(0.04), CommissionAsset: "BTC",
+			},
+			{
+				Symbol: "CUR1BTC", ID: 2, OrderID: 1, IsBuyer: true, Time: 1200,
+				Price: decimal.NewFromFloat(0.1), Qty: decimal.NewFromFloat(200), QuoteQty: decimal.NewFromFloat(20),
+				Commission: decimal.NewFromFloat(0.1), CommissionAsset: "CUR1",
+			},
+			{
+				Symbol: "CUR1BTC", ID: 3, OrderID: 2, Time: 2100,
+				Price: decimal.NewFromFloat(0.2), Qty: decimal.NewFromFloat(300), QuoteQty: decimal.NewFromFloat(60),
+				Commission: decimal.NewFromFloat(0.06), CommissionAsset: "BTC",
+			},
+			{
+				Symbol: "CUR1BTC", ID: 4, OrderID: 3, IsBuyer: true, Time: 3000,
+				Price: decimal.NewFromFloat(0.1), Qty: decimal.NewFromFloat(100), QuoteQty: decimal.NewFromFloat(10),
+				Commission: decimal.NewFromFloat(0.0005), CommissionAsset: "CUR2",
+			},
+			{
+				Symbol: "CUR1BTC", ID: 5, OrderID: 5, IsBuyer: true, Time: 5000,
+				Price: decimal.NewFromFloat(0.02), Qty: decimal.NewFromFloat(200), QuoteQty: decimal.NewFromFloat(4),
+				Commission: decimal.NewFromFloat(0.004), CommissionAsset: "BTC",
+			},
+		},
+		"CUR2BTC": {
+			{
+				Symbol: "CUR2BTC", ID: 1, OrderID: 1, IsBuyer: true, Time: 1500,
+				Price: decimal.NewFromFloat(0.1), Qty: decimal.NewFromFloat(50), QuoteQty: decimal.NewFromFloat(5),
+				Commission: decimal.NewFromFloat(0.005), CommissionAsset: "BTC",
+			},
+		},
+		"BTCUSDT": {},
+	}
+}
+
+func BinanceModelOrders() []domain.Order {
+	usdtRate := utils.DecimalToFloatQuiet(binanceUSDTTicker.LastPrice)
+	tickers := BinanceTickers()
+	return []domain.Order{
+		{
+			Exchange:    domain.ExchangeTypeBinance,
+			Market:      "BTC-CUR1",
+			Time:        time.Unix(5, 0).UTC(),
+			Amount:      200,
+			BuyRate:     0.02,
+			SellNowRate: utils.DecimalToFloatQuiet(tickers[0].BidPrice),
+			BTCRate:     1,
+			USDTRate:    usdtRate,
+		},
+		{
+			Exchange:    domain.ExchangeTypeBinance,
+			Market:      "BTC-CUR1",
+			Time:        time.Unix(3, 0).UTC(),
+			Amount:      100,
+			BuyRate:     0.1,
+			SellNowRate: utils.DecimalToFloatQuiet(tickers[0].BidPrice),
+			BTCRate:     1,
+			USDTRate:    usdtRate,
+		},
+		{
+			Exchange:    domain.ExchangeTypeBinance,
+			Market:      "BTC-CUR2",
+			Time:        time.Unix(1, int64(500*time.Millisecond)).UTC(),
+			Amount:      50,
+			BuyRate:     0.1,
+			SellNowRate: utils.DecimalToFloatQuiet(tickers[1].BidPrice),
+			BTCRate:     1,
+			USDTRate:    usdtRate,
+		},
+	}
+}
+
+func BinanceModelTrades() []domain.Trade {
+	usdtRate := utils.DecimalToFloatQuiet(binanceUSDTTicker.LastPrice)
+	return []domain.Trade{
+		{
+			Exchange: domain.ExchangeTypeBinance,
+			OrderID:  "CUR1BTC:5",
+			Market:   "BTC-CUR1",
+			Type:     domain.TradeTypeBuy,
+			Time:     time.Unix(5, 0).UTC(),
+			Amount:   200,
+			Rate:     0.02,
+			Fee:      0.004,
+			BTCRate:  1,
+			USDTRate: usdtRate,
+		},
+		{
+			Exchange: domain.ExchangeTypeBinance,
+			OrderID:  "CUR1BTC:3",
+			Market:   "BTC-CUR1",
+			Type:     domain.TradeTypeBuy,
+			Time:     time.Unix(3, 0).UTC(),
+			Amount:   100,
+			Rate:     0.1,
+			Fee:      0.02,
+			BTCRate:  1,
+			USDTRate: usdtRate,
+		},
+		{
+			Exchange: domain.ExchangeTypeBinance,
+			OrderID:  "CUR1BTC:2",
+			Market:   "BTC-CUR1",
+			Type:     domain.TradeTypeSell,
+			Time:     time.Unix(2, int64(100*time.Millisecond)).UTC(),
+			Amount:   300,
+			Rate:     0.2,
+			Fee:      0.06,
+			BTCRate:  1,
+			USDTRate: usdtRate,
+		},
+		{
+			Exchange: domain.ExchangeTypeBinance,
+			OrderID:  "CUR2BTC:1",
+			Market:   "BTC-CUR2",
+			Type:     domain.TradeTypeBuy,
+			Time:     time.Unix(1, int64(500*time.Millisecond)).UTC(),
+			Amount:   50,
+			Rate:     0.1,
+			Fee:      0.005,
+			BTCRate:  1,
+			USDTRate: usdtRate,
+		},
+		{
+			Exchange: domain.ExchangeTypeBinance,
+			OrderID:  "CUR1BTC:1",
+			Market:   "BTC-CUR1",
+			Type:     domain.TradeTypeBuy,
+			Time:     time.Unix(1, int64(200*time.Millisecond)).UTC(),
+			Amount:   300,
+			Rate:     0.1,
+			Fee:      0.05,
+			BTCRate:  1,
+			USDTRate: usdtRate,
+		},
+	}
+}
+
+// BinanceDeposits returns deposits with the pending one
+func BinanceDeposits() []binance.Deposit {
+	return []binance.Deposit{
+		{ID: "d1", Coin: "CUR1", Amount: decimal.NewFromFloat(10), Status: binance.DepositStatusSuccess, InsertTime: 2000},
+		{ID: "d2", Coin: "CUR2", Amount: decimal.NewFromFloat(20), Status: 0, InsertTime: 4000},
+	}
+}
+
+// BinanceWithdrawals returns withdrawals with the cancelled one
+func BinanceWithdrawals() []binance.Withdrawal {
+	return []binance.Withdrawal{
+		{ID: "w1", Coin: "BTC", Amount: decimal.NewFromFloat(1), TransactionFee: decimal.NewFromFloat(0.0005),
+			Status: binance.WithdrawalStatusCompleted, ApplyTime: "1970-01-01 00:00:03"},
+		{ID: "w2", Coin: "CUR1", Amount: decimal.NewFromFloat(5), Status: 1, ApplyTime: "1970-01-01 00:00:05"},
+	}
+}
+
+func BinanceModelTransfers() []domain.Transfer {
+	usdtRate := utils.DecimalToFloatQuiet(binanceUSDTTicker.LastPrice)
+	return []domain.Transfer{
+		{
+			Exchange: domain.ExchangeTypeBinance,
+			ID:       "w1",
+			Type:     domain.TransferTypeWithdrawal,
+			Currency: "BTC",
+			Time:     time.Unix(3, 0).UTC(),
+			Amount:   1.0005,
+			Fee:      0.0005,
+			BTCRate:  1,
+			USDTRate: usdtRate,
+		},
+		{
+			Exchange: domain.ExchangeTypeBinance,
+			ID:       "d1",
+			Type:     domain.TransferTypeDeposit,
+			Currency: "CUR1",
+			Time:     time.Unix(2, 0).UTC(),
+			Amount:   10,
+			BTCRate:  10,
+			USDTRate: 10 * usdtRate,
+		},
+	}
+}
@@ -166,7 +166,7 @@ func BittrexOrders() []bittrex.Order {
 			Quantity:  decimal.NewFromFloat(300),
 			Price:     decimal.NewFromFloat(50),
 		},
-		{ //Bad order - can't convert to USDT
+		{ //Converted to USDT through other markets
 			Exchange:  "CUR1-CUR2",
 			OrderType: "LIMIT_BUY",
 			Quantity:  decimal.NewFromFloat(300),
@@ -201,6 +201,7 @@ func ModelOrders() []domain.Order {
 			Amount:      utils.DecimalToFloatQuiet(bittrexOrders[0].Quantity),
 			BuyRate:     utils.DecimalToFloatQuiet(bittrexOrders[0].Price.Div(bittrexOrders[0].Quantity)),
 			SellNowRate: utils.DecimalToFloatQuiet(bittrexMarketSummaries[0].Bid),
+			BTCRate:     1,
 			USDTRate:    utils.DecimalToFloatQuiet(decimal.NewFromFloat(1).Div(usdtMarketSummary.Last)),
 		},
 		{
@@ -210,6 +211,7 @@ func ModelOrders() []domain.Order {
 			Amount:      utils.DecimalToFloatQuiet(bittrexOrders[1].Quantity),
 			BuyRate:     utils.DecimalToFloatQuiet(bittrexOrders[1].Price.Div(bittrexOrders[1].Quantity)),
 			SellNowRate: utils.DecimalToFloatQuiet(bittrexMarketSummaries[1].Bid),
+			BTCRate:     1,
 			USDTRate:    utils.DecimalToFloatQuiet(decimal.NewFromFloat(1).Div(usdtMarketSummary.Last)),
 		},
 		{
@@ -219,6 +221,7 @@ func ModelOrders() []domain.Order {
 			Amount:      utils.DecimalToFloatQuiet(bittrexOrders[2].Quantity),
 			BuyRate:     utils.DecimalToFloatQuiet(bittrexOrders[2].Price.Div(bittrexOrders[2].Quantity)),
 			SellNowRate: utils.DecimalToFloatQuiet(bittrexMarketSummaries[0].Bid),
+			BTCRate:     1,
 			USDTRate:    utils.DecimalToFloatQuiet(decimal.NewFromFloat(1).Div(usdtMarketSummary.Last)),
 		},
 		{
@@ -228,8 +231,188 @@ func ModelOrders() []domain.Order {
 			Amount:      utils.DecimalToFloatQuiet(bittrexOrders[5].Quantity),
 			BuyRate:     utils.DecimalToFloatQuiet(bittrexOrders[5].Price.Div(bittrexOrders[5].Quantity)),
 			SellNowRate: utils.DecimalToFloatQuiet(bittrexMarketSummaries[1].Bid),
+			BTCRate:     1,
 			USDTRate:    utils.DecimalToFloatQuiet(decimal.NewFromFloat(1).Div(usdtMarketSummary.Last)),
 		},
+		{
+			Market:      "CUR1-CUR2",
+			Exchange:    domain.ExchangeTypeBittrex,
+			Amount:      utils.DecimalToFloatQuiet(bittrexOrders[8].Quantity),
+			BuyRate:     utils.DecimalToFloatQuiet(bittrexOrders[8].Price.Div(bittrexOrders[8].Quantity)),
+			SellNowRate: utils.DecimalToFloatQuiet(bittrexMarketSummaries[2].Bid),
+			BTCRate:     utils.DecimalToFloatQuiet(bittrexMarketSummaries[0].Last),
+			USDTRate:    cur1USDTRate(),
+		},
+	}
+}
+
+// cur1USDTRate is the rate of CUR1 without USDT market. CUR1→CUR2→BTC→USDT is the path of the least spreads
+func cur1USDTRate() float64 {
+	one := decimal.NewFromFloat(1)
+	markets := BittrexMarketSummaries()
+	return utils.DecimalToFloatQuiet(one.Mul(one.Div(markets[2].Last)).Mul(markets[1].Last).Mul(one.Div(usdtMarketSummary.Last)))
+}
+
+// BittrexTradeOrders returns order history with partially filled and not filled orders
+func BittrexTradeOrders() []bittrex.Order {
+	orders := []bittrex.Order{
+		{
+			OrderUuid:         "uuid-1",
+			Exchange:          "BTC-CUR1",
+			OrderType:         "LIMIT_SELL",
+			Quantity:          decimal.NewFromFloat(100),
+			QuantityRemaining: decimal.NewFromFloat(60),
+			Commission:        decimal.NewFromFloat(0.01),
+			Price:             decimal.NewFromFloat(4),
+		},
+		{
+			OrderUuid:         "uuid-2",
+			Exchange:          "BTC-CUR1",
+			OrderType:         "LIMIT_BUY",
+			Quantity:          decimal.NewFromFloat(100),
+			QuantityRemaining: decimal.NewFromFloat(100),
+		},
+		{ //Converted to USDT through other markets
+			OrderUuid:  "uuid-3",
+			Exchange:   "CUR1-CUR2",
+			OrderType:  "LIMIT_BUY",
+			Quantity:   decimal.NewFromFloat(10),
+			Commission: decimal.NewFromFloat(0.025),
+			Price:      decimal.NewFromFloat(10),
+		},
+		{ //Bad order - can't convert to BTC
+			OrderUuid: "uuid-4",
+			Exchange:  "CUR5-CUR6",
+			OrderType: "LIMIT_BUY",
+			Quantity:  decimal.NewFromFloat(300),
+			Price:     decimal.NewFromFloat(50),
+		},
+		{
+			OrderUuid:  "uuid-5",
+			Exchange:   "BTC-CUR1",
+			OrderType:  "LIMIT_BUY",
+			Quantity:   decimal.NewFromFloat(200),
+			Commission: decimal.NewFromFloat(0.02),
+			Price:      decimal.NewFromFloat(8),
+		},
+	}
+	basetime := time.Unix(0, 0).UTC().Add(time.Hour * 24 * 1000)
+	for i := range orders {
+		orders[i].TimeStamp.Time = basetime.Add(-time.Hour * time.Duration(i))
+	}
+	return orders
+}
+
+func ModelTrades() []domain.Trade {
+	basetime := time.Unix(0, 0).UTC().Add(time.Hour * 24 * 1000)
+	s := basetime.Format(bittrex.TIME_FORMAT)
+	basetimeAfterBittrexSerialization, err := time.Parse(bittrex.TIME_FORMAT, s)
+	if err != nil {
+		panic(err)
+	}
+	usdtRate := utils.DecimalToFloatQuiet(decimal.NewFromFloat(1).Div(usdtMarketSummary.Last))
+	return []domain.Trade{
+		{
+			Exchange: domain.ExchangeTypeBittrex,
+			OrderID:  "uuid-1",
+			Market:   "BTC-CUR1",
+			Type:     domain.TradeTypeSell,
+			Time:     basetimeAfterBittrexSerialization,
+			Amount:   40,
+			Rate:     0.1,
+			Fee:      0.01,
+			BTCRate:  1,
+			USDTRate: usdtRate,
+		},
+		{
+			Exchange: domain.ExchangeTypeBittrex,
+			OrderID:  "uuid-3",
+			Market:   "CUR1-CUR2",
+			Type:     domain.TradeTypeBuy,
+			Time:     basetimeAfterBittrexSerialization.Add(-time.Hour * 2),
+			Amount:   10,
+			Rate:     1,
+			Fee:      0.025,
+			BTCRate:  utils.DecimalToFloatQuiet(BittrexMarketSummaries()[0].Last),
+			USDTRate: cur1USDTRate(),
+		},
+		{
+			Exchange: domain.ExchangeTypeBittrex,
+			OrderID:  "uuid-5",
+			Market:   "BTC-CUR1",
+			Type:     domain.TradeTypeBuy,
+			Time:     basetimeAfterBittrexSerialization.Add(-time.Hour * 4),
+			Amount:   200,
+			Rate:     0.04,
+			Fee:      0.02,
+			BTCRate:  1,
+			USDTRate: usdtRate,
+		},
+	}
+}
+
+// BittrexDeposits returns deposits with the one of an unknown currency
+func BittrexDeposits() []bittrex.Deposit {
+	deposits := []bittrex.Deposit{
+		{Id: 1, Currency: "CUR1", Amount: decimal.NewFromFloat(10)},
+		{Id: 2, Currency: "CUR9", Amount: decimal.NewFromFloat(5)},
+	}
+	basetime := time.Unix(0, 0).UTC().Add(time.Hour * 24 * 1000)
+	deposits[0].LastUpdated.Time = basetime.Add(-time.Hour)
+	deposits[1].LastUpdated.Time = basetime.Add(-time.Hour * 3)
+	return deposits
+}
+
+// BittrexWithdrawals returns withdrawals with the canceled one
+func BittrexWithdrawals() []bittrex.Withdrawal {
+	withdrawals := []bittrex.Withdrawal{
+		{PaymentUuid: "uuid-w1", Currency: "BTC", Amount: decimal.NewFromFloat(2), TxCost: decimal.NewFromFloat(0.001)},
+		{PaymentUuid: "uuid-w2", Currency: "CUR2", Amount: decimal.NewFromFloat(3), Canceled: true},
+	}
+	basetime := time.Unix(0, 0).UTC().Add(time.Hour * 24 * 1000)
+	withdrawals[0].Opened.Time = basetime.Add(-time.Hour * 2)
+	withdrawals[1].Opened.Time = basetime
+	return withdrawals
+}
+
+func ModelTransfers() []domain.Transfer {
+	basetime := time.Unix(0, 0).UTC().Add(time.Hour * 24 * 1000)
+	s := basetime.Format(bittrex.TIME_FORMAT)
+	basetimeAfterBittrexSerialization, err := time.Parse(bittrex.TIME_FORMAT, s)
+	if err != nil {
+		panic(err)
+	}
+	btcUSDTRate := decimal.NewFromFloat(1).Div(usdtMarketSummary.Last)
+	return []domain.Transfer{
+		{
+			Exchange: domain.ExchangeTypeBittrex,
+			ID:       "1",
+			Type:     domain.TransferTypeDeposit,
+			Currency: "CUR1",
+			Time:     basetimeAfterBittrexSerialization.Add(-time.Hour),
+			Amount:   10,
+			BTCRate:  10,
+			USDTRate: utils.DecimalToFloatQuiet(decimal.NewFromFloat(10).Mul(btcUSDTRate)),
+		},
+		{
+			Exchange: domain.ExchangeTypeBittrex,
+			ID:       "uuid-w1",
+			Type:     domain.TransferTypeWithdrawal,
+			Currency: "BTC",
+			Time:     basetimeAfterBittrexSerialization.Add(-time.Hour * 2),
+			Amount:   2,
+			Fee:      0.001,
+			BTCRate:  1,
+			USDTRate: utils.DecimalToFloatQuiet(btcUSDTRate),
+		},
+		{
+			Exchange: domain.ExchangeTypeBittrex,
+			ID:       "2",
+			Type:     domain.TransferTypeDeposit,
+			Currency: "CUR9",
+			Time:     basetimeAfterBittrexSerialization.Add(-time.Hour * 3),
+			Amount:   5,
+		},
 	}
 }
 
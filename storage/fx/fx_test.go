@@ -0,0 +1,72 @@
+package fx
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestHTTPPriceSource(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.Path+"?"+r.URL.RawQuery)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"amount":1.0,"base":"USD","date":"2026-10-16","rates":{"EUR":0.8,"GBP":0.75}}`))
+	}))
+	defer server.Close()
+
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	source := NewHTTPPriceSource(server.URL + "/").(*httpPriceSource)
+	source.now = func() time.Time { return now }
+
+	rates, err := source.GetUSDRates([]string{"EUR", "gbp", "USD"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]float64{"EUR": 0.8, "GBP": 0.75, "USD": 1}, rates)
+	assert.Equal(t, []string{"/latest?from=USD&to=EUR%2Cgbp%2CUSD"}, queries)
+
+	// cached
+	now = now.Add(cacheTTL - time.Minute)
+	rates, err = source.GetUSDRates([]string{"EUR"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]float64{"EUR": 0.8}, rates)
+	assert.Len(t, queries, 1)
+
+	// expired
+	now = now.Add(time.Minute)
+	_, err = source.GetUSDRates([]string{"EUR"})
+	assert.NoError(t, err)
+	assert.Len(t, queries, 2)
+
+	// the unknown currency is requested again and fails
+	_, err = source.GetUSDRates([]string{"EUR", "XXX"})
+	assert.EqualError(t, err, "rates of XXX not found")
+	assert.Len(t, queries, 3)
+}
+
+func TestHTTPPriceSource_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message":"not found"}`))
+	}))
+	defer server.Close()
+
+	_, err := NewHTTPPriceSource(server.URL).GetUSDRates([]string{"EUR"})
+	assert.EqualError(t, err, `rates aren't received, status 404: {"message":"not found"}`)
+}
+
+func TestFilePriceSource(t *testing.T) {
+	source := NewFilePriceSource("testdata/rates.yml")
+
+	rates, err := source.GetUSDRates([]string{"eur", "GBP"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]float64{"EUR": 0.8, "GBP": 0.75}, rates)
+
+	_, err = source.GetUSDRates([]string{"JPY", "CHF"})
+	assert.EqualError(t, err, "rates of CHF, JPY not found")
+
+	_, err = NewFilePriceSource("testdata/missing.yml").GetUSDRates([]string{"EUR"})
+	assert.Error(t, err)
+}
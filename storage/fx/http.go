@@ -0,0 +1,117 @@
+package fx
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nawa/cryptoexchange-dashboard/storage"
+)
+
+const (
+	// DefaultURL is the API of exchange rates of the European Central Bank
+	DefaultURL = "https://api.frankfurter.app"
+
+	httpTimeout = time.Second * 10
+	// cacheTTL is how long rates are reused, central banks update them once a day
+	cacheTTL = time.Hour
+)
+
+// httpPriceSource requests rates from the API compatible with frankfurter.app: GET /latest?from=USD&to=EUR,GBP
+// returns {"rates": {"EUR": 0.92, "GBP": 0.79}}
+type httpPriceSource struct {
+	url    string
+	client *http.Client
+	now    func() time.Time
+
+	lock      sync.Mutex
+	rates     map[string]float64
+	requested time.Time
+}
+
+type ratesResponse struct {
+	Rates map[string]float64 `json:"rates"`
+}
+
+func NewHTTPPriceSource(url string) storage.PriceSource {
+	return &httpPriceSource{
+		url:    strings.TrimSuffix(url, "/"),
+		client: &http.Client{Timeout: httpTimeout},
+		now:    time.Now,
+	}
+}
+
+// GetUSDRates returns cached rates if they have all currencies and aren't older than an hour
+func (s *httpPriceSource) GetUSDRates(currencies []string) (map[string]float64, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.now().Sub(s.requested) < cacheTTL {
+		rates, err := pickRates(s.rates, currencies)
+		if err == nil {
+			return rates, nil
+		}
+	}
+
+	rates, err := s.request(currencies)
+	if err != nil {
+		return nil, err
+	}
+	s.rates, s.requested = rates, s.now()
+	return pickRates(rates, currencies)
+}
+
+func (s *httpPriceSource) request(currencies []string) (map[string]float64, error) {
+	query := url.Values{}
+	query.Set("from", "USD")
+	query.Set("to", strings.Join(currencies, ","))
+
+	response, err := s.client.Get(s.url + "/latest?" + query.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		message, _ := ioutil.ReadAll(io.LimitReader(response.Body, 512))
+		return nil, fmt.Errorf("rates aren't received, status %d: %s", response.StatusCode, message)
+	}
+
+	var body ratesResponse
+	err = json.NewDecoder(response.Body).Decode(&body)
+	if err != nil {
+		return nil, fmt.Errorf("rates can't be parsed: %s", err)
+	}
+	return body.Rates, nil
+}
+
+// pickRates returns rates of currencies, USD is always known
+func pickRates(rates map[string]float64, currencies []string) (map[string]float64, error) {
+	result := make(map[string]float64, len(currencies))
+	var missing []string
+	for _, currency := range currencies {
+		currency = strings.ToUpper(currency)
+		rate, ok := rates[currency]
+		if currency == "USD" {
+			rate, ok = 1, true
+		}
+		if !ok || rate <= 0 {
+			missing = append(missing, currency)
+			continue
+		}
+		result[currency] = rate
+	}
+
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("rates of %s not found", strings.Join(missing, ", "))
+	}
+	return result, nil
+}
@@ -0,0 +1,18 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+)
+
+type MarketStorage interface {
+	// Init initializes the storage, such as prepares indexes and another
+	Init() error
+	// Save inserts new candles and replaces already saved ones with the same exchange, market and time,
+	// so the same history can be saved many times
+	Save(candle ...domain.MarketCandle) error
+	// FetchCandles returns candles of the market in [from, to) grouped into buckets of the resolution
+	// aligned to the Unix epoch, the latest bucket first. Empty exchange means candles of all exchanges
+	FetchCandles(exchange domain.ExchangeType, market string, from, to time.Time, resolution time.Duration) ([]domain.MarketCandle, error)
+}
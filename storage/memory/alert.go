@@ -0,0 +1,142 @@
+package memory
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage"
+)
+
+// alertStorage keeps alert rules and states by rule IDs. It is safe for concurrent use
+type alertStorage struct {
+	lock     sync.RWMutex
+	states   map[string]domain.AlertState
+	rules    map[string]domain.AlertRule
+	triggers []domain.AlertTrigger
+}
+
+func NewAlertStorage() storage.AlertStorage {
+	return &alertStorage{
+		states: make(map[string]domain.AlertState),
+		rules:  make(map[string]domain.AlertRule),
+	}
+}
+
+func (s *alertStorage) Init() error {
+	return nil
+}
+
+func (s *alertStorage) SaveState(state ...domain.AlertState) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, st := range state {
+		s.states[st.RuleID] = st
+	}
+	return nil
+}
+
+func (s *alertStorage) FetchStates() ([]domain.AlertState, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	result := make([]domain.AlertState, 0, len(s.states))
+	for _, st := range s.states {
+		result = append(result, st)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].RuleID < result[j].RuleID
+	})
+	return result, nil
+}
+
+func (s *alertStorage) SaveRule(rule domain.AlertRule) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.rules[rule.ID] = copyRule(rule)
+	return nil
+}
+
+func (s *alertStorage) FetchRule(id string) (*domain.AlertRule, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	rule, ok := s.rules[id]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	rule = copyRule(rule)
+	return &rule, nil
+}
+
+func (s *alertStorage) FetchRules() ([]domain.AlertRule, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	result := make([]domain.AlertRule, 0, len(s.rules))
+	for _, rule := range s.rules {
+		result = append(result, copyRule(rule))
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+	return result, nil
+}
+
+func (s *alertStorage) DeleteRule(id string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.rules[id]; !ok {
+		return storage.ErrNotFound
+	}
+	delete(s.rules, id)
+	delete(s.states, id)
+	return nil
+}
+
+func (s *alertStorage) SaveTrigger(trigger ...domain.AlertTrigger) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, t := range trigger {
+		// channels aren't the part of the history
+		t.Channels = nil
+		s.triggers = append(s.triggers, t)
+	}
+	return nil
+}
+
+func (s *alertStorage) FetchTriggers(ruleID string, limit int) ([]domain.AlertTrigger, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	result := []domain.AlertTrigger{}
+	for _, t := range s.triggers {
+		if ruleID == "" || t.RuleID == ruleID {
+			result = append(result, t)
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Time.After(result[j].Time)
+	})
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+// copyRule copies channels not to share them with the caller
+func copyRule(rule domain.AlertRule) domain.AlertRule {
+	if len(rule.Channels) == 0 {
+		rule.Channels = nil
+	} else {
+		rule.Channels = append([]string{}, rule.Channels...)
+	}
+	return rule
+}
@@ -0,0 +1,25 @@
+package memory
+
+import (
+	"testing"
+
+	assert "github.com/stretchr/testify/require"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage/testdata"
+)
+
+func TestAlertStorage(t *testing.T) {
+	s := NewAlertStorage().(*alertStorage)
+	assert.NoError(t, s.Init())
+
+	testdata.RunAlertStorageSuite(t, s, func() error {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+
+		s.states = make(map[string]domain.AlertState)
+		s.rules = make(map[string]domain.AlertRule)
+		s.triggers = nil
+		return nil
+	})
+}
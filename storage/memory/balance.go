@@ -0,0 +1,166 @@
+package memory
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage"
+	"github.com/nawa/cryptoexchange-dashboard/storage/downsample"
+)
+
+// balanceStorage keeps balances of every currency sorted by time. It is safe for concurrent use
+type balanceStorage struct {
+	lock     sync.RWMutex
+	balances map[string][]domain.Balance
+	lastTime time.Time
+}
+
+func NewBalanceStorage() storage.BalanceStorage {
+	return &balanceStorage{
+		balances: make(map[string][]domain.Balance),
+	}
+}
+
+func (s *balanceStorage) Init() error {
+	return nil
+}
+
+func (s *balanceStorage) Save(balance ...domain.Balance) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, b := range balance {
+		balances := s.balances[b.Currency]
+		// after all balances with the same time to keep the order of saving
+		i := sort.Search(len(balances), func(i int) bool {
+			return balances[i].Time.After(b.Time)
+		})
+		balances = append(balances, domain.Balance{})
+		copy(balances[i+1:], balances[i:])
+		balances[i] = b
+		s.balances[b.Currency] = balances
+
+		if b.Time.After(s.lastTime) {
+			s.lastTime = b.Time
+		}
+	}
+	return nil
+}
+
+func (s *balanceStorage) FetchHourly(currency string, hours int) ([]domain.Balance, error) {
+	period := time.Now().Add(-1 * time.Hour * time.Duration(hours))
+
+	balances := s.fetch(currency, period, time.Time{})
+	// the latest first
+	for i, j := 0, len(balances)-1; i < j; i, j = i+1, j-1 {
+		balances[i], balances[j] = balances[j], balances[i]
+	}
+	return balances, nil
+}
+
+func (s *balanceStorage) FetchWeekly(currency string) ([]domain.Balance, error) {
+	period := time.Now().Add(-1 * time.Hour * 24 * 7)
+	return downsample.First(s.fetch(currency, period, time.Time{}), downsample.Every(5*time.Minute)), nil
+}
+
+func (s *balanceStorage) FetchMonthly(currency string) ([]domain.Balance, error) {
+	period := time.Now().Add(-1 * time.Hour * 24 * 30)
+	return downsample.First(s.fetch(currency, period, time.Time{}), downsample.Every(time.Hour)), nil
+}
+
+func (s *balanceStorage) FetchAll(currency string) ([]domain.Balance, error) {
+	dailyPeriod := time.Now().Add(-storage.FetchAllDailyPeriod)
+	return downsample.First(s.fetch(currency, time.Time{}, time.Time{}), downsample.DailyThenWeekly(dailyPeriod)), nil
+}
+
+func (s *balanceStorage) FetchRange(currency string, from, to time.Time, resolution time.Duration) ([]domain.BalanceCandle, error) {
+	if resolution < time.Millisecond {
+		return nil, errors.Errorf("resolution %s is less than 1ms", resolution)
+	}
+
+	return downsample.Candles(currency, s.fetch(currency, from, to), resolution), nil
+}
+
+func (s *balanceStorage) GetActiveCurrencies() ([]domain.Balance, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if s.lastTime.IsZero() {
+		return nil, errors.New("last time not found for currency")
+	}
+
+	var result []domain.Balance
+	for _, balances := range s.balances {
+		i := sort.Search(len(balances), func(i int) bool {
+			return !balances[i].Time.Before(s.lastTime)
+		})
+		result = append(result, balances[i:]...)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Currency < result[j].Currency
+	})
+	return result, nil
+}
+
+func (s *balanceStorage) FetchSnapshot(at time.Time) ([]domain.Balance, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	var snapshotTime time.Time
+	for _, balances := range s.balances {
+		i := sort.Search(len(balances), func(i int) bool {
+			return !balances[i].Time.Before(at)
+		})
+		if i < len(balances) && (snapshotTime.IsZero() || balances[i].Time.Before(snapshotTime)) {
+			snapshotTime = balances[i].Time
+		}
+	}
+	if snapshotTime.IsZero() {
+		return nil, nil
+	}
+
+	var result []domain.Balance
+	for _, balances := range s.balances {
+		i := sort.Search(len(balances), func(i int) bool {
+			return !balances[i].Time.Before(snapshotTime)
+		})
+		for ; i < len(balances) && balances[i].Time.Equal(snapshotTime); i++ {
+			result = append(result, balances[i])
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Currency < result[j].Currency
+	})
+	return result, nil
+}
+
+// fetch returns the copy of balances of the currency in [from, to) sorted by time in ascending order.
+// Zero to means no upper bound
+func (s *balanceStorage) fetch(currency string, from, to time.Time) []domain.Balance {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	balances := s.balances[currency]
+	start := sort.Search(len(balances), func(i int) bool {
+		return !balances[i].Time.Before(from)
+	})
+	end := len(balances)
+	if !to.IsZero() {
+		end = sort.Search(len(balances), func(i int) bool {
+			return !balances[i].Time.Before(to)
+		})
+	}
+	if start >= end {
+		return nil
+	}
+
+	result := make([]domain.Balance, end-start)
+	copy(result, balances[start:end])
+	return result
+}
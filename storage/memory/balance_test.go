@@ -0,0 +1,79 @@
+package memory
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/require"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage/testdata"
+)
+
+func TestBalanceStorage(t *testing.T) {
+	s := NewBalanceStorage().(*balanceStorage)
+	assert.NoError(t, s.Init())
+
+	testdata.RunBalanceStorageSuite(t, s, func() error {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+
+		s.balances = make(map[string][]domain.Balance)
+		s.lastTime = time.Time{}
+		return nil
+	})
+}
+
+func TestBalanceStorage_SaveOutOfOrder(t *testing.T) {
+	s := NewBalanceStorage()
+
+	now := time.Now()
+	assert.NoError(t, s.Save(
+		domain.Balance{Currency: "CUR1", Amount: 2, Time: now.Add(-time.Minute)},
+		domain.Balance{Currency: "CUR1", Amount: 3, Time: now},
+		domain.Balance{Currency: "CUR1", Amount: 1, Time: now.Add(-2 * time.Minute)},
+	))
+
+	balances, err := s.FetchHourly("CUR1", 1)
+	assert.NoError(t, err)
+	assert.Len(t, balances, 3)
+	assert.Equal(t, float64(3), balances[0].Amount)
+	assert.Equal(t, float64(2), balances[1].Amount)
+	assert.Equal(t, float64(1), balances[2].Amount)
+
+	// the result doesn't share memory with the storage
+	balances[0].Amount = 100
+	balances, err = s.FetchHourly("CUR1", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(3), balances[0].Amount)
+}
+
+func TestBalanceStorage_Concurrency(t *testing.T) {
+	s := NewBalanceStorage()
+
+	now := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		i := i
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, s.Save(domain.Balance{Currency: "total", BTCAmount: float64(i), Time: now.Add(time.Duration(i) * time.Second)}))
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := s.FetchMonthly("total")
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	balances, err := s.FetchHourly("total", 1)
+	assert.NoError(t, err)
+	assert.Len(t, balances, 10)
+
+	active, err := s.GetActiveCurrencies()
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.Balance{balances[0]}, active)
+}
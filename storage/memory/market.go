@@ -0,0 +1,85 @@
+package memory
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage"
+	"github.com/nawa/cryptoexchange-dashboard/storage/downsample"
+)
+
+// marketStorage keeps candles of every market sorted by time. It is safe for concurrent use
+type marketStorage struct {
+	lock    sync.RWMutex
+	candles map[string][]domain.MarketCandle
+}
+
+func NewMarketStorage() storage.MarketStorage {
+	return &marketStorage{
+		candles: make(map[string][]domain.MarketCandle),
+	}
+}
+
+func (s *marketStorage) Init() error {
+	return nil
+}
+
+func (s *marketStorage) Save(candle ...domain.MarketCandle) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, c := range candle {
+		candles := s.candles[c.Market]
+		i := sort.Search(len(candles), func(i int) bool {
+			return !candles[i].Time.Before(c.Time)
+		})
+
+		replaced := false
+		for j := i; j < len(candles) && candles[j].Time.Equal(c.Time); j++ {
+			if candles[j].Exchange == c.Exchange {
+				candles[j] = c
+				replaced = true
+				break
+			}
+		}
+		if replaced {
+			continue
+		}
+
+		candles = append(candles, domain.MarketCandle{})
+		copy(candles[i+1:], candles[i:])
+		candles[i] = c
+		s.candles[c.Market] = candles
+	}
+	return nil
+}
+
+func (s *marketStorage) FetchCandles(exchange domain.ExchangeType, market string, from, to time.Time, resolution time.Duration) ([]domain.MarketCandle, error) {
+	if resolution < time.Millisecond {
+		return nil, errors.Errorf("resolution %s is less than 1ms", resolution)
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	candles := s.candles[market]
+	start := sort.Search(len(candles), func(i int) bool {
+		return !candles[i].Time.Before(from)
+	})
+
+	var result []domain.MarketCandle
+	for _, c := range candles[start:] {
+		if !c.Time.Before(to) {
+			break
+		}
+		if exchange == "" || c.Exchange == exchange {
+			result = append(result, c)
+		}
+	}
+
+	return downsample.MarketCandles(exchange, market, result, resolution), nil
+}
@@ -0,0 +1,23 @@
+package memory
+
+import (
+	"testing"
+
+	assert "github.com/stretchr/testify/require"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage/testdata"
+)
+
+func TestMarketStorage(t *testing.T) {
+	s := NewMarketStorage().(*marketStorage)
+	assert.NoError(t, s.Init())
+
+	testdata.RunMarketStorageSuite(t, s, func() error {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+
+		s.candles = make(map[string][]domain.MarketCandle)
+		return nil
+	})
+}
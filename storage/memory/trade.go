@@ -0,0 +1,58 @@
+package memory
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage"
+)
+
+// tradeStorage keeps trades by exchange and order ID. It is safe for concurrent use
+type tradeStorage struct {
+	lock   sync.RWMutex
+	trades map[string]domain.Trade
+}
+
+func NewTradeStorage() storage.TradeStorage {
+	return &tradeStorage{
+		trades: make(map[string]domain.Trade),
+	}
+}
+
+func (s *tradeStorage) Init() error {
+	return nil
+}
+
+func (s *tradeStorage) Save(trade ...domain.Trade) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, t := range trade {
+		key := string(t.Exchange) + "/" + t.OrderID
+		if saved, ok := s.trades[key]; ok {
+			t.BTCRate, t.USDTRate = saved.BTCRate, saved.USDTRate
+		}
+		s.trades[key] = t
+	}
+	return nil
+}
+
+func (s *tradeStorage) Fetch(from, to time.Time) ([]domain.Trade, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	var result []domain.Trade
+	for _, t := range s.trades {
+		if t.Time.Before(from) || (!to.IsZero() && !t.Time.Before(to)) {
+			continue
+		}
+		result = append(result, t)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Time.After(result[j].Time)
+	})
+	return result, nil
+}
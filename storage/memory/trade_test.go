@@ -0,0 +1,23 @@
+package memory
+
+import (
+	"testing"
+
+	assert "github.com/stretchr/testify/require"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage/testdata"
+)
+
+func TestTradeStorage(t *testing.T) {
+	s := NewTradeStorage().(*tradeStorage)
+	assert.NoError(t, s.Init())
+
+	testdata.RunTradeStorageSuite(t, s, func() error {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+
+		s.trades = make(map[string]domain.Trade)
+		return nil
+	})
+}
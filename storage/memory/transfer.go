@@ -0,0 +1,58 @@
+package memory
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage"
+)
+
+// transferStorage keeps transfers by exchange and ID. It is safe for concurrent use
+type transferStorage struct {
+	lock      sync.RWMutex
+	transfers map[string]domain.Transfer
+}
+
+func NewTransferStorage() storage.TransferStorage {
+	return &transferStorage{
+		transfers: make(map[string]domain.Transfer),
+	}
+}
+
+func (s *transferStorage) Init() error {
+	return nil
+}
+
+func (s *transferStorage) Save(transfer ...domain.Transfer) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, t := range transfer {
+		key := string(t.Exchange) + "/" + t.ID
+		if saved, ok := s.transfers[key]; ok {
+			t.BTCRate, t.USDTRate = saved.BTCRate, saved.USDTRate
+		}
+		s.transfers[key] = t
+	}
+	return nil
+}
+
+func (s *transferStorage) Fetch(from, to time.Time) ([]domain.Transfer, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	var result []domain.Transfer
+	for _, t := range s.transfers {
+		if t.Time.Before(from) || (!to.IsZero() && !t.Time.Before(to)) {
+			continue
+		}
+		result = append(result, t)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Time.After(result[j].Time)
+	})
+	return result, nil
+}
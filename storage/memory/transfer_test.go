@@ -0,0 +1,23 @@
+package memory
+
+import (
+	"testing"
+
+	assert "github.com/stretchr/testify/require"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage/testdata"
+)
+
+func TestTransferStorage(t *testing.T) {
+	s := NewTransferStorage().(*transferStorage)
+	assert.NoError(t, s.Init())
+
+	testdata.RunTransferStorageSuite(t, s, func() error {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+
+		s.transfers = make(map[string]domain.Transfer)
+		return nil
+	})
+}
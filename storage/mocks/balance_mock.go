@@ -6,6 +6,7 @@ package mocks
 
 import (
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	domain "github.com/nawa/cryptoexchange-dashboard/domain"
@@ -114,6 +115,19 @@ func (mr *MockBalanceStorageMockRecorder) FetchAll(currency interface{}) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchAll", reflect.TypeOf((*MockBalanceStorage)(nil).FetchAll), currency)
 }
 
+// FetchRange mocks base method
+func (m *MockBalanceStorage) FetchRange(currency string, from, to time.Time, resolution time.Duration) ([]domain.BalanceCandle, error) {
+	ret := m.ctrl.Call(m, "FetchRange", currency, from, to, resolution)
+	ret0, _ := ret[0].([]domain.BalanceCandle)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FetchRange indicates an expected call of FetchRange
+func (mr *MockBalanceStorageMockRecorder) FetchRange(currency, from, to, resolution interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchRange", reflect.TypeOf((*MockBalanceStorage)(nil).FetchRange), currency, from, to, resolution)
+}
+
 // GetActiveCurrencies mocks base method
 func (m *MockBalanceStorage) GetActiveCurrencies() ([]domain.Balance, error) {
 	ret := m.ctrl.Call(m, "GetActiveCurrencies")
@@ -126,3 +140,16 @@ func (m *MockBalanceStorage) GetActiveCurrencies() ([]domain.Balance, error) {
 func (mr *MockBalanceStorageMockRecorder) GetActiveCurrencies() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetActiveCurrencies", reflect.TypeOf((*MockBalanceStorage)(nil).GetActiveCurrencies))
 }
+
+// FetchSnapshot mocks base method
+func (m *MockBalanceStorage) FetchSnapshot(at time.Time) ([]domain.Balance, error) {
+	ret := m.ctrl.Call(m, "FetchSnapshot", at)
+	ret0, _ := ret[0].([]domain.Balance)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FetchSnapshot indicates an expected call of FetchSnapshot
+func (mr *MockBalanceStorageMockRecorder) FetchSnapshot(at interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchSnapshot", reflect.TypeOf((*MockBalanceStorage)(nil).FetchSnapshot), at)
+}
@@ -6,6 +6,7 @@ package mocks
 
 import (
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	domain "github.com/nawa/cryptoexchange-dashboard/domain"
@@ -73,6 +74,32 @@ func (mr *MockExchangeMockRecorder) GetOrders() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrders", reflect.TypeOf((*MockExchange)(nil).GetOrders))
 }
 
+// GetTrades mocks base method
+func (m *MockExchange) GetTrades() ([]domain.Trade, error) {
+	ret := m.ctrl.Call(m, "GetTrades")
+	ret0, _ := ret[0].([]domain.Trade)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTrades indicates an expected call of GetTrades
+func (mr *MockExchangeMockRecorder) GetTrades() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTrades", reflect.TypeOf((*MockExchange)(nil).GetTrades))
+}
+
+// GetTransfers mocks base method
+func (m *MockExchange) GetTransfers() ([]domain.Transfer, error) {
+	ret := m.ctrl.Call(m, "GetTransfers")
+	ret0, _ := ret[0].([]domain.Transfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTransfers indicates an expected call of GetTransfers
+func (mr *MockExchangeMockRecorder) GetTransfers() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransfers", reflect.TypeOf((*MockExchange)(nil).GetTransfers))
+}
+
 // Ping mocks base method
 func (m *MockExchange) Ping() error {
 	ret := m.ctrl.Call(m, "Ping")
@@ -84,3 +111,195 @@ func (m *MockExchange) Ping() error {
 func (mr *MockExchangeMockRecorder) Ping() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockExchange)(nil).Ping))
 }
+
+// MockMarketWatcher is a mock of MarketWatcher interface
+type MockMarketWatcher struct {
+	ctrl     *gomock.Controller
+	recorder *MockMarketWatcherMockRecorder
+}
+
+// MockMarketWatcherMockRecorder is the mock recorder for MockMarketWatcher
+type MockMarketWatcherMockRecorder struct {
+	mock *MockMarketWatcher
+}
+
+// NewMockMarketWatcher creates a new mock instance
+func NewMockMarketWatcher(ctrl *gomock.Controller) *MockMarketWatcher {
+	mock := &MockMarketWatcher{ctrl: ctrl}
+	mock.recorder = &MockMarketWatcherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockMarketWatcher) EXPECT() *MockMarketWatcherMockRecorder {
+	return m.recorder
+}
+
+// WatchMarket mocks base method
+func (m *MockMarketWatcher) WatchMarket(market string) (<-chan domain.MarketInfo, func(), error) {
+	ret := m.ctrl.Call(m, "WatchMarket", market)
+	ret0, _ := ret[0].(<-chan domain.MarketInfo)
+	ret1, _ := ret[1].(func())
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// WatchMarket indicates an expected call of WatchMarket
+func (mr *MockMarketWatcherMockRecorder) WatchMarket(market interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WatchMarket", reflect.TypeOf((*MockMarketWatcher)(nil).WatchMarket), market)
+}
+
+// MockCurrencyRates is a mock of CurrencyRates interface
+type MockCurrencyRates struct {
+	ctrl     *gomock.Controller
+	recorder *MockCurrencyRatesMockRecorder
+}
+
+// MockCurrencyRatesMockRecorder is the mock recorder for MockCurrencyRates
+type MockCurrencyRatesMockRecorder struct {
+	mock *MockCurrencyRates
+}
+
+// NewMockCurrencyRates creates a new mock instance
+func NewMockCurrencyRates(ctrl *gomock.Controller) *MockCurrencyRates {
+	mock := &MockCurrencyRates{ctrl: ctrl}
+	mock.recorder = &MockCurrencyRatesMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockCurrencyRates) EXPECT() *MockCurrencyRatesMockRecorder {
+	return m.recorder
+}
+
+// GetRates mocks base method
+func (m *MockCurrencyRates) GetRates(currencies []string, quote string) ([]domain.Rate, error) {
+	ret := m.ctrl.Call(m, "GetRates", currencies, quote)
+	ret0, _ := ret[0].([]domain.Rate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRates indicates an expected call of GetRates
+func (mr *MockCurrencyRatesMockRecorder) GetRates(currencies, quote interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRates", reflect.TypeOf((*MockCurrencyRates)(nil).GetRates), currencies, quote)
+}
+
+// MockMarketHistory is a mock of MarketHistory interface
+type MockMarketHistory struct {
+	ctrl     *gomock.Controller
+	recorder *MockMarketHistoryMockRecorder
+}
+
+// MockMarketHistoryMockRecorder is the mock recorder for MockMarketHistory
+type MockMarketHistoryMockRecorder struct {
+	mock *MockMarketHistory
+}
+
+// NewMockMarketHistory creates a new mock instance
+func NewMockMarketHistory(ctrl *gomock.Controller) *MockMarketHistory {
+	mock := &MockMarketHistory{ctrl: ctrl}
+	mock.recorder = &MockMarketHistoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockMarketHistory) EXPECT() *MockMarketHistoryMockRecorder {
+	return m.recorder
+}
+
+// LastMarketPrices mocks base method
+func (m *MockMarketHistory) LastMarketPrices() []domain.MarketCandle {
+	ret := m.ctrl.Call(m, "LastMarketPrices")
+	ret0, _ := ret[0].([]domain.MarketCandle)
+	return ret0
+}
+
+// LastMarketPrices indicates an expected call of LastMarketPrices
+func (mr *MockMarketHistoryMockRecorder) LastMarketPrices() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LastMarketPrices", reflect.TypeOf((*MockMarketHistory)(nil).LastMarketPrices))
+}
+
+// GetMarketCandles mocks base method
+func (m *MockMarketHistory) GetMarketCandles(market string, interval time.Duration) ([]domain.MarketCandle, error) {
+	ret := m.ctrl.Call(m, "GetMarketCandles", market, interval)
+	ret0, _ := ret[0].([]domain.MarketCandle)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMarketCandles indicates an expected call of GetMarketCandles
+func (mr *MockMarketHistoryMockRecorder) GetMarketCandles(market, interval interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMarketCandles", reflect.TypeOf((*MockMarketHistory)(nil).GetMarketCandles), market, interval)
+}
+
+// MockMultiAccount is a mock of MultiAccount interface
+type MockMultiAccount struct {
+	ctrl     *gomock.Controller
+	recorder *MockMultiAccountMockRecorder
+}
+
+// MockMultiAccountMockRecorder is the mock recorder for MockMultiAccount
+type MockMultiAccountMockRecorder struct {
+	mock *MockMultiAccount
+}
+
+// NewMockMultiAccount creates a new mock instance
+func NewMockMultiAccount(ctrl *gomock.Controller) *MockMultiAccount {
+	mock := &MockMultiAccount{ctrl: ctrl}
+	mock.recorder = &MockMultiAccountMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockMultiAccount) EXPECT() *MockMultiAccountMockRecorder {
+	return m.recorder
+}
+
+// ExchangeTypes mocks base method
+func (m *MockMultiAccount) ExchangeTypes() []domain.ExchangeType {
+	ret := m.ctrl.Call(m, "ExchangeTypes")
+	ret0, _ := ret[0].([]domain.ExchangeType)
+	return ret0
+}
+
+// ExchangeTypes indicates an expected call of ExchangeTypes
+func (mr *MockMultiAccountMockRecorder) ExchangeTypes() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExchangeTypes", reflect.TypeOf((*MockMultiAccount)(nil).ExchangeTypes))
+}
+
+// MockTradeScanner is a mock of TradeScanner interface
+type MockTradeScanner struct {
+	ctrl     *gomock.Controller
+	recorder *MockTradeScannerMockRecorder
+}
+
+// MockTradeScannerMockRecorder is the mock recorder for MockTradeScanner
+type MockTradeScannerMockRecorder struct {
+	mock *MockTradeScanner
+}
+
+// NewMockTradeScanner creates a new mock instance
+func NewMockTradeScanner(ctrl *gomock.Controller) *MockTradeScanner {
+	mock := &MockTradeScanner{ctrl: ctrl}
+	mock.recorder = &MockTradeScannerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockTradeScanner) EXPECT() *MockTradeScannerMockRecorder {
+	return m.recorder
+}
+
+// ScanTrades mocks base method
+func (m *MockTradeScanner) ScanTrades() ([]domain.Trade, error) {
+	ret := m.ctrl.Call(m, "ScanTrades")
+	ret0, _ := ret[0].([]domain.Trade)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ScanTrades indicates an expected call of ScanTrades
+func (mr *MockTradeScannerMockRecorder) ScanTrades() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ScanTrades", reflect.TypeOf((*MockTradeScanner)(nil).ScanTrades))
+}
@@ -0,0 +1,273 @@
+package mongo
+
+import (
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage"
+)
+
+type alertStorage struct {
+	baseStorage
+}
+
+type alertState struct {
+	RuleID    string      `bson:"rule_id"`
+	LastFired time.Time   `bson:"last_fired"`
+	Peaks     []alertPeak `bson:"peaks,omitempty"`
+}
+
+// alertPeak is the peak of the position, position keys can't be field names as they may contain dots
+type alertPeak struct {
+	Position string  `bson:"position"`
+	Price    float64 `bson:"price"`
+}
+
+type alertRule struct {
+	ID       string        `bson:"_id"`
+	Type     string        `bson:"type"`
+	Market   string        `bson:"market,omitempty"`
+	Currency string        `bson:"currency,omitempty"`
+	Quote    string        `bson:"quote,omitempty"`
+	Value    float64       `bson:"value"`
+	Window   time.Duration `bson:"window,omitempty"`
+	Cooldown time.Duration `bson:"cooldown,omitempty"`
+	Channels []string      `bson:"channels,omitempty"`
+}
+
+type alertTrigger struct {
+	RuleID  string    `bson:"rule_id"`
+	Time    time.Time `bson:"time"`
+	Value   float64   `bson:"value"`
+	Message string    `bson:"message"`
+}
+
+func NewAlertStorage(session *mgo.Session, refreshSession bool) storage.AlertStorage {
+	return &alertStorage{
+		baseStorage{
+			baseSession:    session,
+			refreshSession: refreshSession,
+		},
+	}
+}
+
+func (s *alertStorage) Init() error {
+	db, closeSession := s.getDB()
+	defer closeSession()
+
+	err := db.C("alert_state").EnsureIndex(mgo.Index{
+		Name:       "rule_idx",
+		Key:        []string{"rule_id"},
+		Unique:     true,
+		Background: true,
+	})
+	if err != nil {
+		return err
+	}
+
+	return db.C("alert_trigger").EnsureIndex(mgo.Index{
+		Name:       "rule_time_idx",
+		Key:        []string{"rule_id", "-time"},
+		Background: true,
+	})
+}
+
+func (s *alertStorage) SaveState(state ...domain.AlertState) error {
+	if len(state) == 0 {
+		return nil
+	}
+
+	db, closeSession := s.getDB()
+	defer closeSession()
+
+	bulk := db.C("alert_state").Bulk()
+	bulk.Unordered()
+	for _, st := range state {
+		model := alertState{
+			RuleID:    st.RuleID,
+			LastFired: st.LastFired,
+		}
+		for position, price := range st.Peaks {
+			model.Peaks = append(model.Peaks, alertPeak{Position: position, Price: price})
+		}
+		bulk.Upsert(bson.M{"rule_id": st.RuleID}, model)
+	}
+
+	_, err := bulk.Run()
+	return err
+}
+
+func (s *alertStorage) FetchStates() ([]domain.AlertState, error) {
+	db, closeSession := s.getDB()
+	defer closeSession()
+
+	var states []alertState
+	err := db.C("alert_state").
+		Find(nil).
+		Sort("rule_id").
+		All(&states)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]domain.AlertState, len(states))
+	for i, st := range states {
+		result[i] = domain.AlertState{
+			RuleID:    st.RuleID,
+			LastFired: st.LastFired,
+		}
+		if len(st.Peaks) > 0 {
+			result[i].Peaks = make(map[string]float64, len(st.Peaks))
+			for _, peak := range st.Peaks {
+				result[i].Peaks[peak.Position] = peak.Price
+			}
+		}
+	}
+	return result, nil
+}
+
+func (s *alertStorage) SaveRule(rule domain.AlertRule) error {
+	db, closeSession := s.getDB()
+	defer closeSession()
+
+	_, err := db.C("alert_rule").UpsertId(rule.ID, convertAlertRuleFromModel(rule))
+	return err
+}
+
+func (s *alertStorage) FetchRule(id string) (*domain.AlertRule, error) {
+	db, closeSession := s.getDB()
+	defer closeSession()
+
+	var r alertRule
+	err := db.C("alert_rule").FindId(id).One(&r)
+	if err == mgo.ErrNotFound {
+		return nil, storage.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rule := convertAlertRuleToModel(r)
+	return &rule, nil
+}
+
+func (s *alertStorage) FetchRules() ([]domain.AlertRule, error) {
+	db, closeSession := s.getDB()
+	defer closeSession()
+
+	var rules []alertRule
+	err := db.C("alert_rule").
+		Find(nil).
+		Sort("_id").
+		All(&rules)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]domain.AlertRule, len(rules))
+	for i, r := range rules {
+		result[i] = convertAlertRuleToModel(r)
+	}
+	return result, nil
+}
+
+func (s *alertStorage) DeleteRule(id string) error {
+	db, closeSession := s.getDB()
+	defer closeSession()
+
+	err := db.C("alert_rule").RemoveId(id)
+	if err == mgo.ErrNotFound {
+		return storage.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = db.C("alert_state").RemoveAll(bson.M{"rule_id": id})
+	return err
+}
+
+func (s *alertStorage) SaveTrigger(trigger ...domain.AlertTrigger) error {
+	if len(trigger) == 0 {
+		return nil
+	}
+
+	db, closeSession := s.getDB()
+	defer closeSession()
+
+	bulk := db.C("alert_trigger").Bulk()
+	bulk.Unordered()
+	for _, t := range trigger {
+		bulk.Insert(alertTrigger{
+			RuleID:  t.RuleID,
+			Time:    t.Time,
+			Value:   t.Value,
+			Message: t.Message,
+		})
+	}
+
+	_, err := bulk.Run()
+	return err
+}
+
+func (s *alertStorage) FetchTriggers(ruleID string, limit int) ([]domain.AlertTrigger, error) {
+	db, closeSession := s.getDB()
+	defer closeSession()
+
+	query := bson.M{}
+	if ruleID != "" {
+		query["rule_id"] = ruleID
+	}
+
+	var triggers []alertTrigger
+	err := db.C("alert_trigger").
+		Find(query).
+		Sort("-time", "-_id").
+		Limit(limit).
+		All(&triggers)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]domain.AlertTrigger, len(triggers))
+	for i, t := range triggers {
+		result[i] = domain.AlertTrigger{
+			RuleID:  t.RuleID,
+			Time:    t.Time,
+			Value:   t.Value,
+			Message: t.Message,
+		}
+	}
+	return result, nil
+}
+
+func convertAlertRuleFromModel(r domain.AlertRule) alertRule {
+	return alertRule{
+		ID:       r.ID,
+		Type:     string(r.Type),
+		Market:   r.Market,
+		Currency: r.Currency,
+		Quote:    r.Quote,
+		Value:    r.Value,
+		Window:   r.Window,
+		Cooldown: r.Cooldown,
+		Channels: r.Channels,
+	}
+}
+
+func convertAlertRuleToModel(r alertRule) domain.AlertRule {
+	return domain.AlertRule{
+		ID:       r.ID,
+		Type:     domain.AlertType(r.Type),
+		Market:   r.Market,
+		Currency: r.Currency,
+		Quote:    r.Quote,
+		Value:    r.Value,
+		Window:   r.Window,
+		Cooldown: r.Cooldown,
+		Channels: r.Channels,
+	}
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/nawa/cryptoexchange-dashboard/domain"
 	"github.com/nawa/cryptoexchange-dashboard/storage"
+	"github.com/nawa/cryptoexchange-dashboard/storage/downsample"
 )
 
 type balanceStorage struct {
@@ -16,12 +17,33 @@ type balanceStorage struct {
 }
 
 type balance struct {
-	Exchange   string    `bson:"exchange"`
-	Currency   string    `bson:"currency"`
-	Amount     float64   `bson:"amount"`
-	BTCAmount  float64   `bson:"btc_amount"`
-	USDTAmount float64   `bson:"usdt_amount"`
-	Time       time.Time `bson:"time"`
+	Exchange      string             `bson:"exchange"`
+	Account       string             `bson:"account,omitempty"`
+	Currency      string             `bson:"currency"`
+	Amount        float64            `bson:"amount"`
+	BTCAmount     float64            `bson:"btc_amount"`
+	USDTAmount    float64            `bson:"usdt_amount"`
+	Fiat          map[string]float64 `bson:"fiat,omitempty"`
+	Reconstructed bool               `bson:"reconstructed,omitempty"`
+	Time          time.Time          `bson:"time"`
+}
+
+type balanceCandle struct {
+	Start           int64   `bson:"_id"`
+	AmountOpen      float64 `bson:"amount_open"`
+	AmountClose     float64 `bson:"amount_close"`
+	AmountMin       float64 `bson:"amount_min"`
+	AmountMax       float64 `bson:"amount_max"`
+	BTCAmountOpen   float64 `bson:"btc_amount_open"`
+	BTCAmountClose  float64 `bson:"btc_amount_close"`
+	BTCAmountMin    float64 `bson:"btc_amount_min"`
+	BTCAmountMax    float64 `bson:"btc_amount_max"`
+	USDTAmountOpen  float64 `bson:"usdt_amount_open"`
+	USDTAmountClose float64 `bson:"usdt_amount_close"`
+	USDTAmountMin   float64 `bson:"usdt_amount_min"`
+	USDTAmountMax   float64 `bson:"usdt_amount_max"`
+	// Fiat is fiat values of balances in the bucket sorted by time, aggregated into OHLC after fetching
+	Fiat []map[string]float64 `bson:"fiat"`
 }
 
 func NewBalanceStorage(session *mgo.Session, refreshSession bool) storage.BalanceStorage {
@@ -130,7 +152,9 @@ func (s *balanceStorage) FetchWeekly(currency string) ([]domain.Balance, error)
 	//             "time": {$first: "$time"},
 	//             "amount": {$first: "$amount"},
 	//             "btc_amount": {$first: "$btc_amount"},
-	//             "usdt_amount": {$first: "$usdt_amount"}
+	//             "usdt_amount": {$first: "$usdt_amount"},
+	//             "fiat": {$first: "$fiat"},
+	//             "reconstructed": {$first: "$reconstructed"}
 	//         }
 	//     },
 	//     { $sort : { time : -1}}
@@ -161,10 +185,12 @@ func (s *balanceStorage) FetchWeekly(currency string) ([]domain.Balance, error)
 						},
 					},
 				},
-				"time":        bson.M{"$first": "$time"},
-				"amount":      bson.M{"$first": "$amount"},
-				"btc_amount":  bson.M{"$first": "$btc_amount"},
-				"usdt_amount": bson.M{"$first": "$usdt_amount"},
+				"time":          bson.M{"$first": "$time"},
+				"amount":        bson.M{"$first": "$amount"},
+				"btc_amount":    bson.M{"$first": "$btc_amount"},
+				"usdt_amount":   bson.M{"$first": "$usdt_amount"},
+				"fiat":          bson.M{"$first": "$fiat"},
+				"reconstructed": bson.M{"$first": "$reconstructed"},
 			},
 		},
 		{"$sort": bson.M{"time": -1}},
@@ -204,7 +230,9 @@ func (s *balanceStorage) FetchMonthly(currency string) ([]domain.Balance, error)
 	//             "time": {$first: "$time"},
 	//             "amount": {$first: "$amount"},
 	//             "btc_amount": {$first: "$btc_amount"},
-	//             "usdt_amount": {$first: "$usdt_amount"}
+	//             "usdt_amount": {$first: "$usdt_amount"},
+	//             "fiat": {$first: "$fiat"},
+	//             "reconstructed": {$first: "$reconstructed"}
 	//         }
 	//     },
 	//     { $sort : { time : -1}}
@@ -229,10 +257,12 @@ func (s *balanceStorage) FetchMonthly(currency string) ([]domain.Balance, error)
 					"day":   bson.M{"$dayOfMonth": "$time"},
 					"hour":  bson.M{"$hour": "$time"},
 				},
-				"time":        bson.M{"$first": "$time"},
-				"amount":      bson.M{"$first": "$amount"},
-				"btc_amount":  bson.M{"$first": "$btc_amount"},
-				"usdt_amount": bson.M{"$first": "$usdt_amount"},
+				"time":          bson.M{"$first": "$time"},
+				"amount":        bson.M{"$first": "$amount"},
+				"btc_amount":    bson.M{"$first": "$btc_amount"},
+				"usdt_amount":   bson.M{"$first": "$usdt_amount"},
+				"fiat":          bson.M{"$first": "$fiat"},
+				"reconstructed": bson.M{"$first": "$reconstructed"},
 			},
 		},
 		{"$sort": bson.M{"time": -1}},
@@ -251,7 +281,161 @@ func (s *balanceStorage) FetchMonthly(currency string) ([]domain.Balance, error)
 }
 
 func (s *balanceStorage) FetchAll(currency string) ([]domain.Balance, error) {
-	panic("not implemented")
+	db, closeSession := s.getDB()
+	defer closeSession()
+
+	// 	db.balance.aggregate(
+	//     {
+	//         $match: {
+	//             "currency": "total"
+	//         }
+	//     },
+	//     { $sort : { time : 1}},
+	//     {
+	//         $group: {
+	//             "_id" : {
+	//                 $cond: [
+	//                     { $gte: ["$time", new Date((new Date().getTime() - (365 * 24 * 60 * 60 * 1000)))] },
+	//                     { "year": {"$year":"$time"}, "month": {"$month":"$time"}, "day": {"$dayOfMonth":"$time"} },
+	//                     { "year": {"$isoWeekYear":"$time"}, "week": {"$isoWeek":"$time"} }
+	//                 ]
+	//             },
+	//             "time": {$first: "$time"},
+	//             "amount": {$first: "$amount"},
+	//             "btc_amount": {$first: "$btc_amount"},
+	//             "usdt_amount": {$first: "$usdt_amount"},
+	//             "fiat": {$first: "$fiat"},
+	//             "reconstructed": {$first: "$reconstructed"}
+	//         }
+	//     },
+	//     { $sort : { time : -1}}
+	// )
+
+	dailyPeriod := time.Now().Add(-storage.FetchAllDailyPeriod)
+
+	pipe := db.C("balance").Pipe([]bson.M{
+		{
+			"$match": bson.M{
+				"currency": currency,
+			},
+		},
+		{"$sort": bson.M{"time": 1}},
+		{
+			"$group": bson.M{
+				"_id": bson.M{
+					"$cond": []interface{}{
+						bson.M{"$gte": []interface{}{"$time", dailyPeriod}},
+						bson.M{
+							"year":  bson.M{"$year": "$time"},
+							"month": bson.M{"$month": "$time"},
+							"day":   bson.M{"$dayOfMonth": "$time"},
+						},
+						bson.M{
+							"year": bson.M{"$isoWeekYear": "$time"},
+							"week": bson.M{"$isoWeek": "$time"},
+						},
+					},
+				},
+				"time":          bson.M{"$first": "$time"},
+				"amount":        bson.M{"$first": "$amount"},
+				"btc_amount":    bson.M{"$first": "$btc_amount"},
+				"usdt_amount":   bson.M{"$first": "$usdt_amount"},
+				"fiat":          bson.M{"$first": "$fiat"},
+				"reconstructed": bson.M{"$first": "$reconstructed"},
+			},
+		},
+		{"$sort": bson.M{"time": -1}},
+	}).AllowDiskUse()
+
+	var balances []balance
+
+	err := pipe.
+		All(&balances)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return convertBalancesToModel(balances...), nil
+}
+
+func (s *balanceStorage) FetchRange(currency string, from, to time.Time, resolution time.Duration) ([]domain.BalanceCandle, error) {
+	if resolution < time.Millisecond {
+		return nil, errors.Errorf("resolution %s is less than 1ms", resolution)
+	}
+
+	db, closeSession := s.getDB()
+	defer closeSession()
+
+	// 	db.balance.aggregate(
+	//     {
+	//         $match: {
+	//             "time": { $gte: ISODate("2018-02-05T00:00:00Z"), $lt: ISODate("2018-02-07T00:00:00Z") },
+	//             "currency": "total"
+	//         }
+	//     },
+	//     { $sort : { time : 1}},
+	//     {
+	//         $group: {
+	//             "_id" : {
+	//                 "$subtract": [
+	//                     { "$subtract": ["$time", new Date(0)] },
+	//                     { "$mod": [{ "$subtract": ["$time", new Date(0)] }, 15 * 60 * 1000] }
+	//                 ]
+	//             },
+	//             "amount_open": {$first: "$amount"},
+	//             "amount_close": {$last: "$amount"},
+	//             "amount_min": {$min: "$amount"},
+	//             "amount_max": {$max: "$amount"},
+	//             ...the same for btc_amount and usdt_amount
+	//             "fiat": {$push: "$fiat"}
+	//         }
+	//     },
+	//     { $sort : { _id : -1}}
+	// )
+
+	epochMillis := bson.M{"$subtract": []interface{}{"$time", time.Unix(0, 0)}}
+	group := bson.M{
+		"_id": bson.M{
+			"$subtract": []interface{}{
+				epochMillis,
+				bson.M{"$mod": []interface{}{epochMillis, int64(resolution / time.Millisecond)}},
+			},
+		},
+	}
+	for _, field := range []string{"amount", "btc_amount", "usdt_amount"} {
+		group[field+"_open"] = bson.M{"$first": "$" + field}
+		group[field+"_close"] = bson.M{"$last": "$" + field}
+		group[field+"_min"] = bson.M{"$min": "$" + field}
+		group[field+"_max"] = bson.M{"$max": "$" + field}
+	}
+	group["fiat"] = bson.M{"$push": "$fiat"}
+
+	pipe := db.C("balance").Pipe([]bson.M{
+		{
+			"$match": bson.M{
+				"time": bson.M{
+					"$gte": from,
+					"$lt":  to,
+				},
+				"currency": currency,
+			},
+		},
+		{"$sort": bson.M{"time": 1}},
+		{"$group": group},
+		{"$sort": bson.M{"_id": -1}},
+	}).AllowDiskUse()
+
+	var candles []balanceCandle
+
+	err := pipe.
+		All(&candles)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return convertBalanceCandlesToModel(currency, candles...), nil
 }
 
 func (s *balanceStorage) GetActiveCurrencies() ([]domain.Balance, error) {
@@ -295,15 +479,48 @@ func (s *balanceStorage) GetActiveCurrencies() ([]domain.Balance, error) {
 	return convertBalancesToModel(balances...), nil
 }
 
+func (s *balanceStorage) FetchSnapshot(at time.Time) ([]domain.Balance, error) {
+	db, closeSession := s.getDB()
+	defer closeSession()
+
+	var first []balance
+	err := db.C("balance").
+		Find(bson.M{"time": bson.M{"$gte": at}}).
+		Sort("time").
+		Limit(1).
+		All(&first)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(first) == 0 {
+		return nil, nil
+	}
+
+	var balances []balance
+	err = db.C("balance").
+		Find(bson.M{"time": first[0].Time}).
+		Sort("currency").
+		All(&balances)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertBalancesToModel(balances...), nil
+}
+
 func convertBalancesFromModel(balances ...domain.Balance) (result []interface{}) {
 	for _, b := range balances {
 		result = append(result, balance{
-			Exchange:   string(b.Exchange),
-			Currency:   b.Currency,
-			Amount:     b.Amount,
-			BTCAmount:  b.BTCAmount,
-			USDTAmount: b.USDTAmount,
-			Time:       b.Time,
+			Exchange:      string(b.Exchange),
+			Account:       b.Account,
+			Currency:      b.Currency,
+			Amount:        b.Amount,
+			BTCAmount:     b.BTCAmount,
+			USDTAmount:    b.USDTAmount,
+			Fiat:          b.Fiat,
+			Time:          b.Time,
+			Reconstructed: b.Reconstructed,
 		})
 	}
 	return result
@@ -312,12 +529,44 @@ func convertBalancesFromModel(balances ...domain.Balance) (result []interface{})
 func convertBalancesToModel(balances ...balance) (result []domain.Balance) {
 	for _, b := range balances {
 		result = append(result, domain.Balance{
-			Exchange:   domain.ExchangeType(b.Exchange),
-			Currency:   b.Currency,
-			Amount:     b.Amount,
-			BTCAmount:  b.BTCAmount,
-			USDTAmount: b.USDTAmount,
-			Time:       b.Time,
+			Exchange:      domain.ExchangeType(b.Exchange),
+			Account:       b.Account,
+			Currency:      b.Currency,
+			Amount:        b.Amount,
+			BTCAmount:     b.BTCAmount,
+			USDTAmount:    b.USDTAmount,
+			Fiat:          b.Fiat,
+			Time:          b.Time,
+			Reconstructed: b.Reconstructed,
+		})
+	}
+	return result
+}
+
+func convertBalanceCandlesToModel(currency string, candles ...balanceCandle) (result []domain.BalanceCandle) {
+	for _, c := range candles {
+		result = append(result, domain.BalanceCandle{
+			Currency: currency,
+			Time:     time.Unix(0, c.Start*int64(time.Millisecond)).UTC(),
+			Amount: domain.OHLC{
+				Open:  c.AmountOpen,
+				Close: c.AmountClose,
+				Min:   c.AmountMin,
+				Max:   c.AmountMax,
+			},
+			BTCAmount: domain.OHLC{
+				Open:  c.BTCAmountOpen,
+				Close: c.BTCAmountClose,
+				Min:   c.BTCAmountMin,
+				Max:   c.BTCAmountMax,
+			},
+			USDTAmount: domain.OHLC{
+				Open:  c.USDTAmountOpen,
+				Close: c.USDTAmountClose,
+				Min:   c.USDTAmountMin,
+				Max:   c.USDTAmountMax,
+			},
+			Fiat: downsample.Fiat(c.Fiat),
 		})
 	}
 	return result
@@ -10,18 +10,21 @@ import (
 
 	"github.com/globalsign/mgo"
 	"github.com/globalsign/mgo/bson"
-	"github.com/nawa/cryptoexchange-dashboard/storage/mongo/testdata"
-	assert "github.com/stretchr/testify/require"
 
 	"github.com/nawa/cryptoexchange-dashboard/storage"
 	"github.com/nawa/cryptoexchange-dashboard/storage/mongo"
+	"github.com/nawa/cryptoexchange-dashboard/storage/testdata"
 )
 
 const EnvDbTestURL = "DB_TEST_URL"
 
 var (
-	session        *mgo.Session
-	balanceStorage storage.BalanceStorage
+	session         *mgo.Session
+	balanceStorage  storage.BalanceStorage
+	tradeStorage    storage.TradeStorage
+	transferStorage storage.TransferStorage
+	alertStorage    storage.AlertStorage
+	marketStorage   storage.MarketStorage
 )
 
 func TestMain(m *testing.M) {
@@ -48,6 +51,30 @@ func TestMain(m *testing.M) {
 		log.Fatalf("can't instantiate balanceStorage: %s", err.Error())
 	}
 
+	tradeStorage = mongo.NewTradeStorage(session, true)
+	err = tradeStorage.Init()
+	if err != nil {
+		log.Fatalf("can't instantiate tradeStorage: %s", err.Error())
+	}
+
+	transferStorage = mongo.NewTransferStorage(session, true)
+	err = transferStorage.Init()
+	if err != nil {
+		log.Fatalf("can't instantiate transferStorage: %s", err.Error())
+	}
+
+	alertStorage = mongo.NewAlertStorage(session, true)
+	err = alertStorage.Init()
+	if err != nil {
+		log.Fatalf("can't instantiate alertStorage: %s", err.Error())
+	}
+
+	marketStorage = mongo.NewMarketStorage(session, true)
+	err = marketStorage.Init()
+	if err != nil {
+		log.Fatalf("can't instantiate marketStorage: %s", err.Error())
+	}
+
 	err = cleanupData(session)
 	if err != nil {
 		log.Fatalf("can't cleanup data before tests: %s", err.Error())
@@ -58,136 +85,103 @@ func TestMain(m *testing.M) {
 	session.DB("").
 		C("balance").
 		DropCollection()
+	session.DB("").
+		C("trade").
+		DropCollection()
+	session.DB("").
+		C("transfer").
+		DropCollection()
+	session.DB("").
+		C("alert_state").
+		DropCollection()
+	session.DB("").
+		C("alert_rule").
+		DropCollection()
+	session.DB("").
+		C("alert_trigger").
+		DropCollection()
+	session.DB("").
+		C("market_candle").
+		DropCollection()
 
 	os.Exit(code)
 }
 
-func TestBalanceStorage_FetchAll(t *testing.T) {
-	defer func() {
-		err := recover()
-		assert.Equal(t, "not implemented", err)
-	}()
-	balanceStorage.FetchAll("USDT")
+func TestBalanceStorage(t *testing.T) {
+	testdata.RunBalanceStorageSuite(t, balanceStorage, func() error {
+		return cleanupData(session)
+	})
 }
 
-func TestBalanceStorage_FetchHourly(t *testing.T) {
-	assert.NoError(t, cleanupData(session))
-
-	now := time.Now()
-	balances := testdata.Balances()
-	balances[0].Time = now.Add(-5 * time.Hour)
-	balances[1].Time = now.Add(-1 * time.Hour)
-	balances[2].Time = now
-	balances[3].Time = now
-	balances[4].Time = now.Add(-5 * time.Hour)
-	balances[5].Time = now.Add(-1 * time.Hour)
-
-	for _, balance := range balances {
-		err := balanceStorage.Save(balance)
-		assert.NoError(t, err)
-	}
-
-	storageBalances, err := balanceStorage.FetchHourly("total", 2)
-	assert.NoError(t, err)
-	assert.Len(t, storageBalances, 2)
-
-	storageBalances, err = balanceStorage.FetchHourly("CUR1", 2)
-	assert.NoError(t, err)
-	assert.Len(t, storageBalances, 1)
-
-	storageBalances, err = balanceStorage.FetchHourly("CUR2", 2)
-	assert.NoError(t, err)
-	assert.Len(t, storageBalances, 1)
+func TestTradeStorage(t *testing.T) {
+	testdata.RunTradeStorageSuite(t, tradeStorage, func() error {
+		return cleanupData(session)
+	})
 }
 
-func TestBalanceStorage_FetchWeekly(t *testing.T) {
-	assert.NoError(t, cleanupData(session))
-
-	now := time.Now()
-	balances := testdata.Balances()
-	balances[0].Time = now.Add(-10 * 24 * time.Hour)
-	balances[1].Time = now.Add(-1 * 24 * time.Hour)
-	balances[2].Time = now
-	balances[3].Time = now
-	balances[4].Time = now.Add(-10 * 24 * time.Hour)
-	balances[5].Time = now.Add(-1 * 24 * time.Hour)
-
-	for _, balance := range balances {
-		err := balanceStorage.Save(balance)
-		assert.NoError(t, err)
-	}
-
-	storageBalances, err := balanceStorage.FetchWeekly("total")
-	assert.NoError(t, err)
-	assert.Len(t, storageBalances, 2)
+func TestTransferStorage(t *testing.T) {
+	testdata.RunTransferStorageSuite(t, transferStorage, func() error {
+		return cleanupData(session)
+	})
+}
 
-	storageBalances, err = balanceStorage.FetchWeekly("CUR1")
-	assert.NoError(t, err)
-	assert.Len(t, storageBalances, 1)
+func TestAlertStorage(t *testing.T) {
+	testdata.RunAlertStorageSuite(t, alertStorage, func() error {
+		return cleanupData(session)
+	})
+}
 
-	storageBalances, err = balanceStorage.FetchWeekly("CUR2")
-	assert.NoError(t, err)
-	assert.Len(t, storageBalances, 1)
+func TestMarketStorage(t *testing.T) {
+	testdata.RunMarketStorageSuite(t, marketStorage, func() error {
+		return cleanupData(session)
+	})
 }
 
-func TestBalanceStorage_FetchMonthly(t *testing.T) {
-	assert.NoError(t, cleanupData(session))
-
-	now := time.Now()
-	balances := testdata.Balances()
-	balances[0].Time = now.Add(-32 * 24 * time.Hour)
-	balances[1].Time = now.Add(-10 * 24 * time.Hour)
-	balances[2].Time = now
-	balances[3].Time = now
-	balances[4].Time = now.Add(-32 * 24 * time.Hour)
-	balances[5].Time = now.Add(-10 * 24 * time.Hour)
-
-	for _, balance := range balances {
-		err := balanceStorage.Save(balance)
-		assert.NoError(t, err)
+func cleanupData(session *mgo.Session) error {
+	_, err := session.DB("").
+		C("balance").
+		RemoveAll(bson.M{})
+	if err != nil {
+		return err
 	}
 
-	storageBalances, err := balanceStorage.FetchMonthly("total")
-	assert.NoError(t, err)
-	assert.Len(t, storageBalances, 2)
+	_, err = session.DB("").
+		C("trade").
+		RemoveAll(bson.M{})
+	if err != nil {
+		return err
+	}
 
-	storageBalances, err = balanceStorage.FetchMonthly("CUR1")
-	assert.NoError(t, err)
-	assert.Len(t, storageBalances, 1)
+	_, err = session.DB("").
+		C("transfer").
+		RemoveAll(bson.M{})
+	if err != nil {
+		return err
+	}
 
-	storageBalances, err = balanceStorage.FetchMonthly("CUR2")
-	assert.NoError(t, err)
-	assert.Len(t, storageBalances, 1)
-}
+	_, err = session.DB("").
+		C("alert_state").
+		RemoveAll(bson.M{})
+	if err != nil {
+		return err
+	}
 
-func TestBalanceStorage_GetActiveCurrencies(t *testing.T) {
-	assert.NoError(t, cleanupData(session))
-
-	now := time.Now()
-	balances := testdata.Balances()
-	balances[0].Time = now
-	balances[1].Time = now
-	balances[2].Time = now
-	balances[3].Time = now.Add(-10 * 24 * time.Hour)
-	balances[4].Time = now.Add(-10 * 24 * time.Hour)
-	balances[5].Time = now.Add(-10 * 24 * time.Hour)
-
-	for _, balance := range balances {
-		err := balanceStorage.Save(balance)
-		assert.NoError(t, err)
+	_, err = session.DB("").
+		C("alert_rule").
+		RemoveAll(bson.M{})
+	if err != nil {
+		return err
 	}
 
-	storageBalances, err := balanceStorage.GetActiveCurrencies()
-	assert.NoError(t, err)
-	assert.Len(t, storageBalances, 3)
-	assert.Equal(t, now.Truncate(time.Millisecond).UTC(), storageBalances[0].Time.Truncate(time.Millisecond).UTC())
-	assert.Equal(t, now.Truncate(time.Millisecond).UTC(), storageBalances[1].Time.Truncate(time.Millisecond).UTC())
-	assert.Equal(t, now.Truncate(time.Millisecond).UTC(), storageBalances[2].Time.Truncate(time.Millisecond).UTC())
-}
+	_, err = session.DB("").
+		C("alert_trigger").
+		RemoveAll(bson.M{})
+	if err != nil {
+		return err
+	}
 
-func cleanupData(session *mgo.Session) error {
-	_, err := session.DB("").
-		C("balance").
+	_, err = session.DB("").
+		C("market_candle").
 		RemoveAll(bson.M{})
 
 	return err
@@ -0,0 +1,149 @@
+package mongo
+
+import (
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/pkg/errors"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage"
+)
+
+type marketStorage struct {
+	baseStorage
+}
+
+type marketCandle struct {
+	Exchange string    `bson:"exchange"`
+	Market   string    `bson:"market"`
+	Time     time.Time `bson:"time"`
+	Open     float64   `bson:"open"`
+	Close    float64   `bson:"close"`
+	Min      float64   `bson:"min"`
+	Max      float64   `bson:"max"`
+}
+
+type marketBucket struct {
+	Start int64   `bson:"_id"`
+	Open  float64 `bson:"open"`
+	Close float64 `bson:"close"`
+	Min   float64 `bson:"min"`
+	Max   float64 `bson:"max"`
+}
+
+func NewMarketStorage(session *mgo.Session, refreshSession bool) storage.MarketStorage {
+	return &marketStorage{
+		baseStorage{
+			baseSession:    session,
+			refreshSession: refreshSession,
+		},
+	}
+}
+
+func (s *marketStorage) Init() error {
+	db, closeSession := s.getDB()
+	defer closeSession()
+
+	return db.C("market_candle").EnsureIndex(mgo.Index{
+		Name:       "market_time_exchange_idx",
+		Key:        []string{"market", "-time", "exchange"},
+		Unique:     true,
+		Background: true,
+	})
+}
+
+func (s *marketStorage) Save(candle ...domain.MarketCandle) error {
+	if len(candle) == 0 {
+		return nil
+	}
+
+	db, closeSession := s.getDB()
+	defer closeSession()
+
+	bulk := db.C("market_candle").Bulk()
+	bulk.Unordered()
+	for _, c := range candle {
+		bulk.Upsert(bson.M{"market": c.Market, "time": c.Time, "exchange": string(c.Exchange)}, convertMarketCandleFromModel(c))
+	}
+
+	_, err := bulk.Run()
+	return err
+}
+
+func (s *marketStorage) FetchCandles(exchange domain.ExchangeType, market string, from, to time.Time, resolution time.Duration) ([]domain.MarketCandle, error) {
+	if resolution < time.Millisecond {
+		return nil, errors.Errorf("resolution %s is less than 1ms", resolution)
+	}
+
+	db, closeSession := s.getDB()
+	defer closeSession()
+
+	// the same grouping as balance ranges, see balanceStorage.FetchRange
+	match := bson.M{
+		"time": bson.M{
+			"$gte": from,
+			"$lt":  to,
+		},
+		"market": market,
+	}
+	if exchange != "" {
+		match["exchange"] = string(exchange)
+	}
+
+	epochMillis := bson.M{"$subtract": []interface{}{"$time", time.Unix(0, 0)}}
+	pipe := db.C("market_candle").Pipe([]bson.M{
+		{"$match": match},
+		{"$sort": bson.M{"time": 1}},
+		{
+			"$group": bson.M{
+				"_id": bson.M{
+					"$subtract": []interface{}{
+						epochMillis,
+						bson.M{"$mod": []interface{}{epochMillis, int64(resolution / time.Millisecond)}},
+					},
+				},
+				"open":  bson.M{"$first": "$open"},
+				"close": bson.M{"$last": "$close"},
+				"min":   bson.M{"$min": "$min"},
+				"max":   bson.M{"$max": "$max"},
+			},
+		},
+		{"$sort": bson.M{"_id": -1}},
+	}).AllowDiskUse()
+
+	var buckets []marketBucket
+	err := pipe.All(&buckets)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]domain.MarketCandle, len(buckets))
+	for i, b := range buckets {
+		result[i] = domain.MarketCandle{
+			Exchange: exchange,
+			Market:   market,
+			Time:     time.Unix(0, b.Start*int64(time.Millisecond)).UTC(),
+			Price: domain.OHLC{
+				Open:  b.Open,
+				Close: b.Close,
+				Min:   b.Min,
+				Max:   b.Max,
+			},
+		}
+	}
+	return result, nil
+}
+
+func convertMarketCandleFromModel(c domain.MarketCandle) marketCandle {
+	return marketCandle{
+		Exchange: string(c.Exchange),
+		Market:   c.Market,
+		Time:     c.Time,
+		Open:     c.Price.Open,
+		Close:    c.Price.Close,
+		Min:      c.Price.Min,
+		Max:      c.Price.Max,
+	}
+}
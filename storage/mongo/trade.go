@@ -0,0 +1,140 @@
+package mongo
+
+import (
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage"
+)
+
+type tradeStorage struct {
+	baseStorage
+}
+
+type trade struct {
+	Exchange string    `bson:"exchange"`
+	Account  string    `bson:"account,omitempty"`
+	OrderID  string    `bson:"order_id"`
+	Market   string    `bson:"market"`
+	Type     string    `bson:"type"`
+	Time     time.Time `bson:"time"`
+	Amount   float64   `bson:"amount"`
+	Rate     float64   `bson:"rate"`
+	Fee      float64   `bson:"fee"`
+	BTCRate  float64   `bson:"btc_rate"`
+	USDTRate float64   `bson:"usdt_rate"`
+}
+
+func NewTradeStorage(session *mgo.Session, refreshSession bool) storage.TradeStorage {
+	return &tradeStorage{
+		baseStorage{
+			baseSession:    session,
+			refreshSession: refreshSession,
+		},
+	}
+}
+
+func (s *tradeStorage) Init() error {
+	db, closeSession := s.getDB()
+	defer closeSession()
+
+	c := db.C("trade")
+	err := c.EnsureIndex(mgo.Index{
+		Name:       "exchange_order_idx",
+		Key:        []string{"exchange", "order_id"},
+		Unique:     true,
+		Background: true,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	err = c.EnsureIndex(mgo.Index{
+		Name:       "time_idx",
+		Key:        []string{"-time"},
+		Unique:     false,
+		Background: true,
+	})
+
+	return err
+}
+
+func (s *tradeStorage) Save(trade ...domain.Trade) error {
+	if len(trade) == 0 {
+		return nil
+	}
+
+	db, closeSession := s.getDB()
+	defer closeSession()
+
+	bulk := db.C("trade").Bulk()
+	bulk.Unordered()
+	for _, t := range trade {
+		// rates are set only by the first save
+		bulk.Upsert(bson.M{"exchange": string(t.Exchange), "order_id": t.OrderID}, bson.M{
+			"$set": bson.M{
+				"account": t.Account,
+				"market":  t.Market,
+				"type":    string(t.Type),
+				"time":    t.Time,
+				"amount":  t.Amount,
+				"rate":    t.Rate,
+				"fee":     t.Fee,
+			},
+			"$setOnInsert": bson.M{
+				"btc_rate":  t.BTCRate,
+				"usdt_rate": t.USDTRate,
+			},
+		})
+	}
+
+	_, err := bulk.Run()
+	return err
+}
+
+func (s *tradeStorage) Fetch(from, to time.Time) ([]domain.Trade, error) {
+	db, closeSession := s.getDB()
+	defer closeSession()
+
+	period := bson.M{
+		"$gte": from,
+	}
+	if !to.IsZero() {
+		period["$lt"] = to
+	}
+
+	var trades []trade
+	err := db.C("trade").
+		Find(bson.M{"time": period}).
+		Sort("-time").
+		All(&trades)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertTradesToModel(trades...), nil
+}
+
+func convertTradesToModel(trades ...trade) []domain.Trade {
+	result := make([]domain.Trade, len(trades))
+	for i, t := range trades {
+		result[i] = domain.Trade{
+			Exchange: domain.ExchangeType(t.Exchange),
+			Account:  t.Account,
+			OrderID:  t.OrderID,
+			Market:   t.Market,
+			Type:     domain.TradeType(t.Type),
+			Time:     t.Time,
+			Amount:   t.Amount,
+			Rate:     t.Rate,
+			Fee:      t.Fee,
+			BTCRate:  t.BTCRate,
+			USDTRate: t.USDTRate,
+		}
+	}
+	return result
+}
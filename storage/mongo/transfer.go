@@ -0,0 +1,137 @@
+package mongo
+
+import (
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage"
+)
+
+type transferStorage struct {
+	baseStorage
+}
+
+type transfer struct {
+	Exchange string    `bson:"exchange"`
+	Account  string    `bson:"account,omitempty"`
+	ID       string    `bson:"transfer_id"`
+	Type     string    `bson:"type"`
+	Currency string    `bson:"currency"`
+	Time     time.Time `bson:"time"`
+	Amount   float64   `bson:"amount"`
+	Fee      float64   `bson:"fee"`
+	BTCRate  float64   `bson:"btc_rate"`
+	USDTRate float64   `bson:"usdt_rate"`
+}
+
+func NewTransferStorage(session *mgo.Session, refreshSession bool) storage.TransferStorage {
+	return &transferStorage{
+		baseStorage{
+			baseSession:    session,
+			refreshSession: refreshSession,
+		},
+	}
+}
+
+func (s *transferStorage) Init() error {
+	db, closeSession := s.getDB()
+	defer closeSession()
+
+	c := db.C("transfer")
+	err := c.EnsureIndex(mgo.Index{
+		Name:       "exchange_transfer_idx",
+		Key:        []string{"exchange", "transfer_id"},
+		Unique:     true,
+		Background: true,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	err = c.EnsureIndex(mgo.Index{
+		Name:       "time_idx",
+		Key:        []string{"-time"},
+		Unique:     false,
+		Background: true,
+	})
+
+	return err
+}
+
+func (s *transferStorage) Save(transfer ...domain.Transfer) error {
+	if len(transfer) == 0 {
+		return nil
+	}
+
+	db, closeSession := s.getDB()
+	defer closeSession()
+
+	bulk := db.C("transfer").Bulk()
+	bulk.Unordered()
+	for _, t := range transfer {
+		// rates are set only by the first save
+		bulk.Upsert(bson.M{"exchange": string(t.Exchange), "transfer_id": t.ID}, bson.M{
+			"$set": bson.M{
+				"account":  t.Account,
+				"type":     string(t.Type),
+				"currency": t.Currency,
+				"time":     t.Time,
+				"amount":   t.Amount,
+				"fee":      t.Fee,
+			},
+			"$setOnInsert": bson.M{
+				"btc_rate":  t.BTCRate,
+				"usdt_rate": t.USDTRate,
+			},
+		})
+	}
+
+	_, err := bulk.Run()
+	return err
+}
+
+func (s *transferStorage) Fetch(from, to time.Time) ([]domain.Transfer, error) {
+	db, closeSession := s.getDB()
+	defer closeSession()
+
+	period := bson.M{
+		"$gte": from,
+	}
+	if !to.IsZero() {
+		period["$lt"] = to
+	}
+
+	var transfers []transfer
+	err := db.C("transfer").
+		Find(bson.M{"time": period}).
+		Sort("-time").
+		All(&transfers)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertTransfersToModel(transfers...), nil
+}
+
+func convertTransfersToModel(transfers ...transfer) []domain.Transfer {
+	result := make([]domain.Transfer, len(transfers))
+	for i, t := range transfers {
+		result[i] = domain.Transfer{
+			Exchange: domain.ExchangeType(t.Exchange),
+			Account:  t.Account,
+			ID:       t.ID,
+			Type:     domain.TransferType(t.Type),
+			Currency: t.Currency,
+			Time:     t.Time,
+			Amount:   t.Amount,
+			Fee:      t.Fee,
+			BTCRate:  t.BTCRate,
+			USDTRate: t.USDTRate,
+		}
+	}
+	return result
+}
@@ -0,0 +1,291 @@
+package postgres
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage"
+)
+
+type alertStorage struct {
+	db *sql.DB
+}
+
+func NewAlertStorage(db *sql.DB) storage.AlertStorage {
+	return &alertStorage{
+		db: db,
+	}
+}
+
+// Init creates alert rule, state and trigger tables
+func (s *alertStorage) Init() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS alert_state (
+			rule_id    TEXT        PRIMARY KEY,
+			last_fired TIMESTAMPTZ NOT NULL,
+			peaks      JSONB
+		)`)
+	if err != nil {
+		return err
+	}
+
+	// peaks of trailing stop rules by position keys, the column is added to tables created before
+	_, err = s.db.Exec(`ALTER TABLE alert_state ADD COLUMN IF NOT EXISTS peaks JSONB`)
+	if err != nil {
+		return err
+	}
+
+	// channels are the JSON array of notifier names
+	_, err = s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS alert_rule (
+			id          TEXT             PRIMARY KEY,
+			type        TEXT             NOT NULL,
+			market      TEXT             NOT NULL,
+			currency    TEXT             NOT NULL,
+			quote       TEXT             NOT NULL,
+			value       DOUBLE PRECISION NOT NULL,
+			window_ns   BIGINT           NOT NULL,
+			cooldown_ns BIGINT           NOT NULL,
+			channels    TEXT             NOT NULL
+		)`)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS alert_trigger (
+			id      BIGSERIAL        PRIMARY KEY,
+			rule_id TEXT             NOT NULL,
+			time    TIMESTAMPTZ      NOT NULL,
+			value   DOUBLE PRECISION NOT NULL,
+			message TEXT             NOT NULL
+		)`)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`CREATE INDEX IF NOT EXISTS alert_trigger_rule_idx ON alert_trigger (rule_id, time DESC)`)
+	return err
+}
+
+func (s *alertStorage) SaveState(state ...domain.AlertState) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO alert_state (rule_id, last_fired, peaks) VALUES ($1, $2, $3)
+		ON CONFLICT (rule_id) DO UPDATE SET last_fired = EXCLUDED.last_fired, peaks = EXCLUDED.peaks`)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for _, st := range state {
+		// NULL for states without peaks
+		var peaks sql.NullString
+		if len(st.Peaks) > 0 {
+			data, err := json.Marshal(st.Peaks)
+			if err != nil {
+				_ = tx.Rollback()
+				return err
+			}
+			peaks = sql.NullString{String: string(data), Valid: true}
+		}
+
+		_, err = stmt.Exec(st.RuleID, st.LastFired, peaks)
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *alertStorage) FetchStates() ([]domain.AlertState, error) {
+	rows, err := s.db.Query(`SELECT rule_id, last_fired, peaks FROM alert_state ORDER BY rule_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []domain.AlertState{}
+	for rows.Next() {
+		var (
+			st    domain.AlertState
+			peaks []byte
+		)
+		err = rows.Scan(&st.RuleID, &st.LastFired, &peaks)
+		if err != nil {
+			return nil, err
+		}
+		if peaks != nil {
+			err = json.Unmarshal(peaks, &st.Peaks)
+			if err != nil {
+				return nil, err
+			}
+		}
+		result = append(result, st)
+	}
+
+	return result, rows.Err()
+}
+
+func (s *alertStorage) SaveRule(rule domain.AlertRule) error {
+	channels, err := json.Marshal(rule.Channels)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`
+		INSERT INTO alert_rule (id, type, market, currency, quote, value, window_ns, cooldown_ns, channels)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (id) DO UPDATE SET
+			type = EXCLUDED.type, market = EXCLUDED.market, currency = EXCLUDED.currency, quote = EXCLUDED.quote,
+			value = EXCLUDED.value, window_ns = EXCLUDED.window_ns, cooldown_ns = EXCLUDED.cooldown_ns,
+			channels = EXCLUDED.channels`,
+		rule.ID, string(rule.Type), rule.Market, rule.Currency, rule.Quote, rule.Value,
+		int64(rule.Window), int64(rule.Cooldown), string(channels))
+	return err
+}
+
+const selectAlertRule = `SELECT id, type, market, currency, quote, value, window_ns, cooldown_ns, channels FROM alert_rule`
+
+func (s *alertStorage) FetchRule(id string) (*domain.AlertRule, error) {
+	rows, err := s.db.Query(selectAlertRule+` WHERE id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := scanAlertRules(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rules) == 0 {
+		return nil, storage.ErrNotFound
+	}
+	return &rules[0], nil
+}
+
+func (s *alertStorage) FetchRules() ([]domain.AlertRule, error) {
+	rows, err := s.db.Query(selectAlertRule + ` ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	return scanAlertRules(rows)
+}
+
+func scanAlertRules(rows *sql.Rows) ([]domain.AlertRule, error) {
+	defer rows.Close()
+
+	result := []domain.AlertRule{}
+	for rows.Next() {
+		var (
+			rule             domain.AlertRule
+			ruleType         string
+			window, cooldown int64
+			channels         string
+		)
+		err := rows.Scan(&rule.ID, &ruleType, &rule.Market, &rule.Currency, &rule.Quote, &rule.Value, &window, &cooldown, &channels)
+		if err != nil {
+			return nil, err
+		}
+
+		err = json.Unmarshal([]byte(channels), &rule.Channels)
+		if err != nil {
+			return nil, err
+		}
+		if len(rule.Channels) == 0 {
+			rule.Channels = nil
+		}
+		rule.Type = domain.AlertType(ruleType)
+		rule.Window = time.Duration(window)
+		rule.Cooldown = time.Duration(cooldown)
+		result = append(result, rule)
+	}
+
+	return result, rows.Err()
+}
+
+func (s *alertStorage) DeleteRule(id string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(`DELETE FROM alert_rule WHERE id = $1`, id)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if deleted == 0 {
+		_ = tx.Rollback()
+		return storage.ErrNotFound
+	}
+
+	_, err = tx.Exec(`DELETE FROM alert_state WHERE rule_id = $1`, id)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *alertStorage) SaveTrigger(trigger ...domain.AlertTrigger) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(`INSERT INTO alert_trigger (rule_id, time, value, message) VALUES ($1, $2, $3, $4)`)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for _, t := range trigger {
+		_, err = stmt.Exec(t.RuleID, t.Time, t.Value, t.Message)
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *alertStorage) FetchTriggers(ruleID string, limit int) ([]domain.AlertTrigger, error) {
+	rows, err := s.db.Query(`
+		SELECT rule_id, time, value, message FROM alert_trigger
+		WHERE $1 = '' OR rule_id = $1
+		ORDER BY time DESC, id DESC
+		LIMIT $2`, ruleID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []domain.AlertTrigger{}
+	for rows.Next() {
+		var t domain.AlertTrigger
+		err = rows.Scan(&t.RuleID, &t.Time, &t.Value, &t.Message)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, t)
+	}
+
+	return result, rows.Err()
+}
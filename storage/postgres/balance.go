@@ -0,0 +1,276 @@
+package postgres
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage"
+	"github.com/nawa/cryptoexchange-dashboard/storage/downsample"
+)
+
+// balanceColumns are selected by queries returning domain.Balance, see scanBalances
+const balanceColumns = "exchange, account, currency, amount, btc_amount, usdt_amount, fiat, reconstructed, time"
+
+type balanceStorage struct {
+	db *sql.DB
+}
+
+func NewBalanceStorage(db *sql.DB) storage.BalanceStorage {
+	return &balanceStorage{
+		db: db,
+	}
+}
+
+// Init creates the balance table and indexes. The table is turned into a hypertable
+// if TimescaleDB extension is installed in the database
+func (s *balanceStorage) Init() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS balance (
+			exchange      TEXT             NOT NULL DEFAULT '',
+			account       TEXT             NOT NULL DEFAULT '',
+			currency      TEXT             NOT NULL,
+			amount        DOUBLE PRECISION NOT NULL,
+			btc_amount    DOUBLE PRECISION NOT NULL,
+			usdt_amount   DOUBLE PRECISION NOT NULL,
+			fiat          JSONB,
+			reconstructed BOOLEAN          NOT NULL DEFAULT FALSE,
+			time          TIMESTAMPTZ      NOT NULL
+		)`)
+	if err != nil {
+		return err
+	}
+
+	// fiat values by currency codes and the reconstructed flag, columns are added to tables created before
+	_, err = s.db.Exec(`ALTER TABLE balance ADD COLUMN IF NOT EXISTS fiat JSONB`)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`ALTER TABLE balance ADD COLUMN IF NOT EXISTS reconstructed BOOLEAN NOT NULL DEFAULT FALSE`)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`CREATE INDEX IF NOT EXISTS balance_curr_time_idx ON balance (currency, time DESC)`)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`CREATE INDEX IF NOT EXISTS balance_time_idx ON balance (time DESC)`)
+	if err != nil {
+		return err
+	}
+
+	var timescale bool
+	err = s.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'timescaledb')`).Scan(&timescale)
+	if err != nil {
+		return err
+	}
+	if timescale {
+		_, err = s.db.Exec(`SELECT create_hypertable('balance', 'time', if_not_exists => TRUE, migrate_data => TRUE)`)
+	}
+
+	return err
+}
+
+func (s *balanceStorage) Save(balance ...domain.Balance) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(`INSERT INTO balance (` + balanceColumns + `) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for _, b := range balance {
+		// NULL for balances without fiat values
+		var fiat sql.NullString
+		if len(b.Fiat) > 0 {
+			data, err := json.Marshal(b.Fiat)
+			if err != nil {
+				_ = tx.Rollback()
+				return err
+			}
+			fiat = sql.NullString{String: string(data), Valid: true}
+		}
+
+		_, err = stmt.Exec(string(b.Exchange), b.Account, b.Currency, b.Amount, b.BTCAmount, b.USDTAmount, fiat, b.Reconstructed, b.Time)
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *balanceStorage) FetchHourly(currency string, hours int) ([]domain.Balance, error) {
+	period := time.Now().Add(-1 * time.Hour * time.Duration(hours))
+
+	rows, err := s.db.Query(`
+		SELECT `+balanceColumns+` FROM balance
+		WHERE currency = $1 AND time >= $2
+		ORDER BY time DESC`, currency, period)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanBalances(rows)
+}
+
+func (s *balanceStorage) FetchWeekly(currency string) ([]domain.Balance, error) {
+	period := time.Now().Add(-1 * time.Hour * 24 * 7)
+	return s.fetchFirstInBuckets(currency, period, `to_timestamp(floor(extract(epoch FROM time) / 300) * 300)`)
+}
+
+func (s *balanceStorage) FetchMonthly(currency string) ([]domain.Balance, error) {
+	period := time.Now().Add(-1 * time.Hour * 24 * 30)
+	return s.fetchFirstInBuckets(currency, period, `date_trunc('hour', time AT TIME ZONE 'UTC')`)
+}
+
+func (s *balanceStorage) FetchAll(currency string) ([]domain.Balance, error) {
+	dailyPeriod := time.Now().Add(-storage.FetchAllDailyPeriod)
+	// date_trunc('week') truncates to Monday as ISO week does
+	return s.fetchFirstInBuckets(currency, time.Time{}, `
+		CASE WHEN time >= $3
+			THEN date_trunc('day', time AT TIME ZONE 'UTC')
+			ELSE date_trunc('week', time AT TIME ZONE 'UTC')
+		END`, dailyPeriod)
+}
+
+// fetchFirstInBuckets returns the earliest balance of every bucket after the period, the latest bucket first.
+// bucket is SQL expression of the time, it can use the extra arguments starting from $3
+func (s *balanceStorage) fetchFirstInBuckets(currency string, period time.Time, bucket string, args ...interface{}) ([]domain.Balance, error) {
+	rows, err := s.db.Query(`
+		SELECT `+balanceColumns+` FROM (
+			SELECT DISTINCT ON (`+bucket+`) `+balanceColumns+` FROM balance
+			WHERE currency = $1 AND time >= $2
+			ORDER BY `+bucket+`, time
+		) AS first_balance
+		ORDER BY time DESC`, append([]interface{}{currency, period}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanBalances(rows)
+}
+
+func (s *balanceStorage) FetchRange(currency string, from, to time.Time, resolution time.Duration) ([]domain.BalanceCandle, error) {
+	if resolution < time.Millisecond {
+		return nil, errors.Errorf("resolution %s is less than 1ms", resolution)
+	}
+
+	rows, err := s.db.Query(`
+		SELECT
+			(floor(extract(epoch FROM time) * 1000 / $4::BIGINT) * $4::BIGINT)::BIGINT AS start,
+			(array_agg(amount ORDER BY time))[1], (array_agg(amount ORDER BY time DESC))[1],
+			min(amount), max(amount),
+			(array_agg(btc_amount ORDER BY time))[1], (array_agg(btc_amount ORDER BY time DESC))[1],
+			min(btc_amount), max(btc_amount),
+			(array_agg(usdt_amount ORDER BY time))[1], (array_agg(usdt_amount ORDER BY time DESC))[1],
+			min(usdt_amount), max(usdt_amount),
+			json_agg(fiat ORDER BY time) FILTER (WHERE fiat IS NOT NULL)
+		FROM balance
+		WHERE currency = $1 AND time >= $2 AND time < $3
+		GROUP BY start
+		ORDER BY start DESC`, currency, from, to, int64(resolution/time.Millisecond))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []domain.BalanceCandle
+	for rows.Next() {
+		var (
+			start  int64
+			candle = domain.BalanceCandle{Currency: currency}
+			fiat   []byte
+		)
+		err = rows.Scan(&start,
+			&candle.Amount.Open, &candle.Amount.Close, &candle.Amount.Min, &candle.Amount.Max,
+			&candle.BTCAmount.Open, &candle.BTCAmount.Close, &candle.BTCAmount.Min, &candle.BTCAmount.Max,
+			&candle.USDTAmount.Open, &candle.USDTAmount.Close, &candle.USDTAmount.Min, &candle.USDTAmount.Max,
+			&fiat,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		// fiat values of the bucket sorted by time
+		if fiat != nil {
+			var values []map[string]float64
+			err = json.Unmarshal(fiat, &values)
+			if err != nil {
+				return nil, err
+			}
+			candle.Fiat = downsample.Fiat(values)
+		}
+		candle.Time = time.Unix(0, start*int64(time.Millisecond)).UTC()
+		result = append(result, candle)
+	}
+
+	return result, rows.Err()
+}
+
+func (s *balanceStorage) GetActiveCurrencies() ([]domain.Balance, error) {
+	var lastTime *time.Time
+	err := s.db.QueryRow(`SELECT max(time) FROM balance`).Scan(&lastTime)
+	if err != nil {
+		return nil, err
+	}
+
+	if lastTime == nil {
+		return nil, errors.New("last time not found for currency")
+	}
+
+	rows, err := s.db.Query(`SELECT `+balanceColumns+` FROM balance WHERE time = $1`, *lastTime)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanBalances(rows)
+}
+
+func (s *balanceStorage) FetchSnapshot(at time.Time) ([]domain.Balance, error) {
+	rows, err := s.db.Query(`SELECT `+balanceColumns+` FROM balance
+		WHERE time = (SELECT min(time) FROM balance WHERE time >= $1)
+		ORDER BY currency`, at)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanBalances(rows)
+}
+
+func scanBalances(rows *sql.Rows) (result []domain.Balance, err error) {
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			b        domain.Balance
+			exchange string
+			fiat     []byte
+		)
+		err = rows.Scan(&exchange, &b.Account, &b.Currency, &b.Amount, &b.BTCAmount, &b.USDTAmount, &fiat, &b.Reconstructed, &b.Time)
+		if err != nil {
+			return nil, err
+		}
+		if fiat != nil {
+			err = json.Unmarshal(fiat, &b.Fiat)
+			if err != nil {
+				return nil, err
+			}
+		}
+		b.Exchange = domain.ExchangeType(exchange)
+		result = append(result, b)
+	}
+
+	return result, rows.Err()
+}
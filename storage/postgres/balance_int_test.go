@@ -0,0 +1,164 @@
+// +build integration_test
+
+package postgres_test
+
+import (
+	"database/sql"
+	"log"
+	"os"
+	"testing"
+
+	_ "github.com/lib/pq"
+
+	"github.com/nawa/cryptoexchange-dashboard/storage"
+	"github.com/nawa/cryptoexchange-dashboard/storage/postgres"
+	"github.com/nawa/cryptoexchange-dashboard/storage/testdata"
+)
+
+const EnvDbTestURL = "POSTGRES_TEST_URL"
+
+var (
+	db              *sql.DB
+	balanceStorage  storage.BalanceStorage
+	tradeStorage    storage.TradeStorage
+	transferStorage storage.TransferStorage
+	alertStorage    storage.AlertStorage
+	marketStorage   storage.MarketStorage
+)
+
+func TestMain(m *testing.M) {
+	dbURL, ok := os.LookupEnv(EnvDbTestURL)
+	if !ok {
+		log.Fatalf("environment variable '%s' is required", EnvDbTestURL)
+	}
+
+	var err error
+	db, err = sql.Open("postgres", dbURL)
+	if err != nil {
+		log.Fatalf("URL to postgres instance is incorrect: %s", err.Error())
+	}
+	defer db.Close()
+
+	err = db.Ping()
+	if err != nil {
+		log.Fatalf("can't connect to postgres instance: %s", err.Error())
+	}
+
+	balanceStorage = postgres.NewBalanceStorage(db)
+	err = balanceStorage.Init()
+	if err != nil {
+		log.Fatalf("can't instantiate balanceStorage: %s", err.Error())
+	}
+
+	// the second initialization must not fail on the existing schema
+	err = balanceStorage.Init()
+	if err != nil {
+		log.Fatalf("can't reinstantiate balanceStorage: %s", err.Error())
+	}
+
+	tradeStorage = postgres.NewTradeStorage(db)
+	err = tradeStorage.Init()
+	if err != nil {
+		log.Fatalf("can't instantiate tradeStorage: %s", err.Error())
+	}
+
+	transferStorage = postgres.NewTransferStorage(db)
+	err = transferStorage.Init()
+	if err != nil {
+		log.Fatalf("can't instantiate transferStorage: %s", err.Error())
+	}
+
+	alertStorage = postgres.NewAlertStorage(db)
+	err = alertStorage.Init()
+	if err != nil {
+		log.Fatalf("can't instantiate alertStorage: %s", err.Error())
+	}
+
+	marketStorage = postgres.NewMarketStorage(db)
+	err = marketStorage.Init()
+	if err != nil {
+		log.Fatalf("can't instantiate marketStorage: %s", err.Error())
+	}
+
+	err = cleanupData(db)
+	if err != nil {
+		log.Fatalf("can't cleanup data before tests: %s", err.Error())
+	}
+
+	code := m.Run()
+
+	_, _ = db.Exec("DROP TABLE balance")
+	_, _ = db.Exec("DROP TABLE trade")
+	_, _ = db.Exec("DROP TABLE transfer")
+	_, _ = db.Exec("DROP TABLE alert_state")
+	_, _ = db.Exec("DROP TABLE alert_rule")
+	_, _ = db.Exec("DROP TABLE alert_trigger")
+	_, _ = db.Exec("DROP TABLE market_candle")
+
+	os.Exit(code)
+}
+
+func TestBalanceStorage(t *testing.T) {
+	testdata.RunBalanceStorageSuite(t, balanceStorage, func() error {
+		return cleanupData(db)
+	})
+}
+
+func TestTradeStorage(t *testing.T) {
+	testdata.RunTradeStorageSuite(t, tradeStorage, func() error {
+		return cleanupData(db)
+	})
+}
+
+func TestTransferStorage(t *testing.T) {
+	testdata.RunTransferStorageSuite(t, transferStorage, func() error {
+		return cleanupData(db)
+	})
+}
+
+func TestAlertStorage(t *testing.T) {
+	testdata.RunAlertStorageSuite(t, alertStorage, func() error {
+		return cleanupData(db)
+	})
+}
+
+func TestMarketStorage(t *testing.T) {
+	testdata.RunMarketStorageSuite(t, marketStorage, func() error {
+		return cleanupData(db)
+	})
+}
+
+func cleanupData(db *sql.DB) error {
+	_, err := db.Exec("DELETE FROM balance")
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec("DELETE FROM trade")
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec("DELETE FROM transfer")
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec("DELETE FROM alert_state")
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec("DELETE FROM alert_rule")
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec("DELETE FROM alert_trigger")
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec("DELETE FROM market_candle")
+	return err
+}
@@ -0,0 +1,113 @@
+package postgres
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage"
+)
+
+type marketStorage struct {
+	db *sql.DB
+}
+
+func NewMarketStorage(db *sql.DB) storage.MarketStorage {
+	return &marketStorage{
+		db: db,
+	}
+}
+
+// Init creates the market candle table. The table is turned into a hypertable
+// if TimescaleDB extension is installed in the database
+func (s *marketStorage) Init() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS market_candle (
+			exchange TEXT             NOT NULL,
+			market   TEXT             NOT NULL,
+			time     TIMESTAMPTZ      NOT NULL,
+			open     DOUBLE PRECISION NOT NULL,
+			close    DOUBLE PRECISION NOT NULL,
+			min      DOUBLE PRECISION NOT NULL,
+			max      DOUBLE PRECISION NOT NULL,
+			PRIMARY KEY (market, time, exchange)
+		)`)
+	if err != nil {
+		return err
+	}
+
+	var timescale bool
+	err = s.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'timescaledb')`).Scan(&timescale)
+	if err != nil {
+		return err
+	}
+	if timescale {
+		_, err = s.db.Exec(`SELECT create_hypertable('market_candle', 'time', if_not_exists => TRUE, migrate_data => TRUE)`)
+	}
+
+	return err
+}
+
+func (s *marketStorage) Save(candle ...domain.MarketCandle) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO market_candle (exchange, market, time, open, close, min, max) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (market, time, exchange) DO UPDATE SET
+			open = EXCLUDED.open, close = EXCLUDED.close, min = EXCLUDED.min, max = EXCLUDED.max`)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for _, c := range candle {
+		_, err = stmt.Exec(string(c.Exchange), c.Market, c.Time, c.Price.Open, c.Price.Close, c.Price.Min, c.Price.Max)
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *marketStorage) FetchCandles(exchange domain.ExchangeType, market string, from, to time.Time, resolution time.Duration) ([]domain.MarketCandle, error) {
+	if resolution < time.Millisecond {
+		return nil, errors.Errorf("resolution %s is less than 1ms", resolution)
+	}
+
+	rows, err := s.db.Query(`
+		SELECT
+			(floor(extract(epoch FROM time) * 1000 / $4::BIGINT) * $4::BIGINT)::BIGINT AS start,
+			(array_agg(open ORDER BY time))[1], (array_agg(close ORDER BY time DESC))[1], min(min), max(max)
+		FROM market_candle
+		WHERE market = $1 AND time >= $2 AND time < $3 AND ($5 = '' OR exchange = $5)
+		GROUP BY start
+		ORDER BY start DESC`, market, from, to, int64(resolution/time.Millisecond), string(exchange))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []domain.MarketCandle
+	for rows.Next() {
+		var (
+			start  int64
+			candle = domain.MarketCandle{Exchange: exchange, Market: market}
+		)
+		err = rows.Scan(&start, &candle.Price.Open, &candle.Price.Close, &candle.Price.Min, &candle.Price.Max)
+		if err != nil {
+			return nil, err
+		}
+		candle.Time = time.Unix(0, start*int64(time.Millisecond)).UTC()
+		result = append(result, candle)
+	}
+
+	return result, rows.Err()
+}
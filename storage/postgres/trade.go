@@ -0,0 +1,109 @@
+package postgres
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage"
+)
+
+const tradeColumns = "exchange, account, order_id, market, type, time, amount, rate, fee, btc_rate, usdt_rate"
+
+type tradeStorage struct {
+	db *sql.DB
+}
+
+func NewTradeStorage(db *sql.DB) storage.TradeStorage {
+	return &tradeStorage{
+		db: db,
+	}
+}
+
+// Init creates the trade table and indexes
+func (s *tradeStorage) Init() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS trade (
+			exchange  TEXT             NOT NULL,
+			account   TEXT             NOT NULL DEFAULT '',
+			order_id  TEXT             NOT NULL,
+			market    TEXT             NOT NULL,
+			type      TEXT             NOT NULL,
+			time      TIMESTAMPTZ      NOT NULL,
+			amount    DOUBLE PRECISION NOT NULL,
+			rate      DOUBLE PRECISION NOT NULL,
+			fee       DOUBLE PRECISION NOT NULL,
+			btc_rate  DOUBLE PRECISION NOT NULL,
+			usdt_rate DOUBLE PRECISION NOT NULL,
+			PRIMARY KEY (exchange, order_id)
+		)`)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`CREATE INDEX IF NOT EXISTS trade_time_idx ON trade (time DESC)`)
+	return err
+}
+
+func (s *tradeStorage) Save(trade ...domain.Trade) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO trade (` + tradeColumns + `) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (exchange, order_id) DO UPDATE SET
+			account = EXCLUDED.account, market = EXCLUDED.market, type = EXCLUDED.type, time = EXCLUDED.time,
+			amount = EXCLUDED.amount, rate = EXCLUDED.rate, fee = EXCLUDED.fee`)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for _, t := range trade {
+		_, err = stmt.Exec(string(t.Exchange), t.Account, t.OrderID, t.Market, string(t.Type), t.Time,
+			t.Amount, t.Rate, t.Fee, t.BTCRate, t.USDTRate)
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *tradeStorage) Fetch(from, to time.Time) ([]domain.Trade, error) {
+	var upper interface{}
+	if !to.IsZero() {
+		upper = to
+	}
+
+	rows, err := s.db.Query(`
+		SELECT `+tradeColumns+` FROM trade
+		WHERE time >= $1 AND ($2::TIMESTAMPTZ IS NULL OR time < $2)
+		ORDER BY time DESC`, from, upper)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []domain.Trade
+	for rows.Next() {
+		var (
+			t                   domain.Trade
+			exchange, tradeType string
+		)
+		err = rows.Scan(&exchange, &t.Account, &t.OrderID, &t.Market, &tradeType, &t.Time,
+			&t.Amount, &t.Rate, &t.Fee, &t.BTCRate, &t.USDTRate)
+		if err != nil {
+			return nil, err
+		}
+		t.Exchange = domain.ExchangeType(exchange)
+		t.Type = domain.TradeType(tradeType)
+		result = append(result, t)
+	}
+
+	return result, rows.Err()
+}
@@ -0,0 +1,108 @@
+package postgres
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage"
+)
+
+const transferColumns = "exchange, account, transfer_id, type, currency, time, amount, fee, btc_rate, usdt_rate"
+
+type transferStorage struct {
+	db *sql.DB
+}
+
+func NewTransferStorage(db *sql.DB) storage.TransferStorage {
+	return &transferStorage{
+		db: db,
+	}
+}
+
+// Init creates the transfer table and indexes
+func (s *transferStorage) Init() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS transfer (
+			exchange    TEXT             NOT NULL,
+			account     TEXT             NOT NULL DEFAULT '',
+			transfer_id TEXT             NOT NULL,
+			type        TEXT             NOT NULL,
+			currency    TEXT             NOT NULL,
+			time        TIMESTAMPTZ      NOT NULL,
+			amount      DOUBLE PRECISION NOT NULL,
+			fee         DOUBLE PRECISION NOT NULL,
+			btc_rate    DOUBLE PRECISION NOT NULL,
+			usdt_rate   DOUBLE PRECISION NOT NULL,
+			PRIMARY KEY (exchange, transfer_id)
+		)`)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`CREATE INDEX IF NOT EXISTS transfer_time_idx ON transfer (time DESC)`)
+	return err
+}
+
+func (s *transferStorage) Save(transfer ...domain.Transfer) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO transfer (` + transferColumns + `) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (exchange, transfer_id) DO UPDATE SET
+			account = EXCLUDED.account, type = EXCLUDED.type, currency = EXCLUDED.currency, time = EXCLUDED.time,
+			amount = EXCLUDED.amount, fee = EXCLUDED.fee`)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for _, t := range transfer {
+		_, err = stmt.Exec(string(t.Exchange), t.Account, t.ID, string(t.Type), t.Currency, t.Time,
+			t.Amount, t.Fee, t.BTCRate, t.USDTRate)
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *transferStorage) Fetch(from, to time.Time) ([]domain.Transfer, error) {
+	var upper interface{}
+	if !to.IsZero() {
+		upper = to
+	}
+
+	rows, err := s.db.Query(`
+		SELECT `+transferColumns+` FROM transfer
+		WHERE time >= $1 AND ($2::TIMESTAMPTZ IS NULL OR time < $2)
+		ORDER BY time DESC`, from, upper)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []domain.Transfer
+	for rows.Next() {
+		var (
+			t                      domain.Transfer
+			exchange, transferType string
+		)
+		err = rows.Scan(&exchange, &t.Account, &t.ID, &transferType, &t.Currency, &t.Time,
+			&t.Amount, &t.Fee, &t.BTCRate, &t.USDTRate)
+		if err != nil {
+			return nil, err
+		}
+		t.Exchange = domain.ExchangeType(exchange)
+		t.Type = domain.TransferType(transferType)
+		result = append(result, t)
+	}
+
+	return result, rows.Err()
+}
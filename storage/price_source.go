@@ -0,0 +1,7 @@
+package storage
+
+// PriceSource gives rates of fiat currencies, such as EUR or GBP
+type PriceSource interface {
+	// GetUSDRates returns how much of every currency 1 USD costs. It fails if any currency is unknown
+	GetUSDRates(currencies []string) (map[string]float64, error)
+}
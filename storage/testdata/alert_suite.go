@@ -0,0 +1,164 @@
+package testdata
+
+import (
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/require"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage"
+)
+
+// RunAlertStorageSuite checks that the storage implementation follows the semantics of storage.AlertStorage.
+// Every test removes all alert data with cleanup before filling the storage
+func RunAlertStorageSuite(t *testing.T, alertStorage storage.AlertStorage, cleanup func() error) {
+	t.Run("SaveState", func(t *testing.T) {
+		testAlertSaveState(t, alertStorage, cleanup)
+	})
+	t.Run("Rules", func(t *testing.T) {
+		testAlertRules(t, alertStorage, cleanup)
+	})
+	t.Run("Triggers", func(t *testing.T) {
+		testAlertTriggers(t, alertStorage, cleanup)
+	})
+}
+
+func testAlertSaveState(t *testing.T, alertStorage storage.AlertStorage, cleanup func() error) {
+	assert.NoError(t, cleanup())
+
+	states, err := alertStorage.FetchStates()
+	assert.NoError(t, err)
+	assert.Empty(t, states)
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	assert.NoError(t, alertStorage.SaveState(
+		domain.AlertState{RuleID: "rule2", LastFired: now.Add(-time.Hour)},
+		domain.AlertState{RuleID: "rule1", LastFired: now.Add(-2 * time.Hour)},
+	))
+
+	// the rule fired again, peaks of the trailing stop rule are replaced
+	assert.NoError(t, alertStorage.SaveState(domain.AlertState{RuleID: "rule2", LastFired: now}))
+	assert.NoError(t, alertStorage.SaveState(domain.AlertState{RuleID: "rule3", Peaks: map[string]float64{"bittrex/my.account/BTC-ETH/1": 0.05}}))
+	assert.NoError(t, alertStorage.SaveState(domain.AlertState{RuleID: "rule3", Peaks: map[string]float64{"bittrex/my.account/BTC-ETH/1": 0.06}}))
+
+	states, err = alertStorage.FetchStates()
+	assert.NoError(t, err)
+	for i := range states {
+		states[i].LastFired = states[i].LastFired.UTC()
+	}
+	assert.Equal(t, []domain.AlertState{
+		{RuleID: "rule1", LastFired: now.Add(-2 * time.Hour)},
+		{RuleID: "rule2", LastFired: now},
+		{RuleID: "rule3", Peaks: map[string]float64{"bittrex/my.account/BTC-ETH/1": 0.06}},
+	}, states)
+}
+
+func testAlertRules(t *testing.T, alertStorage storage.AlertStorage, cleanup func() error) {
+	assert.NoError(t, cleanup())
+
+	rules, err := alertStorage.FetchRules()
+	assert.NoError(t, err)
+	assert.Empty(t, rules)
+
+	_, err = alertStorage.FetchRule("rule1")
+	assert.Equal(t, storage.ErrNotFound, err)
+
+	rule1 := domain.AlertRule{
+		ID:       "rule1",
+		Type:     domain.AlertTypePercentChange,
+		Market:   "BTC-ETH",
+		Value:    -5.5,
+		Window:   time.Hour,
+		Cooldown: time.Minute * 30,
+		Channels: []string{"mail", "chat"},
+	}
+	rule2 := domain.AlertRule{
+		ID:       "rule2",
+		Type:     domain.AlertTypePortfolioAbove,
+		Currency: domain.TotalCurrency,
+		Quote:    "USDT",
+		Value:    1000,
+	}
+	assert.NoError(t, alertStorage.SaveRule(rule2))
+	assert.NoError(t, alertStorage.SaveRule(rule1))
+
+	rules, err = alertStorage.FetchRules()
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.AlertRule{rule1, rule2}, rules)
+
+	// replaced by ID
+	rule2.Value = 2000
+	rule2.Channels = []string{"mail"}
+	assert.NoError(t, alertStorage.SaveRule(rule2))
+
+	rule, err := alertStorage.FetchRule("rule2")
+	assert.NoError(t, err)
+	assert.Equal(t, rule2, *rule)
+
+	// the state is deleted with the rule
+	assert.NoError(t, alertStorage.SaveState(
+		domain.AlertState{RuleID: "rule1", LastFired: time.Now().UTC().Truncate(time.Millisecond)},
+		domain.AlertState{RuleID: "rule2", LastFired: time.Now().UTC().Truncate(time.Millisecond)},
+	))
+	assert.NoError(t, alertStorage.DeleteRule("rule1"))
+	assert.Equal(t, storage.ErrNotFound, alertStorage.DeleteRule("rule1"))
+
+	rules, err = alertStorage.FetchRules()
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.AlertRule{rule2}, rules)
+
+	states, err := alertStorage.FetchStates()
+	assert.NoError(t, err)
+	assert.Len(t, states, 1)
+	assert.Equal(t, "rule2", states[0].RuleID)
+}
+
+func testAlertTriggers(t *testing.T, alertStorage storage.AlertStorage, cleanup func() error) {
+	assert.NoError(t, cleanup())
+
+	triggers, err := alertStorage.FetchTriggers("", 10)
+	assert.NoError(t, err)
+	assert.Empty(t, triggers)
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	trigger := func(ruleID string, ago time.Duration) domain.AlertTrigger {
+		return domain.AlertTrigger{
+			RuleID:  ruleID,
+			Time:    now.Add(-ago),
+			Value:   float64(ago / time.Minute),
+			Message: ruleID + " fired",
+		}
+	}
+	assert.NoError(t, alertStorage.SaveTrigger(trigger("rule1", time.Minute*3), trigger("rule2", time.Minute*2)))
+	assert.NoError(t, alertStorage.SaveTrigger(trigger("rule1", time.Minute)))
+
+	triggers, err = alertStorage.FetchTriggers("", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.AlertTrigger{
+		trigger("rule1", time.Minute),
+		trigger("rule2", time.Minute*2),
+		trigger("rule1", time.Minute*3),
+	}, utcTriggers(triggers))
+
+	triggers, err = alertStorage.FetchTriggers("rule1", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.AlertTrigger{
+		trigger("rule1", time.Minute),
+		trigger("rule1", time.Minute*3),
+	}, utcTriggers(triggers))
+
+	triggers, err = alertStorage.FetchTriggers("", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.AlertTrigger{
+		trigger("rule1", time.Minute),
+		trigger("rule2", time.Minute*2),
+	}, utcTriggers(triggers))
+}
+
+func utcTriggers(triggers []domain.AlertTrigger) []domain.AlertTrigger {
+	for i := range triggers {
+		triggers[i].Time = triggers[i].Time.UTC()
+	}
+	return triggers
+}
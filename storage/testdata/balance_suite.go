@@ -0,0 +1,354 @@
+package testdata
+
+import (
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/require"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage"
+)
+
+// RunBalanceStorageSuite checks that the storage implementation follows the semantics of storage.BalanceStorage.
+// Every test removes all balances with cleanup before filling the storage
+func RunBalanceStorageSuite(t *testing.T, balanceStorage storage.BalanceStorage, cleanup func() error) {
+	t.Run("FetchAll", func(t *testing.T) {
+		testFetchAll(t, balanceStorage, cleanup)
+	})
+	t.Run("FetchRange", func(t *testing.T) {
+		testFetchRange(t, balanceStorage, cleanup)
+	})
+	t.Run("FetchHourly", func(t *testing.T) {
+		testFetchHourly(t, balanceStorage, cleanup)
+	})
+	t.Run("FetchWeekly", func(t *testing.T) {
+		testFetchWeekly(t, balanceStorage, cleanup)
+	})
+	t.Run("FetchMonthly", func(t *testing.T) {
+		testFetchMonthly(t, balanceStorage, cleanup)
+	})
+	t.Run("GetActiveCurrencies", func(t *testing.T) {
+		testGetActiveCurrencies(t, balanceStorage, cleanup)
+	})
+	t.Run("FetchSnapshot", func(t *testing.T) {
+		testFetchSnapshot(t, balanceStorage, cleanup)
+	})
+	t.Run("Fiat", func(t *testing.T) {
+		testFiat(t, balanceStorage, cleanup)
+	})
+	t.Run("Reconstructed", func(t *testing.T) {
+		testReconstructed(t, balanceStorage, cleanup)
+	})
+}
+
+func testFetchAll(t *testing.T, balanceStorage storage.BalanceStorage, cleanup func() error) {
+	assert.NoError(t, cleanup())
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	day := now.Add(-10 * 24 * time.Hour).Truncate(24 * time.Hour)
+	// Monday of the ISO week two years ago
+	week := now.Add(-2 * storage.FetchAllDailyPeriod).Truncate(24 * time.Hour)
+	week = week.Add(-time.Duration((int(week.Weekday())+6)%7) * 24 * time.Hour)
+
+	balances := append(Balances(), Balances()...)
+	// daily buckets
+	balances[0].Time = now
+	balances[1].Time = now
+	balances[2].Time = now
+	balances[3].Time = day.Add(time.Hour)
+	balances[4].Time = day.Add(time.Hour)
+	balances[5].Time = day.Add(2 * time.Hour)
+	// weekly buckets
+	balances[6].Time = week.Add(time.Hour)
+	balances[7].Time = week.Add(time.Hour)
+	balances[8].Time = week.Add(time.Hour)
+	balances[9].Time = week.Add(2 * 24 * time.Hour)
+	balances[10].Time = week.Add(8 * 24 * time.Hour)
+	balances[11].Time = week.Add(6 * 24 * time.Hour)
+
+	for _, balance := range balances {
+		err := balanceStorage.Save(balance)
+		assert.NoError(t, err)
+	}
+
+	storageBalances, err := balanceStorage.FetchAll("total")
+	assert.NoError(t, err)
+	assert.Len(t, storageBalances, 3)
+	assert.Equal(t, now, storageBalances[0].Time.UTC())
+	assert.Equal(t, day.Add(2*time.Hour), storageBalances[1].Time.UTC())
+	assert.Equal(t, week.Add(time.Hour), storageBalances[2].Time.UTC())
+
+	storageBalances, err = balanceStorage.FetchAll("CUR1")
+	assert.NoError(t, err)
+	assert.Len(t, storageBalances, 3)
+	assert.Equal(t, week.Add(time.Hour), storageBalances[2].Time.UTC())
+
+	// the next week
+	storageBalances, err = balanceStorage.FetchAll("CUR2")
+	assert.NoError(t, err)
+	assert.Len(t, storageBalances, 4)
+
+	storageBalances, err = balanceStorage.FetchAll("CUR3")
+	assert.NoError(t, err)
+	assert.Len(t, storageBalances, 0)
+}
+
+func testFetchRange(t *testing.T, balanceStorage storage.BalanceStorage, cleanup func() error) {
+	assert.NoError(t, cleanup())
+
+	start := time.Date(2018, 2, 6, 10, 0, 0, 0, time.UTC)
+	amounts := []float64{5, 3, 8, 6, 7, 2, 4}
+	for i, amount := range amounts {
+		err := balanceStorage.Save(domain.Balance{
+			Exchange:   domain.ExchangeTypeBittrex,
+			Currency:   "CUR1",
+			Amount:     amount,
+			BTCAmount:  amount * 10,
+			USDTAmount: amount * 100,
+			Time:       start.Add(time.Duration(i) * 20 * time.Minute),
+		})
+		assert.NoError(t, err)
+	}
+
+	// buckets: [10:00 5 3 8] [11:00 6 7 2] [12:00 4 - out of range]
+	candles, err := balanceStorage.FetchRange("CUR1", start.Add(-time.Minute), start.Add(2*time.Hour), time.Hour)
+	assert.NoError(t, err)
+	assert.Len(t, candles, 2)
+
+	assert.Equal(t, start.Add(time.Hour), candles[0].Time)
+	assert.Equal(t, domain.OHLC{Open: 6, Close: 2, Min: 2, Max: 7}, candles[0].Amount)
+	assert.Equal(t, domain.OHLC{Open: 60, Close: 20, Min: 20, Max: 70}, candles[0].BTCAmount)
+	assert.Equal(t, domain.OHLC{Open: 600, Close: 200, Min: 200, Max: 700}, candles[0].USDTAmount)
+
+	assert.Equal(t, start, candles[1].Time)
+	assert.Equal(t, "CUR1", candles[1].Currency)
+	assert.Equal(t, domain.OHLC{Open: 5, Close: 8, Min: 3, Max: 8}, candles[1].Amount)
+
+	candles, err = balanceStorage.FetchRange("CUR1", start, start.Add(3*time.Hour), 40*time.Minute)
+	assert.NoError(t, err)
+	assert.Len(t, candles, 4)
+
+	candles, err = balanceStorage.FetchRange("CUR2", start, start.Add(3*time.Hour), time.Hour)
+	assert.NoError(t, err)
+	assert.Len(t, candles, 0)
+
+	_, err = balanceStorage.FetchRange("CUR1", start, start.Add(3*time.Hour), 0)
+	assert.Error(t, err)
+}
+
+func testFetchHourly(t *testing.T, balanceStorage storage.BalanceStorage, cleanup func() error) {
+	assert.NoError(t, cleanup())
+
+	now := time.Now().Truncate(time.Millisecond)
+	balances := Balances()
+	balances[0].Time = now.Add(-5 * time.Hour)
+	balances[1].Time = now.Add(-1 * time.Hour)
+	balances[2].Time = now
+	balances[3].Time = now
+	balances[4].Time = now.Add(-5 * time.Hour)
+	balances[5].Time = now.Add(-1 * time.Hour)
+
+	for _, balance := range balances {
+		err := balanceStorage.Save(balance)
+		assert.NoError(t, err)
+	}
+
+	storageBalances, err := balanceStorage.FetchHourly("total", 2)
+	assert.NoError(t, err)
+	assert.Len(t, storageBalances, 2)
+
+	storageBalances, err = balanceStorage.FetchHourly("CUR1", 2)
+	assert.NoError(t, err)
+	assert.Len(t, storageBalances, 1)
+
+	storageBalances, err = balanceStorage.FetchHourly("CUR2", 2)
+	assert.NoError(t, err)
+	assert.Len(t, storageBalances, 1)
+}
+
+func testFetchWeekly(t *testing.T, balanceStorage storage.BalanceStorage, cleanup func() error) {
+	assert.NoError(t, cleanup())
+
+	now := time.Now().Truncate(time.Millisecond)
+	balances := Balances()
+	balances[0].Time = now.Add(-10 * 24 * time.Hour)
+	balances[1].Time = now.Add(-1 * 24 * time.Hour)
+	balances[2].Time = now
+	balances[3].Time = now
+	balances[4].Time = now.Add(-10 * 24 * time.Hour)
+	balances[5].Time = now.Add(-1 * 24 * time.Hour)
+
+	for _, balance := range balances {
+		err := balanceStorage.Save(balance)
+		assert.NoError(t, err)
+	}
+
+	storageBalances, err := balanceStorage.FetchWeekly("total")
+	assert.NoError(t, err)
+	assert.Len(t, storageBalances, 2)
+
+	storageBalances, err = balanceStorage.FetchWeekly("CUR1")
+	assert.NoError(t, err)
+	assert.Len(t, storageBalances, 1)
+
+	storageBalances, err = balanceStorage.FetchWeekly("CUR2")
+	assert.NoError(t, err)
+	assert.Len(t, storageBalances, 1)
+}
+
+func testFetchMonthly(t *testing.T, balanceStorage storage.BalanceStorage, cleanup func() error) {
+	assert.NoError(t, cleanup())
+
+	now := time.Now().Truncate(time.Millisecond)
+	balances := Balances()
+	balances[0].Time = now.Add(-32 * 24 * time.Hour)
+	balances[1].Time = now.Add(-10 * 24 * time.Hour)
+	balances[2].Time = now
+	balances[3].Time = now
+	balances[4].Time = now.Add(-32 * 24 * time.Hour)
+	balances[5].Time = now.Add(-10 * 24 * time.Hour)
+
+	for _, balance := range balances {
+		err := balanceStorage.Save(balance)
+		assert.NoError(t, err)
+	}
+
+	storageBalances, err := balanceStorage.FetchMonthly("total")
+	assert.NoError(t, err)
+	assert.Len(t, storageBalances, 2)
+
+	storageBalances, err = balanceStorage.FetchMonthly("CUR1")
+	assert.NoError(t, err)
+	assert.Len(t, storageBalances, 1)
+
+	storageBalances, err = balanceStorage.FetchMonthly("CUR2")
+	assert.NoError(t, err)
+	assert.Len(t, storageBalances, 1)
+}
+
+func testGetActiveCurrencies(t *testing.T, balanceStorage storage.BalanceStorage, cleanup func() error) {
+	assert.NoError(t, cleanup())
+
+	now := time.Now().Truncate(time.Millisecond)
+	balances := Balances()
+	balances[0].Time = now
+	balances[1].Time = now
+	balances[2].Time = now
+	balances[3].Time = now.Add(-10 * 24 * time.Hour)
+	balances[4].Time = now.Add(-10 * 24 * time.Hour)
+	balances[5].Time = now.Add(-10 * 24 * time.Hour)
+
+	for _, balance := range balances {
+		err := balanceStorage.Save(balance)
+		assert.NoError(t, err)
+	}
+
+	storageBalances, err := balanceStorage.GetActiveCurrencies()
+	assert.NoError(t, err)
+	assert.Len(t, storageBalances, 3)
+	assert.Equal(t, now.Truncate(time.Millisecond).UTC(), storageBalances[0].Time.Truncate(time.Millisecond).UTC())
+	assert.Equal(t, now.Truncate(time.Millisecond).UTC(), storageBalances[1].Time.Truncate(time.Millisecond).UTC())
+	assert.Equal(t, now.Truncate(time.Millisecond).UTC(), storageBalances[2].Time.Truncate(time.Millisecond).UTC())
+}
+
+func testFetchSnapshot(t *testing.T, balanceStorage storage.BalanceStorage, cleanup func() error) {
+	assert.NoError(t, cleanup())
+
+	start := time.Now().UTC().Add(-10 * time.Hour).Truncate(time.Hour)
+	balances := Balances()
+	balances[0].Time = start
+	balances[1].Time = start.Add(time.Hour)
+	balances[2].Time = start.Add(time.Hour)
+	balances[3].Time = start.Add(2 * time.Hour)
+	balances[4].Time = start.Add(2 * time.Hour)
+	balances[5].Time = start.Add(2 * time.Hour)
+	assert.NoError(t, balanceStorage.Save(balances...))
+
+	snapshot, err := balanceStorage.FetchSnapshot(start.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Len(t, snapshot, 2)
+	assert.Equal(t, "CUR2", snapshot[0].Currency)
+	assert.Equal(t, "total", snapshot[1].Currency)
+	assert.Equal(t, start.Add(time.Hour), snapshot[0].Time.UTC())
+
+	snapshot, err = balanceStorage.FetchSnapshot(start)
+	assert.NoError(t, err)
+	assert.Len(t, snapshot, 1)
+	assert.Equal(t, "CUR1", snapshot[0].Currency)
+
+	snapshot, err = balanceStorage.FetchSnapshot(start.Add(2 * time.Hour))
+	assert.NoError(t, err)
+	assert.Len(t, snapshot, 3)
+
+	snapshot, err = balanceStorage.FetchSnapshot(start.Add(3 * time.Hour))
+	assert.NoError(t, err)
+	assert.Empty(t, snapshot)
+}
+
+func testFiat(t *testing.T, balanceStorage storage.BalanceStorage, cleanup func() error) {
+	assert.NoError(t, cleanup())
+
+	start := time.Now().UTC().Add(-time.Hour).Truncate(time.Hour)
+	// the first balance is saved before fiat currencies have been configured
+	fiats := []map[string]float64{
+		nil,
+		{"EUR": 240, "GBP": 225},
+		{"EUR": 640, "GBP": 600},
+		{"EUR": 560},
+	}
+	for i, fiat := range fiats {
+		err := balanceStorage.Save(domain.Balance{
+			Exchange:   domain.ExchangeTypeBittrex,
+			Currency:   "CUR1",
+			Amount:     float64(i + 1),
+			BTCAmount:  float64(i+1) * 10,
+			USDTAmount: float64(i+1) * 100,
+			Fiat:       fiat,
+			Time:       start.Add(time.Duration(i) * 10 * time.Minute),
+		})
+		assert.NoError(t, err)
+	}
+
+	storageBalances, err := balanceStorage.FetchHourly("CUR1", 3)
+	assert.NoError(t, err)
+	assert.Len(t, storageBalances, 4)
+	assert.Equal(t, map[string]float64{"EUR": 560}, storageBalances[0].Fiat)
+	assert.Equal(t, map[string]float64{"EUR": 640, "GBP": 600}, storageBalances[1].Fiat)
+	assert.Empty(t, storageBalances[3].Fiat)
+
+	storageBalances, err = balanceStorage.GetActiveCurrencies()
+	assert.NoError(t, err)
+	assert.Len(t, storageBalances, 1)
+	assert.Equal(t, map[string]float64{"EUR": 560}, storageBalances[0].Fiat)
+
+	candles, err := balanceStorage.FetchRange("CUR1", start, start.Add(time.Hour), time.Hour)
+	assert.NoError(t, err)
+	assert.Len(t, candles, 1)
+	assert.Equal(t, map[string]domain.OHLC{
+		"EUR": {Open: 240, Close: 560, Min: 240, Max: 640},
+		"GBP": {Open: 225, Close: 600, Min: 225, Max: 600},
+	}, candles[0].Fiat)
+}
+
+func testReconstructed(t *testing.T, balanceStorage storage.BalanceStorage, cleanup func() error) {
+	assert.NoError(t, cleanup())
+
+	start := time.Now().UTC().Add(-time.Hour).Truncate(time.Hour)
+	for i, reconstructed := range []bool{true, false} {
+		err := balanceStorage.Save(domain.Balance{
+			Exchange:      domain.ExchangeTypeBittrex,
+			Currency:      "CUR1",
+			Amount:        float64(i + 1),
+			Reconstructed: reconstructed,
+			Time:          start.Add(time.Duration(i) * 10 * time.Minute),
+		})
+		assert.NoError(t, err)
+	}
+
+	storageBalances, err := balanceStorage.FetchHourly("CUR1", 3)
+	assert.NoError(t, err)
+	assert.Len(t, storageBalances, 2)
+	assert.False(t, storageBalances[0].Reconstructed)
+	assert.True(t, storageBalances[1].Reconstructed)
+}
@@ -0,0 +1,126 @@
+package testdata
+
+import (
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+)
+
+func Balances() []domain.Balance {
+	return []domain.Balance{
+		{
+			Exchange:   domain.ExchangeTypeBittrex,
+			Currency:   "CUR1",
+			Amount:     1,
+			BTCAmount:  2,
+			USDTAmount: 3,
+		},
+		{
+			Exchange:   domain.ExchangeTypeBittrex,
+			Currency:   "CUR2",
+			Amount:     4,
+			BTCAmount:  5,
+			USDTAmount: 6,
+		},
+		{
+			Exchange:   domain.ExchangeTypeBittrex,
+			Currency:   "total",
+			BTCAmount:  100,
+			USDTAmount: 1000,
+		},
+		{
+			Exchange:   domain.ExchangeTypeBittrex,
+			Currency:   "CUR1",
+			Amount:     1,
+			BTCAmount:  2,
+			USDTAmount: 3,
+		},
+		{
+			Exchange:   domain.ExchangeTypeBittrex,
+			Currency:   "CUR2",
+			Amount:     4,
+			BTCAmount:  5,
+			USDTAmount: 6,
+		},
+		{
+			Exchange:   domain.ExchangeTypeBittrex,
+			Currency:   "total",
+			BTCAmount:  100,
+			USDTAmount: 1000,
+		},
+	}
+
+}
+
+func Trades() []domain.Trade {
+	return []domain.Trade{
+		{
+			Exchange: domain.ExchangeTypeBittrex,
+			Account:  "main",
+			OrderID:  "uuid-1",
+			Market:   "BTC-CUR1",
+			Type:     domain.TradeTypeBuy,
+			Amount:   100,
+			Rate:     0.01,
+			Fee:      0.0025,
+			BTCRate:  1,
+			USDTRate: 10000,
+		},
+		{
+			Exchange: domain.ExchangeTypeBittrex,
+			Account:  "main",
+			OrderID:  "uuid-2",
+			Market:   "BTC-CUR1",
+			Type:     domain.TradeTypeSell,
+			Amount:   50,
+			Rate:     0.02,
+			Fee:      0.0025,
+			BTCRate:  1,
+			USDTRate: 10000,
+		},
+		{
+			Exchange: domain.ExchangeTypeBinance,
+			OrderID:  "CUR2BTC:1",
+			Market:   "BTC-CUR2",
+			Type:     domain.TradeTypeBuy,
+			Amount:   10,
+			Rate:     0.1,
+			Fee:      0.001,
+			BTCRate:  1,
+			USDTRate: 10000,
+		},
+	}
+}
+
+func Transfers() []domain.Transfer {
+	return []domain.Transfer{
+		{
+			Exchange: domain.ExchangeTypeBittrex,
+			Account:  "main",
+			ID:       "1",
+			Type:     domain.TransferTypeDeposit,
+			Currency: "BTC",
+			Amount:   1,
+			BTCRate:  1,
+			USDTRate: 10000,
+		},
+		{
+			Exchange: domain.ExchangeTypeBittrex,
+			Account:  "main",
+			ID:       "uuid-w1",
+			Type:     domain.TransferTypeWithdrawal,
+			Currency: "BTC",
+			Amount:   0.5,
+			Fee:      0.001,
+			BTCRate:  1,
+			USDTRate: 10000,
+		},
+		{
+			Exchange: domain.ExchangeTypeBinance,
+			ID:       "1",
+			Type:     domain.TransferTypeDeposit,
+			Currency: "BTC",
+			Amount:   2,
+			BTCRate:  1,
+			USDTRate: 10000,
+		},
+	}
+}
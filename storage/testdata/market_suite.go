@@ -0,0 +1,113 @@
+package testdata
+
+import (
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/require"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage"
+)
+
+// RunMarketStorageSuite checks that the storage implementation follows the semantics of storage.MarketStorage.
+// Every test removes all candles with cleanup before filling the storage
+func RunMarketStorageSuite(t *testing.T, marketStorage storage.MarketStorage, cleanup func() error) {
+	t.Run("FetchCandles", func(t *testing.T) {
+		testMarketFetchCandles(t, marketStorage, cleanup)
+	})
+	t.Run("SaveTwice", func(t *testing.T) {
+		testMarketSaveTwice(t, marketStorage, cleanup)
+	})
+}
+
+// price returns the candle of the price saved by synchronization
+func price(exchange domain.ExchangeType, market string, t time.Time, value float64) domain.MarketCandle {
+	return domain.MarketCandle{
+		Exchange: exchange,
+		Market:   market,
+		Time:     t,
+		Price:    domain.OHLC{Open: value, Close: value, Min: value, Max: value},
+	}
+}
+
+func testMarketFetchCandles(t *testing.T, marketStorage storage.MarketStorage, cleanup func() error) {
+	assert.NoError(t, cleanup())
+
+	start := time.Date(2018, 2, 6, 10, 0, 0, 0, time.UTC)
+	candles := []domain.MarketCandle{
+		// the hourly candle of the exchange
+		{
+			Exchange: domain.ExchangeTypeBittrex,
+			Market:   "BTC-CUR1",
+			Time:     start,
+			Price:    domain.OHLC{Open: 5, Close: 4, Min: 3, Max: 6},
+		},
+		price(domain.ExchangeTypeBittrex, "BTC-CUR1", start.Add(time.Hour), 7),
+		price(domain.ExchangeTypeBittrex, "BTC-CUR1", start.Add(time.Hour+20*time.Minute), 9),
+		price(domain.ExchangeTypeBittrex, "BTC-CUR1", start.Add(time.Hour+40*time.Minute), 8),
+		price(domain.ExchangeTypeBinance, "BTC-CUR1", start.Add(time.Hour+30*time.Minute), 10),
+		price(domain.ExchangeTypeBittrex, "BTC-CUR2", start.Add(time.Hour), 1),
+		// out of range
+		price(domain.ExchangeTypeBittrex, "BTC-CUR1", start.Add(2*time.Hour), 2),
+	}
+	assert.NoError(t, marketStorage.Save(candles...))
+
+	storageCandles, err := marketStorage.FetchCandles(domain.ExchangeTypeBittrex, "BTC-CUR1", start.Add(-time.Minute), start.Add(2*time.Hour), time.Hour)
+	assert.NoError(t, err)
+	assert.Len(t, storageCandles, 2)
+	assert.Equal(t, domain.MarketCandle{
+		Exchange: domain.ExchangeTypeBittrex,
+		Market:   "BTC-CUR1",
+		Time:     start.Add(time.Hour),
+		Price:    domain.OHLC{Open: 7, Close: 8, Min: 7, Max: 9},
+	}, storageCandles[0])
+	assert.Equal(t, start, storageCandles[1].Time)
+	assert.Equal(t, domain.OHLC{Open: 5, Close: 4, Min: 3, Max: 6}, storageCandles[1].Price)
+
+	// all exchanges
+	storageCandles, err = marketStorage.FetchCandles("", "BTC-CUR1", start.Add(time.Hour), start.Add(2*time.Hour), time.Hour)
+	assert.NoError(t, err)
+	assert.Len(t, storageCandles, 1)
+	assert.Equal(t, domain.ExchangeType(""), storageCandles[0].Exchange)
+	assert.Equal(t, domain.OHLC{Open: 7, Close: 8, Min: 7, Max: 10}, storageCandles[0].Price)
+
+	storageCandles, err = marketStorage.FetchCandles("", "BTC-CUR1", start, start.Add(3*time.Hour), 30*time.Minute)
+	assert.NoError(t, err)
+	assert.Len(t, storageCandles, 4)
+
+	storageCandles, err = marketStorage.FetchCandles(domain.ExchangeTypeBittrex, "BTC-CUR3", start, start.Add(3*time.Hour), time.Hour)
+	assert.NoError(t, err)
+	assert.Len(t, storageCandles, 0)
+
+	_, err = marketStorage.FetchCandles(domain.ExchangeTypeBittrex, "BTC-CUR1", start, start.Add(3*time.Hour), 0)
+	assert.Error(t, err)
+}
+
+func testMarketSaveTwice(t *testing.T, marketStorage storage.MarketStorage, cleanup func() error) {
+	assert.NoError(t, cleanup())
+
+	start := time.Date(2018, 2, 6, 10, 0, 0, 0, time.UTC)
+	assert.NoError(t, marketStorage.Save(
+		price(domain.ExchangeTypeBittrex, "BTC-CUR1", start, 1),
+		price(domain.ExchangeTypeBinance, "BTC-CUR1", start, 2),
+	))
+
+	// the backfill saves the candle of the same time again
+	assert.NoError(t, marketStorage.Save(domain.MarketCandle{
+		Exchange: domain.ExchangeTypeBittrex,
+		Market:   "BTC-CUR1",
+		Time:     start,
+		Price:    domain.OHLC{Open: 1, Close: 3, Min: 1, Max: 4},
+	}))
+
+	storageCandles, err := marketStorage.FetchCandles(domain.ExchangeTypeBittrex, "BTC-CUR1", start, start.Add(time.Hour), time.Minute)
+	assert.NoError(t, err)
+	assert.Len(t, storageCandles, 1)
+	assert.Equal(t, domain.OHLC{Open: 1, Close: 3, Min: 1, Max: 4}, storageCandles[0].Price)
+
+	storageCandles, err = marketStorage.FetchCandles(domain.ExchangeTypeBinance, "BTC-CUR1", start, start.Add(time.Hour), time.Minute)
+	assert.NoError(t, err)
+	assert.Len(t, storageCandles, 1)
+	assert.Equal(t, domain.OHLC{Open: 2, Close: 2, Min: 2, Max: 2}, storageCandles[0].Price)
+}
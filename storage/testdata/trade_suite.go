@@ -0,0 +1,83 @@
+package testdata
+
+import (
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/require"
+
+	"github.com/nawa/cryptoexchange-dashboard/storage"
+)
+
+// RunTradeStorageSuite checks that the storage implementation follows the semantics of storage.TradeStorage.
+// Every test removes all trades with cleanup before filling the storage
+func RunTradeStorageSuite(t *testing.T, tradeStorage storage.TradeStorage, cleanup func() error) {
+	t.Run("Fetch", func(t *testing.T) {
+		testTradeFetch(t, tradeStorage, cleanup)
+	})
+	t.Run("SaveTwice", func(t *testing.T) {
+		testTradeSaveTwice(t, tradeStorage, cleanup)
+	})
+}
+
+func testTradeFetch(t *testing.T, tradeStorage storage.TradeStorage, cleanup func() error) {
+	assert.NoError(t, cleanup())
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	trades := Trades()
+	trades[0].Time = now.Add(-2 * time.Hour)
+	trades[1].Time = now
+	trades[2].Time = now.Add(-time.Hour)
+
+	assert.NoError(t, tradeStorage.Save(trades...))
+
+	storageTrades, err := tradeStorage.Fetch(time.Time{}, time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, storageTrades, 3)
+	for i := range storageTrades {
+		storageTrades[i].Time = storageTrades[i].Time.UTC()
+	}
+	assert.Equal(t, trades[1], storageTrades[0])
+	assert.Equal(t, trades[2], storageTrades[1])
+	assert.Equal(t, trades[0], storageTrades[2])
+
+	storageTrades, err = tradeStorage.Fetch(now.Add(-time.Hour), now)
+	assert.NoError(t, err)
+	assert.Len(t, storageTrades, 1)
+	assert.Equal(t, trades[2].OrderID, storageTrades[0].OrderID)
+
+	storageTrades, err = tradeStorage.Fetch(now.Add(time.Hour), time.Time{})
+	assert.NoError(t, err)
+	assert.Empty(t, storageTrades)
+}
+
+func testTradeSaveTwice(t *testing.T, tradeStorage storage.TradeStorage, cleanup func() error) {
+	assert.NoError(t, cleanup())
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	trades := Trades()
+	for i := range trades {
+		trades[i].Time = now
+	}
+	assert.NoError(t, tradeStorage.Save(trades[:2]...))
+
+	// the partially filled order is filled more and the history is saved again with new rates
+	trades[1].Amount = 80
+	trades[1].USDTRate = 20000
+	trades[2].USDTRate = 20000
+	assert.NoError(t, tradeStorage.Save(trades...))
+
+	storageTrades, err := tradeStorage.Fetch(time.Time{}, time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, storageTrades, 3)
+
+	amounts := make(map[string]float64)
+	usdtRates := make(map[string]float64)
+	for _, trade := range storageTrades {
+		amounts[trade.OrderID] = trade.Amount
+		usdtRates[trade.OrderID] = trade.USDTRate
+	}
+	assert.Equal(t, map[string]float64{"uuid-1": 100, "uuid-2": 80, "CUR2BTC:1": 10}, amounts)
+	// rates of the first save are kept
+	assert.Equal(t, map[string]float64{"uuid-1": 10000, "uuid-2": 10000, "CUR2BTC:1": 20000}, usdtRates)
+}
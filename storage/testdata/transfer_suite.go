@@ -0,0 +1,79 @@
+package testdata
+
+import (
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/require"
+
+	"github.com/nawa/cryptoexchange-dashboard/storage"
+)
+
+// RunTransferStorageSuite checks that the storage implementation follows the semantics of storage.TransferStorage.
+// Every test removes all transfers with cleanup before filling the storage
+func RunTransferStorageSuite(t *testing.T, transferStorage storage.TransferStorage, cleanup func() error) {
+	t.Run("Fetch", func(t *testing.T) {
+		testTransferFetch(t, transferStorage, cleanup)
+	})
+	t.Run("SaveTwice", func(t *testing.T) {
+		testTransferSaveTwice(t, transferStorage, cleanup)
+	})
+}
+
+func testTransferFetch(t *testing.T, transferStorage storage.TransferStorage, cleanup func() error) {
+	assert.NoError(t, cleanup())
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	transfers := Transfers()
+	transfers[0].Time = now.Add(-2 * time.Hour)
+	transfers[1].Time = now
+	transfers[2].Time = now.Add(-time.Hour)
+
+	assert.NoError(t, transferStorage.Save(transfers...))
+
+	storageTransfers, err := transferStorage.Fetch(time.Time{}, time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, storageTransfers, 3)
+	for i := range storageTransfers {
+		storageTransfers[i].Time = storageTransfers[i].Time.UTC()
+	}
+	assert.Equal(t, transfers[1], storageTransfers[0])
+	assert.Equal(t, transfers[2], storageTransfers[1])
+	assert.Equal(t, transfers[0], storageTransfers[2])
+
+	storageTransfers, err = transferStorage.Fetch(now.Add(-time.Hour), now)
+	assert.NoError(t, err)
+	assert.Len(t, storageTransfers, 1)
+	assert.Equal(t, transfers[2].ID, storageTransfers[0].ID)
+
+	storageTransfers, err = transferStorage.Fetch(now.Add(time.Hour), time.Time{})
+	assert.NoError(t, err)
+	assert.Empty(t, storageTransfers)
+}
+
+func testTransferSaveTwice(t *testing.T, transferStorage storage.TransferStorage, cleanup func() error) {
+	assert.NoError(t, cleanup())
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	transfers := Transfers()
+	for i := range transfers {
+		transfers[i].Time = now
+	}
+	assert.NoError(t, transferStorage.Save(transfers[:2]...))
+
+	// the history is synced again with the newer rate of the currency
+	transfers[1].USDTRate = 20000
+	transfers[2].USDTRate = 20000
+	assert.NoError(t, transferStorage.Save(transfers...))
+
+	storageTransfers, err := transferStorage.Fetch(time.Time{}, time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, storageTransfers, 3)
+
+	rates := make(map[string]float64)
+	for _, transfer := range storageTransfers {
+		rates[string(transfer.Exchange)+"/"+transfer.ID] = transfer.USDTRate
+	}
+	// the same ID on different exchanges are different transfers, rates of the first save are kept
+	assert.Equal(t, map[string]float64{"bittrex/1": 10000, "bittrex/uuid-w1": 10000, "binance/1": 20000}, rates)
+}
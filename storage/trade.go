@@ -0,0 +1,18 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+)
+
+type TradeStorage interface {
+	// Init initializes the storage, such as prepares indexes and another
+	Init() error
+	// Save inserts new trades and replaces already saved ones with the same exchange and order ID,
+	// so the same history can be saved many times. BTC and USDT rates of saved trades are kept,
+	// they are the rates of the first sync
+	Save(trade ...domain.Trade) error
+	// Fetch returns trades in [from, to), the latest first. Zero to means no upper bound
+	Fetch(from, to time.Time) ([]domain.Trade, error)
+}
@@ -0,0 +1,18 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+)
+
+type TransferStorage interface {
+	// Init initializes the storage, such as prepares indexes and another
+	Init() error
+	// Save inserts new transfers and replaces already saved ones with the same exchange and ID,
+	// so the same history can be saved many times. BTC and USDT rates of saved transfers are kept,
+	// they are the rates of the first sync
+	Save(transfer ...domain.Transfer) error
+	// Fetch returns transfers in [from, to), the latest first. Zero to means no upper bound
+	Fetch(from, to time.Time) ([]domain.Transfer, error)
+}
@@ -0,0 +1,564 @@
+package usecase
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/hashicorp/go-multierror"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/notification"
+	"github.com/nawa/cryptoexchange-dashboard/storage"
+	"github.com/nawa/cryptoexchange-dashboard/usecase/ticker"
+)
+
+type AlertUsecases interface {
+	StartEvaluatingPeriodically(period time.Duration) (stop func(), err error)
+	// Checks all rules and notifies about fired ones. Rules fired before, even by the previous run,
+	// don't fire again until their cooldown is over
+	Evaluate() error
+}
+
+type alertUsecases struct {
+	rules []domain.AlertRule
+	// rulesFromStorage means that rules are read from the storage by every evaluation, they are managed by REST API
+	rulesFromStorage bool
+	exchange         storage.Exchange
+	alertStorage     storage.AlertStorage
+	notifier         notification.Notifier
+	log              *logrus.Entry
+	now              func() time.Time
+
+	lock sync.Mutex
+	// states are loaded from the storage by the first evaluation
+	states map[string]domain.AlertState
+	// prices are the last prices of markets with percent change rules, the oldest first
+	prices map[string][]pricePoint
+	// windows are the longest percent change windows of markets
+	windows map[string]time.Duration
+	// peaks are the highest prices of held positions since the purchase by position keys,
+	// they're saved with states of trailing stop rules to survive restarts
+	peaks map[string]float64
+}
+
+type pricePoint struct {
+	time  time.Time
+	price float64
+}
+
+// alertObservation is the value the rule is checked against in one evaluation
+type alertObservation struct {
+	markets   map[string]*domain.MarketInfo
+	portfolio []domain.Balance
+	// orders are held positions, nil if there are no position rules or they can't be requested
+	orders []domain.Order
+}
+
+// NewAlertUsecase creates the usecase evaluating the fixed rules
+func NewAlertUsecase(rules []domain.AlertRule, exchange storage.Exchange, alertStorage storage.AlertStorage, notifier notification.Notifier) AlertUsecases {
+	log := logrus.WithField("component", "alertUC")
+	return &alertUsecases{
+		rules:        rules,
+		exchange:     exchange,
+		alertStorage: alertStorage,
+		notifier:     notifier,
+		log:          log,
+		now:          time.Now,
+		prices:       make(map[string][]pricePoint),
+		windows:      alertWindows(rules),
+		peaks:        make(map[string]float64),
+	}
+}
+
+// NewStoredAlertUsecase creates the usecase evaluating rules saved in the storage, changes of rules are applied by the next evaluation
+func NewStoredAlertUsecase(exchange storage.Exchange, alertStorage storage.AlertStorage, notifier notification.Notifier) AlertUsecases {
+	u := NewAlertUsecase(nil, exchange, alertStorage, notifier).(*alertUsecases)
+	u.rulesFromStorage = true
+	return u
+}
+
+// alertWindows returns the longest percent change window of every market
+func alertWindows(rules []domain.AlertRule) map[string]time.Duration {
+	windows := make(map[string]time.Duration)
+	for _, rule := range rules {
+		if rule.Type == domain.AlertTypePercentChange && rule.Window > windows[rule.Market] {
+			windows[rule.Market] = rule.Window
+		}
+	}
+	return windows
+}
+
+func (u *alertUsecases) StartEvaluatingPeriodically(period time.Duration) (stop func(), err error) {
+	ticker := ticker.NewTicker(period, u.Evaluate)
+	err = ticker.Start()
+	if err != nil {
+		return nil, err
+	}
+
+	return func() {
+		ticker.Stop()
+	}, err
+}
+
+func (u *alertUsecases) Evaluate() error {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	if u.states == nil {
+		err := u.loadStates()
+		if err != nil {
+			u.log.WithField("method", "Evaluate").WithError(err).Error("can't load alert states")
+			return err
+		}
+	}
+
+	if u.rulesFromStorage {
+		err := u.loadRules()
+		if err != nil {
+			u.log.WithField("method", "Evaluate").WithError(err).Error("can't load alert rules")
+			return err
+		}
+	}
+
+	now := u.now()
+	observation, result := u.observe(now)
+
+	var (
+		fired    []domain.AlertState
+		triggers []domain.AlertTrigger
+	)
+	changed := u.updatePeakStates(observation)
+	for _, rule := range u.rules {
+		value, message, ok := u.check(rule, observation, now)
+		if !ok || !u.isArmed(rule, now) {
+			continue
+		}
+
+		trigger := domain.AlertTrigger{
+			RuleID:   rule.ID,
+			Time:     now,
+			Value:    value,
+			Message:  message,
+			Channels: rule.Channels,
+		}
+		err := u.notifier.Notify(trigger)
+		if err != nil {
+			// the rule fires again by the next evaluation
+			result = multierror.Append(result, fmt.Errorf("rule '%s': %s", rule.ID, err))
+			continue
+		}
+
+		state := u.states[rule.ID]
+		state.RuleID = rule.ID
+		state.LastFired = now
+		u.states[rule.ID] = state
+		fired = append(fired, state)
+		triggers = append(triggers, trigger)
+		delete(changed, rule.ID)
+	}
+
+	for _, rule := range u.rules {
+		if changed[rule.ID] {
+			fired = append(fired, u.states[rule.ID])
+		}
+	}
+	if len(fired) > 0 {
+		err := u.alertStorage.SaveState(fired...)
+		if err != nil {
+			result = multierror.Append(result, err)
+		}
+	}
+	if len(triggers) > 0 {
+		err := u.alertStorage.SaveTrigger(triggers...)
+		if err != nil {
+			result = multierror.Append(result, err)
+		}
+	}
+
+	if result != nil {
+		u.log.WithField("method", "Evaluate").WithError(result).Error()
+	}
+	return result
+}
+
+// loadRules replaces rules by saved ones, forgets states of changed and removed rules
+// and prices of markets without percent change rules anymore
+func (u *alertUsecases) loadRules() error {
+	rules, err := u.alertStorage.FetchRules()
+	if err != nil {
+		return err
+	}
+
+	loaded := make(map[string]domain.AlertRule, len(rules))
+	for _, rule := range rules {
+		loaded[rule.ID] = rule
+	}
+	for _, rule := range u.rules {
+		if changed, ok := loaded[rule.ID]; !ok || !reflect.DeepEqual(changed, rule) {
+			// the changed rule starts without the cooldown of the old one
+			delete(u.states, rule.ID)
+		}
+	}
+
+	u.rules = rules
+	u.windows = alertWindows(rules)
+	for market := range u.prices {
+		if _, ok := u.windows[market]; !ok {
+			delete(u.prices, market)
+		}
+	}
+	return nil
+}
+
+func (u *alertUsecases) loadStates() error {
+	states, err := u.alertStorage.FetchStates()
+	if err != nil {
+		return err
+	}
+
+	u.states = make(map[string]domain.AlertState, len(states))
+	for _, state := range states {
+		u.states[state.RuleID] = state
+		for key, peak := range state.Peaks {
+			u.peaks[key] = math.Max(u.peaks[key], peak)
+		}
+	}
+	return nil
+}
+
+// updatePeakStates sets peaks of positions watched by trailing stop rules to their states and returns IDs of rules
+// with changed peaks. States aren't changed if positions aren't known
+func (u *alertUsecases) updatePeakStates(observation *alertObservation) map[string]bool {
+	changed := make(map[string]bool)
+	if observation.orders == nil {
+		return changed
+	}
+
+	for _, rule := range u.rules {
+		if rule.Type != domain.AlertTypeTrailingStop {
+			continue
+		}
+
+		peaks := make(map[string]float64)
+		for _, o := range observation.orders {
+			key := positionKey(o)
+			if (rule.Market == "" || o.Market == rule.Market) && u.peaks[key] > 0 {
+				peaks[key] = u.peaks[key]
+			}
+		}
+
+		state := u.states[rule.ID]
+		if len(peaks) == len(state.Peaks) {
+			same := true
+			for key, peak := range peaks {
+				if saved, ok := state.Peaks[key]; !ok || saved != peak {
+					same = false
+					break
+				}
+			}
+			if same {
+				continue
+			}
+		}
+
+		state.RuleID = rule.ID
+		state.Peaks = peaks
+		if len(peaks) == 0 {
+			state.Peaks = nil
+		}
+		u.states[rule.ID] = state
+		changed[rule.ID] = true
+	}
+	return changed
+}
+
+// observe requests every market of rules once, the balance if there are portfolio rules
+// and orders with their markets if there are position rules. Failed requests are skipped, rules depending on them aren't checked
+func (u *alertUsecases) observe(now time.Time) (*alertObservation, error) {
+	var result error
+	observation := &alertObservation{
+		markets: make(map[string]*domain.MarketInfo),
+	}
+
+	portfolioRequested, ordersRequested := false, false
+	for _, rule := range u.rules {
+		switch rule.Type {
+		case domain.AlertTypePortfolioAbove, domain.AlertTypePortfolioBelow:
+			if portfolioRequested {
+				continue
+			}
+			portfolioRequested = true
+
+			balances, err := u.exchange.GetBalance()
+			if err != nil {
+				result = multierror.Append(result, err)
+				continue
+			}
+			if len(balances) > 0 {
+				balances = append(balances, calculateTotals(balances, true)...)
+			}
+			observation.portfolio = balances
+		case domain.AlertTypeTrailingStop, domain.AlertTypePositionChange:
+			if !ordersRequested {
+				ordersRequested = true
+
+				orders, err := u.exchange.GetOrders()
+				if err != nil {
+					result = multierror.Append(result, err)
+					continue
+				}
+				if orders == nil {
+					orders = []domain.Order{}
+				}
+				observation.orders = orders
+			}
+
+			for _, o := range observation.orders {
+				if rule.Market == "" || o.Market == rule.Market {
+					result = u.observeMarket(observation, o.Market, now, result)
+				}
+			}
+		default:
+			result = u.observeMarket(observation, rule.Market, now, result)
+		}
+	}
+
+	if observation.orders != nil {
+		u.updatePeaks(observation, now)
+	}
+	return observation, result
+}
+
+// observeMarket requests the market if it hasn't been requested yet and appends the error to the result
+func (u *alertUsecases) observeMarket(observation *alertObservation, market string, now time.Time, result error) error {
+	if _, ok := observation.markets[market]; ok {
+		return result
+	}
+
+	marketInfo, err := u.exchange.GetMarketInfo(market)
+	if err != nil {
+		// nil marks the failed market not to request it again
+		observation.markets[market] = nil
+		return multierror.Append(result, err)
+	}
+	observation.markets[market] = marketInfo
+	u.addPrice(market, pricePoint{time: now, price: marketInfo.Last})
+	return result
+}
+
+// updatePeaks raises peaks of held positions to the last prices and forgets sold ones. The peak of the new position starts
+// from its buy rate, or from the 24 hours high of the market if the position is older, prices before aren't known
+func (u *alertUsecases) updatePeaks(observation *alertObservation, now time.Time) {
+	peaks := make(map[string]float64, len(observation.orders))
+	for _, o := range observation.orders {
+		key := positionKey(o)
+		peak, ok := u.peaks[key]
+		marketInfo := observation.markets[o.Market]
+		if marketInfo == nil {
+			// the market isn't watched by any rule or has failed, the peak is kept as is
+			if ok {
+				peaks[key] = peak
+			}
+			continue
+		}
+
+		if !ok {
+			peak = o.BuyRate
+			if now.Sub(o.Time) >= time.Hour*24 {
+				peak = math.Max(peak, marketInfo.High)
+			}
+		}
+		peaks[key] = math.Max(peak, marketInfo.Last)
+	}
+	u.peaks = peaks
+}
+
+func positionKey(o domain.Order) string {
+	return fmt.Sprintf("%s/%s/%s/%d", o.Exchange, o.Account, o.Market, o.Time.UnixNano())
+}
+
+// addPrice remembers the price of the market with percent change rules. Prices older than the longest window
+// are dropped except the latest of them, the base of the change over the whole window
+func (u *alertUsecases) addPrice(market string, point pricePoint) {
+	window, ok := u.windows[market]
+	if !ok {
+		return
+	}
+
+	prices := append(u.prices[market], point)
+	start := 0
+	for i := 1; i < len(prices) && !prices[i].time.After(point.time.Add(-window)); i++ {
+		start = i
+	}
+	u.prices[market] = prices[start:]
+}
+
+// basePrice returns the latest price not newer than the window ago
+func (u *alertUsecases) basePrice(market string, window time.Duration, now time.Time) (float64, bool) {
+	var (
+		base  float64
+		found bool
+	)
+	for _, p := range u.prices[market] {
+		if p.time.After(now.Add(-window)) {
+			break
+		}
+		base, found = p.price, true
+	}
+	return base, found
+}
+
+// check reports whether the condition of the rule holds, the observed value and the message for the user
+func (u *alertUsecases) check(rule domain.AlertRule, observation *alertObservation, now time.Time) (value float64, message string, ok bool) {
+	switch rule.Type {
+	case domain.AlertTypePriceAbove, domain.AlertTypePriceBelow:
+		marketInfo := observation.markets[rule.Market]
+		if marketInfo == nil {
+			return 0, "", false
+		}
+
+		value = marketInfo.Last
+		if rule.Type == domain.AlertTypePriceAbove {
+			return value, fmt.Sprintf("%s price %s is above %s", rule.Market, formatFloat(value), formatFloat(rule.Value)), value >= rule.Value
+		}
+		return value, fmt.Sprintf("%s price %s is below %s", rule.Market, formatFloat(value), formatFloat(rule.Value)), value <= rule.Value
+	case domain.AlertTypePercentChange:
+		marketInfo := observation.markets[rule.Market]
+		if marketInfo == nil {
+			return 0, "", false
+		}
+
+		base, found := u.basePrice(rule.Market, rule.Window, now)
+		if !found || base == 0 {
+			// not enough history yet
+			return 0, "", false
+		}
+
+		value = (marketInfo.Last - base) / base * 100
+		message = fmt.Sprintf("%s price changed by %.2f%% over %s to %s", rule.Market, value, rule.Window, formatFloat(marketInfo.Last))
+		if rule.Value > 0 {
+			return value, message, value >= rule.Value
+		}
+		return value, message, value <= rule.Value
+	case domain.AlertTypePortfolioAbove, domain.AlertTypePortfolioBelow:
+		var found bool
+		for _, b := range observation.portfolio {
+			if b.Currency != rule.Currency {
+				continue
+			}
+			found = true
+			if rule.Quote == "BTC" {
+				value += b.BTCAmount
+			} else {
+				value += b.USDTAmount
+			}
+		}
+		if !found {
+			return 0, "", false
+		}
+
+		if rule.Type == domain.AlertTypePortfolioAbove {
+			return value, fmt.Sprintf("%s portfolio %s %s is above %s %s", rule.Currency, formatFloat(value), rule.Quote, formatFloat(rule.Value), rule.Quote), value >= rule.Value
+		}
+		return value, fmt.Sprintf("%s portfolio %s %s is below %s %s", rule.Currency, formatFloat(value), rule.Quote, formatFloat(rule.Value), rule.Quote), value <= rule.Value
+	case domain.AlertTypeTrailingStop, domain.AlertTypePositionChange:
+		return u.checkPositions(rule, observation)
+	}
+	return 0, "", false
+}
+
+// checkPositions checks held positions of the rule. The message tells about every moved position with its profit if it's sold now,
+// the value is the change in percent of the position moved the most
+func (u *alertUsecases) checkPositions(rule domain.AlertRule, observation *alertObservation) (value float64, message string, ok bool) {
+	falling := rule.Type == domain.AlertTypeTrailingStop || rule.Value < 0
+
+	var messages []string
+	for _, o := range observation.orders {
+		if rule.Market != "" && o.Market != rule.Market {
+			continue
+		}
+		marketInfo := observation.markets[o.Market]
+		if marketInfo == nil {
+			continue
+		}
+
+		var (
+			change      float64
+			description string
+		)
+		if rule.Type == domain.AlertTypeTrailingStop {
+			peak := u.peaks[positionKey(o)]
+			if peak == 0 {
+				continue
+			}
+			change = (marketInfo.Last - peak) / peak * 100
+			if -change < rule.Value {
+				continue
+			}
+			description = fmt.Sprintf("dropped by %.2f%% from peak %s to %s", -change, formatFloat(peak), formatFloat(marketInfo.Last))
+		} else {
+			if o.BuyRate == 0 {
+				continue
+			}
+			change = (marketInfo.Last - o.BuyRate) / o.BuyRate * 100
+			if (rule.Value > 0 && change < rule.Value) || (rule.Value < 0 && change > rule.Value) {
+				continue
+			}
+			description = fmt.Sprintf("changed by %.2f%% from buy rate %s to %s", change, formatFloat(o.BuyRate), formatFloat(marketInfo.Last))
+		}
+
+		messages = append(messages, positionMessage(o, marketInfo.Last, description))
+		if !ok || (falling && change < value) || (!falling && change > value) {
+			value = change
+		}
+		ok = true
+	}
+	return value, strings.Join(messages, "; "), ok
+}
+
+// positionMessage describes the position with its profit if it's sold at the price
+func positionMessage(o domain.Order, price float64, description string) string {
+	o.SellNowRate = price
+	profit := openProfit(o)
+
+	position := o.Market + " position"
+	if o.Account != "" {
+		position += " of " + o.Account
+	}
+
+	quote := strings.SplitN(o.Market, "-", 2)[0]
+	pnl := fmt.Sprintf("P&L %s %s (%.2f%%", formatFloat(roundAmount(profit.Profit)), quote, profit.Percent)
+	if quote != "USDT" {
+		pnl += fmt.Sprintf(", %s USDT", formatFloat(roundAmount(profit.ProfitUSDT)))
+	}
+	return fmt.Sprintf("%s %s, %s)", position, description, pnl)
+}
+
+// isArmed reports whether the cooldown of the rule is over since it has fired last time
+func (u *alertUsecases) isArmed(rule domain.AlertRule, now time.Time) bool {
+	state, ok := u.states[rule.ID]
+	if !ok {
+		return true
+	}
+
+	cooldown := rule.Cooldown
+	if cooldown == 0 {
+		cooldown = domain.DefaultAlertCooldown
+	}
+	return !now.Before(state.LastFired.Add(cooldown))
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// roundAmount rounds the amount to 8 decimal places, the precision of exchanges
+func roundAmount(f float64) float64 {
+	return math.Round(f*1e8) / 1e8
+}
@@ -0,0 +1,233 @@
+package usecase
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/pkg/errors"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage"
+)
+
+var (
+	ErrAlertRuleNotFound = errors.New("alert rule not found")
+	ErrAlertRuleExists   = errors.New("alert rule already exists")
+)
+
+// ValidationError is returned for rules which can't be saved
+type ValidationError struct {
+	Err error
+}
+
+func (e ValidationError) Error() string {
+	return e.Err.Error()
+}
+
+type AlertRuleUsecases interface {
+	// All rules ordered by IDs
+	GetRules() ([]domain.AlertRuleInfo, error)
+	// Returns ErrAlertRuleNotFound if the rule doesn't exist
+	GetRule(id string) (*domain.AlertRuleInfo, error)
+	// Saves the new rule, the random ID is generated if it's empty. Returns ErrAlertRuleExists if the ID is taken
+	// and ValidationError if the rule is wrong or its channels aren't configured
+	CreateRule(rule domain.AlertRule) (*domain.AlertRule, error)
+	// Replaces the saved rule with the same ID, the changed rule starts without the cooldown of the old one.
+	// Returns ErrAlertRuleNotFound if the rule doesn't exist and ValidationError if the rule is wrong or its channels aren't configured
+	UpdateRule(rule domain.AlertRule) error
+	// Returns ErrAlertRuleNotFound if the rule doesn't exist
+	DeleteRule(id string) error
+	// At most limit latest triggers of the rule, of all rules if ruleID is empty
+	FetchTriggers(ruleID string, limit int) ([]domain.AlertTrigger, error)
+}
+
+type alertRuleUsecases struct {
+	alertStorage storage.AlertStorage
+	// channels are names of notifiers configured for rules
+	channels map[string]bool
+	log      *logrus.Entry
+}
+
+// NewAlertRuleUsecase creates the usecase of rules which can be sent to the channels only
+func NewAlertRuleUsecase(alertStorage storage.AlertStorage, channels []string) AlertRuleUsecases {
+	log := logrus.WithField("component", "alertRuleUC")
+	u := &alertRuleUsecases{
+		alertStorage: alertStorage,
+		channels:     make(map[string]bool, len(channels)),
+		log:          log,
+	}
+	for _, channel := range channels {
+		u.channels[channel] = true
+	}
+	return u
+}
+
+func (u *alertRuleUsecases) GetRules() ([]domain.AlertRuleInfo, error) {
+	rules, err := u.alertStorage.FetchRules()
+	if err != nil {
+		u.log.WithField("method", "GetRules").WithError(err).Error()
+		return nil, err
+	}
+
+	lastFired, err := u.lastFired()
+	if err != nil {
+		u.log.WithField("method", "GetRules").WithError(err).Error()
+		return nil, err
+	}
+
+	result := make([]domain.AlertRuleInfo, len(rules))
+	for i, rule := range rules {
+		result[i] = domain.AlertRuleInfo{
+			Rule:      rule,
+			LastFired: lastFired[rule.ID].LastFired,
+		}
+	}
+	return result, nil
+}
+
+func (u *alertRuleUsecases) GetRule(id string) (*domain.AlertRuleInfo, error) {
+	rule, err := u.alertStorage.FetchRule(id)
+	if err == storage.ErrNotFound {
+		return nil, ErrAlertRuleNotFound
+	}
+	if err != nil {
+		u.log.WithField("method", "GetRule").WithError(err).Error()
+		return nil, err
+	}
+
+	lastFired, err := u.lastFired()
+	if err != nil {
+		u.log.WithField("method", "GetRule").WithError(err).Error()
+		return nil, err
+	}
+
+	return &domain.AlertRuleInfo{
+		Rule:      *rule,
+		LastFired: lastFired[rule.ID].LastFired,
+	}, nil
+}
+
+func (u *alertRuleUsecases) lastFired() (map[string]domain.AlertState, error) {
+	states, err := u.alertStorage.FetchStates()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]domain.AlertState, len(states))
+	for _, state := range states {
+		result[state.RuleID] = state
+	}
+	return result, nil
+}
+
+func (u *alertRuleUsecases) CreateRule(rule domain.AlertRule) (*domain.AlertRule, error) {
+	if rule.ID == "" {
+		id, err := newAlertRuleID()
+		if err != nil {
+			u.log.WithField("method", "CreateRule").WithError(err).Error()
+			return nil, err
+		}
+		rule.ID = id
+	}
+
+	err := u.validate(rule)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = u.alertStorage.FetchRule(rule.ID)
+	if err == nil {
+		return nil, ErrAlertRuleExists
+	}
+	if err != storage.ErrNotFound {
+		u.log.WithField("method", "CreateRule").WithError(err).Error()
+		return nil, err
+	}
+
+	err = u.alertStorage.SaveRule(rule)
+	if err != nil {
+		u.log.WithField("method", "CreateRule").WithError(err).Error()
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (u *alertRuleUsecases) UpdateRule(rule domain.AlertRule) error {
+	err := u.validate(rule)
+	if err != nil {
+		return err
+	}
+
+	saved, err := u.alertStorage.FetchRule(rule.ID)
+	if err == storage.ErrNotFound {
+		return ErrAlertRuleNotFound
+	}
+	if err != nil {
+		u.log.WithField("method", "UpdateRule").WithError(err).Error()
+		return err
+	}
+
+	err = u.alertStorage.SaveRule(rule)
+	if err != nil {
+		u.log.WithField("method", "UpdateRule").WithError(err).Error()
+		return err
+	}
+
+	if !reflect.DeepEqual(*saved, rule) {
+		// the last firing and peaks of the old rule don't apply to the changed one
+		err = u.alertStorage.SaveState(domain.AlertState{RuleID: rule.ID})
+		if err != nil {
+			u.log.WithField("method", "UpdateRule").WithError(err).Error()
+			return err
+		}
+	}
+	return nil
+}
+
+func (u *alertRuleUsecases) DeleteRule(id string) error {
+	err := u.alertStorage.DeleteRule(id)
+	if err == storage.ErrNotFound {
+		return ErrAlertRuleNotFound
+	}
+	if err != nil {
+		u.log.WithField("method", "DeleteRule").WithError(err).Error()
+		return err
+	}
+	return nil
+}
+
+func (u *alertRuleUsecases) FetchTriggers(ruleID string, limit int) ([]domain.AlertTrigger, error) {
+	triggers, err := u.alertStorage.FetchTriggers(ruleID, limit)
+	if err != nil {
+		u.log.WithField("method", "FetchTriggers").WithError(err).Error()
+		return nil, err
+	}
+	return triggers, nil
+}
+
+// validate returns ValidationError if the rule is wrong or it has channels without configured notifiers
+func (u *alertRuleUsecases) validate(rule domain.AlertRule) error {
+	err := rule.Validate()
+	if err != nil {
+		return ValidationError{err}
+	}
+
+	for _, name := range rule.Channels {
+		if !u.channels[name] {
+			return ValidationError{fmt.Errorf("notifier '%s' isn't defined", name)}
+		}
+	}
+	return nil
+}
+
+func newAlertRuleID() (string, error) {
+	id := make([]byte, 8)
+	_, err := rand.Read(id)
+	if err != nil {
+		return "", fmt.Errorf("can't generate rule ID: %s", err)
+	}
+	return hex.EncodeToString(id), nil
+}
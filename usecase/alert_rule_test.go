@@ -0,0 +1,144 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	assert "github.com/stretchr/testify/require"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage"
+	storagemocks "github.com/nawa/cryptoexchange-dashboard/storage/mocks"
+)
+
+var testAlertRule = domain.AlertRule{ID: "eth", Type: domain.AlertTypePriceAbove, Market: "BTC-ETH", Value: 0.08}
+
+func TestAlertRuleUsecases_GetRules(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	alertStorage := storagemocks.NewMockAlertStorage(ctrl)
+	u := NewAlertRuleUsecase(alertStorage, []string{"desktop", "mail"})
+
+	ltc := domain.AlertRule{ID: "ltc", Type: domain.AlertTypePriceBelow, Market: "BTC-LTC", Value: 0.01}
+	alertStorage.EXPECT().FetchRules().Return([]domain.AlertRule{testAlertRule, ltc}, nil)
+	alertStorage.EXPECT().FetchStates().Return([]domain.AlertState{{RuleID: "ltc", LastFired: time.Unix(100, 0)}}, nil)
+
+	rules, err := u.GetRules()
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.AlertRuleInfo{
+		{Rule: testAlertRule},
+		{Rule: ltc, LastFired: time.Unix(100, 0)},
+	}, rules)
+
+	alertStorage.EXPECT().FetchRules().Return(nil, errExpected)
+	_, err = u.GetRules()
+	assert.Error(t, err)
+
+	alertStorage.EXPECT().FetchRule("eth").Return(&testAlertRule, nil)
+	alertStorage.EXPECT().FetchStates().Return(nil, nil)
+	rule, err := u.GetRule("eth")
+	assert.NoError(t, err)
+	assert.Equal(t, &domain.AlertRuleInfo{Rule: testAlertRule}, rule)
+
+	alertStorage.EXPECT().FetchRule("unknown").Return(nil, storage.ErrNotFound)
+	_, err = u.GetRule("unknown")
+	assert.Equal(t, ErrAlertRuleNotFound, err)
+}
+
+func TestAlertRuleUsecases_CreateRule(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	alertStorage := storagemocks.NewMockAlertStorage(ctrl)
+	u := NewAlertRuleUsecase(alertStorage, []string{"desktop", "mail"})
+
+	alertStorage.EXPECT().FetchRule("eth").Return(nil, storage.ErrNotFound)
+	alertStorage.EXPECT().SaveRule(testAlertRule).Return(nil)
+	rule, err := u.CreateRule(testAlertRule)
+	assert.NoError(t, err)
+	assert.Equal(t, testAlertRule, *rule)
+
+	alertStorage.EXPECT().FetchRule("eth").Return(&testAlertRule, nil)
+	_, err = u.CreateRule(testAlertRule)
+	assert.Equal(t, ErrAlertRuleExists, err)
+
+	// the ID is generated
+	withoutID := testAlertRule
+	withoutID.ID = ""
+	alertStorage.EXPECT().FetchRule(gomock.Any()).Return(nil, storage.ErrNotFound)
+	alertStorage.EXPECT().SaveRule(gomock.Any()).Return(nil)
+	rule, err = u.CreateRule(withoutID)
+	assert.NoError(t, err)
+	assert.Len(t, rule.ID, 16)
+
+	invalid := testAlertRule
+	invalid.Value = 0
+	_, err = u.CreateRule(invalid)
+	assert.IsType(t, ValidationError{}, err)
+
+	// the notifier isn't configured
+	invalid = testAlertRule
+	invalid.Channels = []string{"mail", "mial"}
+	_, err = u.CreateRule(invalid)
+	assert.IsType(t, ValidationError{}, err)
+	assert.EqualError(t, err, "notifier 'mial' isn't defined")
+
+	alertStorage.EXPECT().FetchRule("eth").Return(nil, storage.ErrNotFound)
+	alertStorage.EXPECT().SaveRule(testAlertRule).Return(errExpected)
+	_, err = u.CreateRule(testAlertRule)
+	assert.Equal(t, errExpected, err)
+}
+
+func TestAlertRuleUsecases_UpdateRule(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	alertStorage := storagemocks.NewMockAlertStorage(ctrl)
+	u := NewAlertRuleUsecase(alertStorage, []string{"desktop", "mail"})
+
+	// the state of the changed rule is reset
+	updated := testAlertRule
+	updated.Value = 0.09
+	alertStorage.EXPECT().FetchRule("eth").Return(&testAlertRule, nil)
+	alertStorage.EXPECT().SaveRule(updated).Return(nil)
+	alertStorage.EXPECT().SaveState(domain.AlertState{RuleID: "eth"}).Return(nil)
+	assert.NoError(t, u.UpdateRule(updated))
+
+	alertStorage.EXPECT().FetchRule("eth").Return(&updated, nil)
+	alertStorage.EXPECT().SaveRule(updated).Return(nil)
+	assert.NoError(t, u.UpdateRule(updated))
+
+	alertStorage.EXPECT().FetchRule("eth").Return(&testAlertRule, nil)
+	alertStorage.EXPECT().SaveRule(updated).Return(nil)
+	alertStorage.EXPECT().SaveState(domain.AlertState{RuleID: "eth"}).Return(errExpected)
+	assert.Equal(t, errExpected, u.UpdateRule(updated))
+
+	updated.Channels = []string{"chat"}
+	assert.IsType(t, ValidationError{}, u.UpdateRule(updated))
+	updated.Channels = nil
+
+	alertStorage.EXPECT().FetchRule("eth").Return(nil, storage.ErrNotFound)
+	assert.Equal(t, ErrAlertRuleNotFound, u.UpdateRule(updated))
+
+	updated.Market = ""
+	assert.IsType(t, ValidationError{}, u.UpdateRule(updated))
+}
+
+func TestAlertRuleUsecases_DeleteRule(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	alertStorage := storagemocks.NewMockAlertStorage(ctrl)
+	u := NewAlertRuleUsecase(alertStorage, []string{"desktop", "mail"})
+
+	alertStorage.EXPECT().DeleteRule("eth").Return(nil)
+	assert.NoError(t, u.DeleteRule("eth"))
+
+	alertStorage.EXPECT().DeleteRule("eth").Return(storage.ErrNotFound)
+	assert.Equal(t, ErrAlertRuleNotFound, u.DeleteRule("eth"))
+
+	alertStorage.EXPECT().DeleteRule("eth").Return(errExpected)
+	assert.Equal(t, errExpected, u.DeleteRule("eth"))
+}
@@ -0,0 +1,357 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	assert "github.com/stretchr/testify/require"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	notificationmocks "github.com/nawa/cryptoexchange-dashboard/notification/mocks"
+	storagemocks "github.com/nawa/cryptoexchange-dashboard/storage/mocks"
+	"github.com/nawa/cryptoexchange-dashboard/usecase/testdata"
+)
+
+type alertMocks struct {
+	exchange     *storagemocks.MockExchange
+	alertStorage *storagemocks.MockAlertStorage
+	notifier     *notificationmocks.MockNotifier
+}
+
+func newTestAlertUsecase(ctrl *gomock.Controller, now *time.Time, rules ...domain.AlertRule) (*alertUsecases, alertMocks) {
+	m := alertMocks{
+		exchange:     storagemocks.NewMockExchange(ctrl),
+		alertStorage: storagemocks.NewMockAlertStorage(ctrl),
+		notifier:     notificationmocks.NewMockNotifier(ctrl),
+	}
+	u := NewAlertUsecase(rules, m.exchange, m.alertStorage, m.notifier).(*alertUsecases)
+	u.now = func() time.Time {
+		return *now
+	}
+	return u, m
+}
+
+func TestAlertUsecases_Evaluate_Price(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Unix(1000, 0)
+	rules := []domain.AlertRule{
+		{ID: "above", Type: domain.AlertTypePriceAbove, Market: "BTC-ETH", Value: 0.08, Cooldown: time.Minute},
+		{ID: "below", Type: domain.AlertTypePriceBelow, Market: "BTC-ETH", Value: 0.05, Channels: []string{"mail"}},
+	}
+	u, m := newTestAlertUsecase(ctrl, &now, rules...)
+
+	// "below" has fired before the restart
+	m.alertStorage.EXPECT().FetchStates().Return([]domain.AlertState{{RuleID: "below", LastFired: now.Add(-time.Minute)}}, nil)
+	m.exchange.EXPECT().GetMarketInfo("BTC-ETH").Return(&domain.MarketInfo{MarketName: "BTC-ETH", Last: 0.04}, nil)
+	assert.NoError(t, u.Evaluate())
+
+	// the market is requested once for both rules
+	m.exchange.EXPECT().GetMarketInfo("BTC-ETH").Return(&domain.MarketInfo{MarketName: "BTC-ETH", Last: 0.081}, nil)
+	m.notifier.EXPECT().Notify(domain.AlertTrigger{
+		RuleID:  "above",
+		Time:    now,
+		Value:   0.081,
+		Message: "BTC-ETH price 0.081 is above 0.08",
+	}).Return(nil)
+	m.alertStorage.EXPECT().SaveState(domain.AlertState{RuleID: "above", LastFired: now}).Return(nil)
+	m.alertStorage.EXPECT().SaveTrigger(domain.AlertTrigger{
+		RuleID:  "above",
+		Time:    now,
+		Value:   0.081,
+		Message: "BTC-ETH price 0.081 is above 0.08",
+	}).Return(nil)
+	assert.NoError(t, u.Evaluate())
+
+	// the cooldown isn't over
+	now = now.Add(time.Second * 30)
+	m.exchange.EXPECT().GetMarketInfo("BTC-ETH").Return(&domain.MarketInfo{MarketName: "BTC-ETH", Last: 0.082}, nil)
+	assert.NoError(t, u.Evaluate())
+
+	// re-armed
+	now = now.Add(time.Second * 30)
+	m.exchange.EXPECT().GetMarketInfo("BTC-ETH").Return(&domain.MarketInfo{MarketName: "BTC-ETH", Last: 0.083}, nil)
+	m.notifier.EXPECT().Notify(gomock.Any()).Return(nil)
+	m.alertStorage.EXPECT().SaveState(domain.AlertState{RuleID: "above", LastFired: now}).Return(nil)
+	m.alertStorage.EXPECT().SaveTrigger(gomock.Any()).Return(nil)
+	assert.NoError(t, u.Evaluate())
+
+	// the default cooldown of "below" is over
+	now = now.Add(time.Hour)
+	m.exchange.EXPECT().GetMarketInfo("BTC-ETH").Return(&domain.MarketInfo{MarketName: "BTC-ETH", Last: 0.05}, nil)
+	m.notifier.EXPECT().Notify(domain.AlertTrigger{
+		RuleID:   "below",
+		Time:     now,
+		Value:    0.05,
+		Message:  "BTC-ETH price 0.05 is below 0.05",
+		Channels: []string{"mail"},
+	}).Return(nil)
+	m.alertStorage.EXPECT().SaveState(domain.AlertState{RuleID: "below", LastFired: now}).Return(nil)
+	m.alertStorage.EXPECT().SaveTrigger(gomock.Any()).Return(nil)
+	assert.NoError(t, u.Evaluate())
+}
+
+func TestAlertUsecases_Evaluate_PercentChange(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Unix(1000, 0)
+	u, m := newTestAlertUsecase(ctrl, &now,
+		domain.AlertRule{ID: "drop", Type: domain.AlertTypePercentChange, Market: "BTC-ETH", Value: -10, Window: time.Hour},
+		domain.AlertRule{ID: "rise", Type: domain.AlertTypePercentChange, Market: "BTC-ETH", Value: 5, Window: time.Minute * 10},
+	)
+	m.alertStorage.EXPECT().FetchStates().Return(nil, nil)
+
+	prices := []float64{100, 100, 95, 104, 89}
+	for i, price := range prices {
+		if i > 0 {
+			now = now.Add(time.Minute * 20)
+		}
+		m.exchange.EXPECT().GetMarketInfo("BTC-ETH").Return(&domain.MarketInfo{MarketName: "BTC-ETH", Last: price}, nil)
+		switch i {
+		case 3:
+			// 104 is 9.47% more than 95 twenty minutes ago
+			m.notifier.EXPECT().Notify(domain.AlertTrigger{
+				RuleID:  "rise",
+				Time:    now,
+				Value:   (prices[3] - prices[2]) / prices[2] * 100,
+				Message: "BTC-ETH price changed by 9.47% over 10m0s to 104",
+			}).Return(nil)
+			m.alertStorage.EXPECT().SaveState(domain.AlertState{RuleID: "rise", LastFired: now}).Return(nil)
+			m.alertStorage.EXPECT().SaveTrigger(gomock.Any()).Return(nil)
+		case 4:
+			// 89 is 11% less than 100 an hour ago
+			m.notifier.EXPECT().Notify(domain.AlertTrigger{
+				RuleID:  "drop",
+				Time:    now,
+				Value:   (prices[4] - prices[0]) / prices[0] * 100,
+				Message: "BTC-ETH price changed by -11.00% over 1h0m0s to 89",
+			}).Return(nil)
+			m.alertStorage.EXPECT().SaveState(domain.AlertState{RuleID: "drop", LastFired: now}).Return(nil)
+			m.alertStorage.EXPECT().SaveTrigger(gomock.Any()).Return(nil)
+		}
+		assert.NoError(t, u.Evaluate())
+	}
+
+	// prices older than the longest window are dropped
+	assert.Len(t, u.prices["BTC-ETH"], 4)
+}
+
+func TestAlertUsecases_Evaluate_Portfolio(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Unix(1000, 0)
+	u, m := newTestAlertUsecase(ctrl, &now,
+		domain.AlertRule{ID: "total", Type: domain.AlertTypePortfolioAbove, Currency: domain.TotalCurrency, Quote: "USDT", Value: 900},
+		domain.AlertRule{ID: "coin", Type: domain.AlertTypePortfolioBelow, Currency: "CUR1", Quote: "BTC", Value: 100},
+		domain.AlertRule{ID: "unknown", Type: domain.AlertTypePortfolioBelow, Currency: "CUR3", Quote: "BTC", Value: 100},
+	)
+	m.alertStorage.EXPECT().FetchStates().Return(nil, nil)
+
+	// the balance is requested once for all rules
+	m.exchange.EXPECT().GetBalance().Return(testdata.Balances(), nil)
+	m.notifier.EXPECT().Notify(domain.AlertTrigger{
+		RuleID:  "total",
+		Time:    now,
+		Value:   900,
+		Message: "total portfolio 900 USDT is above 900 USDT",
+	}).Return(nil)
+	m.alertStorage.EXPECT().SaveState(domain.AlertState{RuleID: "total", LastFired: now}).Return(nil)
+	m.alertStorage.EXPECT().SaveTrigger(gomock.Any()).Return(nil)
+	assert.NoError(t, u.Evaluate())
+
+	now = now.Add(time.Hour * 2)
+	m.exchange.EXPECT().GetBalance().Return(nil, errExpected)
+	assert.Error(t, u.Evaluate())
+}
+
+func TestAlertUsecases_Evaluate_Positions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Unix(1000000, 0)
+	u, m := newTestAlertUsecase(ctrl, &now,
+		domain.AlertRule{ID: "stop", Type: domain.AlertTypeTrailingStop, Value: 8, Cooldown: time.Minute},
+		domain.AlertRule{ID: "gain", Type: domain.AlertTypePositionChange, Market: "BTC-ETH", Value: 50},
+	)
+	eth := domain.Order{Exchange: domain.ExchangeTypeBittrex, Account: "main", Market: "BTC-ETH", Time: now.Add(-time.Hour * 48), BuyRate: 0.05, Amount: 2, BTCRate: 1, USDTRate: 10000}
+	btc := domain.Order{Exchange: domain.ExchangeTypeBittrex, Market: "USDT-BTC", Time: now.Add(-time.Hour), BuyRate: 10000, Amount: 0.1, BTCRate: 0.0001, USDTRate: 1}
+	m.alertStorage.EXPECT().FetchStates().Return(nil, nil)
+
+	// the old position starts from the 24 hours high, the new one from the buy rate.
+	// Orders and markets are requested once for all rules
+	m.exchange.EXPECT().GetOrders().Return([]domain.Order{eth, btc}, nil)
+	m.exchange.EXPECT().GetMarketInfo("BTC-ETH").Return(&domain.MarketInfo{MarketName: "BTC-ETH", Last: 0.07, High: 0.1}, nil)
+	m.exchange.EXPECT().GetMarketInfo("USDT-BTC").Return(&domain.MarketInfo{MarketName: "USDT-BTC", Last: 10500, High: 11000}, nil)
+	var trigger domain.AlertTrigger
+	m.notifier.EXPECT().Notify(gomock.Any()).DoAndReturn(func(t domain.AlertTrigger) error {
+		trigger = t
+		return nil
+	})
+	peaks := map[string]float64{positionKey(eth): 0.1, positionKey(btc): 10500}
+	m.alertStorage.EXPECT().SaveState(domain.AlertState{RuleID: "stop", LastFired: now, Peaks: peaks}).Return(nil)
+	m.alertStorage.EXPECT().SaveTrigger(gomock.Any()).Return(nil)
+	assert.NoError(t, u.Evaluate())
+	assert.Equal(t, "stop", trigger.RuleID)
+	assert.InDelta(t, -30, trigger.Value, 1e-9)
+	assert.Equal(t, "BTC-ETH position of main dropped by 30.00% from peak 0.1 to 0.07, P&L 0.0394 BTC (39.30%, 394 USDT)", trigger.Message)
+
+	// both positions have dropped from their peaks, the value is the largest drop
+	now = now.Add(time.Minute)
+	m.exchange.EXPECT().GetOrders().Return([]domain.Order{eth, btc}, nil)
+	m.exchange.EXPECT().GetMarketInfo("BTC-ETH").Return(&domain.MarketInfo{MarketName: "BTC-ETH", Last: 0.076, High: 0.1}, nil)
+	m.exchange.EXPECT().GetMarketInfo("USDT-BTC").Return(&domain.MarketInfo{MarketName: "USDT-BTC", Last: 9600, High: 11000}, nil)
+	var triggers []domain.AlertTrigger
+	m.notifier.EXPECT().Notify(gomock.Any()).Times(2).DoAndReturn(func(t domain.AlertTrigger) error {
+		triggers = append(triggers, t)
+		return nil
+	})
+	m.alertStorage.EXPECT().SaveState(gomock.Any(), gomock.Any()).Return(nil)
+	m.alertStorage.EXPECT().SaveTrigger(gomock.Any(), gomock.Any()).Return(nil)
+	assert.NoError(t, u.Evaluate())
+	assert.Len(t, triggers, 2)
+	assert.Equal(t, "stop", triggers[0].RuleID)
+	assert.InDelta(t, -24, triggers[0].Value, 1e-9)
+	assert.Equal(t, "BTC-ETH position of main dropped by 24.00% from peak 0.1 to 0.076, P&L 0.05137 BTC (51.24%, 513.7 USDT); "+
+		"USDT-BTC position dropped by 8.57% from peak 10500 to 9600, P&L -44.9 USDT (-4.48%)", triggers[0].Message)
+	assert.Equal(t, "gain", triggers[1].RuleID)
+	assert.InDelta(t, 52, triggers[1].Value, 1e-9)
+	assert.Equal(t, "BTC-ETH position of main changed by 52.00% from buy rate 0.05 to 0.076, P&L 0.05137 BTC (51.24%, 513.7 USDT)", triggers[1].Message)
+
+	// peaks of sold positions are forgotten
+	now = now.Add(time.Minute)
+	m.exchange.EXPECT().GetOrders().Return([]domain.Order{btc}, nil)
+	m.exchange.EXPECT().GetMarketInfo("USDT-BTC").Return(&domain.MarketInfo{MarketName: "USDT-BTC", Last: 10000, High: 11000}, nil)
+	m.alertStorage.EXPECT().SaveState(domain.AlertState{RuleID: "stop", LastFired: now.Add(-time.Minute), Peaks: map[string]float64{positionKey(btc): 10500}}).
+		Return(nil)
+	assert.NoError(t, u.Evaluate())
+	assert.Equal(t, map[string]float64{positionKey(btc): 10500}, u.peaks)
+
+	now = now.Add(time.Minute)
+	m.exchange.EXPECT().GetOrders().Return(nil, errExpected)
+	assert.Error(t, u.Evaluate())
+	assert.Len(t, u.peaks, 1)
+}
+
+func TestAlertUsecases_Evaluate_SavedPeaks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Unix(1000000, 0)
+	u, m := newTestAlertUsecase(ctrl, &now, domain.AlertRule{ID: "stop", Type: domain.AlertTypeTrailingStop, Value: 8})
+	eth := domain.Order{Exchange: domain.ExchangeTypeBittrex, Market: "BTC-ETH", Time: now.Add(-time.Hour * 48), BuyRate: 0.05, Amount: 2}
+
+	// the peak of the previous run is higher than the 24 hours high
+	m.alertStorage.EXPECT().FetchStates().Return([]domain.AlertState{
+		{RuleID: "stop", Peaks: map[string]float64{positionKey(eth): 0.1}},
+	}, nil)
+	m.exchange.EXPECT().GetOrders().Return([]domain.Order{eth}, nil)
+	m.exchange.EXPECT().GetMarketInfo("BTC-ETH").Return(&domain.MarketInfo{MarketName: "BTC-ETH", Last: 0.09, High: 0.095}, nil)
+	m.notifier.EXPECT().Notify(gomock.Any()).Return(nil)
+	m.alertStorage.EXPECT().SaveState(domain.AlertState{RuleID: "stop", LastFired: now, Peaks: map[string]float64{positionKey(eth): 0.1}}).Return(nil)
+	m.alertStorage.EXPECT().SaveTrigger(gomock.Any()).Return(nil)
+	assert.NoError(t, u.Evaluate())
+
+	// the new peak is saved without firing
+	now = now.Add(time.Minute)
+	m.exchange.EXPECT().GetOrders().Return([]domain.Order{eth}, nil)
+	m.exchange.EXPECT().GetMarketInfo("BTC-ETH").Return(&domain.MarketInfo{MarketName: "BTC-ETH", Last: 0.11, High: 0.11}, nil)
+	m.alertStorage.EXPECT().SaveState(domain.AlertState{RuleID: "stop", LastFired: now.Add(-time.Minute), Peaks: map[string]float64{positionKey(eth): 0.11}}).
+		Return(nil)
+	assert.NoError(t, u.Evaluate())
+}
+
+func TestAlertUsecases_Evaluate_Errors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Unix(1000, 0)
+	u, m := newTestAlertUsecase(ctrl, &now,
+		domain.AlertRule{ID: "eth", Type: domain.AlertTypePriceAbove, Market: "BTC-ETH", Value: 0.08},
+		domain.AlertRule{ID: "ltc", Type: domain.AlertTypePriceAbove, Market: "BTC-LTC", Value: 0.01},
+	)
+
+	// nothing is evaluated without states not to fire again
+	m.alertStorage.EXPECT().FetchStates().Return(nil, errExpected)
+	assert.Error(t, u.Evaluate())
+
+	// other rules are evaluated when a market fails
+	m.alertStorage.EXPECT().FetchStates().Return(nil, nil)
+	m.exchange.EXPECT().GetMarketInfo("BTC-ETH").Return(nil, errExpected)
+	m.exchange.EXPECT().GetMarketInfo("BTC-LTC").Return(&domain.MarketInfo{MarketName: "BTC-LTC", Last: 0.02}, nil)
+	m.notifier.EXPECT().Notify(gomock.Any()).Return(errExpected)
+	assert.Error(t, u.Evaluate())
+
+	// the failed notification is sent again
+	m.exchange.EXPECT().GetMarketInfo("BTC-ETH").Return(nil, errExpected)
+	m.exchange.EXPECT().GetMarketInfo("BTC-LTC").Return(&domain.MarketInfo{MarketName: "BTC-LTC", Last: 0.02}, nil)
+	m.notifier.EXPECT().Notify(gomock.Any()).Return(nil)
+	m.alertStorage.EXPECT().SaveState(domain.AlertState{RuleID: "ltc", LastFired: now}).Return(errExpected)
+	m.alertStorage.EXPECT().SaveTrigger(gomock.Any()).Return(nil)
+	assert.Error(t, u.Evaluate())
+
+	// the rule isn't fired again even if its state isn't saved
+	m.exchange.EXPECT().GetMarketInfo("BTC-ETH").Return(&domain.MarketInfo{MarketName: "BTC-ETH", Last: 0.07}, nil)
+	m.exchange.EXPECT().GetMarketInfo("BTC-LTC").Return(&domain.MarketInfo{MarketName: "BTC-LTC", Last: 0.02}, nil)
+	assert.NoError(t, u.Evaluate())
+}
+
+func TestAlertUsecases_Evaluate_StoredRules(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Unix(1000, 0)
+	m := alertMocks{
+		exchange:     storagemocks.NewMockExchange(ctrl),
+		alertStorage: storagemocks.NewMockAlertStorage(ctrl),
+		notifier:     notificationmocks.NewMockNotifier(ctrl),
+	}
+	u := NewStoredAlertUsecase(m.exchange, m.alertStorage, m.notifier).(*alertUsecases)
+	u.now = func() time.Time {
+		return now
+	}
+
+	m.alertStorage.EXPECT().FetchStates().Return(nil, nil)
+	m.alertStorage.EXPECT().FetchRules().Return([]domain.AlertRule{
+		{ID: "change", Type: domain.AlertTypePercentChange, Market: "BTC-ETH", Value: 5, Window: time.Hour},
+	}, nil)
+	m.exchange.EXPECT().GetMarketInfo("BTC-ETH").Return(&domain.MarketInfo{MarketName: "BTC-ETH", Last: 0.1}, nil)
+	assert.NoError(t, u.Evaluate())
+	assert.Len(t, u.prices["BTC-ETH"], 1)
+
+	// the rule is replaced by the new one, prices aren't needed anymore
+	m.alertStorage.EXPECT().FetchRules().Return([]domain.AlertRule{
+		{ID: "above", Type: domain.AlertTypePriceAbove, Market: "BTC-LTC", Value: 0.01},
+	}, nil)
+	m.exchange.EXPECT().GetMarketInfo("BTC-LTC").Return(&domain.MarketInfo{MarketName: "BTC-LTC", Last: 0.02}, nil)
+	m.notifier.EXPECT().Notify(gomock.Any()).Return(nil)
+	m.alertStorage.EXPECT().SaveState(domain.AlertState{RuleID: "above", LastFired: now}).Return(nil)
+	m.alertStorage.EXPECT().SaveTrigger(gomock.Any()).Return(nil)
+	assert.NoError(t, u.Evaluate())
+	assert.Empty(t, u.prices)
+
+	// the same rule is in cooldown, the changed one fires without it
+	above := domain.AlertRule{ID: "above", Type: domain.AlertTypePriceAbove, Market: "BTC-LTC", Value: 0.01}
+	m.alertStorage.EXPECT().FetchRules().Return([]domain.AlertRule{above}, nil)
+	m.exchange.EXPECT().GetMarketInfo("BTC-LTC").Return(&domain.MarketInfo{MarketName: "BTC-LTC", Last: 0.02}, nil)
+	assert.NoError(t, u.Evaluate())
+
+	above.Value = 0.015
+	m.alertStorage.EXPECT().FetchRules().Return([]domain.AlertRule{above}, nil)
+	m.exchange.EXPECT().GetMarketInfo("BTC-LTC").Return(&domain.MarketInfo{MarketName: "BTC-LTC", Last: 0.02}, nil)
+	m.notifier.EXPECT().Notify(gomock.Any()).Return(nil)
+	m.alertStorage.EXPECT().SaveState(domain.AlertState{RuleID: "above", LastFired: now}).Return(nil)
+	m.alertStorage.EXPECT().SaveTrigger(gomock.Any()).Return(nil)
+	assert.NoError(t, u.Evaluate())
+
+	// the state of the removed rule is forgotten
+	m.alertStorage.EXPECT().FetchRules().Return(nil, nil)
+	assert.NoError(t, u.Evaluate())
+	assert.Empty(t, u.states)
+
+	m.alertStorage.EXPECT().FetchRules().Return(nil, errExpected)
+	assert.Error(t, u.Evaluate())
+}
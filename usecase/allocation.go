@@ -0,0 +1,263 @@
+package usecase
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/pkg/errors"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage"
+	"github.com/nawa/cryptoexchange-dashboard/storage/downsample"
+)
+
+// minRebalanceBTC is the value of the smallest suggested trade, exchanges reject smaller orders
+const minRebalanceBTC = 0.001
+
+var ErrNoTargetWeights = errors.New("target weights aren't configured")
+
+type AllocationUsecases interface {
+	// Shares of coins and exchanges in the last balance
+	GetAllocation() (*domain.Allocation, error)
+	// Allocations of the first balances of buckets of the resolution in [from, to), the latest first
+	FetchAllocations(from, to time.Time, resolution time.Duration) ([]domain.Allocation, error)
+	// Trades of coins for BTC bringing the last balance to target weights by current rates and exchange fees.
+	// It's only the advice, orders aren't placed. Returns ErrNoTargetWeights if weights aren't configured
+	Rebalance() (*domain.Rebalance, error)
+}
+
+type allocationUsecases struct {
+	exchange       storage.Exchange
+	balanceStorage storage.BalanceStorage
+	targets        map[string]float64
+	log            *logrus.Entry
+}
+
+// NewAllocationUsecase creates allocation usecases. Targets are weights of coins summing to 1,
+// coins missing in them are sold out by rebalancing
+func NewAllocationUsecase(exchange storage.Exchange, balanceStorage storage.BalanceStorage, targets map[string]float64) AllocationUsecases {
+	log := logrus.WithField("component", "allocationUC")
+	upperTargets := make(map[string]float64, len(targets))
+	for currency, weight := range targets {
+		upperTargets[strings.ToUpper(currency)] = weight
+	}
+	return &allocationUsecases{
+		exchange:       exchange,
+		balanceStorage: balanceStorage,
+		targets:        upperTargets,
+		log:            log,
+	}
+}
+
+func (u *allocationUsecases) GetAllocation() (*domain.Allocation, error) {
+	balances, err := u.balanceStorage.GetActiveCurrencies()
+	if err != nil {
+		u.log.WithField("method", "GetAllocation").WithError(err).Error()
+		return nil, err
+	}
+
+	allocation := calculateAllocation(balances)
+	return &allocation, nil
+}
+
+// FetchAllocations takes the first snapshot at or after the start of the range and then the first one
+// of every next bucket having balances
+func (u *allocationUsecases) FetchAllocations(from, to time.Time, resolution time.Duration) ([]domain.Allocation, error) {
+	bucket := downsample.Every(resolution)
+
+	var result []domain.Allocation
+	for t := from; t.Before(to); {
+		snapshot, err := u.balanceStorage.FetchSnapshot(t)
+		if err != nil {
+			u.log.WithField("method", "FetchAllocations").WithError(err).Error()
+			return nil, err
+		}
+		if len(snapshot) == 0 || !snapshot[0].Time.Before(to) {
+			break
+		}
+
+		result = append(result, calculateAllocation(snapshot))
+		t = bucket(snapshot[0].Time).Add(resolution)
+	}
+
+	// the latest first
+	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+		result[i], result[j] = result[j], result[i]
+	}
+	return result, nil
+}
+
+// Rebalance values coins by last rates. Amounts of trades change coin values to targets at last rates,
+// sells are priced by bids and buys by asks. The fee is charged on every market of the conversion path
+func (u *allocationUsecases) Rebalance() (*domain.Rebalance, error) {
+	if len(u.targets) == 0 {
+		return nil, ErrNoTargetWeights
+	}
+
+	converter, ok := u.exchange.(storage.CurrencyRates)
+	if !ok {
+		return nil, errors.New("exchange doesn't give rates")
+	}
+
+	balances, err := u.balanceStorage.GetActiveCurrencies()
+	if err != nil {
+		u.log.WithField("method", "Rebalance").WithError(err).Error()
+		return nil, err
+	}
+	if len(balances) == 0 {
+		return &domain.Rebalance{}, nil
+	}
+
+	var (
+		currencies []string
+		amounts    = make(map[string]float64)
+	)
+	for _, b := range balances {
+		currency := strings.ToUpper(b.Currency)
+		if strings.HasPrefix(b.Currency, domain.TotalCurrency) || b.Amount <= 0 {
+			continue
+		}
+		if _, ok := amounts[currency]; !ok {
+			currencies = append(currencies, currency)
+		}
+		amounts[currency] += b.Amount
+	}
+	var newCurrencies []string
+	for currency := range u.targets {
+		if _, ok := amounts[currency]; !ok {
+			newCurrencies = append(newCurrencies, currency)
+		}
+	}
+	sort.Strings(newCurrencies)
+	currencies = append(currencies, newCurrencies...)
+
+	rates, err := converter.GetRates(currencies, "BTC")
+	if err != nil {
+		u.log.WithField("method", "Rebalance").WithError(err).Error()
+		return nil, err
+	}
+	currencyRates := make(map[string]domain.Rate, len(rates))
+	for _, rate := range rates {
+		currencyRates[rate.Currency] = rate
+	}
+
+	result := &domain.Rebalance{
+		Time: balances[0].Time,
+	}
+	for _, currency := range currencies {
+		rate := currencyRates[currency]
+		if rate.Last <= 0 {
+			result.Unpriced = append(result.Unpriced, currency)
+			continue
+		}
+		result.BTCAmount += amounts[currency] * rate.Last
+	}
+	if result.BTCAmount <= 0 {
+		return result, nil
+	}
+
+	for _, currency := range currencies {
+		rate := currencyRates[currency]
+		if currency == "BTC" || rate.Last <= 0 {
+			continue
+		}
+
+		trade := domain.RebalanceTrade{
+			Exchange:     rate.Exchange,
+			Currency:     currency,
+			Weight:       amounts[currency] * rate.Last / result.BTCAmount,
+			TargetWeight: u.targets[currency],
+		}
+		value := (trade.TargetWeight - trade.Weight) * result.BTCAmount
+		if math.Abs(value) < minRebalanceBTC {
+			continue
+		}
+
+		trade.Type, trade.Price = domain.TradeTypeBuy, rate.Ask
+		if value < 0 {
+			trade.Type, trade.Price = domain.TradeTypeSell, rate.Bid
+		}
+		if trade.Price <= 0 {
+			// the market has no orders on this side
+			result.Unpriced = append(result.Unpriced, currency)
+			continue
+		}
+
+		fee := 1 - math.Pow(1-rate.Exchange.Fee(), float64(rate.Trades))
+		trade.Amount = math.Abs(value) / rate.Last
+		trade.Fee = trade.Amount * trade.Price * fee
+		if trade.Type == domain.TradeTypeBuy {
+			result.NetBTC -= trade.Amount*trade.Price + trade.Fee
+		} else {
+			result.NetBTC += trade.Amount*trade.Price - trade.Fee
+		}
+		result.Trades = append(result.Trades, trade)
+	}
+
+	// sells give BTC for buys
+	sort.SliceStable(result.Trades, func(i, j int) bool {
+		a, b := result.Trades[i], result.Trades[j]
+		if a.Type != b.Type {
+			return a.Type == domain.TradeTypeSell
+		}
+		return a.Amount*a.Price > b.Amount*b.Price
+	})
+	return result, nil
+}
+
+// calculateAllocation returns shares of coins and exchanges in balances of one snapshot, totals are skipped
+func calculateAllocation(balances []domain.Balance) domain.Allocation {
+	var (
+		result    domain.Allocation
+		coins     = make(map[string]*domain.Share)
+		exchanges = make(map[string]*domain.Share)
+	)
+	if len(balances) > 0 {
+		result.Time = balances[0].Time
+	}
+	for _, b := range balances {
+		if strings.HasPrefix(b.Currency, domain.TotalCurrency) || b.Amount <= 0 {
+			continue
+		}
+		addShare(coins, strings.ToUpper(b.Currency), b)
+		addShare(exchanges, string(b.Exchange), b)
+		result.BTCAmount += b.BTCAmount
+		result.USDTAmount += b.USDTAmount
+	}
+
+	result.Coins = sortShares(coins, result.BTCAmount)
+	result.Exchanges = sortShares(exchanges, result.BTCAmount)
+	return result
+}
+
+func addShare(shares map[string]*domain.Share, name string, b domain.Balance) {
+	share, ok := shares[name]
+	if !ok {
+		share = &domain.Share{Name: name}
+		shares[name] = share
+	}
+	share.BTCAmount += b.BTCAmount
+	share.USDTAmount += b.USDTAmount
+}
+
+// sortShares sets parts of the total and returns shares, the largest first
+func sortShares(shares map[string]*domain.Share, total float64) []domain.Share {
+	result := make([]domain.Share, 0, len(shares))
+	for _, share := range shares {
+		if total > 0 {
+			share.Share = share.BTCAmount / total
+		}
+		result = append(result, *share)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].BTCAmount != result[j].BTCAmount {
+			return result[i].BTCAmount > result[j].BTCAmount
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
@@ -0,0 +1,153 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	assert "github.com/stretchr/testify/require"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage/memory"
+	"github.com/nawa/cryptoexchange-dashboard/storage/mocks"
+)
+
+type ratesExchange struct {
+	*mocks.MockExchange
+	*mocks.MockCurrencyRates
+}
+
+// allocationBalances are 1 BTC, 20 ETH on two exchanges worth 1 BTC and DOGE without BTC market
+func allocationBalances(t time.Time) []domain.Balance {
+	return []domain.Balance{
+		{Exchange: domain.ExchangeTypeBittrex, Currency: "BTC", Amount: 1, BTCAmount: 1, USDTAmount: 10000, Time: t},
+		{Exchange: domain.ExchangeTypeBittrex, Currency: "ETH", Amount: 10, BTCAmount: 0.5, USDTAmount: 5000, Time: t},
+		{Exchange: domain.ExchangeTypeBinance, Currency: "ETH", Amount: 10, BTCAmount: 0.5, USDTAmount: 5000, Time: t},
+		{Exchange: domain.ExchangeTypeBittrex, Currency: "DOGE", Amount: 1000, Time: t},
+		{Currency: domain.CoinTotalCurrency("ETH"), Amount: 20, BTCAmount: 1, USDTAmount: 10000, Time: t},
+		{Exchange: domain.ExchangeTypeBittrex, Currency: domain.ExchangeTypeBittrex.TotalCurrency(), BTCAmount: 1.5, USDTAmount: 15000, Time: t},
+		{Exchange: domain.ExchangeTypeBinance, Currency: domain.ExchangeTypeBinance.TotalCurrency(), BTCAmount: 0.5, USDTAmount: 5000, Time: t},
+		{Currency: domain.TotalCurrency, BTCAmount: 2, USDTAmount: 20000, Time: t},
+	}
+}
+
+func TestAllocationUsecases_GetAllocation(t *testing.T) {
+	balanceStorage := memory.NewBalanceStorage()
+	u := NewAllocationUsecase(nil, balanceStorage, nil)
+
+	_, err := u.GetAllocation()
+	assert.Error(t, err)
+
+	now := time.Now().UTC()
+	assert.NoError(t, balanceStorage.Save(allocationBalances(now)...))
+
+	allocation, err := u.GetAllocation()
+	assert.NoError(t, err)
+	assert.Equal(t, &domain.Allocation{
+		Time:       now,
+		BTCAmount:  2,
+		USDTAmount: 20000,
+		Coins: []domain.Share{
+			{Name: "BTC", BTCAmount: 1, USDTAmount: 10000, Share: 0.5},
+			{Name: "ETH", BTCAmount: 1, USDTAmount: 10000, Share: 0.5},
+			{Name: "DOGE"},
+		},
+		Exchanges: []domain.Share{
+			{Name: "bittrex", BTCAmount: 1.5, USDTAmount: 15000, Share: 0.75},
+			{Name: "binance", BTCAmount: 0.5, USDTAmount: 5000, Share: 0.25},
+		},
+	}, allocation)
+}
+
+func TestAllocationUsecases_FetchAllocations(t *testing.T) {
+	balanceStorage := memory.NewBalanceStorage()
+	u := NewAllocationUsecase(nil, balanceStorage, nil)
+
+	start := time.Now().UTC().Truncate(time.Hour).Add(-5 * time.Hour)
+	assert.NoError(t, balanceStorage.Save(allocationBalances(start.Add(10*time.Minute))...))
+	assert.NoError(t, balanceStorage.Save(allocationBalances(start.Add(20*time.Minute))...))
+	later := allocationBalances(start.Add(3*time.Hour + 10*time.Minute))
+	later[0].BTCAmount = 3
+	assert.NoError(t, balanceStorage.Save(later...))
+
+	// the second snapshot is in the same bucket, there are no balances in two next ones
+	allocations, err := u.FetchAllocations(start, start.Add(4*time.Hour), time.Hour)
+	assert.NoError(t, err)
+	assert.Len(t, allocations, 2)
+	assert.Equal(t, start.Add(3*time.Hour+10*time.Minute), allocations[0].Time)
+	assert.Equal(t, 0.75, allocations[0].Coins[0].Share)
+	assert.Equal(t, start.Add(10*time.Minute), allocations[1].Time)
+	assert.Equal(t, 0.5, allocations[1].Coins[0].Share)
+
+	allocations, err = u.FetchAllocations(start.Add(4*time.Hour), start.Add(5*time.Hour), time.Hour)
+	assert.NoError(t, err)
+	assert.Empty(t, allocations)
+}
+
+func TestAllocationUsecases_Rebalance(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	rates := mocks.NewMockCurrencyRates(ctrl)
+	balanceStorage := memory.NewBalanceStorage()
+	u := NewAllocationUsecase(ratesExchange{mocks.NewMockExchange(ctrl), rates}, balanceStorage,
+		map[string]float64{"BTC": 0.5, "ETH": 0.25, "xrp": 0.25})
+
+	now := time.Now().UTC()
+	assert.NoError(t, balanceStorage.Save(allocationBalances(now)...))
+
+	// XRP is converted through ETH on Binance
+	rates.EXPECT().GetRates([]string{"BTC", "DOGE", "ETH", "XRP"}, "BTC").Return([]domain.Rate{
+		{Exchange: domain.ExchangeTypeBittrex, Currency: "BTC", Quote: "BTC", Last: 1, Bid: 1, Ask: 1},
+		{Exchange: domain.ExchangeTypeBittrex, Currency: "ETH", Quote: "BTC", Last: 0.05, Bid: 0.04, Ask: 0.06, Trades: 1},
+		{Exchange: domain.ExchangeTypeBinance, Currency: "XRP", Quote: "BTC", Last: 0.0001, Bid: 0.00005, Ask: 0.0002, Trades: 2},
+	}, nil)
+
+	rebalance, err := u.Rebalance()
+	assert.NoError(t, err)
+	assert.Equal(t, now, rebalance.Time)
+	assert.Equal(t, 2.0, rebalance.BTCAmount)
+	assert.Equal(t, []string{"DOGE"}, rebalance.Unpriced)
+	assert.Len(t, rebalance.Trades, 2)
+
+	sell := rebalance.Trades[0]
+	assert.Equal(t, domain.ExchangeTypeBittrex, sell.Exchange)
+	assert.Equal(t, "ETH", sell.Currency)
+	assert.Equal(t, domain.TradeTypeSell, sell.Type)
+	assert.InDelta(t, 10, sell.Amount, 1e-9)
+	assert.Equal(t, 0.04, sell.Price)
+	assert.InDelta(t, 0.001, sell.Fee, 1e-12)
+	assert.Equal(t, 0.5, sell.Weight)
+	assert.Equal(t, 0.25, sell.TargetWeight)
+
+	buy := rebalance.Trades[1]
+	assert.Equal(t, domain.ExchangeTypeBinance, buy.Exchange)
+	assert.Equal(t, "XRP", buy.Currency)
+	assert.Equal(t, domain.TradeTypeBuy, buy.Type)
+	assert.InDelta(t, 5000, buy.Amount, 1e-9)
+	assert.Equal(t, 0.0002, buy.Price)
+	assert.InDelta(t, 1-0.999*0.999, buy.Fee, 1e-12)
+	assert.Equal(t, 0.0, buy.Weight)
+
+	assert.InDelta(t, 0.4-0.001-1-(1-0.999*0.999), rebalance.NetBTC, 1e-9)
+}
+
+func TestAllocationUsecases_Rebalance_Error(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	balanceStorage := memory.NewBalanceStorage()
+	assert.NoError(t, balanceStorage.Save(allocationBalances(time.Now())...))
+
+	_, err := NewAllocationUsecase(mocks.NewMockExchange(ctrl), balanceStorage, nil).Rebalance()
+	assert.Equal(t, ErrNoTargetWeights, err)
+
+	targets := map[string]float64{"BTC": 1}
+	_, err = NewAllocationUsecase(mocks.NewMockExchange(ctrl), balanceStorage, targets).Rebalance()
+	assert.Error(t, err)
+
+	rates := mocks.NewMockCurrencyRates(ctrl)
+	rates.EXPECT().GetRates(gomock.Any(), "BTC").Return(nil, errExpected)
+	_, err = NewAllocationUsecase(ratesExchange{mocks.NewMockExchange(ctrl), rates}, balanceStorage, targets).Rebalance()
+	assert.Equal(t, errExpected, err)
+}
@@ -0,0 +1,147 @@
+package usecase
+
+import (
+	"math"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+)
+
+// tradingYear is the period volatility and ratios are annualised by, exchanges trade every day
+const tradingYear = 365 * 24 * time.Hour
+
+type AnalyticsUsecases interface {
+	// Risk and return statistics of the balance in [from, to) by returns of periods of the resolution net of
+	// deposits and withdrawals. Rolling returns are over windows of the number of periods
+	Analyze(currency string, from, to time.Time, resolution time.Duration, window int) (*domain.Analytics, error)
+}
+
+type analyticsUsecases struct {
+	balanceUsecase BalanceUsecases
+	log            *logrus.Entry
+}
+
+func NewAnalyticsUsecase(balanceUsecase BalanceUsecases) AnalyticsUsecases {
+	log := logrus.WithField("component", "analyticsUC")
+	return &analyticsUsecases{
+		balanceUsecase: balanceUsecase,
+		log:            log,
+	}
+}
+
+// valuePoint is the balance at the end of the bucket starting at the time and the net flow of transfers during it
+type valuePoint struct {
+	time  time.Time
+	value float64
+	flow  float64
+}
+
+func (u *analyticsUsecases) Analyze(currency string, from, to time.Time, resolution time.Duration, window int) (*domain.Analytics, error) {
+	performance, err := u.balanceUsecase.FetchPerformance(currency, from, to, resolution)
+	if err != nil {
+		u.log.WithField("method", "Analyze").WithError(err).Error()
+		return nil, err
+	}
+
+	// the earliest first to chain returns
+	points := performance.Points
+	btc := make([]valuePoint, len(points))
+	usdt := make([]valuePoint, len(points))
+	for i, p := range points {
+		btc[len(points)-1-i] = valuePoint{time: p.Time, value: p.BTCAmount, flow: p.NetFlowBTC}
+		usdt[len(points)-1-i] = valuePoint{time: p.Time, value: p.USDTAmount, flow: p.NetFlowUSDT}
+	}
+
+	result := &domain.Analytics{
+		BTC:  calculateReturnStats(btc, resolution, window),
+		USDT: calculateReturnStats(usdt, resolution, window),
+	}
+	if len(points) > 0 {
+		result.Periods = len(points) - 1
+	}
+	return result, nil
+}
+
+// calculateReturnStats returns statistics of points, the earliest first. The return of a period is the change
+// of the balance net of the flow, periods after the empty balance have no return. Drawdowns are falls
+// of the value of the first balance growing by returns, so deposits and withdrawals don't move them
+func calculateReturnStats(points []valuePoint, resolution time.Duration, window int) domain.ReturnStats {
+	var (
+		result        domain.ReturnStats
+		returns       []float64
+		index         = make([]float64, len(points))
+		peak, maxPeak float64
+		peakTime      time.Time
+	)
+	for i, p := range points {
+		index[i] = 1
+		if i > 0 {
+			index[i] = index[i-1]
+			if prevValue := points[i-1].value; prevValue > 0 {
+				r := (p.value-p.flow)/prevValue - 1
+				index[i] *= 1 + r
+				returns = append(returns, r)
+
+				if len(returns) == 1 || r > result.Best.Return {
+					result.Best = domain.PeriodReturn{Time: p.time, Return: r}
+				}
+				if len(returns) == 1 || r < result.Worst.Return {
+					result.Worst = domain.PeriodReturn{Time: p.time, Return: r}
+				}
+			}
+		}
+
+		if index[i] >= peak {
+			peak, peakTime = index[i], p.time
+		}
+		if drawdown := 1 - index[i]/peak; drawdown > result.MaxDrawdown {
+			result.MaxDrawdown = drawdown
+			result.DrawdownPeak, result.DrawdownTrough, result.DrawdownRecovery = peakTime, p.time, time.Time{}
+			maxPeak = peak
+		} else if result.MaxDrawdown > 0 && result.DrawdownRecovery.IsZero() && index[i] >= maxPeak {
+			result.DrawdownRecovery = p.time
+		}
+
+		if window > 0 && i >= window && index[i-window] > 0 {
+			result.Rolling = append(result.Rolling, domain.PeriodReturn{Time: p.time, Return: index[i]/index[i-window] - 1})
+		}
+	}
+	if len(points) > 0 {
+		result.Return = index[len(index)-1] - 1
+	}
+	for i, j := 0, len(result.Rolling)-1; i < j; i, j = i+1, j-1 {
+		result.Rolling[i], result.Rolling[j] = result.Rolling[j], result.Rolling[i]
+	}
+
+	if len(returns) < 2 {
+		return result
+	}
+
+	var mean float64
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+
+	var variance, downside float64
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+		if r < 0 {
+			downside += r * r
+		}
+	}
+	deviation := math.Sqrt(variance / float64(len(returns)-1))
+	downsideDeviation := math.Sqrt(downside / float64(len(returns)))
+
+	annualisation := math.Sqrt(float64(tradingYear) / float64(resolution))
+	result.Volatility = deviation * annualisation
+	if deviation > 0 {
+		result.Sharpe = mean / deviation * annualisation
+	}
+	if downsideDeviation > 0 {
+		result.Sortino = mean / downsideDeviation * annualisation
+	}
+	return result
+}
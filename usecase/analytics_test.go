@@ -0,0 +1,84 @@
+package usecase
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	assert "github.com/stretchr/testify/require"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/usecase/mocks"
+)
+
+func TestAnalyticsUsecases_Analyze(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	balanceUC := mocks.NewMockBalanceUsecases(ctrl)
+	u := NewAnalyticsUsecase(balanceUC)
+
+	day := 24 * time.Hour
+	start := time.Unix(0, 0).UTC()
+	from, to := start, start.Add(4*day)
+
+	// 25% up, 20% down, 50% up with 50 USDT deposited
+	balanceUC.EXPECT().FetchPerformance("total", from, to, day).Return(&domain.Performance{
+		Points: []domain.PerformancePoint{
+			{Time: start.Add(3 * day), BTCAmount: 1, USDTAmount: 200, NetFlowUSDT: 50},
+			{Time: start.Add(2 * day), BTCAmount: 1, USDTAmount: 100},
+			{Time: start.Add(day), BTCAmount: 1, USDTAmount: 125},
+			{Time: start, BTCAmount: 1, USDTAmount: 100},
+		},
+	}, nil)
+
+	analytics, err := u.Analyze("total", from, to, day, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, analytics.Periods)
+
+	assert.Equal(t, domain.ReturnStats{
+		Best:    domain.PeriodReturn{Time: start.Add(day)},
+		Worst:   domain.PeriodReturn{Time: start.Add(day)},
+		Rolling: []domain.PeriodReturn{{Time: start.Add(3 * day)}, {Time: start.Add(2 * day)}},
+	}, analytics.BTC)
+
+	stats := analytics.USDT
+	assert.InDelta(t, 0.5, stats.Return, 1e-9)
+	assert.InDelta(t, 0.2, stats.MaxDrawdown, 1e-9)
+	assert.Equal(t, start.Add(day), stats.DrawdownPeak)
+	assert.Equal(t, start.Add(2*day), stats.DrawdownTrough)
+	assert.Equal(t, start.Add(3*day), stats.DrawdownRecovery)
+	assert.Equal(t, domain.PeriodReturn{Time: start.Add(3 * day), Return: 0.5}, stats.Best)
+	assert.Equal(t, start.Add(2*day), stats.Worst.Time)
+	assert.InDelta(t, -0.2, stats.Worst.Return, 1e-9)
+
+	assert.Len(t, stats.Rolling, 2)
+	assert.Equal(t, start.Add(3*day), stats.Rolling[0].Time)
+	assert.InDelta(t, 0.2, stats.Rolling[0].Return, 1e-9)
+	assert.InDelta(t, 0, stats.Rolling[1].Return, 1e-9)
+
+	mean := (0.25 - 0.2 + 0.5) / 3
+	deviation := math.Sqrt((math.Pow(0.25-mean, 2) + math.Pow(-0.2-mean, 2) + math.Pow(0.5-mean, 2)) / 2)
+	assert.InDelta(t, deviation*math.Sqrt(365), stats.Volatility, 1e-9)
+	assert.InDelta(t, mean/deviation*math.Sqrt(365), stats.Sharpe, 1e-9)
+	assert.InDelta(t, mean/math.Sqrt(0.04/3)*math.Sqrt(365), stats.Sortino, 1e-9)
+}
+
+func TestAnalyticsUsecases_Analyze_NoBalances(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	balanceUC := mocks.NewMockBalanceUsecases(ctrl)
+	u := NewAnalyticsUsecase(balanceUC)
+
+	from, to := time.Unix(0, 0), time.Unix(3600, 0)
+	balanceUC.EXPECT().FetchPerformance("CUR1", from, to, time.Hour).Return(&domain.Performance{}, nil)
+	analytics, err := u.Analyze("CUR1", from, to, time.Hour, 24)
+	assert.NoError(t, err)
+	assert.Equal(t, &domain.Analytics{}, analytics)
+
+	balanceUC.EXPECT().FetchPerformance("CUR1", from, to, time.Hour).Return(nil, errExpected)
+	_, err = u.Analyze("CUR1", from, to, time.Hour, 24)
+	assert.Equal(t, errExpected, err)
+}
@@ -0,0 +1,407 @@
+package usecase
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage"
+	"github.com/nawa/cryptoexchange-dashboard/storage/downsample"
+)
+
+// dustAmount is the amount treated as zero, less ones are left by rounding of the history
+const dustAmount = 1e-8
+
+type BackfillUsecases interface {
+	// Saves balances at points of the step before the first saved balance. They are rebuilt by walking the trade
+	// and transfer history backwards from the current balance and valued by saved candles of BTC markets.
+	// Candles of the interval are downloaded first unless it's zero. Returns the number of saved snapshots
+	Reconstruct(step, candleInterval time.Duration) (int, error)
+}
+
+type backfillUsecases struct {
+	exchange        storage.Exchange
+	balanceStorage  storage.BalanceStorage
+	tradeStorage    storage.TradeStorage
+	transferStorage storage.TransferStorage
+	marketUsecase   MarketUsecases
+	log             *logrus.Entry
+}
+
+func NewBackfillUsecase(exchange storage.Exchange, balanceStorage storage.BalanceStorage, tradeStorage storage.TradeStorage,
+	transferStorage storage.TransferStorage, marketUsecase MarketUsecases) BackfillUsecases {
+	log := logrus.WithField("component", "backfillUC")
+	return &backfillUsecases{
+		exchange:        exchange,
+		balanceStorage:  balanceStorage,
+		tradeStorage:    tradeStorage,
+		transferStorage: transferStorage,
+		marketUsecase:   marketUsecase,
+		log:             log,
+	}
+}
+
+// holding is the currency held in the account of the exchange
+type holding struct {
+	exchange domain.ExchangeType
+	account  string
+	currency string
+}
+
+// change is the change of the holding amount by a trade or a transfer
+type change struct {
+	time    time.Time
+	holding holding
+	amount  float64
+}
+
+func (u *backfillUsecases) Reconstruct(step, candleInterval time.Duration) (int, error) {
+	balances, err := u.exchange.GetBalance()
+	if err != nil {
+		u.log.WithField("method", "Reconstruct").WithError(err).Error()
+		return 0, err
+	}
+	trades, err := u.tradeStorage.Fetch(time.Time{}, time.Time{})
+	if err != nil {
+		u.log.WithField("method", "Reconstruct").WithError(err).Error()
+		return 0, err
+	}
+	transfers, err := u.transferStorage.Fetch(time.Time{}, time.Time{})
+	if err != nil {
+		u.log.WithField("method", "Reconstruct").WithError(err).Error()
+		return 0, err
+	}
+
+	changes := u.historyChanges(trades, transfers)
+	if len(changes) == 0 {
+		return 0, nil
+	}
+
+	bucket := downsample.Every(step)
+	now := time.Now()
+	from := bucket(changes[len(changes)-1].time)
+	if !from.Before(now) {
+		return 0, nil
+	}
+
+	// points aren't reconstructed in buckets already having balances, so backfill can be run many times
+	to := bucket(now)
+	saved, err := u.balanceStorage.FetchRange(domain.TotalCurrency, from, now, step)
+	if err != nil {
+		u.log.WithField("method", "Reconstruct").WithError(err).Error()
+		return 0, err
+	}
+	if len(saved) > 0 {
+		to = saved[len(saved)-1].Time
+	}
+	if !from.Before(to) {
+		return 0, nil
+	}
+
+	currencies := make(map[string]bool)
+	amounts := make(map[holding]float64)
+	for _, b := range balances {
+		h := holding{exchange: b.Exchange, account: b.Account, currency: strings.ToUpper(b.Currency)}
+		amounts[h] += b.Amount
+		currencies[h.currency] = true
+	}
+	for _, c := range changes {
+		currencies[c.holding.currency] = true
+	}
+
+	prices := u.fetchPrices(currencies, from, to, step, candleInterval)
+
+	// snapshots are collected from the latest, changes after the point are undone before it
+	var (
+		snapshots  [][]domain.Balance
+		next       int
+		incomplete = make(map[holding]bool)
+	)
+	for t := to.Add(-step); !t.Before(from); t = t.Add(-step) {
+		for ; next < len(changes) && changes[next].time.After(t); next++ {
+			amounts[changes[next].holding] -= changes[next].amount
+		}
+
+		snapshot := prices.value(amounts, t)
+		for h, amount := range amounts {
+			if amount < -dustAmount {
+				incomplete[h] = true
+			}
+		}
+		if len(snapshot) > 0 {
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+	if len(incomplete) > 0 {
+		u.log.WithField("method", "Reconstruct").Warnf("history of %d currencies is incomplete, their negative amounts are skipped", len(incomplete))
+	}
+
+	for i := len(snapshots) - 1; i >= 0; i-- {
+		snapshot := snapshotWithTotals(u.exchange, snapshots[i])
+		for j := range snapshot {
+			snapshot[j].Reconstructed = true
+		}
+		err = u.balanceStorage.Save(snapshot...)
+		if err != nil {
+			u.log.WithField("method", "Reconstruct").WithError(err).Error()
+			return len(snapshots) - 1 - i, err
+		}
+	}
+
+	u.log.WithField("method", "Reconstruct").Infof("%d snapshots reconstructed from %s to %s", len(snapshots), from, to)
+	return len(snapshots), nil
+}
+
+// historyChanges converts trades and transfers to changes of holdings, the latest first.
+// Trade fees are in the quote currency, withdrawal amounts include fees
+func (u *backfillUsecases) historyChanges(trades []domain.Trade, transfers []domain.Transfer) []change {
+	var changes []change
+	for _, t := range trades {
+		toFrom := strings.Split(strings.ToUpper(t.Market), "-")
+		if len(toFrom) != 2 {
+			u.log.WithField("method", "historyChanges").Warnf("market name can't be parsed to QUOTE-BASE format - %s", t.Market)
+			continue
+		}
+
+		base := holding{exchange: t.Exchange, account: t.Account, currency: toFrom[1]}
+		quote := holding{exchange: t.Exchange, account: t.Account, currency: toFrom[0]}
+		if t.Type == domain.TradeTypeSell {
+			changes = append(changes,
+				change{time: t.Time, holding: base, amount: -t.Amount},
+				change{time: t.Time, holding: quote, amount: t.Amount*t.Rate - t.Fee})
+			continue
+		}
+		changes = append(changes,
+			change{time: t.Time, holding: base, amount: t.Amount},
+			change{time: t.Time, holding: quote, amount: -t.Amount*t.Rate - t.Fee})
+	}
+
+	for _, t := range transfers {
+		h := holding{exchange: t.Exchange, account: t.Account, currency: strings.ToUpper(t.Currency)}
+		amount := t.Amount
+		if t.Type == domain.TransferTypeWithdrawal {
+			amount = -amount
+		}
+		changes = append(changes, change{time: t.Time, holding: h, amount: amount})
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].time.After(changes[j].time)
+	})
+	return changes
+}
+
+// fetchPrices returns saved candles of markets valuing currencies and USDT-BTC one. Candles of BTC markets
+// are downloaded first unless the interval is zero, other markets only for currencies without BTC candles
+func (u *backfillUsecases) fetchPrices(currencies map[string]bool, from, to time.Time, step, candleInterval time.Duration) historyPrices {
+	var download func(markets []string) bool
+	if candleInterval > 0 {
+		downloaded := make(map[string]bool)
+		// some markets may be gone, their currencies are valued as zero
+		download = func(markets []string) bool {
+			var notDownloaded []string
+			for _, market := range markets {
+				if !downloaded[market] {
+					notDownloaded = append(notDownloaded, market)
+					downloaded[market] = true
+				}
+			}
+			if len(notDownloaded) == 0 {
+				return false
+			}
+
+			err := u.marketUsecase.Backfill(notDownloaded, candleInterval)
+			if err != nil {
+				u.log.WithField("method", "fetchPrices").WithError(err).Warn("not all market candles are downloaded")
+			}
+			return true
+		}
+		download(btcHistoryMarkets(currencies))
+	}
+
+	prices, err := fetchHistoryPrices(u.marketUsecase, currencies, from, to, step, download)
+	if err != nil {
+		u.log.WithField("method", "fetchPrices").WithError(err).Warn("not all market candles are fetched")
+	}
+	return prices
+}
+
+// historyQuotes are quote currencies of markets valuing coins in the history in the order of preference.
+// A coin without BTC candles is valued by its market of the next quote and the price of the quote
+var historyQuotes = []string{"BTC", "USDT", "ETH", "BNB"}
+
+// historyPrices are candles of markets valuing currencies, the latest first, by markets
+type historyPrices map[string][]domain.MarketCandle
+
+// btcHistoryMarkets returns USDT-BTC market and BTC markets of currencies valuing them
+func btcHistoryMarkets(currencies map[string]bool) []string {
+	markets := []string{"USDT-BTC"}
+	for currency := range currencies {
+		if currency != "BTC" && currency != "USDT" {
+			markets = append(markets, "BTC-"+currency)
+		}
+	}
+	sort.Strings(markets[1:])
+	return markets
+}
+
+// fallbackHistoryMarkets returns markets of currencies to other historyQuotes and BTC markets of these quotes
+func fallbackHistoryMarkets(currencies []string) []string {
+	var markets []string
+	for _, quote := range historyQuotes[1:] {
+		if quote != "USDT" {
+			markets = append(markets, "BTC-"+quote)
+		}
+		for _, currency := range currencies {
+			if currency != quote {
+				markets = append(markets, quote+"-"+currency)
+			}
+		}
+	}
+	return markets
+}
+
+// fetchHistoryPrices returns saved candles of markets valuing currencies in [from, to) of the resolution: USDT-BTC,
+// BTC markets of currencies and, for currencies without BTC candles, their markets of other historyQuotes.
+// missing is called with markets without candles unless it's nil, they are fetched again if it returns true.
+// Markets failed to be fetched are missing, their errors are returned together
+func fetchHistoryPrices(marketUsecase MarketUsecases, currencies map[string]bool, from, to time.Time, resolution time.Duration,
+	missing func(markets []string) bool) (historyPrices, error) {
+	prices := make(historyPrices)
+	fetch := func(markets []string) error {
+		var result error
+		for _, market := range markets {
+			candles, err := marketUsecase.FetchCandles("", market, from, to, resolution)
+			if err != nil {
+				result = multierror.Append(result, errors.Wrapf(err, "market '%s'", market))
+				continue
+			}
+			prices[market] = candles
+		}
+		return result
+	}
+	fetchMissing := func(markets []string) error {
+		err := fetch(markets)
+		if missing == nil {
+			return err
+		}
+
+		var empty []string
+		for _, market := range markets {
+			if _, ok := prices[market]; ok && len(prices[market]) == 0 {
+				empty = append(empty, market)
+			}
+		}
+		if len(empty) > 0 && missing(empty) {
+			err = multierror.Append(err, fetch(empty)).ErrorOrNil()
+		}
+		return err
+	}
+
+	var result error
+	if err := fetchMissing(btcHistoryMarkets(currencies)); err != nil {
+		result = multierror.Append(result, err)
+	}
+
+	var unpriced []string
+	for currency := range currencies {
+		if currency != "BTC" && currency != "USDT" && len(prices["BTC-"+currency]) == 0 {
+			unpriced = append(unpriced, currency)
+		}
+	}
+	if len(unpriced) > 0 {
+		sort.Strings(unpriced)
+		if err := fetchMissing(fallbackHistoryMarkets(unpriced)); err != nil {
+			result = multierror.Append(result, err)
+		}
+	}
+	return prices, result
+}
+
+// price returns the price of the market at the time: the open price of the candle starting then
+// or the close price of the last candle before
+func (p historyPrices) price(market string, t time.Time) float64 {
+	for _, c := range p[market] {
+		if c.Time.Equal(t) {
+			return c.Price.Open
+		}
+		if c.Time.Before(t) {
+			return c.Price.Close
+		}
+	}
+	return 0
+}
+
+// btcRate returns the BTC price of the currency at the time by the market of the first of historyQuotes
+// having the price, zero if there is no such market
+func (p historyPrices) btcRate(currency string, t time.Time) float64 {
+	quoteRate := func(quote string) float64 {
+		switch quote {
+		case "BTC":
+			return 1
+		case "USDT":
+			if usdtRate := p.price("USDT-BTC", t); usdtRate > 0 {
+				return 1 / usdtRate
+			}
+			return 0
+		default:
+			return p.price("BTC-"+quote, t)
+		}
+	}
+
+	if currency == "BTC" || currency == "USDT" {
+		return quoteRate(currency)
+	}
+	for _, quote := range historyQuotes {
+		if quote == currency {
+			continue
+		}
+		if price := p.price(quote+"-"+currency, t); price > 0 {
+			if rate := quoteRate(quote); rate > 0 {
+				return price * rate
+			}
+		}
+	}
+	return 0
+}
+
+// value returns balances of positive amounts at the time sorted by exchanges, accounts and currencies.
+// Currencies without prices have zero BTC and USDT amounts as synced ones without markets
+func (p historyPrices) value(amounts map[holding]float64, t time.Time) []domain.Balance {
+	usdtRate := p.price("USDT-BTC", t)
+
+	var result []domain.Balance
+	for h, amount := range amounts {
+		if amount <= dustAmount {
+			continue
+		}
+
+		btcRate := p.btcRate(h.currency, t)
+		result = append(result, domain.Balance{
+			Exchange:   h.exchange,
+			Account:    h.account,
+			Currency:   h.currency,
+			Amount:     amount,
+			BTCAmount:  amount * btcRate,
+			USDTAmount: amount * btcRate * usdtRate,
+			Time:       t,
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.Exchange != b.Exchange {
+			return a.Exchange < b.Exchange
+		}
+		if a.Account != b.Account {
+			return a.Account < b.Account
+		}
+		return a.Currency < b.Currency
+	})
+	return result
+}
@@ -0,0 +1,127 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	assert "github.com/stretchr/testify/require"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage/memory"
+	"github.com/nawa/cryptoexchange-dashboard/storage/mocks"
+)
+
+func TestBackfillUsecases_Reconstruct(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	exchange := mocks.NewMockExchange(ctrl)
+	balanceStorage := memory.NewBalanceStorage()
+	tradeStorage := memory.NewTradeStorage()
+	transferStorage := memory.NewTransferStorage()
+	marketStorage := memory.NewMarketStorage()
+	u := NewBackfillUsecase(exchange, balanceStorage, tradeStorage, transferStorage, NewMarketUsecase(exchange, marketStorage))
+
+	start := time.Now().UTC().Truncate(time.Hour).Add(-5 * time.Hour)
+
+	// 2.0025 BTC deposited, then 100 CUR1 bought for 1 BTC and 0.0025 BTC fee, the sync started at start+4h
+	assert.NoError(t, transferStorage.Save(domain.Transfer{
+		Exchange: domain.ExchangeTypeBittrex,
+		ID:       "1",
+		Type:     domain.TransferTypeDeposit,
+		Currency: "BTC",
+		Time:     start.Add(30 * time.Minute),
+		Amount:   2.0025,
+	}))
+	assert.NoError(t, tradeStorage.Save(domain.Trade{
+		Exchange: domain.ExchangeTypeBittrex,
+		OrderID:  "uuid-1",
+		Market:   "BTC-CUR1",
+		Type:     domain.TradeTypeBuy,
+		Time:     start.Add(90 * time.Minute),
+		Amount:   100,
+		Rate:     0.01,
+		Fee:      0.0025,
+	}))
+	assert.NoError(t, balanceStorage.Save(domain.Balance{
+		Currency:  domain.TotalCurrency,
+		BTCAmount: 3,
+		Time:      start.Add(4*time.Hour + 10*time.Minute),
+	}))
+	for i := 0; i < 5; i++ {
+		candleTime := start.Add(time.Duration(i) * time.Hour)
+		assert.NoError(t, marketStorage.Save(
+			marketCandle("USDT-BTC", candleTime, 10000, 10000, 10000, 10000),
+			marketCandle("BTC-CUR1", candleTime, 0.02, 0.02, 0.02, 0.02),
+		))
+	}
+
+	exchange.EXPECT().GetBalance().Return([]domain.Balance{
+		{Exchange: domain.ExchangeTypeBittrex, Currency: "BTC", Amount: 1},
+		{Exchange: domain.ExchangeTypeBittrex, Currency: "CUR1", Amount: 100},
+	}, nil).Times(2)
+
+	// nothing is held at start, CUR1 isn't bought at start+1h yet
+	count, err := u.Reconstruct(time.Hour, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	balances, err := balanceStorage.FetchHourly("BTC", 24)
+	assert.NoError(t, err)
+	assert.Len(t, balances, 3)
+	assert.Equal(t, domain.Balance{
+		Exchange:      domain.ExchangeTypeBittrex,
+		Currency:      "BTC",
+		Amount:        2.0025,
+		BTCAmount:     2.0025,
+		USDTAmount:    20025,
+		Reconstructed: true,
+		Time:          start.Add(time.Hour),
+	}, balances[2])
+	assert.Equal(t, 1.0, balances[0].Amount)
+
+	balances, err = balanceStorage.FetchHourly("CUR1", 24)
+	assert.NoError(t, err)
+	assert.Len(t, balances, 2)
+	assert.Equal(t, domain.Balance{
+		Exchange:      domain.ExchangeTypeBittrex,
+		Currency:      "CUR1",
+		Amount:        100,
+		BTCAmount:     2,
+		USDTAmount:    20000,
+		Reconstructed: true,
+		Time:          start.Add(3 * time.Hour),
+	}, balances[0])
+
+	balances, err = balanceStorage.FetchHourly(domain.TotalCurrency, 24)
+	assert.NoError(t, err)
+	assert.Len(t, balances, 4)
+	assert.False(t, balances[0].Reconstructed)
+	assert.True(t, balances[1].Reconstructed)
+	assert.Equal(t, 3.0, balances[1].BTCAmount)
+
+	// buckets having balances are skipped
+	count, err = u.Reconstruct(time.Hour, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestBackfillUsecases_Reconstruct_Error(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	exchange := mocks.NewMockExchange(ctrl)
+	u := NewBackfillUsecase(exchange, memory.NewBalanceStorage(), memory.NewTradeStorage(), memory.NewTransferStorage(),
+		NewMarketUsecase(exchange, memory.NewMarketStorage()))
+
+	exchange.EXPECT().GetBalance().Return(nil, errExpected)
+	_, err := u.Reconstruct(time.Hour, 0)
+	assert.Equal(t, errExpected, err)
+
+	// nothing to reconstruct without the history
+	exchange.EXPECT().GetBalance().Return([]domain.Balance{{Currency: "BTC", Amount: 1}}, nil)
+	count, err := u.Reconstruct(time.Hour, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
@@ -2,6 +2,10 @@ package usecase
 
 import (
 	"encoding/json"
+	"math"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/nawa/cryptoexchange-dashboard/domain"
@@ -12,33 +16,56 @@ import (
 	"github.com/Sirupsen/logrus"
 )
 
+// maxFetchSinceHours limits how far back FetchSince looks, older balances are available in ranges
+const maxFetchSinceHours = 24 * 7
+
+// allMarketPricesPeriod is how often prices of all markets are saved, markets of held currencies are saved
+// on every sync. Prices of coins are kept in the history after they're sold
+const allMarketPricesPeriod = time.Hour
+
 type BalanceUsecases interface {
 	StartSyncFromExchangePeriodically(period time.Duration) (stop func(), err error)
 	SyncFromExchange() error
 	// All records from the last N hours
 	FetchHourly(currency string, hours int) ([]domain.Balance, error)
+	// All records after since but not older than a week, the oldest first
+	FetchSince(currency string, since time.Time) ([]domain.Balance, error)
 	// Records from the last week with 5 min interval
 	FetchWeekly(currency string) ([]domain.Balance, error)
 	// Records from the last month with 1 hour interval
 	FetchMonthly(currency string) ([]domain.Balance, error)
-	//TODO // All records with 1 day???  interval
+	// All records with 1 day interval, older than a year with 1 week interval
 	FetchAll(currency string) ([]domain.Balance, error)
+	// Records in [from, to) aggregated into open/close/min/max buckets of the resolution
+	FetchRange(currency string, from, to time.Time, resolution time.Duration) ([]domain.BalanceCandle, error)
+	// Returns of the balance in [from, to) net of deposits and withdrawals, points are candles of the resolution
+	FetchPerformance(currency string, from, to time.Time, resolution time.Duration) (*domain.Performance, error)
 	// Get currency balances > 0
 	GetActiveCurrencies() ([]domain.Balance, error)
 }
 
 type balanceUsecases struct {
-	exchange       storage.Exchange
-	balanceStorage storage.BalanceStorage
-	log            *logrus.Entry
+	exchange        storage.Exchange
+	balanceStorage  storage.BalanceStorage
+	transferStorage storage.TransferStorage
+	marketStorage   storage.MarketStorage
+	log             *logrus.Entry
+
+	pricesLock    sync.Mutex
+	lastAllPrices time.Time
 }
 
-func NewBalanceUsecase(exchange storage.Exchange, balanceStorage storage.BalanceStorage) BalanceUsecases {
+// NewBalanceUsecase creates balance usecases. Prices of markets of held currencies are saved to the market storage
+// on every sync if the exchange gives them, prices of all markets are saved every allMarketPricesPeriod
+func NewBalanceUsecase(exchange storage.Exchange, balanceStorage storage.BalanceStorage, transferStorage storage.TransferStorage,
+	marketStorage storage.MarketStorage) BalanceUsecases {
 	log := logrus.WithField("component", "balanceUC")
 	return &balanceUsecases{
-		exchange:       exchange,
-		balanceStorage: balanceStorage,
-		log:            log,
+		exchange:        exchange,
+		balanceStorage:  balanceStorage,
+		transferStorage: transferStorage,
+		marketStorage:   marketStorage,
+		log:             log,
 	}
 }
 
@@ -64,20 +91,9 @@ func (u *balanceUsecases) SyncFromExchange() error {
 	if len(balances) == 0 {
 		return nil
 	}
+	prices, allPrices := u.marketPrices(balances)
 
-	total := domain.Balance{
-		Currency: "total",
-		//TODO fix me for multiple exchanges
-		Exchange: balances[0].Exchange,
-		Time:     balances[0].Time,
-	}
-
-	for _, b := range balances {
-		total.BTCAmount += b.BTCAmount
-		total.USDTAmount += b.USDTAmount
-	}
-
-	balances = append(balances, total)
+	balances = snapshotWithTotals(u.exchange, balances)
 
 	err = u.balanceStorage.Save(balances...)
 	if err != nil {
@@ -85,6 +101,18 @@ func (u *balanceUsecases) SyncFromExchange() error {
 		return err
 	}
 
+	// prices are secondary, balances are synced even if they can't be saved
+	if len(prices) > 0 {
+		err = u.marketStorage.Save(prices...)
+		if err != nil {
+			u.log.WithField("method", "SyncFromExchange").WithError(err).Error("market prices aren't saved")
+		} else if allPrices {
+			u.pricesLock.Lock()
+			u.lastAllPrices = time.Now()
+			u.pricesLock.Unlock()
+		}
+	}
+
 	if u.log.Level >= logrus.DebugLevel {
 		jsonBalances, err := json.MarshalIndent(balances, "", "  ")
 		if err != nil {
@@ -96,6 +124,124 @@ func (u *balanceUsecases) SyncFromExchange() error {
 	return nil
 }
 
+// marketPrices returns last prices of markets if the exchange gives them. all is true if they're prices
+// of all markets, otherwise they're markets between held currencies, BTC and USDT, other markets
+// would take space on every sync
+func (u *balanceUsecases) marketPrices(balances []domain.Balance) (result []domain.MarketCandle, all bool) {
+	history, ok := u.exchange.(storage.MarketHistory)
+	if !ok {
+		return nil, false
+	}
+
+	u.pricesLock.Lock()
+	all = time.Since(u.lastAllPrices) >= allMarketPricesPeriod
+	u.pricesLock.Unlock()
+	if all {
+		return history.LastMarketPrices(), true
+	}
+
+	currencies := map[string]bool{"BTC": true, "USDT": true}
+	for _, b := range balances {
+		currencies[strings.ToUpper(b.Currency)] = true
+	}
+
+	for _, candle := range history.LastMarketPrices() {
+		toFrom := strings.Split(strings.ToUpper(candle.Market), "-")
+		if len(toFrom) == 2 && currencies[toFrom[0]] && currencies[toFrom[1]] {
+			result = append(result, candle)
+		}
+	}
+	return result, false
+}
+
+// snapshotWithTotals adds totals to balances of accounts. Coins of configured accounts get totals over all accounts,
+// balances of accounts keep their series. Totals of exchanges are added only if accounts of several exchanges
+// are configured, otherwise they repeat the total
+func snapshotWithTotals(exchange storage.Exchange, balances []domain.Balance) []domain.Balance {
+	accounts, ok := exchange.(storage.MultiAccount)
+	exchangeTotals := ok && len(accounts.ExchangeTypes()) > 1
+	result := append([]domain.Balance{}, balances...)
+	if ok {
+		result = append(result, calculateCoinTotals(balances)...)
+	}
+	return append(result, calculateTotals(balances, exchangeTotals)...)
+}
+
+// calculateCoinTotals sums balances of every coin over accounts keeping the order of coins
+func calculateCoinTotals(balances []domain.Balance) []domain.Balance {
+	var (
+		result    []domain.Balance
+		positions = make(map[string]int)
+	)
+	for _, b := range balances {
+		i, ok := positions[b.Currency]
+		if !ok {
+			i = len(result)
+			positions[b.Currency] = i
+			result = append(result, domain.Balance{
+				Currency: domain.CoinTotalCurrency(b.Currency),
+				Time:     b.Time,
+			})
+		}
+
+		total := &result[i]
+		total.Amount += b.Amount
+		total.BTCAmount += b.BTCAmount
+		total.USDTAmount += b.USDTAmount
+		addFiat(total, b.Fiat)
+	}
+	return result
+}
+
+// calculateTotals sums balances over all exchanges and, if exchangeTotals is set, per exchange
+func calculateTotals(balances []domain.Balance, exchangeTotals bool) []domain.Balance {
+	var exchanges []domain.ExchangeType
+	totals := make(map[domain.ExchangeType]*domain.Balance)
+
+	total := domain.Balance{
+		Currency: domain.TotalCurrency,
+		Time:     balances[0].Time,
+	}
+
+	for _, b := range balances {
+		total.BTCAmount += b.BTCAmount
+		total.USDTAmount += b.USDTAmount
+		addFiat(&total, b.Fiat)
+		if !exchangeTotals {
+			continue
+		}
+
+		exchangeTotal, ok := totals[b.Exchange]
+		if !ok {
+			exchangeTotal = &domain.Balance{
+				Exchange: b.Exchange,
+				Currency: b.Exchange.TotalCurrency(),
+				Time:     b.Time,
+			}
+			totals[b.Exchange] = exchangeTotal
+			exchanges = append(exchanges, b.Exchange)
+		}
+		exchangeTotal.BTCAmount += b.BTCAmount
+		exchangeTotal.USDTAmount += b.USDTAmount
+		addFiat(exchangeTotal, b.Fiat)
+	}
+
+	result := make([]domain.Balance, 0, len(exchanges)+1)
+	for _, exchange := range exchanges {
+		result = append(result, *totals[exchange])
+	}
+	return append(result, total)
+}
+
+func addFiat(total *domain.Balance, fiat map[string]float64) {
+	for currency, amount := range fiat {
+		if total.Fiat == nil {
+			total.Fiat = make(map[string]float64)
+		}
+		total.Fiat[currency] += amount
+	}
+}
+
 func (u *balanceUsecases) FetchHourly(currency string, hours int) ([]domain.Balance, error) {
 	balances, err := u.balanceStorage.FetchHourly(currency, hours)
 	if err != nil {
@@ -106,6 +252,29 @@ func (u *balanceUsecases) FetchHourly(currency string, hours int) ([]domain.Bala
 	return balances, nil
 }
 
+func (u *balanceUsecases) FetchSince(currency string, since time.Time) ([]domain.Balance, error) {
+	hours := int(math.Ceil(time.Since(since).Hours()))
+	if hours < 1 {
+		hours = 1
+	} else if hours > maxFetchSinceHours {
+		hours = maxFetchSinceHours
+	}
+
+	balances, err := u.balanceStorage.FetchHourly(currency, hours)
+	if err != nil {
+		u.log.WithField("method", "FetchSince").WithError(err).Error()
+		return nil, err
+	}
+
+	result := []domain.Balance{}
+	for i := len(balances) - 1; i >= 0; i-- {
+		if balances[i].Time.After(since) {
+			result = append(result, balances[i])
+		}
+	}
+	return result, nil
+}
+
 func (u *balanceUsecases) FetchWeekly(currency string) ([]domain.Balance, error) {
 	balances, err := u.balanceStorage.FetchWeekly(currency)
 	if err != nil {
@@ -136,6 +305,163 @@ func (u *balanceUsecases) FetchAll(currency string) ([]domain.Balance, error) {
 	return balances, nil
 }
 
+func (u *balanceUsecases) FetchRange(currency string, from, to time.Time, resolution time.Duration) ([]domain.BalanceCandle, error) {
+	candles, err := u.balanceStorage.FetchRange(currency, from, to, resolution)
+	if err != nil {
+		u.log.WithField("method", "FetchRange").WithError(err).Error()
+		return nil, err
+	}
+
+	return candles, nil
+}
+
+// FetchPerformance splits the range into sub-periods by candles. The return of a sub-period is
+// (V_i - F_i) / V_i-1 - 1, where V is the closing balance and F is the net flow of transfers during the period,
+// so deposits and withdrawals don't count as gains and losses. Transfers are valued at rates saved at their sync
+// or at the daily balance if they were synced without rates
+func (u *balanceUsecases) FetchPerformance(currency string, from, to time.Time, resolution time.Duration) (*domain.Performance, error) {
+	candles, err := u.balanceStorage.FetchRange(currency, from, to, resolution)
+	if err != nil {
+		u.log.WithField("method", "FetchPerformance").WithError(err).Error()
+		return nil, err
+	}
+
+	transfers, err := u.transferStorage.Fetch(from, to)
+	if err != nil {
+		u.log.WithField("method", "FetchPerformance").WithError(err).Error()
+		return nil, err
+	}
+
+	var (
+		flows      []domain.Transfer
+		currencies []string
+	)
+	for _, t := range transfers {
+		if transferOfCurrency(t, currency) {
+			flows = append(flows, t)
+			currencies = append(currencies, t.Currency)
+		}
+	}
+
+	rates, err := fetchDailyRates(u.balanceStorage, currencies, from.AddDate(0, 0, -1), to)
+	if err != nil {
+		u.log.WithField("method", "FetchPerformance").WithError(err).Error()
+		return nil, err
+	}
+
+	return calculatePerformance(candles, flows, rates, resolution), nil
+}
+
+// transferOfCurrency checks if the transfer changes the balance of the currency, which can be a coin,
+// the total of a coin over accounts, the total over all exchanges or the total of one exchange
+func transferOfCurrency(t domain.Transfer, currency string) bool {
+	switch currency {
+	case domain.TotalCurrency:
+		return true
+	case t.Exchange.TotalCurrency(), domain.CoinTotalCurrency(t.Currency):
+		return true
+	default:
+		return t.Currency == currency
+	}
+}
+
+// calculatePerformance returns performance points of candles, the latest first.
+// Candles and transfers must be the latest first, every transfer is the flow of the latest candle started before it.
+// Flows in the first candle are already in its balance, which is the starting value
+func calculatePerformance(candles []domain.BalanceCandle, transfers []domain.Transfer, rates dailyRates, resolution time.Duration) *domain.Performance {
+	result := &domain.Performance{
+		Points: make([]domain.PerformancePoint, len(candles)),
+	}
+	if len(candles) == 0 {
+		return result
+	}
+
+	// the earliest first to chain returns
+	points := make([]domain.PerformancePoint, len(candles))
+	for i, c := range candles {
+		points[len(candles)-1-i] = domain.PerformancePoint{
+			Time:       c.Time,
+			BTCAmount:  c.BTCAmount.Close,
+			USDTAmount: c.USDTAmount.Close,
+		}
+	}
+
+	var (
+		// the range runs from the close of the first candle to the close of the last one
+		start, end                = points[0].Time.Add(resolution), points[len(points)-1].Time.Add(resolution)
+		flowBTC, flowUSDT         float64
+		weightedBTC, weightedUSDT float64
+	)
+	for _, t := range transfers {
+		amount := t.Amount
+		if t.Type == domain.TransferTypeWithdrawal {
+			amount = -amount
+		}
+		btcRate, btcOK := rates.btcRate(t.Currency, t.Time, t.BTCRate)
+		usdtRate, usdtOK := rates.usdtRate(t.Currency, t.Time, t.USDTRate)
+		if !btcOK || !usdtOK {
+			result.Unpriced++
+			continue
+		}
+		btc, usdt := amount*btcRate, amount*usdtRate
+
+		i := sort.Search(len(points), func(i int) bool {
+			return points[i].Time.After(t.Time)
+		}) - 1
+		if i < 0 {
+			i = 0
+		}
+		points[i].NetFlowBTC += btc
+		points[i].NetFlowUSDT += usdt
+		if i == 0 {
+			continue
+		}
+
+		// Modified Dietz weights flows by the part of the range they were invested
+		weight := 0.0
+		if end.After(start) {
+			weight = math.Max(0, math.Min(1, float64(end.Sub(t.Time))/float64(end.Sub(start))))
+		}
+		flowBTC += btc
+		flowUSDT += usdt
+		weightedBTC += btc * weight
+		weightedUSDT += usdt * weight
+	}
+
+	for i := 1; i < len(points); i++ {
+		points[i].TWRBTC = chainReturn(points[i-1].TWRBTC, points[i-1].BTCAmount, points[i].BTCAmount, points[i].NetFlowBTC)
+		points[i].TWRUSDT = chainReturn(points[i-1].TWRUSDT, points[i-1].USDTAmount, points[i].USDTAmount, points[i].NetFlowUSDT)
+	}
+
+	first, last := points[0], points[len(points)-1]
+	result.TWRBTC = last.TWRBTC
+	result.TWRUSDT = last.TWRUSDT
+	result.MWRBTC = modifiedDietz(first.BTCAmount, last.BTCAmount, flowBTC, weightedBTC)
+	result.MWRUSDT = modifiedDietz(first.USDTAmount, last.USDTAmount, flowUSDT, weightedUSDT)
+
+	for i, p := range points {
+		result.Points[len(points)-1-i] = p
+	}
+	return result
+}
+
+// chainReturn links the return of the sub-period to the return since the start. The sub-period after
+// the empty balance has no return
+func chainReturn(twr, prevValue, value, flow float64) float64 {
+	if prevValue == 0 {
+		return twr
+	}
+	return (1+twr)*(value-flow)/prevValue - 1
+}
+
+func modifiedDietz(startValue, endValue, flow, weightedFlow float64) float64 {
+	invested := startValue + weightedFlow
+	if invested == 0 {
+		return 0
+	}
+	return (endValue - startValue - flow) / invested
+}
+
 func (u *balanceUsecases) GetActiveCurrencies() ([]domain.Balance, error) {
 	balances, err := u.balanceStorage.GetActiveCurrencies()
 	if err != nil {
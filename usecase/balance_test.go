@@ -12,6 +12,7 @@ import (
 
 	"github.com/nawa/cryptoexchange-dashboard/domain"
 	"github.com/nawa/cryptoexchange-dashboard/storage"
+	"github.com/nawa/cryptoexchange-dashboard/storage/memory"
 	"github.com/nawa/cryptoexchange-dashboard/storage/mocks"
 	"github.com/nawa/cryptoexchange-dashboard/usecase/testdata"
 	"github.com/nawa/cryptoexchange-dashboard/utils"
@@ -37,7 +38,7 @@ func TestBalanceUsecases_StartSyncFromExchangePeriodically(t *testing.T) {
 		MinTimes(10).
 		MaxTimes(20)
 
-	balanceUC := NewBalanceUsecase(exchange, balanceStorage)
+	balanceUC := NewBalanceUsecase(exchange, balanceStorage, mocks.NewMockTransferStorage(ctrl), mocks.NewMockMarketStorage(ctrl))
 
 	stop, err := balanceUC.StartSyncFromExchangePeriodically(time.Millisecond * 10)
 	assert.NoError(t, err)
@@ -85,6 +86,47 @@ func TestBalanceUsecases_SyncFromExchange(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "correct with multiple exchanges",
+			fieldsF: func(ctrl *gomock.Controller) fields {
+				balanceStorage := mocks.NewMockBalanceStorage(ctrl)
+				exchange := mocks.NewMockExchange(ctrl)
+
+				exchange.EXPECT().GetBalance().
+					Return(testdata.MultiExchangeBalances(), nil).
+					Times(1)
+
+				balanceStorage.EXPECT().
+					Save(testdata.MultiExchangeBalancesWithTotal()).
+					Return(nil).
+					Times(1)
+
+				return fields{
+					exchange:       newMultiAccountExchange(ctrl, exchange),
+					balanceStorage: balanceStorage,
+					log:            utils.NewDevNullLog(),
+				}
+			},
+			wantErr: false,
+		},
+		{
+			name: "correct with empty balance",
+			fieldsF: func(ctrl *gomock.Controller) fields {
+				balanceStorage := mocks.NewMockBalanceStorage(ctrl)
+				exchange := mocks.NewMockExchange(ctrl)
+
+				exchange.EXPECT().GetBalance().
+					Return([]domain.Balance{}, nil).
+					Times(1)
+
+				return fields{
+					exchange:       exchange,
+					balanceStorage: balanceStorage,
+					log:            utils.NewDevNullLog(),
+				}
+			},
+			wantErr: false,
+		},
 		{
 			name: "error in exchange",
 			fieldsF: func(ctrl *gomock.Controller) fields {
@@ -178,6 +220,156 @@ func TestBalanceUsecases_SyncFromExchange(t *testing.T) {
 	}
 }
 
+func TestBalanceUsecases_SyncFromExchange_Storage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	exchange := mocks.NewMockExchange(ctrl)
+	balanceStorage := memory.NewBalanceStorage()
+	u := NewBalanceUsecase(newMultiAccountExchange(ctrl, exchange), balanceStorage, memory.NewTransferStorage(), memory.NewMarketStorage())
+
+	exchange.EXPECT().GetBalance().Return(testdata.MultiExchangeBalances(), nil)
+	assert.NoError(t, u.SyncFromExchange())
+
+	active, err := u.GetActiveCurrencies()
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, testdata.MultiExchangeBalancesWithTotal(), active)
+
+	// the next snapshot an hour later, BTC is sold on binance
+	next := testdata.MultiExchangeBalances()[:1]
+	next = append(next, testdata.MultiExchangeBalances()[2])
+	for i := range next {
+		next[i].Time = next[i].Time.Add(time.Hour)
+	}
+	exchange.EXPECT().GetBalance().Return(next, nil)
+	assert.NoError(t, u.SyncFromExchange())
+
+	active, err = u.GetActiveCurrencies()
+	assert.NoError(t, err)
+	assert.Len(t, active, 6)
+	for _, b := range active {
+		assert.NotEqual(t, domain.ExchangeTypeBinance.TotalCurrency(), b.Currency)
+	}
+
+	totals, err := u.FetchAll(domain.TotalCurrency)
+	assert.NoError(t, err)
+	assert.Len(t, totals, 1, "both snapshots are in one day")
+	assert.Equal(t, float64(6), totals[0].BTCAmount)
+
+	candles, err := u.FetchRange(domain.TotalCurrency, time.Unix(0, 0), time.Unix(0, 0).Add(2*time.Hour), 2*time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.BalanceCandle{
+		{
+			Currency:   domain.TotalCurrency,
+			Time:       time.Unix(0, 0).UTC(),
+			BTCAmount:  domain.OHLC{Open: 6, Close: 4, Min: 4, Max: 6},
+			USDTAmount: domain.OHLC{Open: 60, Close: 40, Min: 40, Max: 60},
+		},
+	}, candles)
+
+	candles, err = u.FetchRange(domain.ExchangeTypeBinance.TotalCurrency(), time.Unix(0, 0), time.Unix(0, 0).Add(2*time.Hour), time.Hour)
+	assert.NoError(t, err)
+	assert.Len(t, candles, 1)
+
+	// BTC of both accounts is the series of its own, balances of accounts keep theirs
+	candles, err = u.FetchRange(domain.CoinTotalCurrency("BTC"), time.Unix(0, 0), time.Unix(0, 0).Add(2*time.Hour), 2*time.Hour)
+	assert.NoError(t, err)
+	assert.Len(t, candles, 1)
+	assert.Equal(t, domain.OHLC{Open: 3, Close: 1, Min: 1, Max: 3}, candles[0].Amount)
+
+	snapshot, err := balanceStorage.FetchSnapshot(time.Unix(0, 0))
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, testdata.MultiExchangeBalancesWithTotal(), snapshot)
+}
+
+type multiAccountExchange struct {
+	*mocks.MockExchange
+	*mocks.MockMultiAccount
+}
+
+// newMultiAccountExchange creates the exchange of accounts on Bittrex and Binance
+func newMultiAccountExchange(ctrl *gomock.Controller, exchange *mocks.MockExchange) multiAccountExchange {
+	accounts := mocks.NewMockMultiAccount(ctrl)
+	accounts.EXPECT().ExchangeTypes().Return([]domain.ExchangeType{domain.ExchangeTypeBittrex, domain.ExchangeTypeBinance}).AnyTimes()
+	return multiAccountExchange{exchange, accounts}
+}
+
+type historyExchange struct {
+	*mocks.MockExchange
+	*mocks.MockMarketHistory
+}
+
+func TestBalanceUsecases_SyncFromExchange_MarketPrices(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	exchange := mocks.NewMockExchange(ctrl)
+	history := mocks.NewMockMarketHistory(ctrl)
+	marketStorage := memory.NewMarketStorage()
+	u := NewBalanceUsecase(historyExchange{exchange, history}, memory.NewBalanceStorage(), memory.NewTransferStorage(), marketStorage)
+
+	price := func(market string, last float64) domain.MarketCandle {
+		return domain.MarketCandle{
+			Exchange: domain.ExchangeTypeBittrex,
+			Market:   market,
+			Time:     time.Unix(0, 0).UTC(),
+			Price:    domain.OHLC{Open: last, Close: last, Min: last, Max: last},
+		}
+	}
+	// the first sync saves all markets
+	exchange.EXPECT().GetBalance().Return(testdata.MultiExchangeBalances(), nil)
+	history.EXPECT().LastMarketPrices().Return([]domain.MarketCandle{
+		price("USDT-BTC", 10),
+		price("BTC-CUR1", 0.03),
+		price("BTC-CUR2", 0.04),
+		price("ETH-CUR1", 0.5),
+	})
+	assert.NoError(t, u.SyncFromExchange())
+
+	from, to := time.Unix(0, 0), time.Unix(0, 0).Add(2*time.Hour)
+	for _, market := range []string{"USDT-BTC", "BTC-CUR1", "BTC-CUR2", "ETH-CUR1"} {
+		candles, err := marketStorage.FetchCandles(domain.ExchangeTypeBittrex, market, from, to, time.Hour)
+		assert.NoError(t, err)
+		assert.Len(t, candles, 1, market)
+	}
+
+	// markets of not held CUR2 and ETH aren't saved until the next hour
+	next := func(market string, last float64) domain.MarketCandle {
+		candle := price(market, last)
+		candle.Time = candle.Time.Add(time.Hour)
+		return candle
+	}
+	exchange.EXPECT().GetBalance().Return(testdata.MultiExchangeBalances(), nil)
+	history.EXPECT().LastMarketPrices().Return([]domain.MarketCandle{
+		next("USDT-BTC", 10),
+		next("BTC-CUR1", 0.03),
+		next("BTC-CUR2", 0.04),
+		next("ETH-CUR1", 0.5),
+	})
+	assert.NoError(t, u.SyncFromExchange())
+
+	candles, err := marketStorage.FetchCandles(domain.ExchangeTypeBittrex, "BTC-CUR1", from, to, time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.MarketCandle{next("BTC-CUR1", 0.03), price("BTC-CUR1", 0.03)}, candles)
+	candles, err = marketStorage.FetchCandles(domain.ExchangeTypeBittrex, "USDT-BTC", from, to, time.Hour)
+	assert.NoError(t, err)
+	assert.Len(t, candles, 2)
+	candles, err = marketStorage.FetchCandles(domain.ExchangeTypeBittrex, "BTC-CUR2", from, to, time.Hour)
+	assert.NoError(t, err)
+	assert.Len(t, candles, 1)
+	candles, err = marketStorage.FetchCandles(domain.ExchangeTypeBittrex, "ETH-CUR1", from, to, time.Hour)
+	assert.NoError(t, err)
+	assert.Len(t, candles, 1)
+
+	// balances are synced even if prices can't be saved
+	failingStorage := mocks.NewMockMarketStorage(ctrl)
+	failingStorage.EXPECT().Save(gomock.Any()).Return(errExpected)
+	u = NewBalanceUsecase(historyExchange{exchange, history}, memory.NewBalanceStorage(), memory.NewTransferStorage(), failingStorage)
+	exchange.EXPECT().GetBalance().Return(testdata.MultiExchangeBalances(), nil)
+	history.EXPECT().LastMarketPrices().Return([]domain.MarketCandle{price("USDT-BTC", 10)})
+	assert.NoError(t, u.SyncFromExchange())
+}
+
 func TestBalanceUsecases_FetchHourly(t *testing.T) {
 	type fields struct {
 		exchange       storage.Exchange
@@ -270,6 +462,80 @@ func TestBalanceUsecases_FetchHourly(t *testing.T) {
 	}
 }
 
+func TestBalanceUsecases_FetchSince(t *testing.T) {
+	now := time.Now()
+	stored := []domain.Balance{
+		{Currency: "CUR1", Amount: 3, Time: now.Add(-10 * time.Minute)},
+		{Currency: "CUR1", Amount: 2, Time: now.Add(-60 * time.Minute)},
+		{Currency: "CUR1", Amount: 1, Time: now.Add(-100 * time.Minute)},
+	}
+
+	type args struct {
+		currency string
+		since    time.Time
+	}
+	tests := []struct {
+		name         string
+		storageF     func(balanceStorage *mocks.MockBalanceStorage)
+		args         args
+		wantBalances []domain.Balance
+		wantErr      bool
+	}{
+		{
+			name: "correct",
+			storageF: func(balanceStorage *mocks.MockBalanceStorage) {
+				balanceStorage.EXPECT().FetchHourly("CUR1", 2).Return(stored, nil)
+			},
+			args:         args{currency: "CUR1", since: now.Add(-90 * time.Minute)},
+			wantBalances: []domain.Balance{stored[1], stored[0]},
+		},
+		{
+			name: "correct with old since",
+			storageF: func(balanceStorage *mocks.MockBalanceStorage) {
+				balanceStorage.EXPECT().FetchHourly("CUR1", maxFetchSinceHours).Return(stored, nil)
+			},
+			args:         args{currency: "CUR1", since: time.Unix(0, 0)},
+			wantBalances: []domain.Balance{stored[2], stored[1], stored[0]},
+		},
+		{
+			name: "correct with future since",
+			storageF: func(balanceStorage *mocks.MockBalanceStorage) {
+				balanceStorage.EXPECT().FetchHourly("CUR1", 1).Return(stored, nil)
+			},
+			args:         args{currency: "CUR1", since: now.Add(time.Hour)},
+			wantBalances: []domain.Balance{},
+		},
+		{
+			name: "error in balanceStorage",
+			storageF: func(balanceStorage *mocks.MockBalanceStorage) {
+				balanceStorage.EXPECT().FetchHourly("CUR1", 2).Return(nil, errExpected)
+			},
+			args:    args{currency: "CUR1", since: now.Add(-90 * time.Minute)},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			balanceStorage := mocks.NewMockBalanceStorage(ctrl)
+			tt.storageF(balanceStorage)
+			u := &balanceUsecases{
+				balanceStorage: balanceStorage,
+				log:            utils.NewDevNullLog(),
+			}
+			gotBalances, err := u.FetchSince(tt.args.currency, tt.args.since)
+			if tt.wantErr {
+				assert.Equal(t, errExpected, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantBalances, gotBalances)
+		})
+	}
+}
+
 func TestBalanceUsecases_FetchWeekly(t *testing.T) {
 	type fields struct {
 		exchange       storage.Exchange
@@ -525,6 +791,104 @@ func TestBalanceUsecases_FetchAll(t *testing.T) {
 	}
 }
 
+func TestBalanceUsecases_FetchRange(t *testing.T) {
+	type fields struct {
+		exchange       storage.Exchange
+		balanceStorage storage.BalanceStorage
+		log            *logrus.Entry
+	}
+	type args struct {
+		currency   string
+		from       time.Time
+		to         time.Time
+		resolution time.Duration
+	}
+	tests := []struct {
+		name        string
+		fieldsF     func(ctrl *gomock.Controller, args args) fields
+		args        args
+		wantCandles []domain.BalanceCandle
+		wantErr     bool
+	}{
+		{
+			name: "correct",
+			fieldsF: func(ctrl *gomock.Controller, args args) fields {
+				balanceStorage := mocks.NewMockBalanceStorage(ctrl)
+				exchange := mocks.NewMockExchange(ctrl)
+
+				balanceStorage.EXPECT().
+					FetchRange(args.currency, args.from, args.to, args.resolution).
+					Return(testdata.BalanceCandles(), nil).
+					Times(1)
+
+				return fields{
+					exchange:       exchange,
+					balanceStorage: balanceStorage,
+					log:            utils.NewDevNullLog(),
+				}
+			},
+			args: args{
+				currency:   "CUR1",
+				from:       time.Unix(0, 0),
+				to:         time.Unix(0, 0).Add(2 * time.Hour),
+				resolution: time.Hour,
+			},
+			wantCandles: testdata.BalanceCandles(),
+			wantErr:     false,
+		},
+		{
+			name: "error in balanceStorage",
+			fieldsF: func(ctrl *gomock.Controller, args args) fields {
+				balanceStorage := mocks.NewMockBalanceStorage(ctrl)
+				exchange := mocks.NewMockExchange(ctrl)
+
+				balanceStorage.EXPECT().
+					FetchRange(args.currency, args.from, args.to, args.resolution).
+					Return(nil, errExpected).
+					Times(1)
+
+				return fields{
+					exchange:       exchange,
+					balanceStorage: balanceStorage,
+					log:            utils.NewDevNullLog(),
+				}
+			},
+			args: args{
+				currency:   "CUR1",
+				from:       time.Unix(0, 0),
+				to:         time.Unix(0, 0).Add(2 * time.Hour),
+				resolution: time.Hour,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			fields := tt.fieldsF(ctrl, tt.args)
+			u := &balanceUsecases{
+				exchange:       fields.exchange,
+				balanceStorage: fields.balanceStorage,
+				log:            fields.log,
+			}
+			gotCandles, err := u.FetchRange(tt.args.currency, tt.args.from, tt.args.to, tt.args.resolution)
+			if err != nil {
+				if !tt.wantErr {
+					t.Errorf("balanceUsecases.FetchRange() error = %v, wantErr %v", err, tt.wantErr)
+				} else if err != errExpected {
+					t.Errorf("balanceUsecases.FetchRange() error = %v, expected error %v", err, errExpected)
+				}
+				return
+			}
+			if !reflect.DeepEqual(gotCandles, tt.wantCandles) {
+				t.Errorf("balanceUsecases.FetchRange() = %v, want %v", gotCandles, tt.wantCandles)
+			}
+		})
+	}
+}
+
 func TestBalanceUsecases_GetActiveCurrencies(t *testing.T) {
 	type fields struct {
 		exchange       storage.Exchange
@@ -602,3 +966,181 @@ func TestBalanceUsecases_GetActiveCurrencies(t *testing.T) {
 		})
 	}
 }
+
+func TestBalanceUsecases_FetchPerformance(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	balanceStorage := memory.NewBalanceStorage()
+	transferStorage := memory.NewTransferStorage()
+	u := NewBalanceUsecase(mocks.NewMockExchange(ctrl), balanceStorage, transferStorage, memory.NewMarketStorage())
+
+	start := time.Date(2018, 2, 6, 10, 0, 0, 0, time.UTC)
+	assert.NoError(t, balanceStorage.Save(
+		domain.Balance{Currency: domain.TotalCurrency, BTCAmount: 1, USDTAmount: 10000, Time: start.Add(10 * time.Minute)},
+		domain.Balance{Currency: "BTC", Amount: 1, BTCAmount: 1, USDTAmount: 10000, Time: start.Add(10 * time.Minute)},
+		// 10% gain
+		domain.Balance{Currency: domain.TotalCurrency, BTCAmount: 1.1, USDTAmount: 11000, Time: start.Add(70 * time.Minute)},
+		// 1 BTC deposit and 10% gain of the previous balance
+		domain.Balance{Currency: domain.TotalCurrency, BTCAmount: 2.2, USDTAmount: 22000, Time: start.Add(165 * time.Minute)},
+		domain.Balance{Currency: "BTC", Amount: 2.2, BTCAmount: 2.2, USDTAmount: 22000, Time: start.Add(165 * time.Minute)},
+	))
+	assert.NoError(t, transferStorage.Save(
+		domain.Transfer{Exchange: domain.ExchangeTypeBittrex, ID: "1", Type: domain.TransferTypeDeposit, Currency: "BTC",
+			Time: start.Add(150 * time.Minute), Amount: 1, BTCRate: 1, USDTRate: 10000},
+		domain.Transfer{Exchange: domain.ExchangeTypeBittrex, ID: "2", Type: domain.TransferTypeWithdrawal, Currency: "BTC",
+			Time: start.Add(-time.Hour), Amount: 5, BTCRate: 1, USDTRate: 5000},
+		// CUR2 has never been held and the transfer was synced without rates
+		domain.Transfer{Exchange: domain.ExchangeTypeBittrex, ID: "3", Type: domain.TransferTypeDeposit, Currency: "CUR2",
+			Time: start.Add(90 * time.Minute), Amount: 3},
+	))
+
+	performance, err := u.FetchPerformance(domain.TotalCurrency, start, start.Add(3*time.Hour), time.Hour)
+	assert.NoError(t, err)
+
+	want := []domain.PerformancePoint{
+		{Time: start.Add(2 * time.Hour), BTCAmount: 2.2, USDTAmount: 22000, NetFlowBTC: 1, NetFlowUSDT: 10000, TWRBTC: 0.2, TWRUSDT: 0.2},
+		{Time: start.Add(time.Hour), BTCAmount: 1.1, USDTAmount: 11000, TWRBTC: 0.1, TWRUSDT: 0.1},
+		{Time: start, BTCAmount: 1, USDTAmount: 10000},
+	}
+	assert.Len(t, performance.Points, len(want))
+	for i, p := range want {
+		got := performance.Points[i]
+		assert.Equal(t, p.Time, got.Time)
+		assert.InDelta(t, p.BTCAmount, got.BTCAmount, 1e-9)
+		assert.InDelta(t, p.USDTAmount, got.USDTAmount, 1e-9)
+		assert.InDelta(t, p.NetFlowBTC, got.NetFlowBTC, 1e-9)
+		assert.InDelta(t, p.NetFlowUSDT, got.NetFlowUSDT, 1e-9)
+		assert.InDelta(t, p.TWRBTC, got.TWRBTC, 1e-9)
+		assert.InDelta(t, p.TWRUSDT, got.TWRUSDT, 1e-9)
+	}
+	assert.InDelta(t, 0.2, performance.TWRBTC, 1e-9)
+	assert.InDelta(t, 0.2, performance.TWRUSDT, 1e-9)
+	// the deposit is weighted by a quarter of the range from 11:00 to 13:00
+	assert.InDelta(t, 0.16, performance.MWRBTC, 1e-9)
+	assert.InDelta(t, 0.16, performance.MWRUSDT, 1e-9)
+	assert.Equal(t, 1, performance.Unpriced)
+
+	performance, err = u.FetchPerformance("CUR1", start, start.Add(3*time.Hour), time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, &domain.Performance{Points: []domain.PerformancePoint{}}, performance)
+}
+
+func TestBalanceUsecases_FetchPerformance_TransferRates(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	balanceStorage := memory.NewBalanceStorage()
+	transferStorage := memory.NewTransferStorage()
+	u := NewBalanceUsecase(mocks.NewMockExchange(ctrl), balanceStorage, transferStorage, memory.NewMarketStorage())
+
+	start := time.Date(2018, 2, 6, 10, 0, 0, 0, time.UTC)
+	assert.NoError(t, balanceStorage.Save(
+		domain.Balance{Currency: domain.TotalCurrency, BTCAmount: 1, USDTAmount: 10000, Time: start.Add(10 * time.Minute)},
+		domain.Balance{Currency: "BTC", Amount: 1, BTCAmount: 1, USDTAmount: 10000, Time: start.Add(10 * time.Minute)},
+		domain.Balance{Currency: domain.TotalCurrency, BTCAmount: 2.1, USDTAmount: 21000, Time: start.Add(70 * time.Minute)},
+	))
+	assert.NoError(t, transferStorage.Save(
+		// the rate saved at the sync is preferred to the daily balance
+		domain.Transfer{Exchange: domain.ExchangeTypeBittrex, ID: "1", Type: domain.TransferTypeDeposit, Currency: "BTC",
+			Time: start.Add(65 * time.Minute), Amount: 1, BTCRate: 1, USDTRate: 11000},
+		// the transfer synced without rates is valued by the daily balance
+		domain.Transfer{Exchange: domain.ExchangeTypeBittrex, ID: "2", Type: domain.TransferTypeDeposit, Currency: "BTC",
+			Time: start.Add(66 * time.Minute), Amount: 0.1},
+	))
+
+	performance, err := u.FetchPerformance(domain.TotalCurrency, start, start.Add(2*time.Hour), time.Hour)
+	assert.NoError(t, err)
+	assert.Len(t, performance.Points, 2)
+	assert.InDelta(t, 1.1, performance.Points[0].NetFlowBTC, 1e-9)
+	assert.InDelta(t, 12000, performance.Points[0].NetFlowUSDT, 1e-9)
+	assert.Zero(t, performance.Unpriced)
+}
+
+func TestBalanceUsecases_FetchPerformance_Error(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	balanceStorage := mocks.NewMockBalanceStorage(ctrl)
+	transferStorage := mocks.NewMockTransferStorage(ctrl)
+	u := NewBalanceUsecase(mocks.NewMockExchange(ctrl), balanceStorage, transferStorage, memory.NewMarketStorage())
+
+	from, to := time.Unix(0, 0), time.Unix(3600, 0)
+
+	balanceStorage.EXPECT().FetchRange(domain.TotalCurrency, from, to, time.Minute).Return(nil, errExpected)
+	_, err := u.FetchPerformance(domain.TotalCurrency, from, to, time.Minute)
+	assert.Equal(t, errExpected, err)
+
+	balanceStorage.EXPECT().FetchRange(domain.TotalCurrency, from, to, time.Minute).Return(nil, nil)
+	transferStorage.EXPECT().Fetch(from, to).Return(nil, errExpected)
+	_, err = u.FetchPerformance(domain.TotalCurrency, from, to, time.Minute)
+	assert.Equal(t, errExpected, err)
+}
+
+func TestTransferOfCurrency(t *testing.T) {
+	transfer := domain.Transfer{Exchange: domain.ExchangeTypeBinance, Currency: "ETH"}
+
+	assert.True(t, transferOfCurrency(transfer, domain.TotalCurrency))
+	assert.True(t, transferOfCurrency(transfer, domain.ExchangeTypeBinance.TotalCurrency()))
+	assert.False(t, transferOfCurrency(transfer, domain.ExchangeTypeBittrex.TotalCurrency()))
+	assert.True(t, transferOfCurrency(transfer, "ETH"))
+	assert.False(t, transferOfCurrency(transfer, "BTC"))
+}
+
+func TestCalculateTotals_Fiat(t *testing.T) {
+	syncTime := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	totals := calculateTotals([]domain.Balance{
+		{Exchange: domain.ExchangeTypeBittrex, Currency: "BTC", BTCAmount: 1, USDTAmount: 10000, Fiat: map[string]float64{"EUR": 8000, "GBP": 7500}, Time: syncTime},
+		{Exchange: domain.ExchangeTypeBittrex, Currency: "ETH", BTCAmount: 0.5, USDTAmount: 5000, Fiat: map[string]float64{"EUR": 4000, "GBP": 3750}, Time: syncTime},
+		{Exchange: domain.ExchangeTypeBinance, Currency: "ETH", BTCAmount: 0.1, USDTAmount: 1000, Fiat: map[string]float64{"EUR": 800, "GBP": 750}, Time: syncTime},
+	}, true)
+
+	assert.Equal(t, []domain.Balance{
+		{
+			Exchange:   domain.ExchangeTypeBittrex,
+			Currency:   domain.ExchangeTypeBittrex.TotalCurrency(),
+			BTCAmount:  1.5,
+			USDTAmount: 15000,
+			Fiat:       map[string]float64{"EUR": 12000, "GBP": 11250},
+			Time:       syncTime,
+		},
+		{
+			Exchange:   domain.ExchangeTypeBinance,
+			Currency:   domain.ExchangeTypeBinance.TotalCurrency(),
+			BTCAmount:  0.1,
+			USDTAmount: 1000,
+			Fiat:       map[string]float64{"EUR": 800, "GBP": 750},
+			Time:       syncTime,
+		},
+		{
+			Currency:   domain.TotalCurrency,
+			BTCAmount:  1.6,
+			USDTAmount: 16000,
+			Fiat:       map[string]float64{"EUR": 12800, "GBP": 12000},
+			Time:       syncTime,
+		},
+	}, totals)
+	// totals of exchanges are skipped if they repeat the total
+	totals = calculateTotals([]domain.Balance{
+		{Exchange: domain.ExchangeTypeBinance, Account: "main", Currency: "ETH", BTCAmount: 0.25, USDTAmount: 1000, Time: syncTime},
+		{Exchange: domain.ExchangeTypeBinance, Account: "trading", Currency: "ETH", BTCAmount: 0.5, USDTAmount: 2000, Time: syncTime},
+	}, false)
+	assert.Equal(t, []domain.Balance{
+		{Currency: domain.TotalCurrency, BTCAmount: 0.75, USDTAmount: 3000, Time: syncTime},
+	}, totals)
+}
+
+func TestCalculateCoinTotals(t *testing.T) {
+	syncTime := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	balances := []domain.Balance{
+		{Exchange: domain.ExchangeTypeBinance, Account: "main", Currency: "ETH", Amount: 1, BTCAmount: 0.25, USDTAmount: 1000, Fiat: map[string]float64{"EUR": 800}, Time: syncTime},
+		{Exchange: domain.ExchangeTypeBinance, Account: "main", Currency: "BTC", Amount: 1, BTCAmount: 1, USDTAmount: 10000, Time: syncTime},
+		{Exchange: domain.ExchangeTypeBinance, Account: "trading", Currency: "ETH", Amount: 2, BTCAmount: 0.5, USDTAmount: 2000, Fiat: map[string]float64{"EUR": 1600}, Time: syncTime},
+	}
+
+	assert.Equal(t, []domain.Balance{
+		{Currency: "total-ETH", Amount: 3, BTCAmount: 0.75, USDTAmount: 3000, Fiat: map[string]float64{"EUR": 2400}, Time: syncTime},
+		{Currency: "total-BTC", Amount: 1, BTCAmount: 1, USDTAmount: 10000, Time: syncTime},
+	}, calculateCoinTotals(balances))
+	assert.Equal(t, map[string]float64{"EUR": 800}, balances[0].Fiat)
+}
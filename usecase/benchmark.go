@@ -0,0 +1,180 @@
+package usecase
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage"
+)
+
+// candleIntervals are intervals of candles given by exchanges, see storage.MarketHistory
+var candleIntervals = []time.Duration{time.Minute, 5 * time.Minute, 30 * time.Minute, time.Hour, 24 * time.Hour}
+
+type BenchmarkUsecases interface {
+	// Values the portfolio of the first snapshot at or after start and BTC bought by it at times since the snapshot
+	// by saved market prices aggregated into candles of the resolution. Markets without saved candles are downloaded
+	// from the exchange once. Returns no balances without the snapshot
+	Compare(start time.Time, times []time.Time, resolution time.Duration) (*domain.Benchmark, error)
+}
+
+type benchmarkUsecases struct {
+	balanceStorage storage.BalanceStorage
+	marketUsecase  MarketUsecases
+	log            *logrus.Entry
+
+	backfillLock sync.Mutex
+	// backfilled are markets downloaded by intervals, gone markets aren't requested on every compare
+	backfilled map[time.Duration]map[string]bool
+}
+
+func NewBenchmarkUsecase(balanceStorage storage.BalanceStorage, marketUsecase MarketUsecases) BenchmarkUsecases {
+	log := logrus.WithField("component", "benchmarkUC")
+	return &benchmarkUsecases{
+		balanceStorage: balanceStorage,
+		marketUsecase:  marketUsecase,
+		log:            log,
+		backfilled:     make(map[time.Duration]map[string]bool),
+	}
+}
+
+// Compare keeps the order of times. Times without USDT-BTC price are skipped. Coins without the value in the snapshot
+// are valued as zero as in the total balance, other coins without prices make HODL missing at the time
+func (u *benchmarkUsecases) Compare(start time.Time, times []time.Time, resolution time.Duration) (*domain.Benchmark, error) {
+	snapshot, err := u.balanceStorage.FetchSnapshot(start)
+	if err != nil {
+		u.log.WithField("method", "Compare").WithError(err).Error()
+		return nil, err
+	}
+	if len(snapshot) == 0 {
+		return &domain.Benchmark{}, nil
+	}
+
+	result := &domain.Benchmark{
+		Start: snapshot[0].Time,
+	}
+
+	// coins are held together, exchanges and accounts don't matter
+	var (
+		btcAmount  float64
+		amounts    = make(map[holding]float64)
+		currencies = make(map[string]bool)
+		valued     = make(map[string]bool)
+	)
+	for _, b := range snapshot {
+		if strings.HasPrefix(b.Currency, domain.TotalCurrency) {
+			continue
+		}
+		h := holding{currency: strings.ToUpper(b.Currency)}
+		amounts[h] += b.Amount
+		currencies[h.currency] = true
+		valued[h.currency] = valued[h.currency] || b.BTCAmount > 0
+		btcAmount += b.BTCAmount
+	}
+
+	var from, to time.Time
+	for _, t := range times {
+		if t.Before(result.Start) {
+			continue
+		}
+		if from.IsZero() || t.Before(from) {
+			from = t
+		}
+		if t.After(to) {
+			to = t
+		}
+	}
+	if from.IsZero() {
+		return result, nil
+	}
+
+	// the price at the time is the close of the last candle before
+	prices, err := fetchHistoryPrices(u.marketUsecase, currencies, from.Add(-resolution), to.Add(resolution), resolution,
+		u.backfill(resolution))
+	if err != nil {
+		u.log.WithField("method", "Compare").WithError(err).Error()
+		return nil, err
+	}
+
+	unpriced := make(map[string]bool)
+	for _, t := range times {
+		usdtRate := prices.price("USDT-BTC", t)
+		if t.Before(result.Start) || usdtRate == 0 {
+			continue
+		}
+
+		hodl := domain.Balance{
+			Currency: domain.BenchmarkHODL,
+			Time:     t,
+		}
+		complete := true
+		for _, b := range prices.value(amounts, t) {
+			if b.BTCAmount == 0 && valued[b.Currency] {
+				unpriced[b.Currency] = true
+				complete = false
+			}
+			hodl.BTCAmount += b.BTCAmount
+			hodl.USDTAmount += b.USDTAmount
+		}
+		if complete {
+			result.HODL = append(result.HODL, hodl)
+		}
+
+		result.HODLBTC = append(result.HODLBTC, domain.Balance{
+			Currency:   domain.BenchmarkHODLBTC,
+			Amount:     btcAmount,
+			BTCAmount:  btcAmount,
+			USDTAmount: btcAmount * usdtRate,
+			Time:       t,
+		})
+	}
+
+	for currency := range unpriced {
+		result.Unpriced = append(result.Unpriced, currency)
+	}
+	if len(result.Unpriced) > 0 {
+		sort.Strings(result.Unpriced)
+		u.log.WithField("method", "Compare").Warnf("HODL is incomplete, prices of %s aren't saved", strings.Join(result.Unpriced, ", "))
+	}
+	return result, nil
+}
+
+// backfill returns the function downloading candles of the interval closest to the resolution of markets
+// not downloaded before
+func (u *benchmarkUsecases) backfill(resolution time.Duration) func(markets []string) bool {
+	interval := candleIntervals[0]
+	for _, i := range candleIntervals {
+		if i <= resolution {
+			interval = i
+		}
+	}
+
+	return func(markets []string) bool {
+		u.backfillLock.Lock()
+		if u.backfilled[interval] == nil {
+			u.backfilled[interval] = make(map[string]bool)
+		}
+		var notBackfilled []string
+		for _, market := range markets {
+			if !u.backfilled[interval][market] {
+				notBackfilled = append(notBackfilled, market)
+				u.backfilled[interval][market] = true
+			}
+		}
+		u.backfillLock.Unlock()
+		if len(notBackfilled) == 0 {
+			return false
+		}
+
+		// gone markets and exchanges without candles leave coins unpriced
+		err := u.marketUsecase.Backfill(notBackfilled, interval)
+		if err != nil {
+			u.log.WithField("method", "backfill").WithError(err).Warn("not all market candles are downloaded")
+		}
+		return true
+	}
+}
@@ -0,0 +1,134 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	assert "github.com/stretchr/testify/require"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage/memory"
+	"github.com/nawa/cryptoexchange-dashboard/storage/mocks"
+)
+
+func TestBenchmarkUsecases_Compare(t *testing.T) {
+	balanceStorage := memory.NewBalanceStorage()
+	marketStorage := memory.NewMarketStorage()
+	u := NewBenchmarkUsecase(balanceStorage, NewMarketUsecase(nil, marketStorage))
+
+	start := time.Now().UTC().Truncate(time.Hour).Add(-5 * time.Hour)
+	snapshotTime := start.Add(10 * time.Minute)
+	assert.NoError(t, balanceStorage.Save(
+		domain.Balance{Exchange: domain.ExchangeTypeBittrex, Currency: "BTC", Amount: 0.5, BTCAmount: 0.5, Time: snapshotTime},
+		domain.Balance{Exchange: domain.ExchangeTypeBinance, Currency: "BTC", Amount: 0.5, BTCAmount: 0.5, Time: snapshotTime},
+		domain.Balance{Exchange: domain.ExchangeTypeBittrex, Currency: "CUR1", Amount: 100, BTCAmount: 1, Time: snapshotTime},
+		domain.Balance{Currency: domain.TotalCurrency, BTCAmount: 2, Time: snapshotTime},
+		// trades after the start don't change benchmarks
+		domain.Balance{Exchange: domain.ExchangeTypeBittrex, Currency: "BTC", Amount: 5, BTCAmount: 5, Time: start.Add(time.Hour)},
+	))
+	assert.NoError(t, marketStorage.Save(
+		marketCandle("USDT-BTC", start, 10000, 10000, 10000, 10000),
+		marketCandle("BTC-CUR1", start, 0.01, 0.01, 0.01, 0.01),
+		marketCandle("USDT-BTC", start.Add(time.Hour), 20000, 20000, 20000, 20000),
+		marketCandle("BTC-CUR1", start.Add(time.Hour), 0.005, 0.005, 0.005, 0.005),
+	))
+
+	// the latest first as balances of the hourly endpoint
+	times := []time.Time{start.Add(90 * time.Minute), start.Add(30 * time.Minute), start}
+	benchmark, err := u.Compare(start, times, time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, snapshotTime, benchmark.Start)
+	assert.Equal(t, []domain.Balance{
+		{Currency: domain.BenchmarkHODL, BTCAmount: 1.5, USDTAmount: 30000, Time: times[0]},
+		{Currency: domain.BenchmarkHODL, BTCAmount: 2, USDTAmount: 20000, Time: times[1]},
+	}, benchmark.HODL)
+	assert.Equal(t, []domain.Balance{
+		{Currency: domain.BenchmarkHODLBTC, Amount: 2, BTCAmount: 2, USDTAmount: 40000, Time: times[0]},
+		{Currency: domain.BenchmarkHODLBTC, Amount: 2, BTCAmount: 2, USDTAmount: 20000, Time: times[1]},
+	}, benchmark.HODLBTC)
+
+	// no snapshot after the start
+	benchmark, err = u.Compare(start.Add(2*time.Hour), times, time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, &domain.Benchmark{}, benchmark)
+}
+
+func TestBenchmarkUsecases_Compare_MissingPrices(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	history := mocks.NewMockMarketHistory(ctrl)
+	balanceStorage := memory.NewBalanceStorage()
+	marketStorage := memory.NewMarketStorage()
+	u := NewBenchmarkUsecase(balanceStorage, NewMarketUsecase(historyExchange{mocks.NewMockExchange(ctrl), history}, marketStorage))
+
+	// CUR2 has only USDT market, candles of CUR3 are downloaded, DUST has no value in the snapshot
+	start := time.Now().UTC().Truncate(time.Hour).Add(-5 * time.Hour)
+	snapshot := func(t time.Time, currencies ...string) []domain.Balance {
+		balances := []domain.Balance{
+			{Exchange: domain.ExchangeTypeBittrex, Currency: "DUST", Amount: 5, Time: t},
+			{Currency: domain.TotalCurrency, BTCAmount: float64(len(currencies)), Time: t},
+		}
+		// every coin is worth 1 BTC
+		for _, currency := range currencies {
+			amount := 10.0
+			if currency == "BTC" {
+				amount = 1
+			}
+			balances = append(balances, domain.Balance{Exchange: domain.ExchangeTypeBittrex, Currency: currency, Amount: amount, BTCAmount: 1, Time: t})
+		}
+		return balances
+	}
+	assert.NoError(t, balanceStorage.Save(snapshot(start.Add(10*time.Minute), "BTC", "CUR2", "CUR3")...))
+	assert.NoError(t, balanceStorage.Save(snapshot(start.Add(20*time.Minute), "BTC", "CUR2", "CUR3", "CUR4")...))
+	assert.NoError(t, marketStorage.Save(
+		marketCandle("USDT-BTC", start, 10000, 10000, 10000, 10000),
+		marketCandle("USDT-BTC", start.Add(time.Hour), 10000, 10000, 10000, 10000),
+		marketCandle("USDT-CUR2", start, 1000, 1000, 1000, 1000),
+	))
+
+	// every missing market is requested once: BTC-CUR2, BTC-DUST and 7 other markets of CUR2 and DUST by the first
+	// compare, BTC-CUR4 and its USDT, ETH and BNB markets by the second one
+	history.EXPECT().GetMarketCandles("BTC-CUR3", time.Hour).
+		Return([]domain.MarketCandle{marketCandle("BTC-CUR3", start, 0.1, 0.1, 0.1, 0.1)}, nil)
+	history.EXPECT().GetMarketCandles(gomock.Any(), time.Hour).Return(nil, errExpected).Times(13)
+
+	times := []time.Time{start.Add(90 * time.Minute), start.Add(30 * time.Minute)}
+	for i := 0; i < 2; i++ {
+		benchmark, err := u.Compare(start, times, time.Hour)
+		assert.NoError(t, err)
+		assert.Equal(t, []domain.Balance{
+			{Currency: domain.BenchmarkHODL, BTCAmount: 3, USDTAmount: 30000, Time: times[0]},
+			{Currency: domain.BenchmarkHODL, BTCAmount: 3, USDTAmount: 30000, Time: times[1]},
+		}, benchmark.HODL)
+		assert.Empty(t, benchmark.Unpriced)
+	}
+
+	// HODL isn't valued without CUR4 prices
+	benchmark, err := u.Compare(start.Add(15*time.Minute), times, time.Hour)
+	assert.NoError(t, err)
+	assert.Empty(t, benchmark.HODL)
+	assert.Len(t, benchmark.HODLBTC, 2)
+	assert.Equal(t, []string{"CUR4"}, benchmark.Unpriced)
+}
+
+func TestBenchmarkUsecases_Compare_Error(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	balanceStorage := mocks.NewMockBalanceStorage(ctrl)
+	marketStorage := mocks.NewMockMarketStorage(ctrl)
+	u := NewBenchmarkUsecase(balanceStorage, NewMarketUsecase(nil, marketStorage))
+
+	start := time.Unix(0, 0)
+	balanceStorage.EXPECT().FetchSnapshot(start).Return(nil, errExpected)
+	_, err := u.Compare(start, []time.Time{start}, time.Hour)
+	assert.Equal(t, errExpected, err)
+
+	balanceStorage.EXPECT().FetchSnapshot(start).Return([]domain.Balance{{Currency: "BTC", Amount: 1, Time: start}}, nil)
+	marketStorage.EXPECT().FetchCandles(domain.ExchangeType(""), "USDT-BTC", start.Add(-time.Hour), start.Add(time.Hour), time.Hour).
+		Return(nil, errExpected)
+	_, err = u.Compare(start, []time.Time{start}, time.Hour)
+	assert.Error(t, err)
+}
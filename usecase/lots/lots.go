@@ -0,0 +1,124 @@
+// Package lots matches sold amounts of a currency with the amounts bought before
+package lots
+
+import (
+	"fmt"
+	"time"
+)
+
+// dust is the amount left by float rounding errors
+const dust = 1e-10
+
+// Method defines which bought lots are sold first
+type Method string
+
+const (
+	MethodFIFO    = Method("fifo")
+	MethodLIFO    = Method("lifo")
+	MethodAverage = Method("average")
+)
+
+// ParseMethod returns the method by its name
+func ParseMethod(name string) (Method, error) {
+	switch Method(name) {
+	case MethodFIFO, MethodLIFO, MethodAverage:
+		return Method(name), nil
+	default:
+		return "", fmt.Errorf("supported values: [%s|%s|%s]", MethodFIFO, MethodLIFO, MethodAverage)
+	}
+}
+
+// Lot is the bought amount. Cost is the price without the fee, Cost and Fee are in the quote currency.
+// CostUSDT is the price and the fee converted to USDT at the time of the buy, Unpriced means the rate was unknown
+type Lot struct {
+	Time     time.Time
+	Amount   float64
+	Cost     float64
+	Fee      float64
+	CostUSDT float64
+	Unpriced bool
+}
+
+// Book keeps lots of one currency. Lots must be added in the order of buys
+type Book struct {
+	method Method
+	lots   []Lot
+}
+
+func NewBook(method Method) *Book {
+	return &Book{
+		method: method,
+	}
+}
+
+// Amount returns the amount of all lots
+func (b *Book) Amount() float64 {
+	var amount float64
+	for _, lot := range b.lots {
+		amount += lot.Amount
+	}
+	return amount
+}
+
+func (b *Book) Buy(lot Lot) {
+	if lot.Amount <= 0 {
+		return
+	}
+
+	// all lots are merged into one with the average cost, the earliest time is kept
+	if b.method == MethodAverage && len(b.lots) > 0 {
+		b.lots[0].Amount += lot.Amount
+		b.lots[0].Cost += lot.Cost
+		b.lots[0].Fee += lot.Fee
+		b.lots[0].CostUSDT += lot.CostUSDT
+		b.lots[0].Unpriced = b.lots[0].Unpriced || lot.Unpriced
+		return
+	}
+	b.lots = append(b.lots, lot)
+}
+
+// Sell removes the amount from lots and returns the removed part as one lot with the time of the earliest removed one.
+// unmatched is the part of the amount exceeding all lots
+func (b *Book) Sell(amount float64) (matched Lot, unmatched float64) {
+	for amount > dust && len(b.lots) > 0 {
+		i := 0
+		if b.method == MethodLIFO {
+			i = len(b.lots) - 1
+		}
+		lot := &b.lots[i]
+
+		part := *lot
+		if amount < lot.Amount-dust {
+			share := amount / lot.Amount
+			part = Lot{
+				Time:     lot.Time,
+				Amount:   amount,
+				Cost:     lot.Cost * share,
+				Fee:      lot.Fee * share,
+				CostUSDT: lot.CostUSDT * share,
+				Unpriced: lot.Unpriced,
+			}
+			lot.Amount -= part.Amount
+			lot.Cost -= part.Cost
+			lot.Fee -= part.Fee
+			lot.CostUSDT -= part.CostUSDT
+		} else {
+			b.lots = append(b.lots[:i], b.lots[i+1:]...)
+		}
+
+		if matched.Amount == 0 || part.Time.Before(matched.Time) {
+			matched.Time = part.Time
+		}
+		matched.Amount += part.Amount
+		matched.Cost += part.Cost
+		matched.Fee += part.Fee
+		matched.CostUSDT += part.CostUSDT
+		matched.Unpriced = matched.Unpriced || part.Unpriced
+		amount -= part.Amount
+	}
+
+	if amount > dust {
+		unmatched = amount
+	}
+	return matched, unmatched
+}
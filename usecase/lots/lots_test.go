@@ -0,0 +1,78 @@
+package lots
+
+import (
+	"testing"
+	"time"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestParseMethod(t *testing.T) {
+	method, err := ParseMethod("lifo")
+	assert.NoError(t, err)
+	assert.Equal(t, MethodLIFO, method)
+
+	_, err = ParseMethod("hifo")
+	assert.Error(t, err)
+}
+
+func TestBook_Sell(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	buys := []Lot{
+		{Time: start, Amount: 10, Cost: 10, Fee: 1, CostUSDT: 110},
+		{Time: start.Add(time.Hour), Amount: 10, Cost: 30, Fee: 2, CostUSDT: 320},
+	}
+
+	tests := []struct {
+		name          string
+		method        Method
+		wantMatched   Lot
+		wantRemaining float64
+	}{
+		{
+			name:        "fifo",
+			method:      MethodFIFO,
+			wantMatched: Lot{Time: start, Amount: 15, Cost: 25, Fee: 2, CostUSDT: 270},
+		},
+		{
+			name:        "lifo",
+			method:      MethodLIFO,
+			wantMatched: Lot{Time: start, Amount: 15, Cost: 35, Fee: 2.5, CostUSDT: 375},
+		},
+		{
+			name:        "average",
+			method:      MethodAverage,
+			wantMatched: Lot{Time: start, Amount: 15, Cost: 30, Fee: 2.25, CostUSDT: 322.5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			book := NewBook(tt.method)
+			for _, lot := range buys {
+				book.Buy(lot)
+			}
+
+			matched, unmatched := book.Sell(15)
+			assert.Equal(t, tt.wantMatched.Time, matched.Time)
+			assert.InDelta(t, tt.wantMatched.Amount, matched.Amount, 1e-9)
+			assert.InDelta(t, tt.wantMatched.Cost, matched.Cost, 1e-9)
+			assert.InDelta(t, tt.wantMatched.Fee, matched.Fee, 1e-9)
+			assert.InDelta(t, tt.wantMatched.CostUSDT, matched.CostUSDT, 1e-9)
+			assert.Zero(t, unmatched)
+			assert.InDelta(t, 5, book.Amount(), 1e-9)
+
+			// the rest and more
+			matched, unmatched = book.Sell(8)
+			assert.InDelta(t, 5, matched.Amount, 1e-9)
+			assert.InDelta(t, 3, unmatched, 1e-9)
+			assert.Zero(t, book.Amount())
+		})
+	}
+}
+
+func TestBook_SellEmpty(t *testing.T) {
+	book := NewBook(MethodFIFO)
+	matched, unmatched := book.Sell(2)
+	assert.Equal(t, Lot{}, matched)
+	assert.Equal(t, float64(2), unmatched)
+}
@@ -0,0 +1,68 @@
+package usecase
+
+import (
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage"
+)
+
+type MarketUsecases interface {
+	// Saves past candles of markets kept by the exchange for the interval, already saved candles are updated
+	Backfill(markets []string, interval time.Duration) error
+	// Saved prices of the market in [from, to) aggregated into candles of the resolution, the latest first.
+	// Empty exchange means all exchanges
+	FetchCandles(exchange domain.ExchangeType, market string, from, to time.Time, resolution time.Duration) ([]domain.MarketCandle, error)
+}
+
+type marketUsecases struct {
+	exchange      storage.Exchange
+	marketStorage storage.MarketStorage
+	log           *logrus.Entry
+}
+
+func NewMarketUsecase(exchange storage.Exchange, marketStorage storage.MarketStorage) MarketUsecases {
+	log := logrus.WithField("component", "marketUC")
+	return &marketUsecases{
+		exchange:      exchange,
+		marketStorage: marketStorage,
+		log:           log,
+	}
+}
+
+// Backfill goes on with other markets if one fails and returns errors of all failed markets
+func (u *marketUsecases) Backfill(markets []string, interval time.Duration) error {
+	history, ok := u.exchange.(storage.MarketHistory)
+	if !ok {
+		return errors.New("exchange doesn't give market candles")
+	}
+
+	var result error
+	for _, market := range markets {
+		candles, err := history.GetMarketCandles(market, interval)
+		if err == nil {
+			err = u.marketStorage.Save(candles...)
+		}
+		if err != nil {
+			u.log.WithField("method", "Backfill").WithField("market", market).WithError(err).Error()
+			result = multierror.Append(result, errors.Wrapf(err, "market '%s'", market))
+			continue
+		}
+		u.log.WithField("method", "Backfill").Infof("%d candles of %s saved", len(candles), market)
+	}
+	return result
+}
+
+func (u *marketUsecases) FetchCandles(exchange domain.ExchangeType, market string, from, to time.Time, resolution time.Duration) ([]domain.MarketCandle, error) {
+	candles, err := u.marketStorage.FetchCandles(exchange, market, from, to, resolution)
+	if err != nil {
+		u.log.WithField("method", "FetchCandles").WithError(err).Error()
+		return nil, err
+	}
+
+	return candles, nil
+}
@@ -0,0 +1,63 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	assert "github.com/stretchr/testify/require"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage/memory"
+	"github.com/nawa/cryptoexchange-dashboard/storage/mocks"
+)
+
+func marketCandle(market string, t time.Time, open, close, min, max float64) domain.MarketCandle {
+	return domain.MarketCandle{
+		Exchange: domain.ExchangeTypeBittrex,
+		Market:   market,
+		Time:     t,
+		Price:    domain.OHLC{Open: open, Close: close, Min: min, Max: max},
+	}
+}
+
+func TestMarketUsecases_Backfill(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	history := mocks.NewMockMarketHistory(ctrl)
+	u := NewMarketUsecase(historyExchange{mocks.NewMockExchange(ctrl), history}, memory.NewMarketStorage())
+
+	start := time.Unix(0, 0).UTC()
+	history.EXPECT().GetMarketCandles("BTC-CUR1", time.Hour).Return([]domain.MarketCandle{
+		marketCandle("BTC-CUR1", start, 1, 2, 0.5, 3),
+		marketCandle("BTC-CUR1", start.Add(time.Hour), 2, 4, 2, 5),
+	}, nil)
+	history.EXPECT().GetMarketCandles("BTC-CUR2", time.Hour).Return(nil, errExpected)
+
+	// the failed market doesn't stop others
+	err := u.Backfill([]string{"BTC-CUR2", "BTC-CUR1"}, time.Hour)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "market 'BTC-CUR2'")
+
+	candles, err := u.FetchCandles(domain.ExchangeTypeBittrex, "BTC-CUR1", start, start.Add(2*time.Hour), 2*time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.MarketCandle{marketCandle("BTC-CUR1", start, 1, 4, 0.5, 5)}, candles)
+
+	// the exchange without candles
+	u = NewMarketUsecase(mocks.NewMockExchange(ctrl), memory.NewMarketStorage())
+	assert.Error(t, u.Backfill([]string{"BTC-CUR1"}, time.Hour))
+}
+
+func TestMarketUsecases_FetchCandles_Error(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	marketStorage := mocks.NewMockMarketStorage(ctrl)
+	u := NewMarketUsecase(mocks.NewMockExchange(ctrl), marketStorage)
+
+	from, to := time.Unix(0, 0), time.Unix(3600, 0)
+	marketStorage.EXPECT().FetchCandles(domain.ExchangeType(""), "BTC-CUR1", from, to, time.Hour).Return(nil, errExpected)
+	_, err := u.FetchCandles("", "BTC-CUR1", from, to, time.Hour)
+	assert.Equal(t, errExpected, err)
+}
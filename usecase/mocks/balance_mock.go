@@ -73,6 +73,19 @@ func (mr *MockBalanceUsecasesMockRecorder) FetchHourly(currency, hours interface
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchHourly", reflect.TypeOf((*MockBalanceUsecases)(nil).FetchHourly), currency, hours)
 }
 
+// FetchSince mocks base method
+func (m *MockBalanceUsecases) FetchSince(currency string, since time.Time) ([]domain.Balance, error) {
+	ret := m.ctrl.Call(m, "FetchSince", currency, since)
+	ret0, _ := ret[0].([]domain.Balance)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FetchSince indicates an expected call of FetchSince
+func (mr *MockBalanceUsecasesMockRecorder) FetchSince(currency, since interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchSince", reflect.TypeOf((*MockBalanceUsecases)(nil).FetchSince), currency, since)
+}
+
 // FetchWeekly mocks base method
 func (m *MockBalanceUsecases) FetchWeekly(currency string) ([]domain.Balance, error) {
 	ret := m.ctrl.Call(m, "FetchWeekly", currency)
@@ -112,6 +125,32 @@ func (mr *MockBalanceUsecasesMockRecorder) FetchAll(currency interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchAll", reflect.TypeOf((*MockBalanceUsecases)(nil).FetchAll), currency)
 }
 
+// FetchRange mocks base method
+func (m *MockBalanceUsecases) FetchRange(currency string, from, to time.Time, resolution time.Duration) ([]domain.BalanceCandle, error) {
+	ret := m.ctrl.Call(m, "FetchRange", currency, from, to, resolution)
+	ret0, _ := ret[0].([]domain.BalanceCandle)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FetchRange indicates an expected call of FetchRange
+func (mr *MockBalanceUsecasesMockRecorder) FetchRange(currency, from, to, resolution interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchRange", reflect.TypeOf((*MockBalanceUsecases)(nil).FetchRange), currency, from, to, resolution)
+}
+
+// FetchPerformance mocks base method
+func (m *MockBalanceUsecases) FetchPerformance(currency string, from, to time.Time, resolution time.Duration) (*domain.Performance, error) {
+	ret := m.ctrl.Call(m, "FetchPerformance", currency, from, to, resolution)
+	ret0, _ := ret[0].(*domain.Performance)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FetchPerformance indicates an expected call of FetchPerformance
+func (mr *MockBalanceUsecasesMockRecorder) FetchPerformance(currency, from, to, resolution interface{}) *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchPerformance", reflect.TypeOf((*MockBalanceUsecases)(nil).FetchPerformance), currency, from, to, resolution)
+}
+
 // GetActiveCurrencies mocks base method
 func (m *MockBalanceUsecases) GetActiveCurrencies() ([]domain.Balance, error) {
 	ret := m.ctrl.Call(m, "GetActiveCurrencies")
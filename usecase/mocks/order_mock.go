@@ -45,3 +45,16 @@ func (m *MockOrderUsecases) GetActiveOrders() ([]domain.Order, error) {
 func (mr *MockOrderUsecasesMockRecorder) GetActiveOrders() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetActiveOrders", reflect.TypeOf((*MockOrderUsecases)(nil).GetActiveOrders))
 }
+
+// GetPnL mocks base method
+func (m *MockOrderUsecases) GetPnL() (*domain.PnL, error) {
+	ret := m.ctrl.Call(m, "GetPnL")
+	ret0, _ := ret[0].(*domain.PnL)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPnL indicates an expected call of GetPnL
+func (mr *MockOrderUsecasesMockRecorder) GetPnL() *gomock.Call {
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPnL", reflect.TypeOf((*MockOrderUsecases)(nil).GetPnL))
+}
@@ -1,25 +1,37 @@
 package usecase
 
 import (
+	"sort"
+	"strings"
+	"time"
+
 	"github.com/Sirupsen/logrus"
 	"github.com/nawa/cryptoexchange-dashboard/domain"
 	"github.com/nawa/cryptoexchange-dashboard/storage"
+	"github.com/nawa/cryptoexchange-dashboard/usecase/lots"
 )
 
 type OrderUsecases interface {
 	GetActiveOrders() ([]domain.Order, error)
+	// Unrealized profit of active orders at the current sell rate and realized profit of saved sells
+	// over the average rate of buys before them. Only active orders are requested from the exchange
+	GetPnL() (*domain.PnL, error)
 }
 
 type orderUsecases struct {
-	exchange storage.Exchange
-	log      *logrus.Entry
+	exchange       storage.Exchange
+	tradeStorage   storage.TradeStorage
+	balanceStorage storage.BalanceStorage
+	log            *logrus.Entry
 }
 
-func NewOrderUsecase(exchange storage.Exchange) OrderUsecases {
+func NewOrderUsecase(exchange storage.Exchange, tradeStorage storage.TradeStorage, balanceStorage storage.BalanceStorage) OrderUsecases {
 	log := logrus.WithField("component", "orderUC")
 	return &orderUsecases{
-		exchange: exchange,
-		log:      log,
+		exchange:       exchange,
+		tradeStorage:   tradeStorage,
+		balanceStorage: balanceStorage,
+		log:            log,
 	}
 }
 
@@ -32,3 +44,138 @@ func (u *orderUsecases) GetActiveOrders() ([]domain.Order, error) {
 
 	return orders, nil
 }
+
+func (u *orderUsecases) GetPnL() (*domain.PnL, error) {
+	orders, err := u.exchange.GetOrders()
+	if err != nil {
+		u.log.WithField("method", "GetPnL").WithError(err).Error()
+		return nil, err
+	}
+
+	trades, err := u.tradeStorage.Fetch(time.Time{}, time.Time{})
+	if err != nil {
+		u.log.WithField("method", "GetPnL").WithError(err).Error()
+		return nil, err
+	}
+
+	rates, err := u.fetchRates(trades)
+	if err != nil {
+		u.log.WithField("method", "GetPnL").WithError(err).Error()
+		return nil, err
+	}
+
+	pnl := &domain.PnL{
+		Open:   make([]domain.OpenPosition, len(orders)),
+		Closed: closedPositions(trades, rates),
+	}
+	for i, o := range orders {
+		pnl.Open[i] = domain.OpenPosition{
+			Order:  o,
+			Profit: openProfit(o),
+		}
+		pnl.UnrealizedBTC += pnl.Open[i].ProfitBTC
+		pnl.UnrealizedUSDT += pnl.Open[i].ProfitUSDT
+	}
+	for _, c := range pnl.Closed {
+		pnl.RealizedBTC += c.ProfitBTC
+		pnl.RealizedUSDT += c.ProfitUSDT
+	}
+	return pnl, nil
+}
+
+// fetchRates returns daily balances of quote currencies of sells saved without rates, the earliest trade is the last one
+func (u *orderUsecases) fetchRates(trades []domain.Trade) (dailyRates, error) {
+	var quotes []string
+	for _, t := range trades {
+		if t.Type == domain.TradeTypeSell && (t.BTCRate == 0 || t.USDTRate == 0) {
+			quotes = append(quotes, strings.Split(t.Market, "-")[0])
+		}
+	}
+	if len(quotes) == 0 {
+		return make(dailyRates), nil
+	}
+	return fetchDailyRates(u.balanceStorage, quotes, trades[len(trades)-1].Time.AddDate(0, 0, -1), time.Now())
+}
+
+// openProfit is the profit of selling the order amount at the current rate,
+// both buy and sell are charged with the exchange fee
+func openProfit(o domain.Order) domain.Profit {
+	fee := o.Exchange.Fee()
+	buyPrice := o.BuyRate * o.Amount
+	sellPrice := o.SellNowRate * o.Amount
+	return newProfit(buyPrice+buyPrice*fee, sellPrice-sellPrice*fee, (buyPrice+sellPrice)*fee, o.BTCRate, o.USDTRate)
+}
+
+// closedPositions matches every sell with the average cost of coins bought before it
+// in the same market of the account, the latest sell first. Profits are converted to BTC and USDT at rates of sells.
+// The part of the sell exceeding bought coins is ignored, because its cost is unknown
+func closedPositions(trades []domain.Trade, rates dailyRates) []domain.ClosedPosition {
+	sorted := make([]domain.Trade, len(trades))
+	copy(sorted, trades)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Time.Before(sorted[j].Time)
+	})
+
+	books := make(map[string]*lots.Book)
+	result := []domain.ClosedPosition{}
+	for _, t := range sorted {
+		key := string(t.Exchange) + "/" + t.Account + "/" + t.Market
+		book, ok := books[key]
+		if !ok {
+			book = lots.NewBook(lots.MethodAverage)
+			books[key] = book
+		}
+
+		if t.Type == domain.TradeTypeBuy {
+			book.Buy(lots.Lot{
+				Time:   t.Time,
+				Amount: t.Amount,
+				Cost:   t.Amount * t.Rate,
+				Fee:    t.Fee,
+			})
+			continue
+		}
+
+		matched, _ := book.Sell(t.Amount)
+		if matched.Amount == 0 {
+			continue
+		}
+
+		// the profit of the sell whose rates aren't known isn't converted
+		quote := strings.Split(t.Market, "-")[0]
+		btcRate, _ := rates.btcRate(quote, t.Time, t.BTCRate)
+		usdtRate, _ := rates.usdtRate(quote, t.Time, t.USDTRate)
+
+		sellFee := t.Fee * matched.Amount / t.Amount
+		result = append(result, domain.ClosedPosition{
+			Exchange: t.Exchange,
+			Account:  t.Account,
+			Market:   t.Market,
+			Time:     t.Time,
+			Amount:   matched.Amount,
+			BuyRate:  matched.Cost / matched.Amount,
+			SellRate: t.Rate,
+			Profit:   newProfit(matched.Cost+matched.Fee, t.Rate*matched.Amount-sellFee, matched.Fee+sellFee, btcRate, usdtRate),
+		})
+	}
+
+	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+		result[i], result[j] = result[j], result[i]
+	}
+	return result
+}
+
+func newProfit(cost, value, fee, btcRate, usdtRate float64) domain.Profit {
+	profit := domain.Profit{
+		Cost:   cost,
+		Value:  value,
+		Fee:    fee,
+		Profit: value - cost,
+	}
+	profit.ProfitBTC = profit.Profit * btcRate
+	profit.ProfitUSDT = profit.Profit * usdtRate
+	if cost != 0 {
+		profit.Percent = profit.Profit / cost * 100
+	}
+	return profit
+}
@@ -3,11 +3,13 @@ package usecase
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/golang/mock/gomock"
 	"github.com/nawa/cryptoexchange-dashboard/domain"
 	"github.com/nawa/cryptoexchange-dashboard/storage"
+	"github.com/nawa/cryptoexchange-dashboard/storage/memory"
 	"github.com/nawa/cryptoexchange-dashboard/storage/mocks"
 	"github.com/nawa/cryptoexchange-dashboard/usecase/testdata"
 	"github.com/nawa/cryptoexchange-dashboard/utils"
@@ -86,13 +88,140 @@ func TestOrderUsecases_GetActiveOrders(t *testing.T) {
 	}
 }
 
+func TestOrderUsecases_GetPnL(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	exchange := mocks.NewMockExchange(ctrl)
+	tradeStorage := memory.NewTradeStorage()
+	u := NewOrderUsecase(exchange, tradeStorage, memory.NewBalanceStorage())
+
+	exchange.EXPECT().GetOrders().Return([]domain.Order{
+		{
+			Exchange:    domain.ExchangeTypeBittrex,
+			Market:      "BTC-CUR1",
+			BuyRate:     0.01,
+			Amount:      100,
+			SellNowRate: 0.02,
+			BTCRate:     1,
+			USDTRate:    10000,
+		},
+	}, nil)
+	assert.NoError(t, tradeStorage.Save(
+		domain.Trade{Exchange: domain.ExchangeTypeBinance, OrderID: "5", Market: "BTC-CUR1", Type: domain.TradeTypeSell, Time: time.Unix(4, 0), Amount: 100, Rate: 0.05, Fee: 0.005, BTCRate: 1, USDTRate: 10000},
+		domain.Trade{Exchange: domain.ExchangeTypeBinance, OrderID: "4", Market: "BTC-CUR1", Type: domain.TradeTypeSell, Time: time.Unix(3, 0), Amount: 150, Rate: 0.04, Fee: 0.006, BTCRate: 1, USDTRate: 10000},
+		domain.Trade{Exchange: domain.ExchangeTypeBinance, OrderID: "3", Market: "BTC-CUR1", Type: domain.TradeTypeBuy, Time: time.Unix(2, 0), Amount: 100, Rate: 0.03, Fee: 0.003, BTCRate: 1, USDTRate: 10000},
+		domain.Trade{Exchange: domain.ExchangeTypeBinance, OrderID: "2", Market: "BTC-CUR1", Type: domain.TradeTypeBuy, Time: time.Unix(1, 0), Amount: 100, Rate: 0.01, Fee: 0.001, BTCRate: 1, USDTRate: 10000},
+		// bought before the history
+		domain.Trade{Exchange: domain.ExchangeTypeBinance, OrderID: "1", Market: "BTC-CUR2", Type: domain.TradeTypeSell, Time: time.Unix(1, 0), Amount: 100, Rate: 0.01, Fee: 0.001, BTCRate: 1, USDTRate: 10000},
+	))
+
+	pnl, err := u.GetPnL()
+	assert.NoError(t, err)
+
+	assert.Len(t, pnl.Open, 1)
+	open := pnl.Open[0]
+	assert.Equal(t, "BTC-CUR1", open.Market)
+	assert.InDelta(t, 1.0025, open.Cost, 1e-9)
+	assert.InDelta(t, 1.995, open.Value, 1e-9)
+	assert.InDelta(t, 0.0075, open.Fee, 1e-9)
+	assert.InDelta(t, 0.9925, open.Profit.Profit, 1e-9)
+	assert.InDelta(t, 0.9925, open.ProfitBTC, 1e-9)
+	assert.InDelta(t, 9925, open.ProfitUSDT, 1e-6)
+	assert.InDelta(t, 0.9925/1.0025*100, open.Percent, 1e-9)
+	assert.InDelta(t, 0.9925, pnl.UnrealizedBTC, 1e-9)
+	assert.InDelta(t, 9925, pnl.UnrealizedUSDT, 1e-6)
+
+	// the latest sell first, the second sell is matched only with 50 coins left
+	assert.Len(t, pnl.Closed, 2)
+	assert.Equal(t, time.Unix(4, 0), pnl.Closed[0].Time)
+	assert.InDelta(t, 50, pnl.Closed[0].Amount, 1e-9)
+	assert.InDelta(t, 0.02, pnl.Closed[0].BuyRate, 1e-9)
+	assert.InDelta(t, 0.05, pnl.Closed[0].SellRate, 1e-9)
+	assert.InDelta(t, 1.001, pnl.Closed[0].Cost, 1e-9)
+	assert.InDelta(t, 2.4975, pnl.Closed[0].Value, 1e-9)
+	assert.InDelta(t, 0.0035, pnl.Closed[0].Fee, 1e-9)
+	assert.InDelta(t, 1.4965, pnl.Closed[0].Profit.Profit, 1e-9)
+
+	assert.Equal(t, time.Unix(3, 0), pnl.Closed[1].Time)
+	assert.InDelta(t, 150, pnl.Closed[1].Amount, 1e-9)
+	assert.InDelta(t, 0.02, pnl.Closed[1].BuyRate, 1e-9)
+	assert.InDelta(t, 3.003, pnl.Closed[1].Cost, 1e-9)
+	assert.InDelta(t, 5.994, pnl.Closed[1].Value, 1e-9)
+	assert.InDelta(t, 0.009, pnl.Closed[1].Fee, 1e-9)
+	assert.InDelta(t, 2.991, pnl.Closed[1].Profit.Profit, 1e-9)
+	assert.InDelta(t, 2.991/3.003*100, pnl.Closed[1].Percent, 1e-9)
+
+	assert.InDelta(t, 4.4875, pnl.RealizedBTC, 1e-9)
+	assert.InDelta(t, 44875, pnl.RealizedUSDT, 1e-6)
+}
+
+func TestOrderUsecases_GetPnL_DailyRates(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	exchange := mocks.NewMockExchange(ctrl)
+	tradeStorage := memory.NewTradeStorage()
+	balanceStorage := memory.NewBalanceStorage()
+	u := NewOrderUsecase(exchange, tradeStorage, balanceStorage)
+
+	day := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	assert.NoError(t, balanceStorage.Save(
+		domain.Balance{Currency: "ETH", Amount: 2, BTCAmount: 0.1, USDTAmount: 1000, Time: day},
+	))
+	// the sell is saved without rates, they're taken from the balance of the day
+	assert.NoError(t, tradeStorage.Save(
+		domain.Trade{Exchange: domain.ExchangeTypeBinance, OrderID: "1", Market: "ETH-CUR1", Type: domain.TradeTypeBuy, Time: day.Add(-time.Hour), Amount: 10, Rate: 0.1},
+		domain.Trade{Exchange: domain.ExchangeTypeBinance, OrderID: "2", Market: "ETH-CUR1", Type: domain.TradeTypeSell, Time: day, Amount: 10, Rate: 0.2},
+	))
+	exchange.EXPECT().GetOrders().Return(nil, nil)
+
+	pnl, err := u.GetPnL()
+	assert.NoError(t, err)
+	assert.Len(t, pnl.Closed, 1)
+	assert.InDelta(t, 1, pnl.Closed[0].Profit.Profit, 1e-9)
+	assert.InDelta(t, 0.05, pnl.RealizedBTC, 1e-9)
+	assert.InDelta(t, 500, pnl.RealizedUSDT, 1e-9)
+}
+
+func TestOrderUsecases_GetPnL_Error(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	exchange := mocks.NewMockExchange(ctrl)
+	tradeStorage := mocks.NewMockTradeStorage(ctrl)
+	balanceStorage := mocks.NewMockBalanceStorage(ctrl)
+	u := NewOrderUsecase(exchange, tradeStorage, balanceStorage)
+
+	exchange.EXPECT().GetOrders().Return(nil, errExpected)
+	_, err := u.GetPnL()
+	assert.Equal(t, errExpected, err)
+
+	exchange.EXPECT().GetOrders().Return(testdata.Orders(), nil)
+	tradeStorage.EXPECT().Fetch(time.Time{}, time.Time{}).Return(nil, errExpected)
+	_, err = u.GetPnL()
+	assert.Equal(t, errExpected, err)
+
+	exchange.EXPECT().GetOrders().Return(testdata.Orders(), nil)
+	tradeStorage.EXPECT().Fetch(time.Time{}, time.Time{}).Return([]domain.Trade{
+		{Exchange: domain.ExchangeTypeBinance, Market: "ETH-CUR1", Type: domain.TradeTypeSell, Time: time.Unix(1, 0), Amount: 1, Rate: 0.01},
+	}, nil)
+	balanceStorage.EXPECT().FetchRange("ETH", gomock.Any(), gomock.Any(), 24*time.Hour).Return(nil, errExpected)
+	_, err = u.GetPnL()
+	assert.Equal(t, errExpected, err)
+}
+
 func TestNewOrderUsecase(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	exchange := mocks.NewMockExchange(ctrl)
-	u := NewOrderUsecase(exchange)
+	tradeStorage := mocks.NewMockTradeStorage(ctrl)
+	balanceStorage := mocks.NewMockBalanceStorage(ctrl)
+	u := NewOrderUsecase(exchange, tradeStorage, balanceStorage)
 	assert.IsType(t, &orderUsecases{}, u)
 	assert.Equal(t, u.(*orderUsecases).exchange, exchange)
+	assert.Equal(t, u.(*orderUsecases).tradeStorage, tradeStorage)
+	assert.Equal(t, u.(*orderUsecases).balanceStorage, balanceStorage)
 	assert.NotNil(t, u.(*orderUsecases).log)
 }
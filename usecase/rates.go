@@ -0,0 +1,94 @@
+package usecase
+
+import (
+	"time"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage"
+)
+
+// maxSyncRateAge is the age of trades and transfers still valued by current rates at sync,
+// older ones are saved without rates and valued by the history
+const maxSyncRateAge = time.Hour
+
+// maxRateDistance is the farthest distance from the middle of the day whose balance gives the rate of the time
+const maxRateDistance = 3 * 24 * time.Hour
+
+// dailyRates are daily balances of currencies, the latest day first.
+// Rates of a currency in the past are known from its balance of that day
+type dailyRates map[string][]domain.BalanceCandle
+
+// fetchDailyRates returns daily balances of the currencies in [from, to)
+func fetchDailyRates(balanceStorage storage.BalanceStorage, currencies []string, from, to time.Time) (dailyRates, error) {
+	rates := make(dailyRates)
+	for _, currency := range currencies {
+		if _, ok := rates[currency]; ok || currency == "USDT" {
+			continue
+		}
+
+		candles, err := balanceStorage.FetchRange(currency, from, to, 24*time.Hour)
+		if err != nil {
+			return nil, err
+		}
+		rates[currency] = candles
+	}
+	return rates, nil
+}
+
+// usdtRate returns USDT rate of the currency saved with the trade or the transfer at its sync. Trades and transfers
+// older than maxSyncRateAge at the sync are saved without rates, they're valued by the balance of the nearest day
+// to the time not farther than maxRateDistance. ok is false if neither is known, today's rate is never used for the past
+func (r dailyRates) usdtRate(currency string, t time.Time, saved float64) (rate float64, ok bool) {
+	if currency == "USDT" {
+		return 1, true
+	}
+	if saved > 0 {
+		return saved, true
+	}
+
+	candle, ok := r.nearest(currency, t)
+	if !ok {
+		return 0, false
+	}
+	return candle.USDTAmount.Close / candle.Amount.Close, true
+}
+
+// btcRate returns BTC rate of the currency the same way as usdtRate
+func (r dailyRates) btcRate(currency string, t time.Time, saved float64) (rate float64, ok bool) {
+	if currency == "BTC" {
+		return 1, true
+	}
+	if saved > 0 {
+		return saved, true
+	}
+
+	candle, ok := r.nearest(currency, t)
+	if !ok {
+		return 0, false
+	}
+	return candle.BTCAmount.Close / candle.Amount.Close, true
+}
+
+// nearest returns the non zero balance of the currency at the nearest day to the time within maxRateDistance
+func (r dailyRates) nearest(currency string, t time.Time) (result domain.BalanceCandle, ok bool) {
+	var distance time.Duration = -1
+	for _, candle := range r[currency] {
+		if candle.Amount.Close == 0 {
+			continue
+		}
+
+		d := t.Sub(candle.Time.Add(12 * time.Hour))
+		if d < 0 {
+			d = -d
+		}
+		if d > maxRateDistance {
+			continue
+		}
+		if distance < 0 || d < distance {
+			distance = d
+			result = candle
+			ok = true
+		}
+	}
+	return result, ok
+}
@@ -0,0 +1,144 @@
+package usecase
+
+import (
+	"strings"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage"
+	"github.com/nawa/cryptoexchange-dashboard/usecase/lots"
+)
+
+type TaxUsecases interface {
+	// Disposals of the year matched with lots bought before them by the method, the earliest first.
+	// Both legs of trades are booked: a buy disposes of the quote currency and a sell acquires it.
+	// Lots are tracked per currency of the account, prices are converted to USDT at the rate of the trade
+	GetDisposals(year int, method lots.Method) ([]domain.Disposal, error)
+}
+
+type taxUsecases struct {
+	tradeStorage   storage.TradeStorage
+	balanceStorage storage.BalanceStorage
+	log            *logrus.Entry
+}
+
+func NewTaxUsecase(tradeStorage storage.TradeStorage, balanceStorage storage.BalanceStorage) TaxUsecases {
+	log := logrus.WithField("component", "taxUC")
+	return &taxUsecases{
+		tradeStorage:   tradeStorage,
+		balanceStorage: balanceStorage,
+		log:            log,
+	}
+}
+
+func (u *taxUsecases) GetDisposals(year int, method lots.Method) ([]domain.Disposal, error) {
+	from := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(1, 0, 0)
+
+	// lots of the year can be bought years before
+	trades, err := u.tradeStorage.Fetch(time.Time{}, to)
+	if err != nil {
+		u.log.WithField("method", "GetDisposals").WithError(err).Error()
+		return nil, err
+	}
+
+	// the earliest first
+	for i, j := 0, len(trades)-1; i < j; i, j = i+1, j-1 {
+		trades[i], trades[j] = trades[j], trades[i]
+	}
+
+	rates, err := u.fetchUSDTRates(trades, to)
+	if err != nil {
+		u.log.WithField("method", "GetDisposals").WithError(err).Error()
+		return nil, err
+	}
+
+	result := []domain.Disposal{}
+	books := make(map[string]*lots.Book)
+	book := func(t domain.Trade, currency string) *lots.Book {
+		key := string(t.Exchange) + "/" + t.Account + "/" + currency
+		b, ok := books[key]
+		if !ok {
+			b = lots.NewBook(method)
+			books[key] = b
+		}
+		return b
+	}
+	dispose := func(t domain.Trade, currency string, amount, proceeds float64, unpriced bool) {
+		matched, unmatched := book(t, currency).Sell(amount)
+		if t.Time.Before(from) {
+			return
+		}
+
+		proceeds = proceeds * matched.Amount / amount
+		result = append(result, domain.Disposal{
+			Exchange:     t.Exchange,
+			Account:      t.Account,
+			OrderID:      t.OrderID,
+			Market:       t.Market,
+			Currency:     currency,
+			Acquired:     matched.Time,
+			Disposed:     t.Time,
+			Amount:       matched.Amount,
+			Unmatched:    unmatched,
+			CostUSDT:     matched.CostUSDT,
+			ProceedsUSDT: proceeds,
+			GainUSDT:     proceeds - matched.CostUSDT,
+			Unpriced:     unpriced || matched.Unpriced,
+		})
+	}
+
+	for _, t := range trades {
+		toFrom := strings.Split(t.Market, "-")
+		if len(toFrom) != 2 {
+			u.log.WithField("method", "GetDisposals").Warnf("market name can't be parsed to QUOTE-BASE format - %s", t.Market)
+			continue
+		}
+		quote, base := toFrom[0], toFrom[1]
+
+		usdtRate, ok := rates.usdtRate(quote, t.Time, t.USDTRate)
+		price := t.Amount * t.Rate
+		// the quote currency is the other leg of the trade, USDT is the currency of the report
+		if t.Type == domain.TradeTypeBuy {
+			book(t, base).Buy(lots.Lot{
+				Time:     t.Time,
+				Amount:   t.Amount,
+				Cost:     price,
+				Fee:      t.Fee,
+				CostUSDT: (price + t.Fee) * usdtRate,
+				Unpriced: !ok,
+			})
+			// the fee is already in the cost of bought coins
+			if quote != "USDT" {
+				dispose(t, quote, price+t.Fee, (price+t.Fee)*usdtRate, !ok)
+			}
+			continue
+		}
+
+		dispose(t, base, t.Amount, (price-t.Fee)*usdtRate, !ok)
+		if quote != "USDT" {
+			book(t, quote).Buy(lots.Lot{
+				Time:     t.Time,
+				Amount:   price - t.Fee,
+				Cost:     price - t.Fee,
+				CostUSDT: (price - t.Fee) * usdtRate,
+				Unpriced: !ok,
+			})
+		}
+	}
+	return result, nil
+}
+
+// fetchUSDTRates returns daily balances of quote currencies of trades
+func (u *taxUsecases) fetchUSDTRates(trades []domain.Trade, to time.Time) (dailyRates, error) {
+	if len(trades) == 0 {
+		return make(dailyRates), nil
+	}
+
+	var quotes []string
+	for _, t := range trades {
+		quotes = append(quotes, strings.Split(t.Market, "-")[0])
+	}
+	return fetchDailyRates(u.balanceStorage, quotes, trades[0].Time.AddDate(0, 0, -1), to)
+}
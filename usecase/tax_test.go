@@ -0,0 +1,148 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	assert "github.com/stretchr/testify/require"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage/memory"
+	"github.com/nawa/cryptoexchange-dashboard/storage/mocks"
+	"github.com/nawa/cryptoexchange-dashboard/usecase/lots"
+)
+
+func TestTaxUsecases_GetDisposals(t *testing.T) {
+	day := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 12, 0, 0, 0, time.UTC)
+	}
+
+	balanceStorage := memory.NewBalanceStorage()
+	assert.NoError(t, balanceStorage.Save(
+		domain.Balance{Currency: "BTC", Amount: 1, USDTAmount: 60000, Time: day(2024, 6, 1)},
+		domain.Balance{Currency: "BTC", Amount: 2, USDTAmount: 160000, Time: day(2025, 3, 1)},
+		domain.Balance{Currency: "BTC", Amount: 1, USDTAmount: 100000, Time: day(2025, 9, 1)},
+	))
+
+	tradeStorage := memory.NewTradeStorage()
+	assert.NoError(t, tradeStorage.Save(
+		domain.Trade{Exchange: domain.ExchangeTypeBinance, OrderID: "1", Market: "BTC-CUR1", Type: domain.TradeTypeBuy, Time: day(2024, 6, 1), Amount: 100, Rate: 0.001, Fee: 0.0001},
+		// sold the year before, but lots are spent
+		domain.Trade{Exchange: domain.ExchangeTypeBinance, OrderID: "2", Market: "BTC-CUR1", Type: domain.TradeTypeSell, Time: day(2024, 6, 2), Amount: 10, Rate: 0.002, Fee: 0.00002},
+		domain.Trade{Exchange: domain.ExchangeTypeBinance, OrderID: "3", Market: "BTC-CUR1", Type: domain.TradeTypeBuy, Time: day(2025, 3, 1), Amount: 100, Rate: 0.002, Fee: 0.0002},
+		// bought before the history
+		domain.Trade{Exchange: domain.ExchangeTypeBinance, OrderID: "4", Market: "USDT-CUR2", Type: domain.TradeTypeSell, Time: day(2025, 5, 1), Amount: 5, Rate: 2, Fee: 0.01},
+		// the rate saved at the sync is preferred to the daily balance
+		domain.Trade{Exchange: domain.ExchangeTypeBinance, OrderID: "5", Market: "BTC-CUR1", Type: domain.TradeTypeSell, Time: day(2025, 9, 1), Amount: 150, Rate: 0.003, Fee: 0.00045, USDTRate: 101000},
+		// the next year
+		domain.Trade{Exchange: domain.ExchangeTypeBinance, OrderID: "6", Market: "BTC-CUR1", Type: domain.TradeTypeSell, Time: day(2026, 1, 1), Amount: 10, Rate: 0.003, Fee: 0.00003},
+	))
+
+	tests := []struct {
+		method   lots.Method
+		acquired time.Time
+		cost     float64
+	}{
+		{
+			// 90 of the first lot and 60 of the second one
+			method:   lots.MethodFIFO,
+			acquired: day(2024, 6, 1),
+			cost:     0.1001*60000*0.9 + 0.2002*80000*0.6,
+		},
+		{
+			// the second lot and 50 of the first one
+			method:   lots.MethodLIFO,
+			acquired: day(2024, 6, 1),
+			cost:     0.2002*80000 + 0.1001*60000*0.5,
+		},
+		{
+			method:   lots.MethodAverage,
+			acquired: day(2024, 6, 1),
+			cost:     (0.1001*60000*0.9 + 0.2002*80000) * 150 / 190,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.method), func(t *testing.T) {
+			u := NewTaxUsecase(tradeStorage, balanceStorage)
+			disposals, err := u.GetDisposals(2025, tt.method)
+			assert.NoError(t, err)
+			assert.Len(t, disposals, 3)
+
+			// BTC spent on the buy is matched with BTC got by the sell of the year before,
+			// BTC spent on the first buy is unknown
+			btcCost := (0.02 - 0.00002) * 60000
+			btcProceeds := (0.02 - 0.00002) * 80000
+			assert.Equal(t, "3", disposals[0].OrderID)
+			assert.Equal(t, "BTC", disposals[0].Currency)
+			assert.Equal(t, day(2024, 6, 2), disposals[0].Acquired)
+			assert.InDelta(t, 0.01998, disposals[0].Amount, 1e-12)
+			assert.InDelta(t, 0.2002-0.01998, disposals[0].Unmatched, 1e-12)
+			assert.InDelta(t, btcCost, disposals[0].CostUSDT, 1e-6)
+			assert.InDelta(t, btcProceeds, disposals[0].ProceedsUSDT, 1e-6)
+			assert.InDelta(t, btcProceeds-btcCost, disposals[0].GainUSDT, 1e-6)
+
+			// USDT is the currency of the report, it isn't booked
+			assert.Equal(t, "4", disposals[1].OrderID)
+			assert.Equal(t, "CUR2", disposals[1].Currency)
+			assert.Zero(t, disposals[1].Amount)
+			assert.Equal(t, float64(5), disposals[1].Unmatched)
+			assert.Zero(t, disposals[1].GainUSDT)
+
+			proceeds := (0.45 - 0.00045) * 101000
+			assert.Equal(t, "5", disposals[2].OrderID)
+			assert.Equal(t, "CUR1", disposals[2].Currency)
+			assert.Equal(t, tt.acquired, disposals[2].Acquired)
+			assert.Equal(t, day(2025, 9, 1), disposals[2].Disposed)
+			assert.InDelta(t, 150, disposals[2].Amount, 1e-9)
+			assert.Zero(t, disposals[2].Unmatched)
+			assert.InDelta(t, tt.cost, disposals[2].CostUSDT, 1e-6)
+			assert.InDelta(t, proceeds, disposals[2].ProceedsUSDT, 1e-6)
+			assert.InDelta(t, proceeds-tt.cost, disposals[2].GainUSDT, 1e-6)
+		})
+	}
+}
+
+func TestTaxUsecases_GetDisposals_Unpriced(t *testing.T) {
+	day := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 12, 0, 0, 0, time.UTC)
+	}
+
+	// the balance is too far from the buy to give the rate of BTC
+	balanceStorage := memory.NewBalanceStorage()
+	assert.NoError(t, balanceStorage.Save(
+		domain.Balance{Currency: "BTC", Amount: 1, USDTAmount: 100000, Time: day(2025, 9, 1)},
+	))
+
+	tradeStorage := memory.NewTradeStorage()
+	assert.NoError(t, tradeStorage.Save(
+		domain.Trade{Exchange: domain.ExchangeTypeBinance, OrderID: "1", Market: "BTC-CUR1", Type: domain.TradeTypeBuy, Time: day(2025, 3, 1), Amount: 100, Rate: 0.001},
+		domain.Trade{Exchange: domain.ExchangeTypeBinance, OrderID: "2", Market: "BTC-CUR1", Type: domain.TradeTypeSell, Time: day(2025, 9, 2), Amount: 100, Rate: 0.002},
+	))
+
+	disposals, err := NewTaxUsecase(tradeStorage, balanceStorage).GetDisposals(2025, lots.MethodFIFO)
+	assert.NoError(t, err)
+	assert.Len(t, disposals, 2)
+
+	assert.Equal(t, "BTC", disposals[0].Currency)
+	assert.True(t, disposals[0].Unpriced)
+	assert.Zero(t, disposals[0].ProceedsUSDT)
+
+	// proceeds are priced by the balance of the day before, the cost is unknown
+	assert.Equal(t, "CUR1", disposals[1].Currency)
+	assert.True(t, disposals[1].Unpriced)
+	assert.Zero(t, disposals[1].CostUSDT)
+	assert.InDelta(t, 0.2*100000, disposals[1].ProceedsUSDT, 1e-6)
+}
+
+func TestTaxUsecases_GetDisposals_Error(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tradeStorage := mocks.NewMockTradeStorage(ctrl)
+	u := NewTaxUsecase(tradeStorage, mocks.NewMockBalanceStorage(ctrl))
+
+	tradeStorage.EXPECT().Fetch(time.Time{}, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)).Return(nil, errExpected)
+	_, err := u.GetDisposals(2025, lots.MethodFIFO)
+	assert.Equal(t, errExpected, err)
+}
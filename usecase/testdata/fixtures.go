@@ -28,7 +28,6 @@ func Balances() []domain.Balance {
 
 func BalancesWithTotal() []domain.Balance {
 	return append(Balances(), domain.Balance{
-		Exchange:   domain.ExchangeTypeBittrex,
 		Currency:   "total",
 		Amount:     0,
 		BTCAmount:  700,
@@ -37,6 +36,89 @@ func BalancesWithTotal() []domain.Balance {
 	})
 }
 
+func MultiExchangeBalances() []domain.Balance {
+	return []domain.Balance{
+		{
+			Exchange:   domain.ExchangeTypeBittrex,
+			Account:    "main",
+			Currency:   "BTC",
+			Amount:     1,
+			BTCAmount:  1,
+			USDTAmount: 10,
+			Time:       time.Unix(0, 0).UTC(),
+		}, {
+			Exchange:   domain.ExchangeTypeBinance,
+			Account:    "trading",
+			Currency:   "BTC",
+			Amount:     2,
+			BTCAmount:  2,
+			USDTAmount: 20,
+			Time:       time.Unix(0, 0).UTC(),
+		}, {
+			Exchange:   domain.ExchangeTypeBittrex,
+			Account:    "savings",
+			Currency:   "CUR1",
+			Amount:     100,
+			BTCAmount:  3,
+			USDTAmount: 30,
+			Time:       time.Unix(0, 0).UTC(),
+		},
+	}
+}
+
+// MultiExchangeBalancesWithTotal are MultiExchangeBalances synced from accounts of both exchanges with totals
+// of coins over accounts and totals of exchanges
+func MultiExchangeBalancesWithTotal() []domain.Balance {
+	return append(MultiExchangeBalances(), domain.Balance{
+		Currency:   "total-BTC",
+		Amount:     3,
+		BTCAmount:  3,
+		USDTAmount: 30,
+		Time:       time.Unix(0, 0).UTC(),
+	}, domain.Balance{
+		Currency:   "total-CUR1",
+		Amount:     100,
+		BTCAmount:  3,
+		USDTAmount: 30,
+		Time:       time.Unix(0, 0).UTC(),
+	}, domain.Balance{
+		Exchange:   domain.ExchangeTypeBittrex,
+		Currency:   "total-bittrex",
+		BTCAmount:  4,
+		USDTAmount: 40,
+		Time:       time.Unix(0, 0).UTC(),
+	}, domain.Balance{
+		Exchange:   domain.ExchangeTypeBinance,
+		Currency:   "total-binance",
+		BTCAmount:  2,
+		USDTAmount: 20,
+		Time:       time.Unix(0, 0).UTC(),
+	}, domain.Balance{
+		Currency:   "total",
+		BTCAmount:  6,
+		USDTAmount: 60,
+		Time:       time.Unix(0, 0).UTC(),
+	})
+}
+
+func BalanceCandles() []domain.BalanceCandle {
+	return []domain.BalanceCandle{
+		{
+			Currency:   "CUR1",
+			Time:       time.Unix(0, 0).UTC().Add(time.Hour),
+			Amount:     domain.OHLC{Open: 100, Close: 110, Min: 90, Max: 120},
+			BTCAmount:  domain.OHLC{Open: 200, Close: 220, Min: 180, Max: 240},
+			USDTAmount: domain.OHLC{Open: 300, Close: 330, Min: 270, Max: 360},
+		}, {
+			Currency:   "CUR1",
+			Time:       time.Unix(0, 0).UTC(),
+			Amount:     domain.OHLC{Open: 100, Close: 100, Min: 100, Max: 100},
+			BTCAmount:  domain.OHLC{Open: 200, Close: 200, Min: 200, Max: 200},
+			USDTAmount: domain.OHLC{Open: 300, Close: 300, Min: 300, Max: 300},
+		},
+	}
+}
+
 func Orders() []domain.Order {
 	return []domain.Order{
 		{
@@ -55,3 +137,54 @@ func Orders() []domain.Order {
 		},
 	}
 }
+
+func Trades() []domain.Trade {
+	return []domain.Trade{
+		{
+			Exchange: domain.ExchangeTypeBittrex,
+			OrderID:  "uuid-2",
+			Market:   "BTC-CUR1",
+			Type:     domain.TradeTypeSell,
+			Time:     time.Unix(0, 0).UTC().Add(time.Hour),
+			Amount:   50,
+			Rate:     0.02,
+			Fee:      0.0025,
+		},
+		{
+			Exchange: domain.ExchangeTypeBittrex,
+			OrderID:  "uuid-1",
+			Market:   "BTC-CUR1",
+			Type:     domain.TradeTypeBuy,
+			Time:     time.Unix(0, 0).UTC(),
+			Amount:   100,
+			Rate:     0.01,
+			Fee:      0.0025,
+		},
+	}
+}
+
+func Transfers() []domain.Transfer {
+	return []domain.Transfer{
+		{
+			Exchange: domain.ExchangeTypeBittrex,
+			ID:       "uuid-w1",
+			Type:     domain.TransferTypeWithdrawal,
+			Currency: "BTC",
+			Time:     time.Unix(0, 0).UTC().Add(time.Hour),
+			Amount:   0.5,
+			Fee:      0.001,
+			BTCRate:  1,
+			USDTRate: 10000,
+		},
+		{
+			Exchange: domain.ExchangeTypeBittrex,
+			ID:       "1",
+			Type:     domain.TransferTypeDeposit,
+			Currency: "BTC",
+			Time:     time.Unix(0, 0).UTC(),
+			Amount:   1,
+			BTCRate:  1,
+			USDTRate: 10000,
+		},
+	}
+}
@@ -0,0 +1,113 @@
+package usecase
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage"
+	"github.com/nawa/cryptoexchange-dashboard/usecase/ticker"
+)
+
+// fullTradeScanPeriod is how often trades are looked for in all markets of exchanges returning them per market
+// to find coins bought and sold out between syncs
+const fullTradeScanPeriod = 24 * time.Hour
+
+type TradeUsecases interface {
+	StartSyncFromExchangePeriodically(period time.Duration) (stop func(), err error)
+	// Saves the trade history of the exchange, already saved trades are updated except their rates.
+	// Exchanges returning trades per market are scanned fully by the first sync and then every fullTradeScanPeriod
+	SyncFromExchange() error
+	// Saved trades in [from, to), the latest first. Zero to means no upper bound
+	FetchTrades(from, to time.Time) ([]domain.Trade, error)
+}
+
+type tradeUsecases struct {
+	exchange     storage.Exchange
+	tradeStorage storage.TradeStorage
+	log          *logrus.Entry
+
+	scanLock sync.Mutex
+	lastScan time.Time
+}
+
+func NewTradeUsecase(exchange storage.Exchange, tradeStorage storage.TradeStorage) TradeUsecases {
+	log := logrus.WithField("component", "tradeUC")
+	return &tradeUsecases{
+		exchange:     exchange,
+		tradeStorage: tradeStorage,
+		log:          log,
+	}
+}
+
+func (u *tradeUsecases) StartSyncFromExchangePeriodically(period time.Duration) (stop func(), err error) {
+	ticker := ticker.NewTicker(period, u.SyncFromExchange)
+	err = ticker.Start()
+	if err != nil {
+		return nil, err
+	}
+
+	return func() {
+		ticker.Stop()
+	}, err
+}
+
+func (u *tradeUsecases) SyncFromExchange() error {
+	trades, err := u.getTrades()
+	if err != nil {
+		u.log.WithField("method", "SyncFromExchange").WithError(err).Error()
+		return err
+	}
+
+	if len(trades) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	for i := range trades {
+		if now.Sub(trades[i].Time) > maxSyncRateAge {
+			trades[i].BTCRate, trades[i].USDTRate = 0, 0
+		}
+	}
+
+	err = u.tradeStorage.Save(trades...)
+	if err != nil {
+		u.log.WithField("method", "SyncFromExchange").WithError(err).Error()
+		return err
+	}
+
+	u.log.WithField("method", "SyncFromExchange").Debugf("%d trades saved", len(trades))
+	return nil
+}
+
+// getTrades scans all markets if the exchange supports it and the last scan is older than fullTradeScanPeriod
+func (u *tradeUsecases) getTrades() ([]domain.Trade, error) {
+	scanner, ok := u.exchange.(storage.TradeScanner)
+	if !ok {
+		return u.exchange.GetTrades()
+	}
+
+	u.scanLock.Lock()
+	defer u.scanLock.Unlock()
+
+	if time.Since(u.lastScan) < fullTradeScanPeriod {
+		return u.exchange.GetTrades()
+	}
+	trades, err := scanner.ScanTrades()
+	if err != nil {
+		return nil, err
+	}
+	u.lastScan = time.Now()
+	return trades, nil
+}
+
+func (u *tradeUsecases) FetchTrades(from, to time.Time) ([]domain.Trade, error) {
+	trades, err := u.tradeStorage.Fetch(from, to)
+	if err != nil {
+		u.log.WithField("method", "FetchTrades").WithError(err).Error()
+		return nil, err
+	}
+
+	return trades, nil
+}
@@ -0,0 +1,240 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/golang/mock/gomock"
+	assert "github.com/stretchr/testify/require"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage"
+	"github.com/nawa/cryptoexchange-dashboard/storage/memory"
+	"github.com/nawa/cryptoexchange-dashboard/storage/mocks"
+	"github.com/nawa/cryptoexchange-dashboard/usecase/testdata"
+	"github.com/nawa/cryptoexchange-dashboard/utils"
+)
+
+func TestTradeUsecases_SyncFromExchange(t *testing.T) {
+	type fields struct {
+		exchange     storage.Exchange
+		tradeStorage storage.TradeStorage
+		log          *logrus.Entry
+	}
+	tests := []struct {
+		name    string
+		fieldsF func(ctrl *gomock.Controller) fields
+		wantErr bool
+	}{
+		{
+			name: "correct",
+			fieldsF: func(ctrl *gomock.Controller) fields {
+				tradeStorage := mocks.NewMockTradeStorage(ctrl)
+				exchange := mocks.NewMockExchange(ctrl)
+
+				exchange.EXPECT().GetTrades().
+					Return(testdata.Trades(), nil).
+					Times(1)
+
+				tradeStorage.EXPECT().
+					Save(testdata.Trades()[0], testdata.Trades()[1]).
+					Return(nil).
+					Times(1)
+
+				return fields{
+					exchange:     exchange,
+					tradeStorage: tradeStorage,
+					log:          utils.NewDevNullLog(),
+				}
+			},
+			wantErr: false,
+		},
+		{
+			name: "correct with no trades",
+			fieldsF: func(ctrl *gomock.Controller) fields {
+				tradeStorage := mocks.NewMockTradeStorage(ctrl)
+				exchange := mocks.NewMockExchange(ctrl)
+
+				exchange.EXPECT().GetTrades().
+					Return([]domain.Trade{}, nil).
+					Times(1)
+
+				return fields{
+					exchange:     exchange,
+					tradeStorage: tradeStorage,
+					log:          utils.NewDevNullLog(),
+				}
+			},
+			wantErr: false,
+		},
+		{
+			name: "error in exchange",
+			fieldsF: func(ctrl *gomock.Controller) fields {
+				tradeStorage := mocks.NewMockTradeStorage(ctrl)
+				exchange := mocks.NewMockExchange(ctrl)
+
+				exchange.EXPECT().GetTrades().
+					Return(nil, errExpected).
+					Times(1)
+
+				return fields{
+					exchange:     exchange,
+					tradeStorage: tradeStorage,
+					log:          utils.NewDevNullLog(),
+				}
+			},
+			wantErr: true,
+		},
+		{
+			name: "error in storage",
+			fieldsF: func(ctrl *gomock.Controller) fields {
+				tradeStorage := mocks.NewMockTradeStorage(ctrl)
+				exchange := mocks.NewMockExchange(ctrl)
+
+				exchange.EXPECT().GetTrades().
+					Return(testdata.Trades(), nil).
+					Times(1)
+
+				tradeStorage.EXPECT().
+					Save(gomock.Any(), gomock.Any()).
+					Return(errExpected).
+					Times(1)
+
+				return fields{
+					exchange:     exchange,
+					tradeStorage: tradeStorage,
+					log:          utils.NewDevNullLog(),
+				}
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			fields := tt.fieldsF(ctrl)
+			u := &tradeUsecases{
+				exchange:     fields.exchange,
+				tradeStorage: fields.tradeStorage,
+				log:          fields.log,
+			}
+			err := u.SyncFromExchange()
+			if err != nil {
+				if !tt.wantErr {
+					t.Errorf("tradeUsecases.SyncFromExchange() error = %v, wantErr %v", err, tt.wantErr)
+				} else if err != errExpected {
+					t.Errorf("tradeUsecases.SyncFromExchange() error = %v, expected error %v", err, errExpected)
+				}
+				return
+			}
+			if tt.wantErr {
+				t.Errorf("tradeUsecases.SyncFromExchange() error is expected")
+			}
+		})
+	}
+}
+
+func TestTradeUsecases_SyncFromExchange_Storage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	exchange := mocks.NewMockExchange(ctrl)
+	u := NewTradeUsecase(exchange, memory.NewTradeStorage())
+
+	// the exchange stops returning the old trade, but it is kept
+	exchange.EXPECT().GetTrades().Return(testdata.Trades(), nil)
+	assert.NoError(t, u.SyncFromExchange())
+	exchange.EXPECT().GetTrades().Return(testdata.Trades()[:1], nil)
+	assert.NoError(t, u.SyncFromExchange())
+
+	trades, err := u.FetchTrades(time.Time{}, time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, testdata.Trades(), trades)
+
+	trades, err = u.FetchTrades(time.Unix(0, 0), time.Unix(0, 0).Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, testdata.Trades()[1:], trades)
+}
+
+func TestTradeUsecases_SyncFromExchange_Rates(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	exchange := mocks.NewMockExchange(ctrl)
+	u := NewTradeUsecase(exchange, memory.NewTradeStorage())
+
+	// current rates are kept for the recent trade only
+	now := time.Now().UTC()
+	synced := testdata.Trades()
+	synced[0].Time = now.Add(-time.Minute)
+	for i := range synced {
+		synced[i].BTCRate, synced[i].USDTRate = 1, 10000
+	}
+	exchange.EXPECT().GetTrades().Return(synced, nil)
+	assert.NoError(t, u.SyncFromExchange())
+
+	// the next sync doesn't change rates of saved trades
+	resynced := testdata.Trades()
+	resynced[0].Time = synced[0].Time
+	for i := range resynced {
+		resynced[i].BTCRate, resynced[i].USDTRate = 1, 20000
+	}
+	exchange.EXPECT().GetTrades().Return(resynced, nil)
+	assert.NoError(t, u.SyncFromExchange())
+
+	trades, err := u.FetchTrades(time.Time{}, time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, trades, 2)
+	assert.Equal(t, 10000.0, trades[0].USDTRate)
+	assert.Equal(t, 0.0, trades[1].USDTRate)
+	assert.Equal(t, 0.0, trades[1].BTCRate)
+}
+
+func TestTradeUsecases_FetchTrades_Error(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tradeStorage := mocks.NewMockTradeStorage(ctrl)
+	u := NewTradeUsecase(mocks.NewMockExchange(ctrl), tradeStorage)
+
+	tradeStorage.EXPECT().Fetch(time.Time{}, time.Time{}).Return(nil, errExpected)
+	_, err := u.FetchTrades(time.Time{}, time.Time{})
+	assert.Equal(t, errExpected, err)
+}
+
+type scanningExchange struct {
+	*mocks.MockExchange
+	*mocks.MockTradeScanner
+}
+
+func TestTradeUsecases_SyncFromExchange_Scan(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	exchange := mocks.NewMockExchange(ctrl)
+	scanner := mocks.NewMockTradeScanner(ctrl)
+	tradeStorage := memory.NewTradeStorage()
+	u := NewTradeUsecase(scanningExchange{exchange, scanner}, tradeStorage)
+
+	// the failed scan is repeated by the next sync
+	scanner.EXPECT().ScanTrades().Return(nil, errExpected)
+	assert.Equal(t, errExpected, u.SyncFromExchange())
+
+	scanner.EXPECT().ScanTrades().Return(testdata.Trades()[:1], nil)
+	assert.NoError(t, u.SyncFromExchange())
+
+	// only markets of held coins and traded before are looked through until the next scan
+	exchange.EXPECT().GetTrades().Return(testdata.Trades()[1:2], nil)
+	assert.NoError(t, u.SyncFromExchange())
+
+	trades, err := tradeStorage.Fetch(time.Time{}, time.Time{})
+	assert.NoError(t, err)
+	assert.Len(t, trades, 2)
+
+	u.(*tradeUsecases).lastScan = time.Now().Add(-fullTradeScanPeriod)
+	scanner.EXPECT().ScanTrades().Return(nil, nil)
+	assert.NoError(t, u.SyncFromExchange())
+}
@@ -0,0 +1,83 @@
+package usecase
+
+import (
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage"
+	"github.com/nawa/cryptoexchange-dashboard/usecase/ticker"
+)
+
+type TransferUsecases interface {
+	StartSyncFromExchangePeriodically(period time.Duration) (stop func(), err error)
+	// Saves deposits and withdrawals of the exchange, already saved transfers are updated
+	SyncFromExchange() error
+	// Saved transfers in [from, to), the latest first. Zero to means no upper bound
+	FetchTransfers(from, to time.Time) ([]domain.Transfer, error)
+}
+
+type transferUsecases struct {
+	exchange        storage.Exchange
+	transferStorage storage.TransferStorage
+	log             *logrus.Entry
+}
+
+func NewTransferUsecase(exchange storage.Exchange, transferStorage storage.TransferStorage) TransferUsecases {
+	log := logrus.WithField("component", "transferUC")
+	return &transferUsecases{
+		exchange:        exchange,
+		transferStorage: transferStorage,
+		log:             log,
+	}
+}
+
+func (u *transferUsecases) StartSyncFromExchangePeriodically(period time.Duration) (stop func(), err error) {
+	ticker := ticker.NewTicker(period, u.SyncFromExchange)
+	err = ticker.Start()
+	if err != nil {
+		return nil, err
+	}
+
+	return func() {
+		ticker.Stop()
+	}, err
+}
+
+func (u *transferUsecases) SyncFromExchange() error {
+	transfers, err := u.exchange.GetTransfers()
+	if err != nil {
+		u.log.WithField("method", "SyncFromExchange").WithError(err).Error()
+		return err
+	}
+
+	if len(transfers) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	for i := range transfers {
+		if now.Sub(transfers[i].Time) > maxSyncRateAge {
+			transfers[i].BTCRate, transfers[i].USDTRate = 0, 0
+		}
+	}
+
+	err = u.transferStorage.Save(transfers...)
+	if err != nil {
+		u.log.WithField("method", "SyncFromExchange").WithError(err).Error()
+		return err
+	}
+
+	u.log.WithField("method", "SyncFromExchange").Debugf("%d transfers saved", len(transfers))
+	return nil
+}
+
+func (u *transferUsecases) FetchTransfers(from, to time.Time) ([]domain.Transfer, error) {
+	transfers, err := u.transferStorage.Fetch(from, to)
+	if err != nil {
+		u.log.WithField("method", "FetchTransfers").WithError(err).Error()
+		return nil, err
+	}
+
+	return transfers, nil
+}
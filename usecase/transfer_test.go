@@ -0,0 +1,88 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	assert "github.com/stretchr/testify/require"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/storage/memory"
+	"github.com/nawa/cryptoexchange-dashboard/storage/mocks"
+	"github.com/nawa/cryptoexchange-dashboard/usecase/testdata"
+)
+
+func TestTransferUsecases_SyncFromExchange(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	exchange := mocks.NewMockExchange(ctrl)
+	u := NewTransferUsecase(exchange, memory.NewTransferStorage())
+
+	// the exchange stops returning the old transfer, but it is kept
+	exchange.EXPECT().GetTransfers().Return(testdata.Transfers(), nil)
+	assert.NoError(t, u.SyncFromExchange())
+	exchange.EXPECT().GetTransfers().Return(testdata.Transfers()[:1], nil)
+	assert.NoError(t, u.SyncFromExchange())
+	exchange.EXPECT().GetTransfers().Return([]domain.Transfer{}, nil)
+	assert.NoError(t, u.SyncFromExchange())
+
+	// current rates aren't kept for old transfers
+	want := testdata.Transfers()
+	for i := range want {
+		want[i].BTCRate, want[i].USDTRate = 0, 0
+	}
+
+	transfers, err := u.FetchTransfers(time.Time{}, time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, want, transfers)
+
+	transfers, err = u.FetchTransfers(time.Unix(0, 0), time.Unix(0, 0).Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, want[1:], transfers)
+}
+
+func TestTransferUsecases_SyncFromExchange_Rates(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	exchange := mocks.NewMockExchange(ctrl)
+	u := NewTransferUsecase(exchange, memory.NewTransferStorage())
+
+	recent := testdata.Transfers()[:1]
+	recent[0].Time = time.Now().UTC().Add(-time.Minute)
+	exchange.EXPECT().GetTransfers().Return(recent, nil)
+	assert.NoError(t, u.SyncFromExchange())
+
+	// the next sync doesn't change rates of the saved transfer
+	resynced := testdata.Transfers()[:1]
+	resynced[0].Time = recent[0].Time
+	resynced[0].USDTRate = 20000
+	exchange.EXPECT().GetTransfers().Return(resynced, nil)
+	assert.NoError(t, u.SyncFromExchange())
+
+	transfers, err := u.FetchTransfers(time.Time{}, time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, recent, transfers)
+}
+
+func TestTransferUsecases_Error(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	exchange := mocks.NewMockExchange(ctrl)
+	transferStorage := mocks.NewMockTransferStorage(ctrl)
+	u := NewTransferUsecase(exchange, transferStorage)
+
+	exchange.EXPECT().GetTransfers().Return(nil, errExpected)
+	assert.Equal(t, errExpected, u.SyncFromExchange())
+
+	exchange.EXPECT().GetTransfers().Return(testdata.Transfers(), nil)
+	transferStorage.EXPECT().Save(gomock.Any(), gomock.Any()).Return(errExpected)
+	assert.Equal(t, errExpected, u.SyncFromExchange())
+
+	transferStorage.EXPECT().Fetch(time.Time{}, time.Time{}).Return(nil, errExpected)
+	_, err := u.FetchTransfers(time.Time{}, time.Time{})
+	assert.Equal(t, errExpected, err)
+}
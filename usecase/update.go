@@ -0,0 +1,164 @@
+package usecase
+
+import (
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/hashicorp/go-multierror"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/usecase/ticker"
+)
+
+// updatesBuffer is the number of updates a slow subscriber can lag behind before updates are dropped
+const updatesBuffer = 16
+
+type UpdateUsecases interface {
+	StartWatchingPeriodically(period time.Duration) (stop func(), err error)
+	// Publishes the latest balance snapshot and the profit of orders if they have changed since the last check.
+	// The snapshot can be written by the sync loop of another process. The profit requests orders from the exchange,
+	// so it's checked only while there are subscribers
+	CheckUpdates() error
+	// Channel of all published updates, it is closed by cancel
+	Subscribe() (updates <-chan domain.Update, cancel func())
+}
+
+type updateUsecases struct {
+	balanceUsecase BalanceUsecases
+	orderUsecase   OrderUsecases
+	log            *logrus.Entry
+
+	lock        sync.Mutex
+	subscribers map[chan domain.Update]bool
+	balanceTime time.Time
+	pnl         *domain.PnL
+}
+
+func NewUpdateUsecase(balanceUsecase BalanceUsecases, orderUsecase OrderUsecases) UpdateUsecases {
+	log := logrus.WithField("component", "updateUC")
+	return &updateUsecases{
+		balanceUsecase: balanceUsecase,
+		orderUsecase:   orderUsecase,
+		log:            log,
+		subscribers:    make(map[chan domain.Update]bool),
+	}
+}
+
+func (u *updateUsecases) StartWatchingPeriodically(period time.Duration) (stop func(), err error) {
+	ticker := ticker.NewTicker(period, u.CheckUpdates)
+	err = ticker.Start()
+	if err != nil {
+		return nil, err
+	}
+
+	return func() {
+		ticker.Stop()
+	}, err
+}
+
+func (u *updateUsecases) CheckUpdates() error {
+	var result error
+
+	balances, err := u.balanceUsecase.GetActiveCurrencies()
+	if err != nil {
+		result = multierror.Append(result, err)
+	} else if snapshotTime := latestTime(balances); snapshotTime.After(u.lastBalanceTime()) {
+		u.lock.Lock()
+		u.balanceTime = snapshotTime
+		u.lock.Unlock()
+
+		u.publish(domain.Update{
+			Type:     domain.UpdateTypeBalance,
+			Balances: balances,
+		})
+	}
+
+	if !u.hasSubscribers() {
+		// the profit is published to the next subscriber even if it hasn't changed
+		u.lock.Lock()
+		u.pnl = nil
+		u.lock.Unlock()
+		return u.logResult(result)
+	}
+
+	pnl, err := u.orderUsecase.GetPnL()
+	if err != nil {
+		result = multierror.Append(result, err)
+	} else {
+		u.lock.Lock()
+		changed := !reflect.DeepEqual(u.pnl, pnl)
+		u.pnl = pnl
+		u.lock.Unlock()
+
+		if changed {
+			u.publish(domain.Update{
+				Type: domain.UpdateTypePnL,
+				PnL:  pnl,
+			})
+		}
+	}
+
+	return u.logResult(result)
+}
+
+func (u *updateUsecases) logResult(result error) error {
+	if result != nil {
+		u.log.WithField("method", "CheckUpdates").WithError(result).Error()
+	}
+	return result
+}
+
+func (u *updateUsecases) hasSubscribers() bool {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	return len(u.subscribers) > 0
+}
+
+func (u *updateUsecases) Subscribe() (<-chan domain.Update, func()) {
+	ch := make(chan domain.Update, updatesBuffer)
+
+	u.lock.Lock()
+	u.subscribers[ch] = true
+	u.lock.Unlock()
+
+	return ch, func() {
+		u.lock.Lock()
+		defer u.lock.Unlock()
+
+		if u.subscribers[ch] {
+			delete(u.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+func (u *updateUsecases) lastBalanceTime() time.Time {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	return u.balanceTime
+}
+
+func latestTime(balances []domain.Balance) time.Time {
+	var result time.Time
+	for _, b := range balances {
+		if b.Time.After(result) {
+			result = b.Time
+		}
+	}
+	return result
+}
+
+func (u *updateUsecases) publish(update domain.Update) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	for ch := range u.subscribers {
+		select {
+		case ch <- update:
+		default:
+			u.log.WithField("method", "publish").Warnf("subscriber is too slow, %s update is dropped", update.Type)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	assert "github.com/stretchr/testify/require"
+
+	"github.com/nawa/cryptoexchange-dashboard/domain"
+	"github.com/nawa/cryptoexchange-dashboard/usecase/mocks"
+	"github.com/nawa/cryptoexchange-dashboard/usecase/testdata"
+)
+
+func TestUpdateUsecases_CheckUpdates(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	balanceUC := mocks.NewMockBalanceUsecases(ctrl)
+	orderUC := mocks.NewMockOrderUsecases(ctrl)
+	u := NewUpdateUsecase(balanceUC, orderUC)
+
+	updates, cancel := u.Subscribe()
+
+	pnl := &domain.PnL{UnrealizedBTC: 1}
+	balanceUC.EXPECT().GetActiveCurrencies().Return(testdata.Balances(), nil)
+	orderUC.EXPECT().GetPnL().Return(pnl, nil)
+
+	assert.NoError(t, u.CheckUpdates())
+	assert.Equal(t, domain.Update{Type: domain.UpdateTypeBalance, Balances: testdata.Balances()}, <-updates)
+	assert.Equal(t, domain.Update{Type: domain.UpdateTypePnL, PnL: pnl}, <-updates)
+
+	// nothing has changed
+	balanceUC.EXPECT().GetActiveCurrencies().Return(testdata.Balances(), nil)
+	orderUC.EXPECT().GetPnL().Return(&domain.PnL{UnrealizedBTC: 1}, nil)
+
+	assert.NoError(t, u.CheckUpdates())
+	assert.Len(t, updates, 0)
+
+	// the new snapshot and the same profit
+	newBalances := testdata.Balances()
+	for i := range newBalances {
+		newBalances[i].Time = newBalances[i].Time.Add(time.Hour)
+	}
+	balanceUC.EXPECT().GetActiveCurrencies().Return(newBalances, nil)
+	orderUC.EXPECT().GetPnL().Return(&domain.PnL{UnrealizedBTC: 1}, nil)
+
+	assert.NoError(t, u.CheckUpdates())
+	assert.Equal(t, domain.Update{Type: domain.UpdateTypeBalance, Balances: newBalances}, <-updates)
+	assert.Len(t, updates, 0)
+
+	// the profit is published even if balances fail
+	balanceUC.EXPECT().GetActiveCurrencies().Return(nil, errExpected)
+	orderUC.EXPECT().GetPnL().Return(&domain.PnL{UnrealizedBTC: 2}, nil)
+
+	assert.Error(t, u.CheckUpdates())
+	assert.Equal(t, domain.Update{Type: domain.UpdateTypePnL, PnL: &domain.PnL{UnrealizedBTC: 2}}, <-updates)
+
+	cancel()
+	_, ok := <-updates
+	assert.False(t, ok)
+	// repeated cancel is ignored
+	cancel()
+
+	// the profit isn't requested without subscribers
+	balanceUC.EXPECT().GetActiveCurrencies().Return(newBalances, nil)
+	assert.NoError(t, u.CheckUpdates())
+
+	// the next subscriber gets the same profit
+	updates, cancel = u.Subscribe()
+	defer cancel()
+	balanceUC.EXPECT().GetActiveCurrencies().Return(newBalances, nil)
+	orderUC.EXPECT().GetPnL().Return(&domain.PnL{UnrealizedBTC: 2}, nil)
+	assert.NoError(t, u.CheckUpdates())
+	assert.Equal(t, domain.Update{Type: domain.UpdateTypePnL, PnL: &domain.PnL{UnrealizedBTC: 2}}, <-updates)
+}
+
+func TestUpdateUsecases_SlowSubscriber(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	balanceUC := mocks.NewMockBalanceUsecases(ctrl)
+	orderUC := mocks.NewMockOrderUsecases(ctrl)
+	u := NewUpdateUsecase(balanceUC, orderUC)
+
+	updates, cancel := u.Subscribe()
+	defer cancel()
+
+	for i := 0; i < updatesBuffer+1; i++ {
+		balanceUC.EXPECT().GetActiveCurrencies().Return(nil, errExpected)
+		orderUC.EXPECT().GetPnL().Return(&domain.PnL{UnrealizedBTC: float64(i)}, nil)
+		assert.Error(t, u.CheckUpdates())
+	}
+	assert.Len(t, updates, updatesBuffer)
+}